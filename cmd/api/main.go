@@ -1,27 +0,0 @@
-package main
-
-import (
-	"bookwise/internal/api"
-	"bookwise/internal/config"
-)
-
-func main() {
-	c := config.New()
-	var cfg config.Config
-
-	cfg.Port = c.Server.Port
-	cfg.Env = "development"
-	cfg.DB.DSN = c.DB.DSN
-	cfg.DB.MaxOpenConns = c.DB.MaxOpenConns
-	cfg.DB.MaxIdleConns = c.DB.MaxIdleConns
-	cfg.DB.MaxIdleTime = c.DB.MaxIdleTime
-	cfg.Limiter.RPS = c.RateLimiter.RPS
-	cfg.Limiter.Burst = c.RateLimiter.Burst
-	cfg.Limiter.Enabled = c.RateLimiter.Enabled
-
-	app := api.NewApp(cfg)
-	err := app.Server()
-	if err != nil {
-		app.Logger.PrintFatal(err, nil)
-	}
-}
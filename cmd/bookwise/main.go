@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bookwise/internal/api"
+	"bookwise/internal/config"
+	"os"
+)
+
+func main() {
+	c := config.New()
+	var cfg config.Config
+
+	cfg.Port = c.Server.Port
+	cfg.Env = "development"
+	cfg.DB.DSN = c.DB.DSN
+	cfg.DB.MaxOpenConns = c.DB.MaxOpenConns
+	cfg.DB.MaxIdleConns = c.DB.MaxIdleConns
+	cfg.DB.MaxIdleTime = c.DB.MaxIdleTime
+	cfg.Limiter.RPS = c.RateLimiter.RPS
+	cfg.Limiter.Burst = c.RateLimiter.Burst
+	cfg.Limiter.Enabled = c.RateLimiter.Enabled
+	cfg.Security.SecretKey = c.Security.SecretKey
+	cfg.Password.Memory = c.Password.Memory
+	cfg.Password.Iterations = c.Password.Iterations
+	cfg.Password.Parallelism = c.Password.Parallelism
+	cfg.Password.SaltLength = c.Password.SaltLength
+	cfg.Password.KeyLength = c.Password.KeyLength
+	cfg.Phone.DefaultCountryCode = c.Phone.DefaultCountryCode
+	cfg.Metadata.DumpPath = c.Metadata.DumpPath
+	cfg.Metadata.CacheTTL = c.Metadata.CacheTTL
+	cfg.Storage.Driver = c.Storage.Driver
+	cfg.Storage.LocalPath = c.Storage.LocalPath
+	cfg.Storage.S3.Endpoint = c.Storage.S3Endpoint
+	cfg.Storage.S3.Region = c.Storage.S3Region
+	cfg.Storage.S3.Bucket = c.Storage.S3Bucket
+	cfg.Storage.S3.AccessKey = c.Storage.S3AccessKey
+	cfg.Storage.S3.SecretKey = c.Storage.S3SecretKey
+	cfg.Storage.S3.PathStyle = c.Storage.S3PathStyle
+	cfg.Covers.MaxBytes = c.Covers.MaxBytes
+	cfg.Covers.ThumbnailWidth = c.Covers.ThumbnailWidth
+	cfg.Covers.ThumbnailHeight = c.Covers.ThumbnailHeight
+	cfg.Trash.Retention = c.Trash.Retention
+	cfg.Trash.PurgeInterval = c.Trash.PurgeInterval
+	cfg.Imports.MaxBytes = c.Imports.MaxBytes
+	cfg.Imports.PollInterval = c.Imports.PollInterval
+	cfg.Files.MaxBytes = c.Files.MaxBytes
+	cfg.Files.UploadRetention = c.Files.UploadRetention
+	cfg.Loans.ReminderInterval = c.Loans.ReminderInterval
+	cfg.App.URL = c.App.URL
+
+	// Commands write their report to stdout, so their logs go to stderr.
+	logs := os.Stdout
+	if len(os.Args) > 1 {
+		logs = os.Stderr
+	}
+
+	app := api.NewApp(cfg, logs)
+
+	var err error
+	if len(os.Args) > 1 {
+		err = app.RunCommand(os.Args[1:])
+	} else {
+		err = app.Server()
+	}
+	if err != nil {
+		app.Logger.PrintFatal(err, nil)
+	}
+}
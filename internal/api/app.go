@@ -3,10 +3,11 @@ package api
 import (
 	"bookwise/internal/config"
 	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
 	"context"
 	"database/sql"
 	"expvar"
-	"os"
+	"io"
 	"runtime"
 	"sync"
 	"time"
@@ -21,8 +22,10 @@ type application struct {
 
 const version = "1.0.0"
 
-func NewApp(cfg config.Config) *application {
-	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
+// NewApp connects to the database and sets up an application that logs to
+// logs.
+func NewApp(cfg config.Config, logs io.Writer) *application {
+	logger := jsonlog.New(logs, jsonlog.LevelInfo)
 
 	db, err := openDB(cfg)
 	if err != nil {
@@ -31,6 +34,17 @@ func NewApp(cfg config.Config) *application {
 
 	logger.PrintInfo("database connection pool established", nil)
 
+	err = models.SetPasswordParams(models.PasswordParams{
+		Memory:      cfg.Password.Memory,
+		Iterations:  cfg.Password.Iterations,
+		Parallelism: cfg.Password.Parallelism,
+		SaltLength:  cfg.Password.SaltLength,
+		KeyLength:   cfg.Password.KeyLength,
+	})
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	expvar.NewString("version").Set(version)
 
 	expvar.Publish("goroutines", expvar.Func(func() any {
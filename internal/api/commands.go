@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bookwise/internal/clippings"
+	"bookwise/internal/services"
+	"bookwise/utils/validator"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// RunCommand runs a one-off command instead of the server, such as
+//
+//	bookwise import-highlights -user reader@example.com "My Clippings.txt"
+//	bookwise import-dir -user reader@example.com -dry-run /mnt/nas/ebooks
+//	bookwise import-kobo -user reader@example.com /media/KOBOeReader/.kobo/KoboReader.sqlite
+func (app *application) RunCommand(args []string) error {
+	defer app.db.Close()
+
+	if len(args) == 0 {
+		return errors.New("no command given")
+	}
+
+	switch args[0] {
+	case "import-highlights":
+		return app.importHighlights(args[1:])
+	case "import-dir":
+		return app.importDir(args[1:])
+	case "import-kobo":
+		return app.importKobo(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func (app *application) importHighlights(args []string) error {
+	fs := flag.NewFlagSet("import-highlights", flag.ContinueOnError)
+	email := fs.String("user", "", "email of the user the highlights belong to")
+	format := fs.String("format", "", "kindle or koreader; detected when empty")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without saving")
+	createMissing := fs.Bool("create-missing", false, "create the books that match none of the user's")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || fs.NArg() != 1 {
+		return errors.New("usage: import-highlights -user EMAIL [-format kindle|koreader] [-dry-run] [-create-missing] FILE")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	s := services.NewServices(app.Logger, app.db, app.config)
+	v := validator.New()
+
+	user, err := s.User.GetUserByEmail(*email, v)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", *email, err)
+	}
+
+	result, err := s.Highlight.Import(data, clippings.Format(*format), user.ID, *dryRun, *createMissing, v)
+	if err != nil {
+		if !v.Valid() {
+			return fmt.Errorf("%w: %v", err, v.Errors)
+		}
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(result)
+}
+
+func (app *application) importDir(args []string) error {
+	fs := flag.NewFlagSet("import-dir", flag.ContinueOnError)
+	email := fs.String("user", "", "email of the user the books belong to")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without saving")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || fs.NArg() != 1 {
+		return errors.New("usage: import-dir -user EMAIL [-dry-run] DIR")
+	}
+
+	dir := fs.Arg(0)
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	s := services.NewServices(app.Logger, app.db, app.config)
+	v := validator.New()
+
+	user, err := s.User.GetUserByEmail(*email, v)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", *email, err)
+	}
+
+	result, err := s.BookFile.ImportDir(os.DirFS(dir), user.ID, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(result)
+}
+
+func (app *application) importKobo(args []string) error {
+	fs := flag.NewFlagSet("import-kobo", flag.ContinueOnError)
+	email := fs.String("user", "", "email of the user who read the books")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without saving")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || fs.NArg() != 1 {
+		return errors.New("usage: import-kobo -user EMAIL [-dry-run] FILE")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	s := services.NewServices(app.Logger, app.db, app.config)
+	v := validator.New()
+
+	user, err := s.User.GetUserByEmail(*email, v)
+	if err != nil {
+		return fmt.Errorf("user %s: %w", *email, err)
+	}
+
+	preferences, err := s.Preferences.FindByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.Kobo.Import(data, user.ID, preferences.Location(), *dryRun, v)
+	if err != nil {
+		if !v.Valid() {
+			return fmt.Errorf("%w: %v", err, v.Errors)
+		}
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "\t")
+	return enc.Encode(result)
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// runPeriodically calls fn right away and then every interval until stop is
+// closed. The job is tracked by app.wg so shutdown waits for a running call
+// to finish.
+func (app *application) runPeriodically(name string, interval time.Duration, stop <-chan struct{}, fn func() error) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			app.runJob(name, fn)
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (app *application) runJob(name string, fn func() error) {
+	defer func() {
+		if err := recover(); err != nil {
+			app.Logger.PrintError(fmt.Errorf("%v", err), map[string]string{
+				"job": name,
+			})
+		}
+	}()
+
+	if err := fn(); err != nil {
+		app.Logger.PrintError(err, map[string]string{
+			"job": name,
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
@@ -31,6 +32,51 @@ func (app *application) Server() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	stopJobs := make(chan struct{})
+	trash := r.Services().Trash
+	app.runPeriodically("purge trash", app.config.Trash.PurgeInterval, stopJobs, func() error {
+		purged, err := trash.PurgeExpired()
+		if err == nil && purged > 0 {
+			app.Logger.PrintInfo("purged expired trash", map[string]string{
+				"rows": strconv.FormatInt(purged, 10),
+			})
+		}
+		return err
+	})
+
+	files := r.Services().BookFile
+	app.runPeriodically("purge unattached book files", app.config.Trash.PurgeInterval, stopJobs, func() error {
+		purged, err := files.PurgeUnattached()
+		if purged > 0 {
+			app.Logger.PrintInfo("purged unattached book files", map[string]string{
+				"files": strconv.Itoa(purged),
+			})
+		}
+		return err
+	})
+
+	imports := r.Services().LibraryImport
+	app.runPeriodically("run library imports", app.config.Imports.PollInterval, stopJobs, func() error {
+		ran, err := imports.RunPending()
+		if ran > 0 {
+			app.Logger.PrintInfo("ran library imports", map[string]string{
+				"imports": strconv.Itoa(ran),
+			})
+		}
+		return err
+	})
+
+	loans := r.Services().Loan
+	app.runPeriodically("remind overdue loans", app.config.Loans.ReminderInterval, stopJobs, func() error {
+		reminded, err := loans.RemindOverdue()
+		if reminded > 0 {
+			app.Logger.PrintInfo("reminded overdue loans", map[string]string{
+				"loans": strconv.Itoa(reminded),
+			})
+		}
+		return err
+	})
+
 	shutdownError := make(chan error)
 
 	go func() {
@@ -52,6 +98,8 @@ func (app *application) Server() error {
 
 		defer app.db.Close()
 
+		close(stopJobs)
+
 		app.Logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": srv.Addr,
 		})
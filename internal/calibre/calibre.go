@@ -0,0 +1,404 @@
+// Package calibre reads the books of a Calibre library from its metadata.db.
+package calibre
+
+import (
+	"bookwise/internal/librarycsv"
+	"bookwise/internal/sqlitefile"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+var ErrNotCalibre = errors.New("not a Calibre library")
+
+// pageColumns are the lookup names of the custom columns the Count Pages
+// plugin and most users keep page counts in.
+var pageColumns = []string{"pages", "pagecount", "page_count", "nopages"}
+
+// Detect tells whether data looks like a SQLite database. Whether it is a
+// Calibre library is only known once Read opens it.
+func Detect(data []byte) bool {
+	return sqlitefile.Detect(data)
+}
+
+// Read returns the books of the library in data, in the order Calibre added
+// them. Line is the Calibre ID of the book and ExternalID the same ID as a
+// string, so importing the library again recognizes the books imported
+// before. Tags become shelves and comments the description.
+func Read(data []byte) ([]librarycsv.Entry, error) {
+	if !Detect(data) {
+		return nil, ErrNotCalibre
+	}
+
+	db, closeDB, err := sqlitefile.Open(data, "calibre-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	r := &reader{db: db, ctx: ctx}
+	return r.read()
+}
+
+type reader struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+func (r *reader) read() ([]librarycsv.Entry, error) {
+	var tables int
+	err := r.db.QueryRowContext(r.ctx, `
+	select count(*)
+	from sqlite_master
+	where type = 'table' and name in ('books', 'authors', 'books_authors_link')
+	`).Scan(&tables)
+	if err != nil || tables != 3 {
+		return nil, ErrNotCalibre
+	}
+
+	entries, index, err := r.books()
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []func([]librarycsv.Entry, map[int64]int) error{
+		r.authors,
+		r.series,
+		r.publishers,
+		r.languages,
+		r.tags,
+		r.ratings,
+		r.comments,
+		r.identifiers,
+		r.formats,
+		r.pages,
+	}
+	for _, step := range steps {
+		if err := step(entries, index); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// books reads the books and returns them with the index of every book by its
+// Calibre ID.
+func (r *reader) books() ([]librarycsv.Entry, map[int64]int, error) {
+	rows, err := r.db.QueryContext(r.ctx, `
+	select
+		id,
+		title,
+		coalesce(series_index, 1),
+		coalesce(isbn, ''),
+		coalesce(substr(pubdate, 1, 10), ''),
+		coalesce(substr(timestamp, 1, 10), '')
+	from books
+	order by id
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNotCalibre, err)
+	}
+	defer rows.Close()
+
+	entries := []librarycsv.Entry{}
+	index := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var entry librarycsv.Entry
+		var seriesIndex float64
+		var published, added string
+		if err := rows.Scan(&id, &entry.Title, &seriesIndex, &entry.ISBN, &published, &added); err != nil {
+			return nil, nil, err
+		}
+
+		entry.Line = int(id)
+		entry.ExternalID = strconv.FormatInt(id, 10)
+		entry.Title = strings.TrimSpace(entry.Title)
+		entry.SeriesPosition = &seriesIndex
+		entry.PublishedDate = parseDate(published)
+		entry.DateAdded = parseDate(added)
+		if entry.PublishedDate != nil {
+			entry.PublishedYear = entry.PublishedDate.Year()
+		}
+
+		index[id] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	return entries, index, rows.Err()
+}
+
+// authors joins the authors of every book in the order Calibre lists them.
+// Calibre stores the commas of names as bars.
+func (r *reader) authors(entries []librarycsv.Entry, index map[int64]int) error {
+	names := map[int64][]string{}
+	err := r.each(`
+	select l.book, a.name
+	from books_authors_link l
+	join authors a on a.id = l.author
+	order by l.id
+	`, func(book int64, value string) {
+		names[book] = append(names[book], strings.ReplaceAll(value, "|", ","))
+	})
+	if err != nil {
+		return err
+	}
+
+	for book, list := range names {
+		if i, ok := index[book]; ok {
+			entries[i].Author = strings.Join(list, " & ")
+		}
+	}
+	return nil
+}
+
+func (r *reader) series(entries []librarycsv.Entry, index map[int64]int) error {
+	err := r.each(`
+	select l.book, s.name
+	from books_series_link l
+	join series s on s.id = l.series
+	`, func(book int64, value string) {
+		if i, ok := index[book]; ok {
+			entries[i].SeriesName = value
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Calibre gives every book a series index, series or not.
+	for i := range entries {
+		if entries[i].SeriesName == "" {
+			entries[i].SeriesPosition = nil
+		}
+	}
+	return nil
+}
+
+func (r *reader) publishers(entries []librarycsv.Entry, index map[int64]int) error {
+	return r.each(`
+	select l.book, p.name
+	from books_publishers_link l
+	join publishers p on p.id = l.publisher
+	`, func(book int64, value string) {
+		if i, ok := index[book]; ok {
+			entries[i].Publisher = value
+		}
+	})
+}
+
+// languages keeps the first language of every book. Calibre stores ISO 639-2
+// codes, which are shortened to their two letter form where there is one.
+func (r *reader) languages(entries []librarycsv.Entry, index map[int64]int) error {
+	return r.each(`
+	select l.book, g.lang_code
+	from books_languages_link l
+	join languages g on g.id = l.lang_code
+	order by l.item_order desc
+	`, func(book int64, value string) {
+		i, ok := index[book]
+		if !ok {
+			return
+		}
+
+		if base, err := language.ParseBase(value); err == nil {
+			value = base.String()
+		}
+		entries[i].Language = value
+	})
+}
+
+func (r *reader) tags(entries []librarycsv.Entry, index map[int64]int) error {
+	return r.each(`
+	select l.book, t.name
+	from books_tags_link l
+	join tags t on t.id = l.tag
+	order by t.name
+	`, func(book int64, value string) {
+		if i, ok := index[book]; ok {
+			entries[i].Shelves = append(entries[i].Shelves, value)
+		}
+	})
+}
+
+// ratings reads the ratings, which Calibre keeps in half stars from 0 to 10.
+func (r *reader) ratings(entries []librarycsv.Entry, index map[int64]int) error {
+	return r.each(`
+	select l.book, cast(g.rating as text)
+	from books_ratings_link l
+	join ratings g on g.id = l.rating
+	where g.rating > 0
+	`, func(book int64, value string) {
+		i, ok := index[book]
+		if !ok {
+			return
+		}
+
+		if rating, err := strconv.ParseFloat(value, 64); err == nil {
+			rating /= 2
+			entries[i].Rating = &rating
+		}
+	})
+}
+
+// comments reads the comments of the books, the HTML description Calibre
+// downloads or the user writes, as text.
+func (r *reader) comments(entries []librarycsv.Entry, index map[int64]int) error {
+	return r.each(`
+	select book, text
+	from comments
+	`, func(book int64, value string) {
+		if i, ok := index[book]; ok {
+			entries[i].Description = htmlText(value)
+		}
+	})
+}
+
+// identifiers reads the identifiers of the books, such as their Goodreads or
+// Amazon IDs, by lower-case type. ISBNs fill the ISBN of books without one.
+func (r *reader) identifiers(entries []librarycsv.Entry, index map[int64]int) error {
+	rows, err := r.db.QueryContext(r.ctx, `
+	select book, type, val
+	from identifiers
+	`)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotCalibre, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var book int64
+		var kind, value string
+		if err := rows.Scan(&book, &kind, &value); err != nil {
+			return err
+		}
+
+		i, ok := index[book]
+		if !ok {
+			continue
+		}
+
+		entry := &entries[i]
+		kind, value = strings.ToLower(strings.TrimSpace(kind)), strings.TrimSpace(value)
+		if entry.Identifiers == nil {
+			entry.Identifiers = map[string]string{}
+		}
+		entry.Identifiers[kind] = value
+
+		if kind == "isbn" && entry.ISBN == "" {
+			entry.ISBN = value
+		}
+	}
+
+	return rows.Err()
+}
+
+// formats marks the books Calibre holds files of as ebooks.
+func (r *reader) formats(entries []librarycsv.Entry, index map[int64]int) error {
+	return r.each(`
+	select book, format
+	from data
+	`, func(book int64, _ string) {
+		if i, ok := index[book]; ok {
+			entries[i].Format = "ebook"
+		}
+	})
+}
+
+// pages reads the page counts of the first custom column named after them,
+// as Calibre does not count pages itself.
+func (r *reader) pages(entries []librarycsv.Entry, index map[int64]int) error {
+	var column int64
+	err := r.db.QueryRowContext(r.ctx, fmt.Sprintf(`
+	select id
+	from custom_columns
+	where datatype in ('int', 'float') and lower(label) in ('%s')
+	order by id
+	limit 1
+	`, strings.Join(pageColumns, "', '"))).Scan(&column)
+	if err != nil {
+		// Libraries without such a column, or without custom columns at all.
+		return nil
+	}
+
+	return r.each(fmt.Sprintf(`
+	select book, cast(value as text)
+	from custom_column_%d
+	`, column), func(book int64, value string) {
+		i, ok := index[book]
+		if !ok {
+			return
+		}
+
+		if pages, err := strconv.ParseFloat(value, 64); err == nil && pages > 0 {
+			entries[i].Pages = int(pages)
+		}
+	})
+}
+
+// each calls fn with the book and value of every row of query. Tables that
+// older libraries lack are skipped.
+func (r *reader) each(query string, fn func(book int64, value string)) error {
+	rows, err := r.db.QueryContext(r.ctx, query)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrNotCalibre, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var book int64
+		var value sql.NullString
+		if err := rows.Scan(&book, &value); err != nil {
+			return err
+		}
+
+		if value := strings.TrimSpace(value.String); value != "" {
+			fn(book, value)
+		}
+	}
+
+	return rows.Err()
+}
+
+// parseDate reads the date of a Calibre timestamp. Calibre marks unknown
+// dates as the year 101.
+func parseDate(s string) *time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil || t.Year() < 1000 {
+		return nil
+	}
+	return &t
+}
+
+var (
+	breakRX = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	tagRX   = regexp.MustCompile(`<[^>]*>`)
+	blankRX = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// htmlText turns the HTML of a comment into paragraphs of plain text.
+func htmlText(s string) string {
+	s = breakRX.ReplaceAllString(s, "\n\n")
+	s = html.UnescapeString(tagRX.ReplaceAllString(s, ""))
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(blankRX.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,67 @@
+// Package clippings reads highlights exported from e-readers.
+package clippings
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatKindle   Format = "kindle"
+	FormatKOReader Format = "koreader"
+)
+
+var ErrUnknownFormat = errors.New("unrecognized highlights export")
+
+// Clipping is a highlighted passage together with the book it was taken from
+// as the e-reader names it.
+type Clipping struct {
+	Title   string
+	Author  string
+	Text    string
+	Note    string
+	Page    int
+	Chapter string
+	Color   string
+	AddedAt *time.Time
+}
+
+// Detect guesses the format of an export from its content.
+func Detect(data []byte) (Format, error) {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte(bom)), " \t\r\n")
+
+	switch {
+	case len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '['):
+		return FormatKOReader, nil
+	case bytes.Contains(data, []byte(kindleSeparator)):
+		return FormatKindle, nil
+	}
+
+	return "", ErrUnknownFormat
+}
+
+// Parse reads an export in the given format.
+func Parse(format Format, r io.Reader) ([]Clipping, error) {
+	switch format {
+	case FormatKindle:
+		return ParseKindle(r)
+	case FormatKOReader:
+		return ParseKOReader(r)
+	}
+
+	return nil, ErrUnknownFormat
+}
+
+// Hash identifies the passage of c regardless of case and whitespace, so the
+// same highlight exported twice hashes the same.
+func (c Clipping) Hash() string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.Join(strings.Fields(c.Text), " "))))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,178 @@
+package clippings
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	kindleSeparator = "=========="
+	bom             = "\ufeff"
+)
+
+var (
+	kindleKindRX     = regexp.MustCompile(`(?i)\b(highlight|note|bookmark)\b`)
+	kindlePageRX     = regexp.MustCompile(`(?i)\bpage\s+(\d+)`)
+	kindleLocationRX = regexp.MustCompile(`(?i)\bloc(?:ation|\.)?\s+(\d+)(?:-(\d+))?`)
+	kindleAddedRX    = regexp.MustCompile(`(?i)added on\s+(.+)$`)
+)
+
+var kindleDateLayouts = []string{
+	"Monday, January 2, 2006 3:04:05 PM",
+	"Monday, January 2, 2006, 03:04 PM",
+	"Monday, 2 January 2006 15:04:05",
+	"Monday, 2 January 2006, 15:04",
+}
+
+// kindleEntry is one block of My Clippings.txt before notes are attached to
+// their highlights.
+type kindleEntry struct {
+	Clipping
+	kind  string
+	start int
+	end   int
+}
+
+// ParseKindle reads the "My Clippings.txt" file of a Kindle. Notes are
+// attached to the highlight they were written on and bookmarks are ignored.
+// When a highlight was extended on the device, only its latest version is
+// kept.
+func ParseKindle(r io.Reader) ([]Clipping, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\n"), bom, "")
+
+	entries := []*kindleEntry{}
+	for _, block := range strings.Split(text, kindleSeparator) {
+		if entry := parseKindleEntry(block); entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	highlights := []*kindleEntry{}
+	for _, entry := range entries {
+		switch entry.kind {
+		case "highlight":
+			highlights = replaceExtended(highlights, entry)
+		case "note":
+			attachNote(highlights, entry)
+		}
+	}
+
+	clippings := make([]Clipping, 0, len(highlights))
+	for _, h := range highlights {
+		if h.Text != "" {
+			clippings = append(clippings, h.Clipping)
+		}
+	}
+
+	return clippings, nil
+}
+
+func parseKindleEntry(block string) *kindleEntry {
+	lines := strings.Split(strings.Trim(block, "\n"), "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	kind := kindleKindRX.FindStringSubmatch(lines[1])
+	if kind == nil {
+		return nil
+	}
+
+	entry := &kindleEntry{kind: strings.ToLower(kind[1])}
+	entry.Title, entry.Author = splitKindleTitle(strings.TrimSpace(lines[0]))
+	entry.Text = strings.TrimSpace(strings.Join(lines[2:], "\n"))
+
+	if m := kindlePageRX.FindStringSubmatch(lines[1]); m != nil {
+		entry.Page, _ = strconv.Atoi(m[1])
+	}
+
+	if m := kindleLocationRX.FindStringSubmatch(lines[1]); m != nil {
+		entry.start, _ = strconv.Atoi(m[1])
+		entry.end = entry.start
+		if m[2] != "" {
+			entry.end = expandLocation(m[1], m[2])
+		}
+	}
+
+	if m := kindleAddedRX.FindStringSubmatch(lines[1]); m != nil {
+		for _, layout := range kindleDateLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(m[1])); err == nil {
+				entry.AddedAt = &t
+				break
+			}
+		}
+	}
+
+	return entry
+}
+
+// splitKindleTitle separates "Title (Author)". Only the last parenthesized
+// group is the author, so titles like "Dune (Dune Chronicles 1)" survive.
+func splitKindleTitle(line string) (title, author string) {
+	if !strings.HasSuffix(line, ")") {
+		return line, ""
+	}
+
+	depth := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		switch line[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1 : len(line)-1])
+			}
+		}
+	}
+
+	return line, ""
+}
+
+// expandLocation completes an abbreviated range end such as the 25 of
+// "123-25", which older Kindles write for 123-125.
+func expandLocation(start, end string) int {
+	if len(end) < len(start) {
+		end = start[:len(start)-len(end)] + end
+	}
+	n, _ := strconv.Atoi(end)
+	return n
+}
+
+// replaceExtended adds entry to highlights, replacing an earlier highlight of
+// the same book that starts at the same location.
+func replaceExtended(highlights []*kindleEntry, entry *kindleEntry) []*kindleEntry {
+	for i, h := range highlights {
+		if h.Title == entry.Title && entry.start != 0 && h.start == entry.start {
+			entry.Note = h.Note
+			highlights[i] = entry
+			return highlights
+		}
+	}
+	return append(highlights, entry)
+}
+
+// attachNote adds the note to the latest highlight of the same book whose
+// location range contains the note's location.
+func attachNote(highlights []*kindleEntry, note *kindleEntry) {
+	for i := len(highlights) - 1; i >= 0; i-- {
+		h := highlights[i]
+		if h.Title != note.Title || note.start < h.start || note.start > h.end {
+			continue
+		}
+
+		if h.Note != "" {
+			h.Note += "\n\n"
+		}
+		h.Note += note.Text
+		return
+	}
+}
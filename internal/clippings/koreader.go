@@ -0,0 +1,100 @@
+package clippings
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// koreaderDocument is a book in the JSON export of KOReader's exporter
+// plugin.
+type koreaderDocument struct {
+	Title   string          `json:"title"`
+	Author  string          `json:"author"`
+	Entries []koreaderEntry `json:"entries"`
+}
+
+type koreaderEntry struct {
+	Text    string          `json:"text"`
+	Note    string          `json:"note"`
+	Chapter string          `json:"chapter"`
+	Page    json.RawMessage `json:"page"`
+	Time    int64           `json:"time"`
+	Color   string          `json:"color"`
+	Sort    string          `json:"sort"`
+}
+
+// ParseKOReader reads a KOReader JSON export, which holds either a single
+// book or, when several books were exported together, a "documents" list.
+// A bare array of books is accepted as well.
+func ParseKOReader(r io.Reader) ([]Clipping, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = []byte(strings.TrimPrefix(string(data), bom))
+
+	var documents []koreaderDocument
+
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &documents); err != nil {
+			return nil, err
+		}
+	} else {
+		var export struct {
+			koreaderDocument
+			Documents []koreaderDocument `json:"documents"`
+		}
+		if err := json.Unmarshal(data, &export); err != nil {
+			return nil, err
+		}
+		documents = append(export.Documents, export.koreaderDocument)
+	}
+
+	clippings := []Clipping{}
+	for _, document := range documents {
+		for _, entry := range document.Entries {
+			if entry.Sort == "bookmark" || strings.TrimSpace(entry.Text) == "" {
+				continue
+			}
+
+			clipping := Clipping{
+				Title:   strings.TrimSpace(document.Title),
+				Author:  strings.TrimSpace(document.Author),
+				Text:    strings.TrimSpace(entry.Text),
+				Note:    strings.TrimSpace(entry.Note),
+				Page:    koreaderPage(entry.Page),
+				Chapter: strings.TrimSpace(entry.Chapter),
+				Color:   entry.Color,
+			}
+
+			if entry.Time > 0 {
+				t := time.Unix(entry.Time, 0).UTC()
+				clipping.AddedAt = &t
+			}
+
+			clippings = append(clippings, clipping)
+		}
+	}
+
+	return clippings, nil
+}
+
+// koreaderPage reads the page, which KOReader writes as a number for paged
+// documents and as a string for reflowable ones. Locations that are not a
+// page number yield 0.
+func koreaderPage(raw json.RawMessage) int {
+	var page int
+	if err := json.Unmarshal(raw, &page); err == nil {
+		return page
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		page, _ = strconv.Atoi(strings.TrimSpace(s))
+	}
+
+	return page
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"time"
 
 	"github.com/joeshaw/envdecode"
 )
@@ -26,6 +27,55 @@ type Config struct {
 	Security struct {
 		SecretKey string
 	}
+	Phone struct {
+		DefaultCountryCode string
+	}
+	Password struct {
+		Memory      uint32
+		Iterations  uint32
+		Parallelism uint8
+		SaltLength  uint32
+		KeyLength   uint32
+	}
+	Metadata struct {
+		DumpPath string
+		CacheTTL time.Duration
+	}
+	Storage struct {
+		Driver    string
+		LocalPath string
+		S3        struct {
+			Endpoint  string
+			Region    string
+			Bucket    string
+			AccessKey string
+			SecretKey string
+			PathStyle bool
+		}
+	}
+	Covers struct {
+		MaxBytes        int64
+		ThumbnailWidth  int
+		ThumbnailHeight int
+	}
+	Trash struct {
+		Retention     time.Duration
+		PurgeInterval time.Duration
+	}
+	Imports struct {
+		MaxBytes     int64
+		PollInterval time.Duration
+	}
+	Files struct {
+		MaxBytes        int64
+		UploadRetention time.Duration
+	}
+	Loans struct {
+		ReminderInterval time.Duration
+	}
+	App struct {
+		URL string
+	}
 }
 
 type Conf struct {
@@ -33,6 +83,16 @@ type Conf struct {
 	DB          ConfDB
 	RateLimiter ConfRL
 	Security    ConfSecurity
+	Password    ConfPassword
+	Phone       ConfPhone
+	Metadata    ConfMetadata
+	Storage     ConfStorage
+	Covers      ConfCovers
+	Trash       ConfTrash
+	Imports     ConfImports
+	Files       ConfFiles
+	Loans       ConfLoans
+	App         ConfApp
 }
 
 type ConfServer struct {
@@ -57,6 +117,55 @@ type ConfSecurity struct {
 	SecretKey string `env:"SECRET_KEY,required"`
 }
 
+type ConfPassword struct {
+	Memory      uint32 `env:"ARGON2_MEMORY,default=65536"`
+	Iterations  uint32 `env:"ARGON2_ITERATIONS,default=3"`
+	Parallelism uint8  `env:"ARGON2_PARALLELISM,default=2"`
+	SaltLength  uint32 `env:"ARGON2_SALT_LENGTH,default=16"`
+	KeyLength   uint32 `env:"ARGON2_KEY_LENGTH,default=32"`
+}
+
+type ConfPhone struct {
+	DefaultCountryCode string `env:"PHONE_DEFAULT_COUNTRY_CODE,default=55"`
+}
+
+type ConfMetadata struct {
+	DumpPath string        `env:"METADATA_DUMP_PATH"`
+	CacheTTL time.Duration `env:"METADATA_CACHE_TTL,default=720h"`
+}
+
+type ConfStorage struct {
+	Driver      string `env:"STORAGE_DRIVER,default=local"`
+	LocalPath   string `env:"STORAGE_LOCAL_PATH,default=./data/blobs"`
+	S3Endpoint  string `env:"S3_ENDPOINT"`
+	S3Region    string `env:"S3_REGION,default=us-east-1"`
+	S3Bucket    string `env:"S3_BUCKET"`
+	S3AccessKey string `env:"S3_ACCESS_KEY"`
+	S3SecretKey string `env:"S3_SECRET_KEY"`
+	S3PathStyle bool   `env:"S3_PATH_STYLE,default=true"`
+}
+
+type ConfCovers struct {
+	MaxBytes        int64 `env:"COVER_MAX_BYTES,default=5242880"`
+	ThumbnailWidth  int   `env:"COVER_THUMBNAIL_WIDTH,default=200"`
+	ThumbnailHeight int   `env:"COVER_THUMBNAIL_HEIGHT,default=300"`
+}
+
+type ConfTrash struct {
+	Retention     time.Duration `env:"TRASH_RETENTION,default=720h"`
+	PurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL,default=1h"`
+}
+
+type ConfImports struct {
+	MaxBytes     int64         `env:"IMPORT_MAX_BYTES,default=10485760"`
+	PollInterval time.Duration `env:"IMPORT_POLL_INTERVAL,default=5s"`
+}
+
+type ConfFiles struct {
+	MaxBytes        int64         `env:"FILE_MAX_BYTES,default=104857600"`
+	UploadRetention time.Duration `env:"FILE_UPLOAD_RETENTION,default=24h"`
+}
+
 func New() *Conf {
 	var c Conf
 	if err := envdecode.StrictDecode(&c); err != nil {
@@ -74,3 +183,13 @@ func NewDB() *ConfDB {
 
 	return &c
 }
+
+type ConfLoans struct {
+	ReminderInterval time.Duration `env:"LOAN_REMINDER_INTERVAL,default=1h"`
+}
+
+// ConfApp describes the web app serving the library, which printed labels
+// link to.
+type ConfApp struct {
+	URL string `env:"APP_URL,default=http://localhost:3000"`
+}
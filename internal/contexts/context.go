@@ -8,7 +8,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey        = contextKey("user")
+	preferencesContextKey = contextKey("preferences")
+)
 
 func ContextSetUser(r *http.Request, user *models.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -22,3 +25,16 @@ func ContextGetUser(r *http.Request) *models.User {
 	}
 	return user
 }
+
+func ContextSetPreferences(r *http.Request, preferences *models.Preferences) *http.Request {
+	ctx := context.WithValue(r.Context(), preferencesContextKey, preferences)
+	return r.WithContext(ctx)
+}
+
+func ContextGetPreferences(r *http.Request) *models.Preferences {
+	preferences, ok := r.Context().Value(preferencesContextKey).(*models.Preferences)
+	if !ok {
+		panic("missing preferences value in request context")
+	}
+	return preferences
+}
@@ -0,0 +1,169 @@
+// Package ebook reads the bibliographic metadata embedded in ebook files.
+package ebook
+
+import (
+	"bookwise/utils/isbn"
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatEPUB Format = "epub"
+	FormatPDF  Format = "pdf"
+	FormatMOBI Format = "mobi"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported ebook format")
+	ErrMalformed         = errors.New("malformed ebook")
+)
+
+// maxCoverBytes bounds the cover image read out of a file.
+const maxCoverBytes = 10 << 20
+
+// Creator is a person credited in a file. Role is a MARC relator code such as
+// "aut" or "trl", empty when the file does not say.
+type Creator struct {
+	Name     string
+	SortName string
+	Role     string
+}
+
+// Chapter is an entry of the table of contents of a file. StartPage is an
+// estimate for files without pages of their own, made the same way as their
+// page count.
+type Chapter struct {
+	Title     string
+	StartPage int
+}
+
+// Metadata is what a file says about itself. Empty fields are unknown.
+type Metadata struct {
+	Title         string
+	Creators      []Creator
+	Language      string
+	Description   string
+	Publisher     string
+	PublishedDate *time.Time
+	ISBN          string
+	Identifiers   []string
+	Pages         int
+	Chapters      []Chapter
+	Cover         []byte
+}
+
+// Detect tells the format of a file from its content.
+func Detect(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) && bytes.Contains(data[:min(len(data), 128)], []byte("application/epub+zip")):
+		return FormatEPUB, nil
+	case bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")):
+		return FormatPDF, nil
+	case isMOBI(data):
+		return FormatMOBI, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Read detects the format of a file and returns its metadata.
+func Read(data []byte) (*Metadata, Format, error) {
+	format, err := Detect(data)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var metadata *Metadata
+	switch format {
+	case FormatEPUB:
+		metadata, err = readEPUB(data)
+	case FormatPDF:
+		metadata, err = readPDF(data)
+	case FormatMOBI:
+		metadata, err = readMOBI(data)
+	}
+	if err != nil {
+		return nil, format, err
+	}
+
+	metadata.Title = cleanText(metadata.Title)
+	metadata.Description = cleanText(metadata.Description)
+	metadata.Publisher = cleanText(metadata.Publisher)
+	metadata.Language = strings.TrimSpace(metadata.Language)
+
+	return metadata, format, nil
+}
+
+// ContentType returns the media type files of the format are served as.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatEPUB:
+		return "application/epub+zip"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatMOBI:
+		return "application/x-mobipocket-ebook"
+	}
+	return "application/octet-stream"
+}
+
+// Extension returns the file name extension of the format, with its dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+var isbnRX = regexp.MustCompile(`(?i)(?:97[89][\s-]?)?(?:\d[\s-]?){9}[\dx]`)
+
+// findISBN returns the first valid ISBN mentioned in texts in ISBN-13 form.
+func findISBN(texts ...string) string {
+	for _, text := range texts {
+		for _, candidate := range isbnRX.FindAllString(text, -1) {
+			if normalized, err := isbn.Normalize(candidate); err == nil {
+				return normalized
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	tagRX   = regexp.MustCompile(`<[^>]*>`)
+	spaceRX = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankRX = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// cleanText strips the markup descriptions often carry and collapses
+// whitespace, keeping paragraph breaks.
+func cleanText(s string) string {
+	s = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n\n").Replace(s)
+	s = tagRX.ReplaceAllString(s, "")
+	s = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'", "&nbsp;", " ").Replace(s)
+	s = spaceRX.ReplaceAllString(s, " ")
+	s = blankRX.ReplaceAllString(s, "\n\n")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// parseDate reads the year, month and day of dates such as 2019, 2019-05 or
+// 2019-05-14T00:00:00Z, as far as they are given.
+func parseDate(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		if len(s) < len(layout) {
+			continue
+		}
+
+		if t, err := time.Parse(layout, s[:len(layout)]); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
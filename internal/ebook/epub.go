@@ -0,0 +1,346 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+const (
+	// maxOPFBytes bounds the package document, container and content
+	// documents read out of an EPUB.
+	maxOPFBytes = 4 << 20
+	// epubCharsPerPage turns the length of the text of an EPUB into a rough
+	// page count, as EPUBs have no pages of their own.
+	epubCharsPerPage = 1800
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// opfPackage is the part of an OPF package document Bookwise reads. Elements
+// match by local name, so the dc: and opf: prefixes of EPUB 2 and 3 both work.
+type opfPackage struct {
+	Metadata struct {
+		Titles       []string        `xml:"title"`
+		Creators     []opfCreator    `xml:"creator"`
+		Languages    []string        `xml:"language"`
+		Identifiers  []opfIdentifier `xml:"identifier"`
+		Descriptions []string        `xml:"description"`
+		Publishers   []string        `xml:"publisher"`
+		Dates        []opfDate       `xml:"date"`
+		Metas        []opfMeta       `xml:"meta"`
+	} `xml:"metadata"`
+	Items []opfItem `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+type opfCreator struct {
+	ID     string `xml:"id,attr"`
+	Role   string `xml:"role,attr"`
+	FileAs string `xml:"file-as,attr"`
+	Name   string `xml:",chardata"`
+}
+
+type opfIdentifier struct {
+	Scheme string `xml:"scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type opfDate struct {
+	Event string `xml:"event,attr"`
+	Value string `xml:",chardata"`
+}
+
+type opfMeta struct {
+	Name     string `xml:"name,attr"`
+	Content  string `xml:"content,attr"`
+	Property string `xml:"property,attr"`
+	Refines  string `xml:"refines,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type opfItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// readEPUB reads the package document the container points at.
+func readEPUB(data []byte) (*Metadata, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	var container epubContainer
+	if err := decodeZipXML(archive, "META-INF/container.xml", &container); err != nil {
+		return nil, err
+	}
+
+	opfPath := ""
+	for _, rootfile := range container.Rootfiles {
+		if rootfile.MediaType == "" || rootfile.MediaType == "application/oebps-package+xml" {
+			opfPath = rootfile.FullPath
+			break
+		}
+	}
+	if opfPath == "" {
+		return nil, fmt.Errorf("%w: no package document", ErrMalformed)
+	}
+
+	var pkg opfPackage
+	if err := decodeZipXML(archive, opfPath, &pkg); err != nil {
+		return nil, err
+	}
+
+	m := pkg.Metadata
+	metadata := &Metadata{
+		Title:       first(m.Titles),
+		Language:    first(m.Languages),
+		Description: first(m.Descriptions),
+		Publisher:   first(m.Publishers),
+		Creators:    opfCreators(m.Creators, m.Metas),
+	}
+
+	for _, date := range m.Dates {
+		if metadata.PublishedDate == nil || strings.EqualFold(date.Event, "publication") {
+			metadata.PublishedDate = parseDate(date.Value)
+		}
+	}
+
+	for _, identifier := range m.Identifiers {
+		value := strings.TrimSpace(identifier.Value)
+		if value == "" {
+			continue
+		}
+		metadata.Identifiers = append(metadata.Identifiers, value)
+
+		lower := strings.ToLower(value)
+		if metadata.ISBN == "" && !strings.HasPrefix(lower, "urn:uuid:") {
+			if strings.EqualFold(identifier.Scheme, "isbn") || strings.Contains(lower, "isbn") || isbnRX.MatchString(value) {
+				metadata.ISBN = findISBN(value)
+			}
+		}
+	}
+
+	spine := readSpine(archive, pkg, opfPath)
+	metadata.Pages = spine.pages()
+	metadata.Chapters = epubChapters(archive, pkg, opfPath, spine)
+
+	if item := opfCover(pkg); item != nil {
+		// A missing or oversized cover leaves the book without one rather
+		// than failing the whole file.
+		cover, err := readZipFile(archive, opfHref(opfPath, *item), maxCoverBytes)
+		if err == nil {
+			metadata.Cover = cover
+		}
+	}
+
+	return metadata, nil
+}
+
+// epubSpine is the documents of the spine of an EPUB, in reading order, with
+// the length of the text before each of them.
+type epubSpine struct {
+	starts map[string]int
+	chars  int
+}
+
+// readSpine measures the text of the documents of the spine.
+func readSpine(archive *zip.Reader, pkg opfPackage, opfPath string) *epubSpine {
+	items := make(map[string]opfItem, len(pkg.Items))
+	for _, item := range pkg.Items {
+		items[item.ID] = item
+	}
+
+	spine := &epubSpine{starts: make(map[string]int)}
+	for _, ref := range pkg.Spine {
+		item, ok := items[ref.IDRef]
+		if !ok {
+			continue
+		}
+
+		name := opfHref(opfPath, item)
+		data, err := readZipFile(archive, name, maxOPFBytes)
+		if err != nil {
+			continue
+		}
+
+		if _, seen := spine.starts[name]; !seen {
+			spine.starts[name] = spine.chars
+		}
+		spine.chars += textLength(data)
+	}
+
+	return spine
+}
+
+// pages estimates the page count from the length of the text.
+func (s *epubSpine) pages() int {
+	return (s.chars + epubCharsPerPage - 1) / epubCharsPerPage
+}
+
+// pageAt returns the page the text at offset chars falls on.
+func (s *epubSpine) pageAt(chars int) int {
+	return min(chars/epubCharsPerPage+1, max(s.pages(), 1))
+}
+
+// textLength counts the characters of the text of a document, without its
+// markup and with its whitespace collapsed.
+func textLength(data []byte) int {
+	return utf8.RuneCountInString(strings.Join(strings.Fields(tagRX.ReplaceAllString(string(data), " ")), " "))
+}
+
+// opfHref resolves the path of a manifest item, which is relative to the
+// package document.
+func opfHref(opfPath string, item opfItem) string {
+	href, err := url.PathUnescape(item.Href)
+	if err != nil {
+		href = item.Href
+	}
+	return path.Join(path.Dir(opfPath), href)
+}
+
+// opfCreators reads the creators with their roles and sort names, which EPUB
+// 2 gives as attributes and EPUB 3 as meta elements refining the creator.
+func opfCreators(creators []opfCreator, metas []opfMeta) []Creator {
+	refinements := make(map[string]map[string]string)
+	for _, meta := range metas {
+		if !strings.HasPrefix(meta.Refines, "#") {
+			continue
+		}
+
+		id := strings.TrimPrefix(meta.Refines, "#")
+		if refinements[id] == nil {
+			refinements[id] = make(map[string]string)
+		}
+		refinements[id][meta.Property] = strings.TrimSpace(meta.Value)
+	}
+
+	result := []Creator{}
+	for _, creator := range creators {
+		name := strings.Join(strings.Fields(creator.Name), " ")
+		if name == "" {
+			continue
+		}
+
+		role, sortName := creator.Role, creator.FileAs
+		if refined, ok := refinements[creator.ID]; ok {
+			if role == "" {
+				role = refined["role"]
+			}
+			if sortName == "" {
+				sortName = refined["file-as"]
+			}
+		}
+
+		result = append(result, Creator{
+			Name:     name,
+			SortName: strings.TrimSpace(sortName),
+			Role:     strings.ToLower(strings.TrimSpace(role)),
+		})
+	}
+
+	return result
+}
+
+// opfCover finds the cover image of the manifest, marked as such in EPUB 3
+// and pointed at by a meta element in EPUB 2.
+func opfCover(pkg opfPackage) *opfItem {
+	coverID := ""
+	for _, meta := range pkg.Metadata.Metas {
+		if meta.Name == "cover" {
+			coverID = meta.Content
+		}
+	}
+
+	for i, item := range pkg.Items {
+		if strings.Contains(" "+item.Properties+" ", " cover-image ") {
+			return &pkg.Items[i]
+		}
+	}
+
+	for i, item := range pkg.Items {
+		if coverID != "" && item.ID == coverID && strings.HasPrefix(item.MediaType, "image/") {
+			return &pkg.Items[i]
+		}
+	}
+
+	return nil
+}
+
+func decodeZipXML(archive *zip.Reader, name string, v any) error {
+	data, err := readZipFile(archive, name, maxOPFBytes)
+	if err != nil {
+		return err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		encoding, err := ianaindex.IANA.Encoding(label)
+		if err != nil || encoding == nil {
+			return nil, fmt.Errorf("%w: unknown charset %q", ErrMalformed, label)
+		}
+		return encoding.NewDecoder().Reader(input), nil
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrMalformed, name, err)
+	}
+	return nil
+}
+
+func readZipFile(archive *zip.Reader, name string, maxBytes int64) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+
+		if file.UncompressedSize64 > uint64(maxBytes) {
+			return nil, fmt.Errorf("%w: %s is too large", ErrMalformed, name)
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		defer rc.Close()
+
+		data, err := io.ReadAll(io.LimitReader(rc, maxBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if int64(len(data)) > maxBytes {
+			return nil, fmt.Errorf("%w: %s is too large", ErrMalformed, name)
+		}
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s is missing", ErrMalformed, name)
+}
+
+func first(values []string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// maxChapters bounds the table of contents read out of an EPUB.
+const maxChapters = 1000
+
+// tocEntry is an entry of the table of contents, depth 1 being the
+// outermost.
+type tocEntry struct {
+	title string
+	href  string
+	depth int
+}
+
+// ncxPoint is a navPoint of an EPUB 2 NCX document.
+type ncxPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Points []ncxPoint `xml:"navPoint"`
+}
+
+// epubChapters reads the table of contents of an EPUB, from the EPUB 3
+// navigation document or else the EPUB 2 NCX, and places its entries on the
+// estimated pages of spine. Books whose contents hold a single entry, such
+// as the title of the book, get the entries inside it. Entries pointing
+// outside the spine are left out.
+func epubChapters(archive *zip.Reader, pkg opfPackage, opfPath string, spine *epubSpine) []Chapter {
+	var (
+		entries []tocEntry
+		base    string
+	)
+	for _, item := range pkg.Items {
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			base = opfHref(opfPath, item)
+			if data, err := readZipFile(archive, base, maxOPFBytes); err == nil {
+				entries = navEntries(data)
+			}
+			break
+		}
+	}
+
+	if len(entries) == 0 {
+		for _, item := range pkg.Items {
+			if item.MediaType == "application/x-dtbncx+xml" {
+				var ncx struct {
+					Points []ncxPoint `xml:"navMap>navPoint"`
+				}
+				base = opfHref(opfPath, item)
+				if err := decodeZipXML(archive, base, &ncx); err == nil {
+					entries = ncxEntries(ncx.Points, 1, nil)
+				}
+				break
+			}
+		}
+	}
+
+	depth := 1
+	for count(entries, depth) == 1 && count(entries, depth+1) > 0 {
+		depth++
+	}
+
+	chapters := []Chapter{}
+	documents := make(map[string][]byte)
+	for _, entry := range entries {
+		if entry.depth != depth || entry.title == "" {
+			continue
+		}
+
+		chars, ok := spine.offset(archive, base, entry.href, documents)
+		if !ok {
+			continue
+		}
+
+		chapters = append(chapters, Chapter{Title: entry.title, StartPage: spine.pageAt(chars)})
+		if len(chapters) == maxChapters {
+			break
+		}
+	}
+
+	return chapters
+}
+
+func count(entries []tocEntry, depth int) int {
+	n := 0
+	for _, entry := range entries {
+		if entry.depth == depth {
+			n++
+		}
+	}
+	return n
+}
+
+func ncxEntries(points []ncxPoint, depth int, entries []tocEntry) []tocEntry {
+	for _, point := range points {
+		entries = append(entries, tocEntry{
+			title: strings.Join(strings.Fields(point.Label), " "),
+			href:  point.Content.Src,
+			depth: depth,
+		})
+		entries = ncxEntries(point.Points, depth+1, entries)
+	}
+	return entries
+}
+
+// navEntries reads the links of the toc nav element of an EPUB 3 navigation
+// document, their depth being that of the lists they are in.
+func navEntries(data []byte) []tocEntry {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var (
+		entries []tocEntry
+		inTOC   bool
+		navs    int
+		lists   int
+		link    *tocEntry
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF || err != nil {
+			return entries
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "nav":
+				if inTOC {
+					navs++
+				} else if strings.Contains(" "+attr(t, "type")+" ", " toc ") {
+					inTOC, navs = true, 1
+				}
+			case "ol":
+				if inTOC {
+					lists++
+				}
+			case "a":
+				if inTOC && lists > 0 {
+					link = &tocEntry{href: attr(t, "href"), depth: lists}
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "nav":
+				if inTOC {
+					if navs--; navs == 0 {
+						return entries
+					}
+				}
+			case "ol":
+				if inTOC && lists > 0 {
+					lists--
+				}
+			case "a":
+				if link != nil {
+					link.title = strings.Join(strings.Fields(link.title), " ")
+					entries = append(entries, *link)
+					link = nil
+				}
+			}
+		case xml.CharData:
+			if link != nil {
+				link.title += " " + string(t)
+			}
+		}
+	}
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// offset returns the length of the text of the spine before href, resolved
+// against the document base. Fragments are looked up in the markup of their
+// documents, which are kept in documents once read.
+func (s *epubSpine) offset(archive *zip.Reader, base, href string, documents map[string][]byte) (int, bool) {
+	target, fragment, _ := strings.Cut(href, "#")
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+
+	name := base
+	if target != "" {
+		name = path.Join(path.Dir(base), target)
+	}
+
+	start, ok := s.starts[name]
+	if !ok || fragment == "" {
+		return start, ok
+	}
+
+	data, ok := documents[name]
+	if !ok {
+		data, _ = readZipFile(archive, name, maxOPFBytes)
+		documents[name] = data
+	}
+
+	anchor := regexp.MustCompile(`\b(?:id|name)\s*=\s*["']` + regexp.QuoteMeta(fragment) + `["']`).FindIndex(data)
+	if anchor == nil {
+		return start, true
+	}
+
+	// The anchor's own tag is cut off, so the text before it is measured.
+	before := data[:anchor[0]]
+	if tag := bytes.LastIndexByte(before, '<'); tag >= 0 {
+		before = before[:tag]
+	}
+	return start + textLength(before), true
+}
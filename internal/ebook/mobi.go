@@ -0,0 +1,149 @@
+package ebook
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// EXTH record types read from MOBI files.
+const (
+	exthAuthor      = 100
+	exthPublisher   = 101
+	exthDescription = 103
+	exthISBN        = 104
+	exthPublished   = 106
+	exthCoverOffset = 201
+	exthTitle       = 503
+	exthLanguage    = 524
+)
+
+// mobiBytesPerPage turns the length of the text of a MOBI file, markup
+// included, into a rough page count.
+const mobiBytesPerPage = 4000
+
+// readMOBI reads the MOBI header and EXTH records of a Mobipocket or Kindle
+// (AZW, AZW3) file, which is a Palm database whose first record holds the
+// headers.
+func readMOBI(data []byte) (*Metadata, error) {
+	records, err := palmRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := records[0]
+	if len(header) < 132 || string(header[16:20]) != "MOBI" {
+		return nil, fmt.Errorf("%w: no MOBI header", ErrMalformed)
+	}
+
+	textLength := binary.BigEndian.Uint32(header[4:8])
+	mobiLength := binary.BigEndian.Uint32(header[20:24])
+	encoding := binary.BigEndian.Uint32(header[28:32])
+	nameOffset := binary.BigEndian.Uint32(header[84:88])
+	nameLength := binary.BigEndian.Uint32(header[88:92])
+	firstImage := binary.BigEndian.Uint32(header[108:112])
+	exthFlags := binary.BigEndian.Uint32(header[128:132])
+
+	decode := func(b []byte) string {
+		if encoding == 1252 {
+			b, _ = charmap.Windows1252.NewDecoder().Bytes(b)
+		}
+		return strings.TrimSpace(string(b))
+	}
+
+	metadata := &Metadata{
+		Pages: int((textLength + mobiBytesPerPage - 1) / mobiBytesPerPage),
+	}
+
+	if end := uint64(nameOffset) + uint64(nameLength); end <= uint64(len(header)) {
+		metadata.Title = decode(header[nameOffset:end])
+	}
+
+	if exthFlags&0x40 == 0 {
+		return metadata, nil
+	}
+
+	exth := header[min(len(header), 16+int(mobiLength)):]
+	if len(exth) < 12 || string(exth[:4]) != "EXTH" {
+		return metadata, nil
+	}
+
+	count := binary.BigEndian.Uint32(exth[8:12])
+	rest := exth[12:]
+	for i := uint32(0); i < count && len(rest) >= 8; i++ {
+		kind := binary.BigEndian.Uint32(rest[:4])
+		size := binary.BigEndian.Uint32(rest[4:8])
+		if size < 8 || uint64(size) > uint64(len(rest)) {
+			break
+		}
+		value := rest[8:size]
+		rest = rest[size:]
+
+		switch kind {
+		case exthAuthor:
+			metadata.Creators = append(metadata.Creators, Creator{Name: decode(value)})
+		case exthPublisher:
+			metadata.Publisher = decode(value)
+		case exthDescription:
+			metadata.Description = decode(value)
+		case exthISBN:
+			metadata.Identifiers = append(metadata.Identifiers, decode(value))
+			if metadata.ISBN == "" {
+				metadata.ISBN = findISBN(decode(value))
+			}
+		case exthPublished:
+			metadata.PublishedDate = parseDate(decode(value))
+		case exthTitle:
+			metadata.Title = decode(value)
+		case exthLanguage:
+			metadata.Language = decode(value)
+		case exthCoverOffset:
+			if len(value) != 4 {
+				continue
+			}
+
+			index := uint64(firstImage) + uint64(binary.BigEndian.Uint32(value))
+			if index < uint64(len(records)) && len(records[index]) <= maxCoverBytes {
+				metadata.Cover = records[index]
+			}
+		}
+	}
+
+	return metadata, nil
+}
+
+// palmRecords splits a Palm database into its records.
+func palmRecords(data []byte) ([][]byte, error) {
+	if len(data) < 78 {
+		return nil, fmt.Errorf("%w: truncated header", ErrMalformed)
+	}
+
+	count := int(binary.BigEndian.Uint16(data[76:78]))
+	if count == 0 || len(data) < 78+8*count {
+		return nil, fmt.Errorf("%w: truncated record list", ErrMalformed)
+	}
+
+	offsets := make([]int, count+1)
+	for i := 0; i < count; i++ {
+		offsets[i] = int(binary.BigEndian.Uint32(data[78+8*i:]))
+	}
+	offsets[count] = len(data)
+
+	records := make([][]byte, count)
+	for i := 0; i < count; i++ {
+		start, end := offsets[i], offsets[i+1]
+		if start > end || end > len(data) {
+			return nil, fmt.Errorf("%w: bad record offset", ErrMalformed)
+		}
+		records[i] = data[start:end]
+	}
+
+	return records, nil
+}
+
+func isMOBI(data []byte) bool {
+	return len(data) >= 68 && bytes.Equal(data[60:68], []byte("BOOKMOBI"))
+}
@@ -0,0 +1,391 @@
+package ebook
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxObjectStreamBytes bounds the size a compressed object stream may
+// inflate to.
+const maxObjectStreamBytes = 16 << 20
+
+var (
+	pdfObjectRX    = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfRootRX      = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+	pdfInfoRX      = regexp.MustCompile(`/Info\s+(\d+)\s+\d+\s+R`)
+	pdfPagesRefRX  = regexp.MustCompile(`/Pages\s+(\d+)\s+\d+\s+R`)
+	pdfCountRX     = regexp.MustCompile(`/Count\s+(\d+)`)
+	pdfPagesTypeRX = regexp.MustCompile(`/Type\s*/Pages\b`)
+	pdfObjStmRX    = regexp.MustCompile(`/Type\s*/ObjStm\b`)
+	pdfFirstRX     = regexp.MustCompile(`/First\s+(\d+)`)
+	pdfRefRX       = regexp.MustCompile(`^(\d+)\s+\d+\s+R`)
+)
+
+// pdfDocEncoding maps the bytes of PDFDocEncoding that differ from Latin-1.
+var pdfDocEncoding = map[byte]rune{
+	0x80: '•', 0x81: '†', 0x82: '‡', 0x83: '…', 0x84: '—', 0x85: '–', 0x86: 'ƒ',
+	0x87: '⁄', 0x88: '‹', 0x89: '›', 0x8a: '−', 0x8b: '‰', 0x8c: '„', 0x8d: '“',
+	0x8e: '”', 0x8f: '‘', 0x90: '’', 0x91: '‚', 0x92: '™', 0x93: 'ﬁ', 0x94: 'ﬂ',
+	0x95: 'Ł', 0x96: 'Œ', 0x97: 'Š', 0x98: 'Ÿ', 0x99: 'Ž', 0x9a: 'ı', 0x9b: 'ł',
+	0x9c: 'œ', 0x9d: 'š', 0x9e: 'ž', 0xa0: '€',
+}
+
+// pdfObjects holds the bodies of the objects of a file by number, including
+// those packed into compressed object streams.
+type pdfObjects map[int][]byte
+
+// readPDF reads the document information dictionary and counts the pages of
+// the page tree. It does not interpret the content of pages, and the strings
+// of encrypted files are left alone as they cannot be read without the key.
+func readPDF(data []byte) (*Metadata, error) {
+	objects := scanPDFObjects(data)
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("%w: no objects", ErrMalformed)
+	}
+
+	metadata := &Metadata{
+		Pages: pdfPageCount(data, objects),
+	}
+
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return metadata, nil
+	}
+
+	match := lastSubmatch(pdfInfoRX, data)
+	if match == nil {
+		return metadata, nil
+	}
+
+	info := objects[atoi(match)]
+	metadata.Title = objects.text(info, "Title")
+	metadata.Description = objects.text(info, "Subject")
+
+	if author := objects.text(info, "Author"); author != "" {
+		metadata.Creators = []Creator{{Name: author}}
+	}
+
+	metadata.ISBN = findISBN(
+		objects.text(info, "Keywords"),
+		metadata.Description,
+		metadata.Title,
+	)
+
+	return metadata, nil
+}
+
+// scanPDFObjects finds every "n g obj" in the file. Later definitions of a
+// number, from incremental updates, replace earlier ones.
+func scanPDFObjects(data []byte) pdfObjects {
+	objects := make(pdfObjects)
+	streams := [][]byte{}
+
+	for _, loc := range pdfObjectRX.FindAllSubmatchIndex(data, -1) {
+		number, err := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		if err != nil {
+			continue
+		}
+
+		body := data[loc[1]:]
+		if end := bytes.Index(body, []byte("endobj")); end >= 0 {
+			body = body[:end]
+		}
+
+		objects[number] = body
+		if pdfObjStmRX.Match(pdfDictionary(body)) {
+			streams = append(streams, body)
+		}
+	}
+
+	for _, stream := range streams {
+		for number, body := range readObjectStream(stream) {
+			if _, ok := objects[number]; !ok {
+				objects[number] = body
+			}
+		}
+	}
+
+	return objects
+}
+
+// readObjectStream unpacks a compressed object stream, whose data starts with
+// pairs of object numbers and offsets relative to /First.
+func readObjectStream(body []byte) map[int][]byte {
+	dict := pdfDictionary(body)
+	if !bytes.Contains(dict, []byte("/FlateDecode")) {
+		return nil
+	}
+
+	firstMatch := pdfFirstRX.FindSubmatch(dict)
+	if firstMatch == nil {
+		return nil
+	}
+	first, _ := strconv.Atoi(string(firstMatch[1]))
+
+	content, err := pdfStreamData(body)
+	if err != nil {
+		return nil
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil
+	}
+	defer zr.Close()
+
+	inflated, err := io.ReadAll(io.LimitReader(zr, maxObjectStreamBytes))
+	if err != nil && len(inflated) == 0 || first > len(inflated) {
+		return nil
+	}
+
+	header := strings.Fields(string(inflated[:first]))
+	objects := make(map[int][]byte)
+	for i := 0; i+1 < len(header); i += 2 {
+		number, err1 := strconv.Atoi(header[i])
+		start, err2 := strconv.Atoi(header[i+1])
+		// Offsets are checked against the room left after /First, so
+		// neither negative nor huge ones can reach the slice bounds.
+		if err1 != nil || err2 != nil || start < 0 || start > len(inflated)-first {
+			continue
+		}
+
+		end := len(inflated)
+		if i+3 < len(header) {
+			if next, err := strconv.Atoi(header[i+3]); err == nil && next >= start && next <= len(inflated)-first {
+				end = first + next
+			}
+		}
+
+		objects[number] = inflated[first+start : end]
+	}
+
+	return objects
+}
+
+// pdfStreamData returns the raw bytes between the stream and endstream
+// keywords of an object body.
+func pdfStreamData(body []byte) ([]byte, error) {
+	start := bytes.Index(body, []byte("stream"))
+	if start < 0 {
+		return nil, fmt.Errorf("%w: object has no stream", ErrMalformed)
+	}
+
+	content := body[start+len("stream"):]
+	content = bytes.TrimPrefix(content, []byte("\r"))
+	content = bytes.TrimPrefix(content, []byte("\n"))
+
+	if end := bytes.LastIndex(content, []byte("endstream")); end >= 0 {
+		content = content[:end]
+	}
+	return content, nil
+}
+
+// pdfPageCount reads /Count from the root of the page tree, falling back on
+// the largest count of any page tree node when the catalog cannot be found.
+func pdfPageCount(data []byte, objects pdfObjects) int {
+	if root := lastSubmatch(pdfRootRX, data); root != nil {
+		catalog := pdfDictionary(objects[atoi(root)])
+		if pages := pdfPagesRefRX.FindSubmatch(catalog); pages != nil {
+			tree := pdfDictionary(objects[atoi(pages[1])])
+			if count := pdfCountRX.FindSubmatch(tree); count != nil {
+				return atoi(count[1])
+			}
+		}
+	}
+
+	pages := 0
+	for _, body := range objects {
+		dict := pdfDictionary(body)
+		if !pdfPagesTypeRX.Match(dict) {
+			continue
+		}
+
+		if count := pdfCountRX.FindSubmatch(dict); count != nil {
+			pages = max(pages, atoi(count[1]))
+		}
+	}
+	return pages
+}
+
+// pdfDictionary returns the part of an object body before its stream, if any.
+func pdfDictionary(body []byte) []byte {
+	if end := bytes.Index(body, []byte("stream")); end >= 0 {
+		return body[:end]
+	}
+	return body
+}
+
+// text returns the string value of key in dict, following a reference to an
+// indirect string object.
+func (objects pdfObjects) text(dict []byte, key string) string {
+	value := pdfValue(dict, key)
+	if value == nil {
+		return ""
+	}
+
+	if ref := pdfRefRX.FindSubmatch(value); ref != nil {
+		value = bytes.TrimSpace(objects[atoi(ref[1])])
+	}
+
+	return pdfString(value)
+}
+
+// pdfValue returns the bytes following /key in dict.
+func pdfValue(dict []byte, key string) []byte {
+	name := []byte("/" + key)
+	for offset := 0; ; {
+		i := bytes.Index(dict[offset:], name)
+		if i < 0 {
+			return nil
+		}
+
+		rest := dict[offset+i+len(name):]
+		if len(rest) > 0 && !isPDFDelimiter(rest[0]) {
+			offset += i + len(name)
+			continue
+		}
+		return bytes.TrimLeft(rest, " \t\r\n\f\x00")
+	}
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte(" \t\r\n\f\x00()<>[]{}/%", c) >= 0
+}
+
+// pdfString decodes the literal or hexadecimal string value starts with.
+func pdfString(value []byte) string {
+	var raw []byte
+	switch {
+	case bytes.HasPrefix(value, []byte("(")):
+		raw = pdfLiteral(value[1:])
+	case bytes.HasPrefix(value, []byte("<")) && !bytes.HasPrefix(value, []byte("<<")):
+		raw = pdfHex(value[1:])
+	default:
+		return ""
+	}
+
+	return strings.TrimSpace(decodePDFText(raw))
+}
+
+// pdfLiteral reads a literal string up to its closing parenthesis, which
+// balances any unescaped ones inside.
+func pdfLiteral(s []byte) []byte {
+	var out []byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '(':
+			depth++
+			out = append(out, c)
+		case c == ')':
+			if depth == 0 {
+				return out
+			}
+			depth--
+			out = append(out, c)
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch c = s[i]; c {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if i+1 < len(s) && s[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if c >= '0' && c <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(s) && s[i] >= '0' && s[i] <= '7'; j++ {
+						n = n*8 + int(s[i]-'0')
+						i++
+					}
+					i--
+					out = append(out, byte(n))
+				} else {
+					out = append(out, c)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// pdfHex reads a hexadecimal string up to its closing angle bracket. A
+// missing last digit counts as zero.
+func pdfHex(s []byte) []byte {
+	var digits []byte
+	for _, c := range s {
+		if c == '>' {
+			break
+		}
+		if strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		n, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(n)
+	}
+	return out
+}
+
+// decodePDFText decodes a text string, which is UTF-16BE or UTF-8 when it
+// starts with a byte order mark and PDFDocEncoding otherwise.
+func decodePDFText(raw []byte) string {
+	switch {
+	case bytes.HasPrefix(raw, []byte{0xfe, 0xff}):
+		raw = raw[2:]
+		units := make([]uint16, 0, len(raw)/2)
+		for i := 0; i+1 < len(raw); i += 2 {
+			units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+		}
+		return string(utf16.Decode(units))
+	case bytes.HasPrefix(raw, []byte{0xef, 0xbb, 0xbf}):
+		return string(raw[3:])
+	}
+
+	var b strings.Builder
+	for _, c := range raw {
+		if r, ok := pdfDocEncoding[c]; ok {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+func lastSubmatch(rx *regexp.Regexp, data []byte) []byte {
+	matches := rx.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	return matches[len(matches)-1][1]
+}
+
+func atoi(b []byte) int {
+	n, _ := strconv.Atoi(string(b))
+	return n
+}
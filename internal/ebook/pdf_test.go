@@ -0,0 +1,263 @@
+package ebook
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// pdfFile builds a PDF out of object bodies by number, with the catalog as
+// object 1 and the information dictionary as object 2.
+func pdfFile(objects map[int]string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	for _, number := range slices.Sorted(maps.Keys(objects)) {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", number, objects[number])
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R /Info 2 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+// objectStream packs object bodies by number into a compressed object
+// stream.
+func objectStream(objects map[int]string) string {
+	var header, content strings.Builder
+	for _, number := range slices.Sorted(maps.Keys(objects)) {
+		fmt.Fprintf(&header, "%d %d ", number, content.Len())
+		content.WriteString(objects[number] + "\n")
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte(header.String() + content.String()))
+	zw.Close()
+
+	return fmt.Sprintf(
+		"<< /Type /ObjStm /N %d /First %d /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream",
+		len(objects),
+		header.Len(),
+		compressed.Len(),
+		compressed.Bytes(),
+	)
+}
+
+// infoPDF builds a PDF with info as its information dictionary and a page
+// tree of 12 pages.
+func infoPDF(info string) []byte {
+	return pdfFile(map[int]string{1: pdfCatalog, 2: info, 3: pdfPages})
+}
+
+const (
+	pdfCatalog = "<< /Type /Catalog /Pages 3 0 R >>"
+	pdfPages   = "<< /Type /Pages /Kids [] /Count 12 >>"
+)
+
+func TestReadPDF(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		title  string
+		author string
+		isbn   string
+		pages  int
+	}{
+		{
+			name:   "literal strings",
+			data:   infoPDF("<< /Title (Dune) /Author (Frank Herbert) >>"),
+			title:  "Dune",
+			author: "Frank Herbert",
+			pages:  12,
+		},
+		{
+			name:  "octal escapes",
+			data:  infoPDF(`<< /Title (Caf\351 \(au lait\) \201 \1010) >>`),
+			title: "Café (au lait) † A0",
+			pages: 12,
+		},
+		{
+			name:  "balanced parentheses and line continuation",
+			data:  infoPDF("<< /Title (Notes (annotated\\\n) edition) >>"),
+			title: "Notes (annotated) edition",
+			pages: 12,
+		},
+		{
+			name:   "UTF-16 hexadecimal",
+			data:   infoPDF("<< /Title <FEFF00C9 0074 00E9> /Author <FEFF004C00E9006F> >>"),
+			title:  "Été",
+			author: "Léo",
+			pages:  12,
+		},
+		{
+			name:  "UTF-16 literal with a surrogate pair",
+			data:  infoPDF(`<< /Title (\376\377\000O\000k\330=\336\000) >>`),
+			title: "Ok\U0001F600",
+			pages: 12,
+		},
+		{
+			name: "indirect title",
+			data: pdfFile(map[int]string{
+				1: pdfCatalog,
+				2: "<< /Title 4 0 R /Keywords (isbn 978-0-441-17271-9) >>",
+				3: pdfPages,
+				4: "(Dune Messiah)",
+			}),
+			title: "Dune Messiah",
+			isbn:  "9780441172719",
+			pages: 12,
+		},
+		{
+			name: "object stream",
+			data: pdfFile(map[int]string{
+				1: pdfCatalog,
+				4: objectStream(map[int]string{
+					2: "<< /Title (Children of Dune) /Author 5 0 R >>",
+					3: pdfPages,
+					5: "<FEFF0048006500720062006500720074>",
+				}),
+				6: "<< /Type /Pages /Count 99 >>",
+			}),
+			title:  "Children of Dune",
+			author: "Herbert",
+			pages:  12,
+		},
+		{
+			name:  "encrypted",
+			data:  infoPDF("<< /Title (x\001\002) /Encrypt 9 0 R >>"),
+			pages: 12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata, err := readPDF(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if metadata.Title != tt.title {
+				t.Errorf("title = %q, want %q", metadata.Title, tt.title)
+			}
+			author := ""
+			if len(metadata.Creators) > 0 {
+				author = metadata.Creators[0].Name
+			}
+			if author != tt.author {
+				t.Errorf("author = %q, want %q", author, tt.author)
+			}
+			if metadata.ISBN != tt.isbn {
+				t.Errorf("isbn = %q, want %q", metadata.ISBN, tt.isbn)
+			}
+			if metadata.Pages != tt.pages {
+				t.Errorf("pages = %d, want %d", metadata.Pages, tt.pages)
+			}
+		})
+	}
+}
+
+func TestReadObjectStream(t *testing.T) {
+	stream := func(header, content string, first int) []byte {
+		if first == 0 {
+			first = len(header)
+		}
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write([]byte(header + content))
+		zw.Close()
+		return fmt.Appendf(nil, "<< /Type /ObjStm /First %d /Filter /FlateDecode >>\nstream\n%s\nendstream", first, compressed.Bytes())
+	}
+
+	tests := []struct {
+		name string
+		body []byte
+		want map[int]string
+	}{
+		{
+			name: "two objects",
+			body: stream("7 0 8 5 ", "(one)(two)", 0),
+			want: map[int]string{7: "(one)", 8: "(two)"},
+		},
+		{
+			name: "negative offset",
+			body: stream("7 -3 8 0 ", "(one)", 0),
+			want: map[int]string{8: "(one)"},
+		},
+		{
+			name: "offset past the end",
+			body: stream("7 0 8 99999999999 ", "(one)", 0),
+			want: map[int]string{7: "(one)"},
+		},
+		{
+			name: "next offset before the start",
+			body: stream("7 3 8 1 ", "(one)", 0),
+			want: map[int]string{7: "e)", 8: "one)"},
+		},
+		{
+			name: "first past the end",
+			body: stream("7 0 ", "", 50),
+		},
+		{
+			name: "not compressed",
+			body: []byte("<< /Type /ObjStm /First 4 >>\nstream\n7 0 (one)\nendstream"),
+		},
+		{
+			name: "corrupt data",
+			body: []byte("<< /Type /ObjStm /First 4 /Filter /FlateDecode >>\nstream\nnot zlib\nendstream"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := readObjectStream(tt.body)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d objects, want %d", len(got), len(tt.want))
+			}
+			for number, body := range tt.want {
+				if string(got[number]) != body {
+					t.Errorf("object %d = %q, want %q", number, got[number], body)
+				}
+			}
+		})
+	}
+}
+
+func TestPDFLiteral(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`plain) rest`, "plain"},
+		{`a (b (c)) d)`, "a (b (c)) d"},
+		{`\(unbalanced)`, "(unbalanced"},
+		{`\n\r\t\b\f\\)`, "\n\r\t\b\f\\"},
+		{`\0\12\101\1011)`, "\x00\nAA1"},
+		{`\400)`, "\x00"},
+		{`\q)`, "q"},
+		{"a\\\r\nb)", "ab"},
+		{`no end`, "no end"},
+		{`trailing \`, "trailing \\"},
+	}
+
+	for _, tt := range tests {
+		if got := string(pdfLiteral([]byte(tt.in))); got != tt.want {
+			t.Errorf("pdfLiteral(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func FuzzReadPDF(f *testing.F) {
+	f.Add(infoPDF(`<< /Title (Caf\351) /Author <FEFF004C00E9006F> >>`))
+	f.Add(pdfFile(map[int]string{1: pdfCatalog, 4: objectStream(map[int]string{2: "<< /Title (Dune) >>", 3: pdfPages})}))
+	f.Add(pdfFile(map[int]string{1: pdfCatalog, 2: "<< /Title 4 0 R >>", 4: `(\376\377\330=`}))
+	f.Add([]byte("%PDF-1.4\n1 0 obj << /Type /ObjStm /First 3 /Filter /FlateDecode >> stream\nx\x9c endstream endobj"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		metadata, err := readPDF(data)
+		if err == nil && metadata == nil {
+			t.Fatal("no metadata and no error")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bookwise/internal/contexts"
 	"bookwise/internal/services"
 	"bookwise/utils"
 	"bookwise/utils/errors"
@@ -15,6 +16,9 @@ type AuthHandler struct {
 
 type AuthHandlerInterface interface {
 	LoginHandler(w http.ResponseWriter, r *http.Request)
+	FindFeedToken(w http.ResponseWriter, r *http.Request)
+	CreateFeedToken(w http.ResponseWriter, r *http.Request)
+	RevokeFeedToken(w http.ResponseWriter, r *http.Request)
 }
 
 func NewAuthHandler(authService services.AuthServiceInterface, errResp errors.ErrorResponseInterface) *AuthHandler {
@@ -48,3 +52,39 @@ func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		h.errorResponse.ServerErrorResponse(w, r, err)
 	}
 }
+
+// FindFeedToken describes the feed token of the user, without the token
+// itself, which is only shown when it is created.
+func (h *AuthHandler) FindFeedToken(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	token, err := h.auth.FindFeedToken(user.ID)
+	if err != nil {
+		h.errorResponse.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"feed_token": token.ToDTO()}, nil, h.errorResponse)
+}
+
+// CreateFeedToken answers with a new feed token and the catalog URL it
+// opens, replacing the token the user had.
+func (h *AuthHandler) CreateFeedToken(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	token, err := h.auth.CreateFeedToken(user.ID)
+	if err != nil {
+		h.errorResponse.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusCreated, utils.Envelope{"feed_token": token.ToDTO()}, nil, h.errorResponse)
+}
+
+func (h *AuthHandler) RevokeFeedToken(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	if err := h.auth.RevokeFeedToken(user.ID); err != nil {
+		h.errorResponse.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type authorHandler struct {
+	author services.AuthorService
+	errRsp e.ErrorResponseInterface
+}
+
+type AuthorHandler interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+	FindByID(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+}
+
+func NewAuthorHandler(
+	author services.AuthorService,
+	errRsp e.ErrorResponseInterface,
+) *authorHandler {
+	return &authorHandler{
+		author: author,
+		errRsp: errRsp,
+	}
+}
+
+func (h *authorHandler) FindAll(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		name string
+		filters.Filters
+	}
+
+	v := validator.New()
+	preferences := contexts.ContextGetPreferences(r)
+
+	qs := r.URL.Query()
+	input.name = utils.ReadString(qs, "name", "")
+	input.Filters.Page = utils.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = utils.ReadInt(qs, "page_size", preferences.PageSize, v)
+	input.Filters.Sort = utils.ReadString(qs, "sort", "sort_name")
+	input.Filters.SortSafelist = []string{"id", "name", "sort_name", "-id", "-name", "-sort_name"}
+
+	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	authors, metadata, err := h.author.FindAll(input.name, user.ID, input.Filters)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	dtos := make([]*models.AuthorDTO, 0, len(authors))
+	for _, author := range authors {
+		dtos = append(dtos, author.ToDTO())
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"authors": dtos, "metadata": metadata}, nil, h.errRsp)
+}
+
+func (h *authorHandler) FindByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	author, err := h.author.FindByID(id, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"author": author.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *authorHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var dto models.AuthorDTO
+	if err := utils.ReadJSON(w, r, &dto); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	author := dto.ToModel()
+
+	if err := h.author.Update(author, user.ID, v); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"author": author.ToDTO()}, nil, h.errRsp)
+}
@@ -7,51 +7,100 @@ import (
 	"bookwise/internal/services"
 	"bookwise/utils"
 	e "bookwise/utils/errors"
+	"bookwise/utils/isbn"
 	"bookwise/utils/validator"
 	"net/http"
+	"net/url"
+	"slices"
+	"time"
 )
 
 type bookHandler struct {
-	book   services.BookService
-	errRsp e.ErrorResponseInterface
+	book          services.BookService
+	shelf         services.ShelfService
+	errRsp        e.ErrorResponseInterface
+	maxCoverBytes int64
 	GenericHandlerInterface[models.Book, models.BookDTO]
 }
 
 func NewBookHandler(
 	book services.BookService,
+	shelf services.ShelfService,
 	errRsp e.ErrorResponseInterface,
+	maxCoverBytes int64,
 ) *bookHandler {
 	return &bookHandler{
 		book:                    book,
+		shelf:                   shelf,
 		errRsp:                  errRsp,
+		maxCoverBytes:           maxCoverBytes,
 		GenericHandlerInterface: NewGenericHandler(book, errRsp),
 	}
 }
 
 type BookHandler interface {
 	FindAll(w http.ResponseWriter, r *http.Request)
+	UploadCover(w http.ResponseWriter, r *http.Request)
+	DeleteCover(w http.ResponseWriter, r *http.Request)
+	Cover(w http.ResponseWriter, r *http.Request)
+	CoverThumbnail(w http.ResponseWriter, r *http.Request)
+	Spending(w http.ResponseWriter, r *http.Request)
 	GenericHandlerInterface[
 		models.Book,
 		models.BookDTO,
 	]
 }
 
+// readBookFilters reads and checks the book search parameters shared by the
+// book list and the library export.
+func readBookFilters(qs url.Values, v *validator.Validator) filters.BookFilters {
+	var search filters.BookFilters
+
+	search.Title = utils.ReadString(qs, "title", "")
+	search.Author = utils.ReadString(qs, "author", "")
+	search.AuthorID = int64(utils.ReadInt(qs, "author_id", 0, v))
+	search.SeriesID = int64(utils.ReadInt(qs, "series_id", 0, v))
+	search.LocationID = int64(utils.ReadInt(qs, "location_id", 0, v))
+	search.IDs = utils.ReadIntList(qs, "ids", v)
+	if raw := utils.ReadString(qs, "isbn", ""); raw != "" {
+		normalized, err := isbn.Normalize(raw)
+		v.Check(err == nil, "isbn", "must be a valid ISBN-10 or ISBN-13")
+		search.ISBN = normalized
+	}
+	// Repeated shelves would never all match with shelf_mode=and.
+	search.ShelfIDs = utils.ReadIntList(qs, "shelves", v)
+	slices.Sort(search.ShelfIDs)
+	search.ShelfIDs = slices.Compact(search.ShelfIDs)
+	search.ShelfMatchAll = utils.ReadString(qs, "shelf_mode", "or") == "and"
+	search.MinRating = utils.ReadFloat(qs, "min_rating", v)
+	search.MaxRating = utils.ReadFloat(qs, "max_rating", v)
+	search.Acquisition = utils.ReadStringList(qs, "acquisition")
+
+	v.Check(validator.In(utils.ReadString(qs, "shelf_mode", "or"), "and", "or"), "shelf_mode", "must be and or or")
+	filters.ValidateBookFilters(v, search)
+
+	return search
+}
+
 func (h *bookHandler) FindAll(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		title,
-		author string
+		filters.BookFilters
 		filters.Filters
 	}
 
 	v := validator.New()
 
+	preferences := contexts.ContextGetPreferences(r)
+
 	qs := r.URL.Query()
-	input.title = utils.ReadString(qs, "title", "")
-	input.author = utils.ReadString(qs, "author", "")
+	input.BookFilters = readBookFilters(qs, v)
 	input.Filters.Page = utils.ReadInt(qs, "page", 1, v)
-	input.Filters.PageSize = utils.ReadInt(qs, "page_size", 20, v)
+	input.Filters.PageSize = utils.ReadInt(qs, "page_size", preferences.PageSize, v)
 	input.Filters.Sort = utils.ReadString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+	input.Filters.SortSafelist = []string{
+		"id", "title", "published_date", "rating",
+		"-id", "-title", "-published_date", "-rating",
+	}
 
 	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
 		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
@@ -60,8 +109,7 @@ func (h *bookHandler) FindAll(w http.ResponseWriter, r *http.Request) {
 
 	user := contexts.ContextGetUser(r)
 	books, metadata, err := h.book.FindAll(
-		input.title,
-		input.author,
+		input.BookFilters,
 		user.ID,
 		input.Filters,
 	)
@@ -71,11 +119,118 @@ func (h *bookHandler) FindAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	shelves, err := h.shelf.CountBooks(input.BookFilters, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
 	dtos := make([]*models.BookDTO, 0, len(books))
 
 	for _, book := range books {
 		dtos = append(dtos, book.ToDTO())
 	}
 
-	respond(w, r, http.StatusOK, utils.Envelope{"books": dtos, "metadata": metadata}, nil, h.errRsp)
+	respond(
+		w, r,
+		http.StatusOK,
+		utils.Envelope{"books": dtos, "metadata": metadata, "shelves": shelvesToDTO(shelves)},
+		nil,
+		h.errRsp,
+	)
+}
+
+// UploadCover accepts the cover image either as the raw request body or as the
+// "cover" field of a multipart form.
+func (h *bookHandler) UploadCover(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	data, err := readUpload(w, r, "cover", h.maxCoverBytes)
+	if err != nil {
+		uploadErrorResponse(w, r, err, h.errRsp)
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+
+	book, err := h.book.SetCover(id, user.ID, data, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"book": book.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *bookHandler) DeleteCover(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	if err := h.book.RemoveCover(id, user.ID); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *bookHandler) Cover(w http.ResponseWriter, r *http.Request) {
+	h.serveCover(w, r, false)
+}
+
+func (h *bookHandler) CoverThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveCover(w, r, true)
+}
+
+func (h *bookHandler) serveCover(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	body, info, err := h.book.Cover(id, user.ID, thumbnail)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	serveBlob(w, r, body, info)
+}
+
+// Spending reports what the user spent on books, optionally only on those
+// bought from the date from to the date to.
+func (h *bookHandler) Spending(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	from := utils.ReadDate(qs, "from", time.DateOnly)
+	to := utils.ReadDate(qs, "to", time.DateOnly)
+
+	v.Check(qs.Get("from") == "" || from != nil, "from", "must be a date such as 2024-01-31")
+	v.Check(qs.Get("to") == "" || to != nil, "to", "must be a date such as 2024-01-31")
+	if from != nil && to != nil {
+		v.Check(!from.After(*to), "from", "must not be after to")
+	}
+
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	spending, err := h.book.Spending(user.ID, from, to)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"spending": spending}, nil, h.errRsp)
 }
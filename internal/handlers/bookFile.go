@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+type bookFileHandler struct {
+	bookFile services.BookFileService
+	errRsp   e.ErrorResponseInterface
+	maxBytes int64
+}
+
+type BookFileHandler interface {
+	Upload(w http.ResponseWriter, r *http.Request)
+	FindUpload(w http.ResponseWriter, r *http.Request)
+	Confirm(w http.ResponseWriter, r *http.Request)
+	Discard(w http.ResponseWriter, r *http.Request)
+	FindByBook(w http.ResponseWriter, r *http.Request)
+	Download(w http.ResponseWriter, r *http.Request)
+}
+
+func NewBookFileHandler(
+	bookFile services.BookFileService,
+	errRsp e.ErrorResponseInterface,
+	maxBytes int64,
+) *bookFileHandler {
+	return &bookFileHandler{
+		bookFile: bookFile,
+		errRsp:   errRsp,
+		maxBytes: maxBytes,
+	}
+}
+
+// Upload accepts an EPUB, PDF or MOBI file, as the raw request body or as the
+// "file" field of a multipart form, and answers with the book its metadata
+// describes and what the book still lacks. Nothing is added to the library
+// until the upload is confirmed.
+func (h *bookFileHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	data, name, err := readNamedUpload(w, r, "file", h.maxBytes)
+	if err != nil {
+		uploadErrorResponse(w, r, err, h.errRsp)
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	upload, err := h.bookFile.Upload(data, name, user.ID, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/books/files/%d", upload.File.ID))
+
+	respond(w, r, http.StatusCreated, utils.Envelope{"upload": upload.ToDTO()}, headers, h.errRsp)
+}
+
+func (h *bookFileHandler) FindUpload(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	upload, err := h.bookFile.FindUpload(id, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"upload": upload.ToDTO()}, nil, h.errRsp)
+}
+
+// Confirm creates the book of an upload from the book sent, usually the one
+// the upload answered with as completed by the user.
+func (h *bookFileHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	var dto models.BookDTO
+	if err := utils.ReadJSON(w, r, &dto); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	book := dto.ToModel()
+	if dto.Format == nil {
+		book.Format = models.BookFormatEbook
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	book, err := h.bookFile.Confirm(id, user.ID, book, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusCreated, utils.Envelope{"book": book.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *bookFileHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	if err := h.bookFile.Discard(id, user.ID); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *bookFileHandler) FindByBook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	files, err := h.bookFile.FindByBook(id, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	dtos := make([]*models.BookFileDTO, 0, len(files))
+	for _, file := range files {
+		dtos = append(dtos, file.ToDTO())
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"files": dtos}, nil, h.errRsp)
+}
+
+// Download serves a file of a book under the name it was uploaded with.
+func (h *bookFileHandler) Download(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	id, err := utils.ReadIntPathVariable(r, "fileID")
+	if err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	file, body, info, err := h.bookFile.Open(bookID, id, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	setAttachment(w, file, bookID)
+	serveBlob(w, r, body, info)
+}
+
+// setAttachment has a file downloaded under the name it was uploaded with.
+func setAttachment(w http.ResponseWriter, file *models.BookFile, bookID int64) {
+	name := file.FileName
+	if name == "" {
+		name = fmt.Sprintf("book-%d.%s", bookID, file.Format)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type chapterHandler struct {
+	chapter services.ChapterService
+	errRsp  e.ErrorResponseInterface
+}
+
+type ChapterHandler interface {
+	FindByBook(w http.ResponseWriter, r *http.Request)
+	Replace(w http.ResponseWriter, r *http.Request)
+	ImportFromFile(w http.ResponseWriter, r *http.Request)
+}
+
+func NewChapterHandler(
+	chapter services.ChapterService,
+	errRsp e.ErrorResponseInterface,
+) *chapterHandler {
+	return &chapterHandler{
+		chapter: chapter,
+		errRsp:  errRsp,
+	}
+}
+
+func (h *chapterHandler) FindByBook(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	chapters, err := h.chapter.FindByBook(bookID, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	h.respondChapters(w, r, chapters)
+}
+
+// Replace sets the table of contents of a book to the chapters sent, in
+// their order.
+func (h *chapterHandler) Replace(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Chapters []*models.ChapterDTO `json:"chapters"`
+	}
+
+	if err := utils.ReadJSON(w, r, &input); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	chapters := make([]*models.Chapter, 0, len(input.Chapters))
+	for _, dto := range input.Chapters {
+		if dto != nil {
+			chapters = append(chapters, dto.ToModel())
+		}
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	chapters, err := h.chapter.Replace(bookID, user.ID, chapters, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	h.respondChapters(w, r, chapters)
+}
+
+// ImportFromFile takes the table of contents of a book from one of its EPUB
+// files.
+func (h *chapterHandler) ImportFromFile(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	fileID, err := utils.ReadIntPathVariable(r, "fileID")
+	if err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	chapters, err := h.chapter.ImportFromFile(bookID, fileID, user.ID, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	h.respondChapters(w, r, chapters)
+}
+
+func (h *chapterHandler) respondChapters(w http.ResponseWriter, r *http.Request, chapters []*models.Chapter) {
+	dtos := make([]*models.ChapterDTO, 0, len(chapters))
+	for _, chapter := range chapters {
+		dtos = append(dtos, chapter.ToDTO())
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"chapters": dtos}, nil, h.errRsp)
+}
@@ -11,11 +11,28 @@ import (
 )
 
 type Handler struct {
-	User        UserHandlerInterface
-	Auth        AuthHandlerInterface
-	Book        BookHandler
-	ReadingPlan ReadingPlanHandler
-	Service     *services.Services
+	User          UserHandlerInterface
+	Auth          AuthHandlerInterface
+	Book          BookHandler
+	ReadingPlan   ReadingPlanHandler
+	Preferences   PreferencesHandler
+	Shelf         ShelfHandler
+	Author        AuthorHandler
+	Series        SeriesHandler
+	Trash         TrashHandler
+	Review        ReviewHandler
+	Highlight     HighlightHandler
+	LibraryImport LibraryImportHandler
+	LibraryExport LibraryExportHandler
+	BookFile      BookFileHandler
+	OPDS          OPDSHandler
+	KOReader      KOReaderHandler
+	Kobo          KoboHandler
+	Loan          LoanHandler
+	Location      LocationHandler
+	Label         LabelHandler
+	Chapter       ChapterHandler
+	Service       *services.Services
 }
 
 func NewHandler(
@@ -30,8 +47,29 @@ func NewHandler(
 		Service:     s,
 		User:        NewUserHandler(s.User, errRsp),
 		Auth:        NewAuthHandler(s.Auth, errRsp),
-		Book:        NewBookHandler(s.Book, errRsp),
+		Book:        NewBookHandler(s.Book, s.Shelf, errRsp, config.Covers.MaxBytes),
 		ReadingPlan: NewReadingPlanHandler(s.ReadingPlan, errRsp),
+		Preferences: NewPreferencesHandler(s.Preferences, errRsp),
+		Shelf:       NewShelfHandler(s.Shelf, errRsp),
+		Author:      NewAuthorHandler(s.Author, errRsp),
+		Series:      NewSeriesHandler(s.Series, errRsp),
+		Trash:       NewTrashHandler(s.Trash, errRsp, config.Trash.Retention),
+		Review:      NewReviewHandler(s.Review, errRsp),
+		Highlight:   NewHighlightHandler(s.Highlight, errRsp),
+		LibraryImport: NewLibraryImportHandler(
+			s.LibraryImport,
+			errRsp,
+			config.Imports.MaxBytes,
+		),
+		LibraryExport: NewLibraryExportHandler(s.LibraryExport, errRsp, logger),
+		BookFile:      NewBookFileHandler(s.BookFile, errRsp, config.Files.MaxBytes),
+		OPDS:          NewOPDSHandler(s.Book, s.BookFile, s.Shelf, s.Author, s.Series, errRsp),
+		KOReader:      NewKOReaderHandler(s.KOReader, errRsp),
+		Kobo:          NewKoboHandler(s.Kobo, errRsp, config.Files.MaxBytes),
+		Loan:          NewLoanHandler(s.Loan, errRsp),
+		Location:      NewLocationHandler(s.Location, errRsp),
+		Label:         NewLabelHandler(s.Label, errRsp),
+		Chapter:       NewChapterHandler(s.Chapter, errRsp),
 	}
 }
 
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bookwise/internal/clippings"
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxClippingsBytes bounds an uploaded e-reader export. A Kindle's
+// My Clippings.txt grows with every highlight ever made on the device.
+const maxClippingsBytes = 20 << 20
+
+type highlightHandler struct {
+	highlight services.HighlightService
+	errRsp    e.ErrorResponseInterface
+	GenericHandlerInterface[models.Highlight, models.HighlightDTO]
+}
+
+type HighlightHandler interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+	FindByBook(w http.ResponseWriter, r *http.Request)
+	Random(w http.ResponseWriter, r *http.Request)
+	Export(w http.ResponseWriter, r *http.Request)
+	Import(w http.ResponseWriter, r *http.Request)
+	GenericHandlerInterface[models.Highlight, models.HighlightDTO]
+}
+
+func NewHighlightHandler(
+	highlight services.HighlightService,
+	errRsp e.ErrorResponseInterface,
+) *highlightHandler {
+	return &highlightHandler{
+		highlight:               highlight,
+		errRsp:                  errRsp,
+		GenericHandlerInterface: NewGenericHandler(highlight, errRsp),
+	}
+}
+
+func readHighlightFilters(qs url.Values, v *validator.Validator) filters.HighlightFilters {
+	return filters.HighlightFilters{
+		Query:  strings.TrimSpace(utils.ReadString(qs, "q", "")),
+		BookID: int64(utils.ReadInt(qs, "book_id", 0, v)),
+		Color:  utils.ReadString(qs, "color", ""),
+	}
+}
+
+// FindAll searches the passages and notes across all of the user's books.
+func (h *highlightHandler) FindAll(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, 0)
+}
+
+func (h *highlightHandler) FindByBook(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	h.list(w, r, bookID)
+}
+
+func (h *highlightHandler) list(w http.ResponseWriter, r *http.Request, bookID int64) {
+	var input struct {
+		filters.HighlightFilters
+		filters.Filters
+	}
+
+	v := validator.New()
+	preferences := contexts.ContextGetPreferences(r)
+
+	qs := r.URL.Query()
+	input.HighlightFilters = readHighlightFilters(qs, v)
+	if bookID != 0 {
+		input.BookID = bookID
+	}
+	input.Filters.Page = utils.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = utils.ReadInt(qs, "page_size", preferences.PageSize, v)
+	input.Filters.Sort = utils.ReadString(qs, "sort", "-created_at")
+	input.Filters.SortSafelist = []string{"id", "page", "created_at", "-id", "-page", "-created_at"}
+
+	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	highlights, metadata, err := h.highlight.FindAll(input.HighlightFilters, user.ID, input.Filters)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	dtos := make([]*models.HighlightDTO, 0, len(highlights))
+	for _, highlight := range highlights {
+		dtos = append(dtos, highlight.ToDTO())
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"highlights": dtos, "metadata": metadata}, nil, h.errRsp)
+}
+
+// Random returns a random matching highlight. With daily=true the same one is
+// returned for the whole day in the user's time zone.
+func (h *highlightHandler) Random(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	search := readHighlightFilters(qs, v)
+	daily := utils.ReadString(qs, "daily", "false")
+	v.Check(validator.In(daily, "true", "false"), "daily", "must be true or false")
+
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	seed := ""
+	if daily == "true" {
+		loc := contexts.ContextGetPreferences(r).Location()
+		seed = time.Now().In(loc).Format("2006-01-02")
+	}
+
+	user := contexts.ContextGetUser(r)
+	highlight, err := h.highlight.Random(search, user.ID, seed)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"highlight": highlight.ToDTO()}, nil, h.errRsp)
+}
+
+// Export downloads the matching highlights as a Markdown document.
+func (h *highlightHandler) Export(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	search := readHighlightFilters(r.URL.Query(), v)
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	highlights, err := h.highlight.Export(search, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := models.WriteHighlightsMarkdown(&buf, highlights); err != nil {
+		h.errRsp.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="highlights.md"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// Import adds the highlights of a Kindle My Clippings.txt or a KOReader JSON
+// export, sent as the raw body or as the "file" field of a multipart form.
+// The format is detected unless given, dry_run=true only previews it and
+// create_missing=true creates the books that match none of the user's.
+func (h *highlightHandler) Import(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	format := utils.ReadString(qs, "format", "")
+	dryRun := utils.ReadString(qs, "dry_run", "false")
+	createMissing := utils.ReadString(qs, "create_missing", "false")
+	v.Check(
+		validator.In(format, "", string(clippings.FormatKindle), string(clippings.FormatKOReader)),
+		"format",
+		"must be kindle or koreader",
+	)
+	v.Check(validator.In(dryRun, "true", "false"), "dry_run", "must be true or false")
+	v.Check(validator.In(createMissing, "true", "false"), "create_missing", "must be true or false")
+
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	data, err := readUpload(w, r, "file", maxClippingsBytes)
+	if err != nil {
+		uploadErrorResponse(w, r, err, h.errRsp)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	result, err := h.highlight.Import(
+		data,
+		clippings.Format(format),
+		user.ID,
+		dryRun == "true",
+		createMissing == "true",
+		v,
+	)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"import": result}, nil, h.errRsp)
+}
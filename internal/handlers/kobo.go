@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type koboHandler struct {
+	kobo     services.KoboService
+	errRsp   e.ErrorResponseInterface
+	maxBytes int64
+}
+
+type KoboHandler interface {
+	Import(w http.ResponseWriter, r *http.Request)
+}
+
+func NewKoboHandler(
+	kobo services.KoboService,
+	errRsp e.ErrorResponseInterface,
+	maxBytes int64,
+) *koboHandler {
+	return &koboHandler{
+		kobo:     kobo,
+		errRsp:   errRsp,
+		maxBytes: maxBytes,
+	}
+}
+
+// Import records the reading time, progress and highlights of a Kobo's
+// KoboReader.sqlite, sent as the raw body or as the "file" field of a
+// multipart form. Sessions are dated in the user's time zone, and
+// dry_run=true only previews the import.
+func (h *koboHandler) Import(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	dryRun := utils.ReadString(r.URL.Query(), "dry_run", "false")
+	v.Check(validator.In(dryRun, "true", "false"), "dry_run", "must be true or false")
+
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	data, err := readUpload(w, r, "file", h.maxBytes)
+	if err != nil {
+		uploadErrorResponse(w, r, err, h.errRsp)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	loc := contexts.ContextGetPreferences(r).Location()
+	result, err := h.kobo.Import(data, user.ID, loc, dryRun == "true", v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"import": result}, nil, h.errRsp)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/koreader"
+	"bookwise/internal/models"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+type koreaderHandler struct {
+	koreader services.KOReaderService
+	errRsp   e.ErrorResponseInterface
+}
+
+// KOReaderHandler serves the progress sync protocol of KOReader, whose
+// responses follow the protocol rather than the rest of the API, and the
+// settings of the account the plugin logs in with.
+type KOReaderHandler interface {
+	Healthcheck(w http.ResponseWriter, r *http.Request)
+	Register(w http.ResponseWriter, r *http.Request)
+	Authorize(w http.ResponseWriter, r *http.Request)
+	UpdateProgress(w http.ResponseWriter, r *http.Request)
+	FindProgress(w http.ResponseWriter, r *http.Request)
+	FindAccount(w http.ResponseWriter, r *http.Request)
+	SaveAccount(w http.ResponseWriter, r *http.Request)
+	DeleteAccount(w http.ResponseWriter, r *http.Request)
+}
+
+func NewKOReaderHandler(
+	koreader services.KOReaderService,
+	errRsp e.ErrorResponseInterface,
+) *koreaderHandler {
+	return &koreaderHandler{
+		koreader: koreader,
+		errRsp:   errRsp,
+	}
+}
+
+func (h *koreaderHandler) Healthcheck(w http.ResponseWriter, r *http.Request) {
+	respond(w, r, http.StatusOK, utils.Envelope{"state": "OK"}, nil, h.errRsp)
+}
+
+// Register answers the register button of the plugin. Accounts are set up
+// in Bookwise, so it only confirms an account that already exists.
+func (h *koreaderHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	if err := utils.ReadJSON(w, r, &input); err != nil || input.Username == "" || input.Password == "" {
+		koreader.WriteError(w, http.StatusBadRequest, koreader.CodeInvalidFields, "Invalid request")
+		return
+	}
+
+	err := h.koreader.Register(input.Username, input.Password)
+	switch {
+	case err == nil:
+		respond(w, r, http.StatusCreated, utils.Envelope{"username": input.Username}, nil, h.errRsp)
+	case errors.Is(err, e.ErrRecordNotFound):
+		koreader.WriteError(w, http.StatusForbidden, koreader.CodeInvalidFields, "Set up KOReader sync in your Bookwise settings first.")
+	case errors.Is(err, e.ErrDuplicateUser):
+		koreader.WriteError(w, http.StatusPaymentRequired, koreader.CodeUserExists, "Username is already registered.")
+	default:
+		h.errRsp.ServerErrorResponse(w, r, err)
+	}
+}
+
+// Authorize answers the login of the plugin, whose credentials
+// AuthenticateKOReader already checked.
+func (h *koreaderHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	respond(w, r, http.StatusOK, utils.Envelope{"authorized": "OK"}, nil, h.errRsp)
+}
+
+// UpdateProgress saves the position the plugin sends, recording the pages
+// read in the plan of the book of the document.
+func (h *koreaderHandler) UpdateProgress(w http.ResponseWriter, r *http.Request) {
+	var dto models.KOReaderProgressDTO
+	if err := utils.ReadJSON(w, r, &dto); err != nil {
+		koreader.WriteError(w, http.StatusBadRequest, koreader.CodeInvalidFields, "Invalid request")
+		return
+	}
+
+	progress := dto.ToModel()
+	user := contexts.ContextGetUser(r)
+	today := time.Now().In(contexts.ContextGetPreferences(r).Location())
+
+	v := validator.New()
+	err := h.koreader.UpdateProgress(progress, user.ID, today, v)
+	switch {
+	case err == nil:
+		respond(w, r, http.StatusOK, utils.Envelope{
+			"document":  progress.Document,
+			"timestamp": progress.UpdatedAt.Unix(),
+		}, nil, h.errRsp)
+	case errors.Is(err, e.ErrInvalidData):
+		if _, ok := v.Errors["document"]; ok {
+			koreader.WriteError(w, http.StatusForbidden, koreader.CodeDocumentMissing, "Field 'document' not provided.")
+			return
+		}
+		koreader.WriteError(w, http.StatusForbidden, koreader.CodeInvalidFields, "Invalid request")
+	default:
+		h.errRsp.ServerErrorResponse(w, r, err)
+	}
+}
+
+// FindProgress answers with the last position of a document, or an empty
+// object when it was never synced.
+func (h *koreaderHandler) FindProgress(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	progress, err := h.koreader.FindProgress(user.ID, chi.URLParam(r, "document"))
+	switch {
+	case err == nil:
+		respond(w, r, http.StatusOK, utils.Envelope{
+			"document":   progress.Document,
+			"progress":   progress.Progress,
+			"percentage": progress.Percentage,
+			"device":     progress.Device,
+			"device_id":  progress.DeviceID,
+			"timestamp":  progress.UpdatedAt.Unix(),
+		}, nil, h.errRsp)
+	case errors.Is(err, e.ErrRecordNotFound):
+		respond(w, r, http.StatusOK, utils.Envelope{}, nil, h.errRsp)
+	default:
+		h.errRsp.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *koreaderHandler) FindAccount(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	account, err := h.koreader.FindAccount(user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"koreader": account.ToDTO()}, nil, h.errRsp)
+}
+
+// SaveAccount sets the username and password to enter in the progress sync
+// settings of KOReader, replacing the ones the user had.
+func (h *koreaderHandler) SaveAccount(w http.ResponseWriter, r *http.Request) {
+	var dto models.KOReaderAccountDTO
+	if err := utils.ReadJSON(w, r, &dto); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	account := dto.ToModel()
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	if err := h.koreader.SaveAccount(account, user.ID, v); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"koreader": account.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *koreaderHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	if err := h.koreader.DeleteAccount(user.ID); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type labelHandler struct {
+	label  services.LabelService
+	errRsp e.ErrorResponseInterface
+}
+
+type LabelHandler interface {
+	Labels(w http.ResponseWriter, r *http.Request)
+}
+
+func NewLabelHandler(
+	label services.LabelService,
+	errRsp e.ErrorResponseInterface,
+) *labelHandler {
+	return &labelHandler{
+		label:  label,
+		errRsp: errRsp,
+	}
+}
+
+// Labels sends a PDF of label sheets for the books matching the same filters
+// as the book list, such as location_id or ids, on the paper named by the
+// paper parameter.
+func (h *labelHandler) Labels(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	search := readBookFilters(qs, v)
+	paper := utils.ReadString(qs, "paper", "a4")
+
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	document, err := h.label.Labels(search, user.ID, paper, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `attachment; filename="labels.pdf"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
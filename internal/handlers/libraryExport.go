@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/librarycsv"
+	"bookwise/internal/models"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// exportFlushEvery is how many books are buffered before they are sent
+	// to the client.
+	exportFlushEvery = 100
+	// exportWriteTimeout is how long the client has to take each flush, so
+	// large exports are not cut off by the server's write timeout.
+	exportWriteTimeout = 30 * time.Second
+)
+
+type libraryExportHandler struct {
+	libraryExport services.LibraryExportService
+	errRsp        e.ErrorResponseInterface
+	logger        jsonlog.Logger
+}
+
+type LibraryExportHandler interface {
+	Export(w http.ResponseWriter, r *http.Request)
+}
+
+func NewLibraryExportHandler(
+	libraryExport services.LibraryExportService,
+	errRsp e.ErrorResponseInterface,
+	logger jsonlog.Logger,
+) *libraryExportHandler {
+	return &libraryExportHandler{
+		libraryExport: libraryExport,
+		errRsp:        errRsp,
+		logger:        logger,
+	}
+}
+
+// Export streams the books matching the same filters as the book list as
+// format=json, with their review, shelves, plans and sessions, as
+// format=csv, one dataset of books, plans or sessions at a time, or as
+// format=goodreads, a CSV Goodreads and StoryGraph import. Errors before the
+// first books are sent get the usual error response; later ones abort the
+// connection, as the status has already gone out.
+func (h *libraryExportHandler) Export(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	format := utils.ReadString(qs, "format", "json")
+	dataset := utils.ReadString(qs, "dataset", string(models.LibraryDatasetBooks))
+	search := readBookFilters(qs, v)
+
+	v.Check(validator.In(format, "json", "csv", "goodreads"), "format", "must be json, csv or goodreads")
+	v.Check(
+		validator.In(
+			dataset,
+			string(models.LibraryDatasetBooks),
+			string(models.LibraryDatasetPlans),
+			string(models.LibraryDatasetSessions),
+		),
+		"dataset",
+		"must be books, plans or sessions",
+	)
+
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	out := &exportResponse{w: w}
+	var enc libraryEncoder
+	switch format {
+	case "csv":
+		out.contentType = "text/csv; charset=utf-8"
+		out.filename = "bookwise-" + dataset + ".csv"
+		enc = &csvLibraryEncoder{w: csv.NewWriter(out), dataset: models.LibraryDataset(dataset)}
+	case "goodreads":
+		out.contentType = "text/csv; charset=utf-8"
+		out.filename = "goodreads_library_export.csv"
+		enc = &goodreadsLibraryEncoder{w: librarycsv.NewGoodreadsWriter(out)}
+	default:
+		out.contentType = "application/json"
+		out.filename = "bookwise-library.json"
+		enc = &jsonLibraryEncoder{w: bufio.NewWriter(out)}
+	}
+
+	// The deadlines only fail on writers that cannot take them, where the
+	// server's write timeout is all there is.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+
+	user := contexts.ContextGetUser(r)
+	written := 0
+	err := h.libraryExport.Export(search, user.ID, func(entry *models.LibraryEntry) error {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+
+		written++
+		if written%exportFlushEvery != 0 {
+			return nil
+		}
+
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+		_ = rc.Flush()
+		_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		return nil
+	})
+	if err == nil {
+		err = enc.Close()
+	}
+
+	if err != nil {
+		if !out.started {
+			h.errRsp.HandlerErrorResponse(w, r, err, v)
+			return
+		}
+
+		h.logger.PrintError(err, map[string]string{
+			"export":  format,
+			"written": strconv.Itoa(written),
+		})
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportResponse sends the status and headers of an export with its first
+// bytes, so nothing is committed while an error can still be reported.
+type exportResponse struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (er *exportResponse) Write(p []byte) (int, error) {
+	if !er.started {
+		er.started = true
+		er.w.Header().Set("Content-Type", er.contentType)
+		er.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", er.filename))
+		er.w.WriteHeader(http.StatusOK)
+	}
+	return er.w.Write(p)
+}
+
+// libraryEncoder writes the entries of an export in one format. Output is
+// buffered until Flush or Close.
+type libraryEncoder interface {
+	Encode(entry *models.LibraryEntry) error
+	Flush() error
+	Close() error
+}
+
+// jsonLibraryEncoder writes {"library": [...]}, one entry per line.
+type jsonLibraryEncoder struct {
+	w       *bufio.Writer
+	written int
+}
+
+func (enc *jsonLibraryEncoder) Encode(entry *models.LibraryEntry) error {
+	js, err := json.Marshal(entry.ToDTO())
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if enc.written == 0 {
+		sep = "{\"library\": [\n"
+	}
+	enc.written++
+
+	if _, err := enc.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = enc.w.Write(js)
+	return err
+}
+
+func (enc *jsonLibraryEncoder) Flush() error {
+	return enc.w.Flush()
+}
+
+func (enc *jsonLibraryEncoder) Close() error {
+	end := "\n]}\n"
+	if enc.written == 0 {
+		end = "{\"library\": []}\n"
+	}
+
+	if _, err := enc.w.WriteString(end); err != nil {
+		return err
+	}
+	return enc.w.Flush()
+}
+
+type csvLibraryEncoder struct {
+	w           *csv.Writer
+	dataset     models.LibraryDataset
+	wroteHeader bool
+}
+
+func (enc *csvLibraryEncoder) Encode(entry *models.LibraryEntry) error {
+	if err := enc.writeHeader(); err != nil {
+		return err
+	}
+
+	for _, record := range entry.CSVRecords(enc.dataset) {
+		if err := enc.w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (enc *csvLibraryEncoder) writeHeader() error {
+	if enc.wroteHeader {
+		return nil
+	}
+
+	enc.wroteHeader = true
+	return enc.w.Write(models.LibraryCSVHeader(enc.dataset))
+}
+
+func (enc *csvLibraryEncoder) Flush() error {
+	enc.w.Flush()
+	return enc.w.Error()
+}
+
+func (enc *csvLibraryEncoder) Close() error {
+	if err := enc.writeHeader(); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+type goodreadsLibraryEncoder struct {
+	w *librarycsv.GoodreadsWriter
+}
+
+func (enc *goodreadsLibraryEncoder) Encode(entry *models.LibraryEntry) error {
+	return enc.w.Write(entry.GoodreadsEntry())
+}
+
+func (enc *goodreadsLibraryEncoder) Flush() error {
+	return enc.w.Flush()
+}
+
+func (enc *goodreadsLibraryEncoder) Close() error {
+	return enc.w.Flush()
+}
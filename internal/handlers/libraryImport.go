@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/librarycsv"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"fmt"
+	"net/http"
+)
+
+type libraryImportHandler struct {
+	libraryImport services.LibraryImportService
+	errRsp        e.ErrorResponseInterface
+	maxBytes      int64
+}
+
+type LibraryImportHandler interface {
+	Start(w http.ResponseWriter, r *http.Request)
+	FindByID(w http.ResponseWriter, r *http.Request)
+}
+
+func NewLibraryImportHandler(
+	libraryImport services.LibraryImportService,
+	errRsp e.ErrorResponseInterface,
+	maxBytes int64,
+) *libraryImportHandler {
+	return &libraryImportHandler{
+		libraryImport: libraryImport,
+		errRsp:        errRsp,
+		maxBytes:      maxBytes,
+	}
+}
+
+// Start queues a Goodreads or StoryGraph CSV export or a Calibre metadata.db,
+// sent as the raw body or as the "file" field of a multipart form, and
+// answers 202 Accepted with the import, whose progress is polled at the
+// Location returned. The source is
+// detected unless given, and dry_run=true reports what the import would do
+// without saving anything.
+func (h *libraryImportHandler) Start(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	source := utils.ReadString(qs, "source", "")
+	dryRun := utils.ReadString(qs, "dry_run", "false")
+	v.Check(
+		validator.In(
+			source,
+			"",
+			string(librarycsv.SourceGoodreads),
+			string(librarycsv.SourceStoryGraph),
+			string(librarycsv.SourceCalibre),
+		),
+		"source",
+		"must be goodreads, storygraph or calibre",
+	)
+	v.Check(validator.In(dryRun, "true", "false"), "dry_run", "must be true or false")
+
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	data, err := readUpload(w, r, "file", h.maxBytes)
+	if err != nil {
+		uploadErrorResponse(w, r, err, h.errRsp)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	job, err := h.libraryImport.Start(data, librarycsv.Source(source), user.ID, dryRun == "true", v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/imports/%d", job.ID))
+
+	respond(w, r, http.StatusAccepted, utils.Envelope{"import": job.ToDTO()}, headers, h.errRsp)
+}
+
+// FindByID reports the progress of an import and the errors of the rows
+// processed so far.
+func (h *libraryImportHandler) FindByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	job, err := h.libraryImport.FindByID(id, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"import": job.ToDTO()}, nil, h.errRsp)
+}
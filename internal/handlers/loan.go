@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type loanHandler struct {
+	loan   services.LoanService
+	errRsp e.ErrorResponseInterface
+	GenericHandlerInterface[models.Loan, models.LoanDTO]
+}
+
+type LoanHandler interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+	Return(w http.ResponseWriter, r *http.Request)
+	GenericHandlerInterface[models.Loan, models.LoanDTO]
+}
+
+func NewLoanHandler(
+	loan services.LoanService,
+	errRsp e.ErrorResponseInterface,
+) *loanHandler {
+	return &loanHandler{
+		loan:                    loan,
+		errRsp:                  errRsp,
+		GenericHandlerInterface: NewGenericHandler(loan, errRsp),
+	}
+}
+
+// FindAll lists the user's loans. status=overdue narrows them to the open
+// loans past their due date, and open and returned to the others.
+func (h *loanHandler) FindAll(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		filters.LoanFilters
+		filters.Filters
+	}
+
+	v := validator.New()
+	preferences := contexts.ContextGetPreferences(r)
+
+	qs := r.URL.Query()
+	input.LoanFilters.Query = strings.TrimSpace(utils.ReadString(qs, "q", ""))
+	input.LoanFilters.BookID = int64(utils.ReadInt(qs, "book_id", 0, v))
+	input.LoanFilters.Status = utils.ReadString(qs, "status", "")
+	input.Filters.Page = utils.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = utils.ReadInt(qs, "page_size", preferences.PageSize, v)
+	input.Filters.Sort = utils.ReadString(qs, "sort", "-lent_at")
+	input.Filters.SortSafelist = []string{
+		"id", "lent_at", "due_at", "returned_at",
+		"-id", "-lent_at", "-due_at", "-returned_at",
+	}
+
+	filters.ValidateLoanFilters(v, input.LoanFilters)
+	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	loans, metadata, err := h.loan.FindAll(input.LoanFilters, user.ID, input.Filters)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	dtos := make([]*models.LoanDTO, 0, len(loans))
+	for _, loan := range loans {
+		dtos = append(dtos, loan.ToDTO())
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"loans": dtos, "metadata": metadata}, nil, h.errRsp)
+}
+
+// Return closes a loan on the returnedAt of the body, or today when the
+// body is empty.
+func (h *loanHandler) Return(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ReturnedAt *time.Time `json:"returnedAt"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := utils.ReadJSON(w, r, &input); err != nil {
+			h.errRsp.BadRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	loan, err := h.loan.Return(id, user.ID, input.ReturnedAt, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"loan": loan.ToDTO()}, nil, h.errRsp)
+}
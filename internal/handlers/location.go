@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type locationHandler struct {
+	location services.LocationService
+	errRsp   e.ErrorResponseInterface
+	GenericHandlerInterface[models.Location, models.LocationDTO]
+}
+
+type LocationHandler interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+	Where(w http.ResponseWriter, r *http.Request)
+	AddBooks(w http.ResponseWriter, r *http.Request)
+	RemoveBooks(w http.ResponseWriter, r *http.Request)
+	GenericHandlerInterface[models.Location, models.LocationDTO]
+}
+
+func NewLocationHandler(
+	location services.LocationService,
+	errRsp e.ErrorResponseInterface,
+) *locationHandler {
+	return &locationHandler{
+		location:                location,
+		errRsp:                  errRsp,
+		GenericHandlerInterface: NewGenericHandler(location, errRsp),
+	}
+}
+
+func (h *locationHandler) FindAll(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	locations, err := h.location.FindAll(user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	dtos := make([]*models.LocationDTO, 0, len(locations))
+	for _, location := range locations {
+		dtos = append(dtos, location.ToDTO())
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"locations": dtos}, nil, h.errRsp)
+}
+
+// Where answers "where is it" for the books whose title, author or ISBN
+// matches the q parameter.
+func (h *locationHandler) Where(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+
+	books, err := h.location.Where(utils.ReadString(r.URL.Query(), "q", ""), user.ID, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	dtos := make([]*models.LocatedBookDTO, 0, len(books))
+	for _, book := range books {
+		dtos = append(dtos, book.ToLocatedDTO())
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"books": dtos}, nil, h.errRsp)
+}
+
+func (h *locationHandler) AddBooks(w http.ResponseWriter, r *http.Request) {
+	h.changeBooks(w, r, h.location.AddBooks, "added")
+}
+
+func (h *locationHandler) RemoveBooks(w http.ResponseWriter, r *http.Request) {
+	h.changeBooks(w, r, h.location.RemoveBooks, "removed")
+}
+
+func (h *locationHandler) changeBooks(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error),
+	key string,
+) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		BookIDs []int64 `json:"bookIds"`
+	}
+
+	if err := utils.ReadJSON(w, r, &input); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	count, err := change(id, user.ID, input.BookIDs, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{key: count}, nil, h.errRsp)
+}
@@ -0,0 +1,522 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/ebook"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/opds"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// opdsPageSize is the number of entries of a catalog page. E-reader apps
+// cannot choose it, so it is fixed.
+const opdsPageSize = 50
+
+type opdsHandler struct {
+	book     services.BookService
+	bookFile services.BookFileService
+	shelf    services.ShelfService
+	author   services.AuthorService
+	series   services.SeriesService
+	errRsp   e.ErrorResponseInterface
+}
+
+type OPDSHandler interface {
+	Root(w http.ResponseWriter, r *http.Request)
+	Recent(w http.ResponseWriter, r *http.Request)
+	Books(w http.ResponseWriter, r *http.Request)
+	Shelves(w http.ResponseWriter, r *http.Request)
+	Shelf(w http.ResponseWriter, r *http.Request)
+	Authors(w http.ResponseWriter, r *http.Request)
+	Author(w http.ResponseWriter, r *http.Request)
+	SeriesList(w http.ResponseWriter, r *http.Request)
+	Series(w http.ResponseWriter, r *http.Request)
+	Cover(w http.ResponseWriter, r *http.Request)
+	CoverThumbnail(w http.ResponseWriter, r *http.Request)
+	Download(w http.ResponseWriter, r *http.Request)
+}
+
+func NewOPDSHandler(
+	book services.BookService,
+	bookFile services.BookFileService,
+	shelf services.ShelfService,
+	author services.AuthorService,
+	series services.SeriesService,
+	errRsp e.ErrorResponseInterface,
+) *opdsHandler {
+	return &opdsHandler{
+		book:     book,
+		bookFile: bookFile,
+		shelf:    shelf,
+		author:   author,
+		series:   series,
+		errRsp:   errRsp,
+	}
+}
+
+// catalog is the request for a feed: where the catalog is mounted, which
+// version it is served as and the page asked for.
+type catalog struct {
+	base    string
+	version opds.Version
+	page    int
+	user    *models.User
+}
+
+// readCatalog reads the catalog of the request. The same routes serve OPDS
+// 1.2 under .../opds and OPDS 2.0 under .../opds/v2, for the basic and the
+// feed token catalogs alike.
+func (h *opdsHandler) readCatalog(w http.ResponseWriter, r *http.Request) (*catalog, bool) {
+	c := &catalog{
+		version: opds.Version1,
+		user:    contexts.ContextGetUser(r),
+	}
+
+	p := r.URL.Path
+	i := strings.Index(p, "/opds")
+	c.base = p[:i+len("/opds")]
+	if rest := p[len(c.base):]; rest == "/v2" || strings.HasPrefix(rest, "/v2/") {
+		c.base += "/v2"
+		c.version = opds.Version2
+	}
+
+	v := validator.New()
+	c.page = utils.ReadInt(r.URL.Query(), "page", 1, v)
+	v.Check(c.page > 0, "page", "must be greater than zero")
+	v.Check(c.page <= 10_000_000, "page", "must be a maximum of 10 million")
+	if !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return nil, false
+	}
+
+	return c, true
+}
+
+func (c *catalog) href(format string, args ...any) string {
+	return c.base + fmt.Sprintf(format, args...)
+}
+
+func (c *catalog) filters(sort string) filters.Filters {
+	return filters.Filters{
+		Page:         c.page,
+		PageSize:     opdsPageSize,
+		Sort:         sort,
+		SortSafelist: []string{sort},
+	}
+}
+
+// newFeed starts a feed at path with the links every feed has.
+func (c *catalog) newFeed(path, title string, kind opds.Kind) *opds.Feed {
+	return &opds.Feed{
+		ID:      "urn:bookwise:" + strings.Trim(strings.ReplaceAll(path, "/", ":"), ":"),
+		Title:   title,
+		Kind:    kind,
+		Updated: time.Now(),
+		Links: []opds.Link{
+			{Rel: opds.RelSelf, Href: c.pageHref(path, c.page)},
+			{Rel: opds.RelStart, Href: c.href("/"), Title: "Bookwise"},
+		},
+	}
+}
+
+func (c *catalog) pageHref(path string, page int) string {
+	if page <= 1 {
+		return c.href("%s", path)
+	}
+	return c.href("%s?%s", path, url.Values{"page": {fmt.Sprint(page)}}.Encode())
+}
+
+// paginate adds the paging of metadata to feed.
+func (c *catalog) paginate(feed *opds.Feed, path string, metadata filters.Metadata) {
+	feed.TotalResults = metadata.TotalRecords
+	feed.ItemsPerPage = opdsPageSize
+	feed.CurrentPage = c.page
+
+	if metadata.LastPage <= 1 {
+		return
+	}
+
+	feed.Links = append(feed.Links,
+		opds.Link{Rel: opds.RelFirst, Href: c.pageHref(path, 1)},
+		opds.Link{Rel: opds.RelLast, Href: c.pageHref(path, metadata.LastPage)},
+	)
+	if c.page > 1 {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelPrevious, Href: c.pageHref(path, min(c.page-1, metadata.LastPage))})
+	}
+	if c.page < metadata.LastPage {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelNext, Href: c.pageHref(path, c.page+1)})
+	}
+}
+
+// write renders feed before answering, so a feed that fails half way is
+// answered with an error rather than cut short.
+func (h *opdsHandler) write(w http.ResponseWriter, r *http.Request, c *catalog, feed *opds.Feed) {
+	var buf bytes.Buffer
+	if err := feed.Write(&buf, c.version); err != nil {
+		h.errRsp.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", opds.ContentType(c.version, feed.Kind))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// Root answers with the navigation feed every catalog starts at.
+func (h *opdsHandler) Root(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	feed := c.newFeed("/", "Bookwise", opds.KindNavigation)
+	feed.Navigation = []opds.Navigation{
+		{
+			ID:      "urn:bookwise:recent",
+			Title:   "Recently added",
+			Summary: "The books added to the library last",
+			Href:    c.href("/recent"),
+			Kind:    opds.KindAcquisition,
+		},
+		{
+			ID:      "urn:bookwise:books",
+			Title:   "All books",
+			Summary: "Every book of the library by title",
+			Href:    c.href("/books"),
+			Kind:    opds.KindAcquisition,
+		},
+		{
+			ID:      "urn:bookwise:shelves",
+			Title:   "Shelves",
+			Summary: "The books of the library by shelf",
+			Href:    c.href("/shelves"),
+			Kind:    opds.KindNavigation,
+		},
+		{
+			ID:      "urn:bookwise:authors",
+			Title:   "Authors",
+			Summary: "The books of the library by author",
+			Href:    c.href("/authors"),
+			Kind:    opds.KindNavigation,
+		},
+		{
+			ID:      "urn:bookwise:series",
+			Title:   "Series",
+			Summary: "The books of the library by series",
+			Href:    c.href("/series"),
+			Kind:    opds.KindNavigation,
+		},
+	}
+	for i := range feed.Navigation {
+		feed.Navigation[i].Updated = feed.Updated
+	}
+
+	h.write(w, r, c, feed)
+}
+
+func (h *opdsHandler) Recent(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	h.bookFeed(w, r, c, "/recent", "Recently added", filters.BookFilters{}, "-id")
+}
+
+func (h *opdsHandler) Books(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	h.bookFeed(w, r, c, "/books", "All books", filters.BookFilters{}, "title")
+}
+
+func (h *opdsHandler) Shelves(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	shelves, err := h.shelf.CountBooks(filters.BookFilters{}, c.user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	feed := c.newFeed("/shelves", "Shelves", opds.KindNavigation)
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: c.href("/")})
+	for _, shelf := range shelves {
+		feed.Navigation = append(feed.Navigation, opds.Navigation{
+			ID:      fmt.Sprintf("urn:bookwise:shelves:%d", shelf.ID),
+			Title:   shelf.Name,
+			Href:    c.href("/shelves/%d", shelf.ID),
+			Kind:    opds.KindAcquisition,
+			Count:   shelf.BookCount,
+			Updated: updatedAt(shelf.BaseModel),
+		})
+	}
+
+	h.write(w, r, c, feed)
+}
+
+func (h *opdsHandler) Shelf(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	shelf, err := h.shelf.FindByID(id, c.user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	search := filters.BookFilters{ShelfIDs: []int64{shelf.ID}}
+	h.bookFeed(w, r, c, fmt.Sprintf("/shelves/%d", shelf.ID), shelf.Name, search, "title")
+}
+
+func (h *opdsHandler) Authors(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	authors, metadata, err := h.author.FindAll("", c.user.ID, c.filters("sort_name"))
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	feed := c.newFeed("/authors", "Authors", opds.KindNavigation)
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: c.href("/")})
+	c.paginate(feed, "/authors", metadata)
+	for _, author := range authors {
+		feed.Navigation = append(feed.Navigation, opds.Navigation{
+			ID:      fmt.Sprintf("urn:bookwise:authors:%d", author.ID),
+			Title:   author.Name,
+			Href:    c.href("/authors/%d", author.ID),
+			Kind:    opds.KindAcquisition,
+			Updated: updatedAt(author.BaseModel),
+		})
+	}
+
+	h.write(w, r, c, feed)
+}
+
+func (h *opdsHandler) Author(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	author, err := h.author.FindByID(id, c.user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	search := filters.BookFilters{AuthorID: author.ID}
+	h.bookFeed(w, r, c, fmt.Sprintf("/authors/%d", author.ID), author.Name, search, "title")
+}
+
+func (h *opdsHandler) SeriesList(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	series, metadata, err := h.series.FindAll("", c.user.ID, c.filters("name"))
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	feed := c.newFeed("/series", "Series", opds.KindNavigation)
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: c.href("/")})
+	c.paginate(feed, "/series", metadata)
+	for _, s := range series {
+		feed.Navigation = append(feed.Navigation, opds.Navigation{
+			ID:      fmt.Sprintf("urn:bookwise:series:%d", s.ID),
+			Title:   s.Name,
+			Href:    c.href("/series/%d", s.ID),
+			Kind:    opds.KindAcquisition,
+			Count:   len(s.Entries),
+			Updated: updatedAt(s.BaseModel),
+		})
+	}
+
+	h.write(w, r, c, feed)
+}
+
+func (h *opdsHandler) Series(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.readCatalog(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	series, err := h.series.FindByID(id, c.user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	search := filters.BookFilters{SeriesID: series.ID}
+	h.bookFeed(w, r, c, fmt.Sprintf("/series/%d", series.ID), series.Name, search, "series_position")
+}
+
+// bookFeed answers with a page of the books matching search as an
+// acquisition feed.
+func (h *opdsHandler) bookFeed(
+	w http.ResponseWriter,
+	r *http.Request,
+	c *catalog,
+	path, title string,
+	search filters.BookFilters,
+	sort string,
+) {
+	books, metadata, err := h.book.FindAll(search, c.user.ID, c.filters(sort))
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	files, err := h.bookFile.FindByBooks(books, c.user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	feed := c.newFeed(path, title, opds.KindAcquisition)
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: c.href("/")})
+	c.paginate(feed, path, metadata)
+	for _, book := range books {
+		feed.Publications = append(feed.Publications, c.publication(book, files[book.ID]))
+	}
+
+	h.write(w, r, c, feed)
+}
+
+func (c *catalog) publication(book *models.Book, files []*models.BookFile) opds.Publication {
+	pub := opds.Publication{
+		ID:             fmt.Sprintf("urn:bookwise:books:%d", book.ID),
+		Title:          book.Title,
+		Summary:        book.Description,
+		Language:       book.Language,
+		Publisher:      book.Publisher,
+		Published:      book.PublishedDate,
+		ISBN:           book.ISBN,
+		Series:         book.SeriesName,
+		SeriesPosition: book.SeriesPosition,
+		Updated:        updatedAt(book.BaseModel),
+	}
+
+	for _, credit := range book.Authors {
+		if credit.Role == models.AuthorRoleAuthor {
+			pub.Authors = append(pub.Authors, credit.Author.Name)
+		}
+	}
+	if len(pub.Authors) == 0 && book.Author != "" {
+		pub.Authors = []string{book.Author}
+	}
+
+	switch {
+	case book.CoverKey != "":
+		pub.Image = c.href("/books/%d/cover", book.ID)
+		pub.Thumbnail = c.href("/books/%d/cover/thumbnail", book.ID)
+	case book.CoverURL != "":
+		pub.Image = book.CoverURL
+	}
+
+	for _, file := range files {
+		pub.Acquisitions = append(pub.Acquisitions, opds.Acquisition{
+			Href:   c.href("/books/%d/files/%d", book.ID, file.ID),
+			Type:   ebook.Format(file.Format).ContentType(),
+			Title:  file.FileName,
+			Length: file.Size,
+		})
+	}
+
+	return pub
+}
+
+func updatedAt(m models.BaseModel) time.Time {
+	if m.UpdatedAt != nil {
+		return *m.UpdatedAt
+	}
+	return m.CreatedAt
+}
+
+func (h *opdsHandler) Cover(w http.ResponseWriter, r *http.Request) {
+	h.serveCover(w, r, false)
+}
+
+func (h *opdsHandler) CoverThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveCover(w, r, true)
+}
+
+func (h *opdsHandler) serveCover(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	body, info, err := h.book.Cover(id, user.ID, thumbnail)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	serveBlob(w, r, body, info)
+}
+
+// Download serves a file of a book for the acquisition links of the
+// catalog, which apps follow with the credentials of the feed.
+func (h *opdsHandler) Download(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	id, err := utils.ReadIntPathVariable(r, "fileID")
+	if err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	file, body, info, err := h.bookFile.Open(bookID, id, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	setAttachment(w, file, bookID)
+	serveBlob(w, r, body, info)
+}
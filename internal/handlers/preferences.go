@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type preferencesHandler struct {
+	preferences services.PreferencesService
+	errRsp      e.ErrorResponseInterface
+}
+
+type PreferencesHandler interface {
+	Find(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+}
+
+func NewPreferencesHandler(
+	preferences services.PreferencesService,
+	errRsp e.ErrorResponseInterface,
+) *preferencesHandler {
+	return &preferencesHandler{
+		preferences: preferences,
+		errRsp:      errRsp,
+	}
+}
+
+func (h *preferencesHandler) Find(w http.ResponseWriter, r *http.Request) {
+	preferences := contexts.ContextGetPreferences(r)
+	respond(w, r, http.StatusOK, utils.Envelope{"preferences": preferences.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *preferencesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var dto models.PreferencesDTO
+	if err := utils.ReadJSON(w, r, &dto); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	preferences, err := h.preferences.FindByUserID(user.ID)
+	if err != nil {
+		h.errRsp.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	dto.ApplyTo(preferences)
+
+	v := validator.New()
+	if err := h.preferences.Update(preferences, v); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"preferences": preferences.ToDTO()}, nil, h.errRsp)
+}
@@ -31,6 +31,7 @@ func NewReadingPlanHandler(
 
 type ReadingPlanHandler interface {
 	FindAll(w http.ResponseWriter, r *http.Request)
+	Schedule(w http.ResponseWriter, r *http.Request)
 	GenericHandlerInterface[models.ReadingPlan, models.ReadingPlanDTO]
 }
 
@@ -41,7 +42,7 @@ func (h *readingPlanHandler) FindAll(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var input struct {
-		status     models.ReadingStatus
+		status     *models.ReadingStatus
 		startDate  *time.Time
 		targetDate *time.Time
 		filters.Filters
@@ -49,14 +50,22 @@ func (h *readingPlanHandler) FindAll(w http.ResponseWriter, r *http.Request) {
 
 	v := validator.New()
 
+	preferences := contexts.ContextGetPreferences(r)
+	loc := preferences.Location()
+
 	qs := r.URL.Query()
-	input.status = models.ReadingStatusFromString(utils.ReadString(qs, "status", ""))
-	input.startDate = utils.ReadDate(qs, "start_date", "2006-01-02")
-	input.targetDate = utils.ReadDate(qs, "target_date", "2006-01-02")
+	if status := models.ReadingStatusFromString(utils.ReadString(qs, "status", "")); status != 0 {
+		input.status = &status
+	}
+	input.startDate = utils.ReadDateIn(qs, "start_date", "2006-01-02", loc)
+	input.targetDate = utils.ReadDateIn(qs, "target_date", "2006-01-02", loc)
 	input.Filters.Page = utils.ReadInt(qs, "page", 1, v)
-	input.Filters.PageSize = utils.ReadInt(qs, "page_size", 20, v)
+	input.Filters.PageSize = utils.ReadInt(qs, "page_size", preferences.PageSize, v)
 	input.Filters.Sort = utils.ReadString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "description", "-id", "-description"}
+	input.Filters.SortSafelist = []string{
+		"id", "start_date", "target_date", "priority",
+		"-id", "-start_date", "-target_date", "-priority",
+	}
 
 	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
 		h.errRsp.FailedValidationResponse(w, r, v.Errors)
@@ -66,7 +75,7 @@ func (h *readingPlanHandler) FindAll(w http.ResponseWriter, r *http.Request) {
 	user := contexts.ContextGetUser(r)
 
 	objects, m, err := h.readingPlan.FindAll(
-		&input.status,
+		input.status,
 		input.startDate,
 		input.targetDate,
 		user.ID,
@@ -87,3 +96,28 @@ func (h *readingPlanHandler) FindAll(w http.ResponseWriter, r *http.Request) {
 
 	respond(w, r, http.StatusOK, utils.Envelope{"reading_plans": dtos, "metadata": m}, nil, h.errRsp)
 }
+
+// Schedule shows what to read each day to finish a plan. With
+// align=chapters, days end at chapter boundaries where possible.
+func (h *readingPlanHandler) Schedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	align := utils.ReadString(r.URL.Query(), "align", "")
+	if v.Check(align == "" || align == "chapters", "align", "must be chapters"); !v.Valid() {
+		h.errRsp.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	schedule, err := h.readingPlan.Schedule(id, user.ID, align == "chapters", v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"schedule": schedule}, nil, h.errRsp)
+}
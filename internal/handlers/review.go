@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type reviewHandler struct {
+	review services.ReviewService
+	errRsp e.ErrorResponseInterface
+}
+
+type ReviewHandler interface {
+	FindByBookID(w http.ResponseWriter, r *http.Request)
+	Save(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+func NewReviewHandler(
+	review services.ReviewService,
+	errRsp e.ErrorResponseInterface,
+) *reviewHandler {
+	return &reviewHandler{
+		review: review,
+		errRsp: errRsp,
+	}
+}
+
+func (h *reviewHandler) FindByBookID(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	review, err := h.review.FindByBookID(bookID, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"review": review.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *reviewHandler) Save(w http.ResponseWriter, r *http.Request) {
+	h.write(w, r, http.StatusCreated, h.review.Save)
+}
+
+func (h *reviewHandler) Update(w http.ResponseWriter, r *http.Request) {
+	h.write(w, r, http.StatusOK, h.review.Update)
+}
+
+// write decodes the review of the book in the path and hands it to save.
+func (h *reviewHandler) write(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	save func(*models.Review, int64, *validator.Validator) error,
+) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	var dto models.ReviewDTO
+	if err := utils.ReadJSON(w, r, &dto); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	review := dto.ToModel()
+	review.BookID = bookID
+
+	if err := save(review, user.ID, v); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, status, utils.Envelope{"review": review.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *reviewHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	if err := h.review.Delete(bookID, user.ID); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusNoContent, nil, nil, h.errRsp)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type seriesHandler struct {
+	series services.SeriesService
+	errRsp e.ErrorResponseInterface
+}
+
+type SeriesHandler interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+	FindByID(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+}
+
+func NewSeriesHandler(
+	series services.SeriesService,
+	errRsp e.ErrorResponseInterface,
+) *seriesHandler {
+	return &seriesHandler{
+		series: series,
+		errRsp: errRsp,
+	}
+}
+
+func (h *seriesHandler) FindAll(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		name string
+		filters.Filters
+	}
+
+	v := validator.New()
+	preferences := contexts.ContextGetPreferences(r)
+
+	qs := r.URL.Query()
+	input.name = utils.ReadString(qs, "name", "")
+	input.Filters.Page = utils.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = utils.ReadInt(qs, "page_size", preferences.PageSize, v)
+	input.Filters.Sort = utils.ReadString(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	series, metadata, err := h.series.FindAll(input.name, user.ID, input.Filters)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	dtos := make([]*models.SeriesDTO, 0, len(series))
+	for _, s := range series {
+		dtos = append(dtos, s.ToDTO())
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"series": dtos, "metadata": metadata}, nil, h.errRsp)
+}
+
+func (h *seriesHandler) FindByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	series, err := h.series.FindByID(id, user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"series": series.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *seriesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var dto models.SeriesDTO
+	if err := utils.ReadJSON(w, r, &dto); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	series := dto.ToModel()
+
+	if err := h.series.Update(series, user.ID, v); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"series": series.ToDTO()}, nil, h.errRsp)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+)
+
+type shelfHandler struct {
+	shelf  services.ShelfService
+	errRsp e.ErrorResponseInterface
+	GenericHandlerInterface[models.Shelf, models.ShelfDTO]
+}
+
+type ShelfHandler interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+	AddBooks(w http.ResponseWriter, r *http.Request)
+	RemoveBooks(w http.ResponseWriter, r *http.Request)
+	GenericHandlerInterface[models.Shelf, models.ShelfDTO]
+}
+
+func NewShelfHandler(
+	shelf services.ShelfService,
+	errRsp e.ErrorResponseInterface,
+) *shelfHandler {
+	return &shelfHandler{
+		shelf:                   shelf,
+		errRsp:                  errRsp,
+		GenericHandlerInterface: NewGenericHandler(shelf, errRsp),
+	}
+}
+
+func (h *shelfHandler) FindAll(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	shelves, err := h.shelf.FindAll(user.ID)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"shelves": shelvesToDTO(shelves)}, nil, h.errRsp)
+}
+
+func (h *shelfHandler) AddBooks(w http.ResponseWriter, r *http.Request) {
+	h.changeBooks(w, r, h.shelf.AddBooks, "added")
+}
+
+func (h *shelfHandler) RemoveBooks(w http.ResponseWriter, r *http.Request) {
+	h.changeBooks(w, r, h.shelf.RemoveBooks, "removed")
+}
+
+func (h *shelfHandler) changeBooks(
+	w http.ResponseWriter,
+	r *http.Request,
+	change func(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error),
+	key string,
+) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		BookIDs []int64 `json:"bookIds"`
+	}
+
+	if err := utils.ReadJSON(w, r, &input); err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+	count, err := change(id, user.ID, input.BookIDs, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{key: count}, nil, h.errRsp)
+}
+
+func shelvesToDTO(shelves []*models.Shelf) []*models.ShelfDTO {
+	dtos := make([]*models.ShelfDTO, 0, len(shelves))
+	for _, shelf := range shelves {
+		dtos = append(dtos, shelf.ToDTO())
+	}
+	return dtos
+}
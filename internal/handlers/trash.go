@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bookwise/internal/contexts"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/services"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"net/http"
+	"time"
+)
+
+type trashHandler struct {
+	trash     services.TrashService
+	errRsp    e.ErrorResponseInterface
+	retention time.Duration
+}
+
+type TrashHandler interface {
+	FindAll(w http.ResponseWriter, r *http.Request)
+	Empty(w http.ResponseWriter, r *http.Request)
+	RestoreBook(w http.ResponseWriter, r *http.Request)
+	PurgeBook(w http.ResponseWriter, r *http.Request)
+	RestorePlan(w http.ResponseWriter, r *http.Request)
+	PurgePlan(w http.ResponseWriter, r *http.Request)
+}
+
+func NewTrashHandler(
+	trash services.TrashService,
+	errRsp e.ErrorResponseInterface,
+	retention time.Duration,
+) *trashHandler {
+	return &trashHandler{
+		trash:     trash,
+		errRsp:    errRsp,
+		retention: retention,
+	}
+}
+
+func (h *trashHandler) FindAll(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		itemType models.TrashItemType
+		filters.Filters
+	}
+
+	v := validator.New()
+	preferences := contexts.ContextGetPreferences(r)
+
+	qs := r.URL.Query()
+	input.itemType = models.TrashItemType(utils.ReadString(qs, "type", ""))
+	input.Filters.Page = utils.ReadInt(qs, "page", 1, v)
+	input.Filters.PageSize = utils.ReadInt(qs, "page_size", preferences.PageSize, v)
+	input.Filters.Sort = utils.ReadString(qs, "sort", "-deleted_at")
+	input.Filters.SortSafelist = []string{"deleted_at", "title", "-deleted_at", "-title"}
+
+	v.Check(
+		validator.In(string(input.itemType), "", string(models.TrashItemBook), string(models.TrashItemPlan)),
+		"type",
+		"must be book or plan",
+	)
+	if filters.ValidateFilters(v, input.Filters); !v.Valid() {
+		h.errRsp.HandlerErrorResponse(w, r, e.ErrInvalidData, v)
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	items, metadata, err := h.trash.FindAll(input.itemType, user.ID, input.Filters)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	dtos := make([]*models.TrashItemDTO, 0, len(items))
+	for _, item := range items {
+		dtos = append(dtos, item.ToDTO(h.retention))
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"trash": dtos, "metadata": metadata}, nil, h.errRsp)
+}
+
+func (h *trashHandler) Empty(w http.ResponseWriter, r *http.Request) {
+	user := contexts.ContextGetUser(r)
+	if err := h.trash.Empty(user.ID); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreBook accepts an optional body with the title to restore the book
+// under when its old title has been taken in the meantime.
+func (h *trashHandler) RestoreBook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title string `json:"title"`
+	}
+
+	if r.ContentLength != 0 {
+		if err := utils.ReadJSON(w, r, &input); err != nil {
+			h.errRsp.BadRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+
+	book, err := h.trash.RestoreBook(id, user.ID, input.Title, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"book": book.ToDTO()}, nil, h.errRsp)
+}
+
+func (h *trashHandler) PurgeBook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	if err := h.trash.PurgeBook(id, user.ID); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *trashHandler) RestorePlan(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	v := validator.New()
+	user := contexts.ContextGetUser(r)
+
+	if err := h.trash.RestorePlan(id, user.ID, v); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(w, r, http.StatusOK, utils.Envelope{"message": "reading plan restored"}, nil, h.errRsp)
+}
+
+func (h *trashHandler) PurgePlan(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseID(w, r, h.errRsp)
+	if !ok {
+		return
+	}
+
+	user := contexts.ContextGetUser(r)
+	if err := h.trash.PurgePlan(id, user.ID); err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bookwise/internal/storage"
+	e "bookwise/utils/errors"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// readUpload reads a file of at most maxBytes, sent either as the raw request
+// body or as the field part of a multipart form. Oversized uploads return
+// e.ErrPayloadTooLarge.
+func readUpload(w http.ResponseWriter, r *http.Request, field string, maxBytes int64) ([]byte, error) {
+	data, _, err := readNamedUpload(w, r, field, maxBytes)
+	return data, err
+}
+
+// readNamedUpload reads a file like readUpload together with the name the
+// client gave it, taken from the Content-Disposition of the part or of the
+// request. The name is empty when the client sent none.
+func readNamedUpload(w http.ResponseWriter, r *http.Request, field string, maxBytes int64) ([]byte, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	var body io.Reader = r.Body
+	name := ""
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		part, err := findPart(r, field)
+		if err != nil {
+			return nil, "", err
+		}
+		body = part
+		name = part.FileName()
+	} else if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+		name = params["filename"]
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, "", uploadError(err)
+	}
+
+	if len(data) == 0 {
+		return nil, "", errors.New("body must not be empty")
+	}
+
+	return data, name, nil
+}
+
+func findPart(r *http.Request, field string) (*multipart.Part, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, fmt.Errorf("form field %q must be provided", field)
+		}
+		if err != nil {
+			return nil, uploadError(err)
+		}
+
+		if part.FormName() == field {
+			return part, nil
+		}
+	}
+}
+
+func uploadError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return e.ErrPayloadTooLarge
+	}
+	return err
+}
+
+// uploadErrorResponse reports a failed readUpload.
+func uploadErrorResponse(w http.ResponseWriter, r *http.Request, err error, errRsp e.ErrorResponseInterface) {
+	if errors.Is(err, e.ErrPayloadTooLarge) {
+		errRsp.PayloadTooLargeResponse(w, r)
+		return
+	}
+	errRsp.BadRequestResponse(w, r, err)
+}
+
+// serveBlob writes a stored blob with caching headers, answering conditional
+// requests whose ETag still matches with 304 Not Modified. It closes body.
+func serveBlob(w http.ResponseWriter, r *http.Request, body io.ReadCloser, info *storage.BlobInfo) {
+	defer body.Close()
+
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if info.ETag != "" {
+		w.Header().Set("ETag", info.ETag)
+
+		if etagMatches(r.Header.Get("If-None-Match"), info.ETag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if !info.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if info.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method != http.MethodHead {
+		io.Copy(w, body)
+	}
+}
+
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
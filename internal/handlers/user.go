@@ -17,6 +17,7 @@ type UserHandler struct {
 type UserHandlerInterface interface {
 	ActivateUserHandler(w http.ResponseWriter, r *http.Request)
 	CreateUserHandler(w http.ResponseWriter, r *http.Request)
+	SendActivationCodeHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func NewUserHandler(
@@ -105,3 +106,36 @@ func (h *UserHandler) CreateUserHandler(w http.ResponseWriter, r *http.Request)
 		h.errRsp,
 	)
 }
+
+func (h *UserHandler) SendActivationCodeHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email   string                   `json:"email"`
+		Channel models.ActivationChannel `json:"channel"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		h.errRsp.BadRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Channel == "" {
+		input.Channel = models.ActivationChannelEmail
+	}
+
+	v := validator.New()
+	err = h.user.SendActivationCode(input.Email, input.Channel, v)
+	if err != nil {
+		h.errRsp.HandlerErrorResponse(w, r, err, v)
+		return
+	}
+
+	respond(
+		w,
+		r,
+		http.StatusAccepted,
+		utils.Envelope{"message": "if the account exists and is not activated yet, an activation code has been sent via " + string(input.Channel)},
+		nil,
+		h.errRsp,
+	)
+}
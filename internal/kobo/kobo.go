@@ -0,0 +1,398 @@
+// Package kobo reads the reading progress, reading time and highlights Kobo
+// e-readers keep in their KoboReader.sqlite database.
+package kobo
+
+import (
+	"bookwise/internal/clippings"
+	"bookwise/internal/sqlitefile"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNotKobo = errors.New("not a Kobo database")
+
+// Content types of the content table: books, and the chapters of books.
+const (
+	contentBook    = 6
+	contentChapter = 9
+)
+
+// Read statuses of a book.
+const (
+	StatusUnread   = 0
+	StatusReading  = 1
+	StatusFinished = 2
+)
+
+// highlightColors are the names of the colors newer firmware highlights in.
+var highlightColors = map[int]string{
+	0: "yellow",
+	1: "pink",
+	2: "blue",
+	3: "green",
+}
+
+// Book is a book the device has opened. ContentID identifies it on the
+// device: the path of sideloaded files and a UUID for store books. Seconds
+// is the total reading time, and Percent how far the book was read, from 0
+// to 100. Readings are the stretches of reading the device still has a log
+// of, oldest first.
+type Book struct {
+	ContentID  string
+	Title      string
+	Author     string
+	ISBN       string
+	Status     int
+	Percent    float64
+	Seconds    int
+	LastRead   *time.Time
+	Readings   []Reading
+	Highlights []clippings.Clipping
+}
+
+// Reading is a stretch of reading the device logged when the book was left:
+// when, for how many seconds, and how far the book was read then, from 0 to
+// 100, when logged. The device drops its log once it syncs with the Kobo
+// store, so readings may cover only part of the reading time of a book.
+type Reading struct {
+	At      time.Time
+	Seconds int
+	Percent *float64
+}
+
+// Detect tells whether data looks like a SQLite database.
+func Detect(data []byte) bool {
+	return sqlitefile.Detect(data)
+}
+
+// Read returns the books of the database in data that were read or
+// highlighted, in the order the device added them.
+func Read(data []byte) ([]*Book, error) {
+	if !Detect(data) {
+		return nil, ErrNotKobo
+	}
+
+	db, closeDB, err := sqlitefile.Open(data, "kobo-*.sqlite")
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	r := &reader{db: db, ctx: ctx}
+	return r.read()
+}
+
+type reader struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+func (r *reader) read() ([]*Book, error) {
+	content, err := r.columns("content")
+	if err != nil || !content["ContentID"] || !content["___PercentRead"] {
+		return nil, ErrNotKobo
+	}
+
+	books, index, err := r.books(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.readings(index); err != nil {
+		return nil, err
+	}
+
+	if err := r.bookmarks(index); err != nil {
+		return nil, err
+	}
+
+	active := []*Book{}
+	for _, book := range books {
+		if book.Status != StatusUnread || book.Seconds > 0 || book.Percent > 0 || len(book.Highlights) > 0 {
+			active = append(active, book)
+		}
+	}
+	return active, nil
+}
+
+// columns returns the columns of table, none when the table is missing.
+func (r *reader) columns(table string) (map[string]bool, error) {
+	rows, err := r.db.QueryContext(r.ctx, `select name from pragma_table_info(?)`, table)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotKobo, err)
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// optional selects column, which may be qualified by a table alias, when the
+// firmware that wrote the database has it, and fallback otherwise.
+func optional(columns map[string]bool, column, fallback string) string {
+	_, name, ok := strings.Cut(column, ".")
+	if !ok {
+		name = column
+	}
+	if columns[name] {
+		return "coalesce(" + column + ", " + fallback + ")"
+	}
+	return fallback
+}
+
+func (r *reader) books(columns map[string]bool) ([]*Book, map[string]*Book, error) {
+	rows, err := r.db.QueryContext(r.ctx, fmt.Sprintf(`
+	select
+		ContentID,
+		coalesce(Title, ''),
+		%s,
+		%s,
+		%s,
+		coalesce(___PercentRead, 0),
+		%s,
+		%s
+	from content
+	where ContentType = %d
+	order by rowid
+	`,
+		optional(columns, "Attribution", "''"),
+		optional(columns, "ISBN", "''"),
+		optional(columns, "ReadStatus", "0"),
+		optional(columns, "TimeSpentReading", "0"),
+		optional(columns, "DateLastRead", "''"),
+		contentBook,
+	))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrNotKobo, err)
+	}
+	defer rows.Close()
+
+	books := []*Book{}
+	index := map[string]*Book{}
+	for rows.Next() {
+		var book Book
+		var lastRead string
+		err := rows.Scan(
+			&book.ContentID,
+			&book.Title,
+			&book.Author,
+			&book.ISBN,
+			&book.Status,
+			&book.Percent,
+			&book.Seconds,
+			&lastRead,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		book.Title = strings.TrimSpace(book.Title)
+		book.Author = strings.TrimSpace(book.Author)
+		book.ISBN = strings.TrimSpace(book.ISBN)
+		book.Percent = min(max(book.Percent, 0), 100)
+		book.Seconds = max(book.Seconds, 0)
+		book.LastRead = parseTime(lastRead)
+
+		books = append(books, &book)
+		index[book.ContentID] = &book
+	}
+
+	return books, index, rows.Err()
+}
+
+// leaveContent is the type of the analytics events logged when a book is
+// left, with how long it was read and how far.
+const leaveContent = "LeaveContent"
+
+// readings reads the stretches of reading of the books from the analytics
+// events of the device. Their attributes and metrics are JSON objects whose
+// values firmware versions write as strings or numbers.
+func (r *reader) readings(index map[string]*Book) error {
+	columns, err := r.columns("AnalyticsEvents")
+	if err != nil {
+		return err
+	}
+	if !columns["Type"] || !columns["Timestamp"] || !columns["Attributes"] || !columns["Metrics"] {
+		// Older firmware keeps no analytics, and synced devices may have
+		// dropped the table.
+		return nil
+	}
+
+	rows, err := r.db.QueryContext(r.ctx, `
+	select
+		coalesce(Timestamp, ''),
+		coalesce(Attributes, ''),
+		coalesce(Metrics, '')
+	from AnalyticsEvents
+	where Type = ?
+	order by Timestamp, rowid
+	`, leaveContent)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotKobo, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var timestamp, attributes, metrics string
+		if err := rows.Scan(&timestamp, &attributes, &metrics); err != nil {
+			return err
+		}
+
+		at := parseTime(timestamp)
+		if at == nil {
+			continue
+		}
+
+		volume, _ := eventValue(attributes, "volumeid")
+		book, ok := index[volume]
+		if !ok {
+			continue
+		}
+
+		reading := Reading{At: *at}
+		if seconds, ok := eventValue(metrics, "SecondsRead"); ok {
+			if n, err := strconv.ParseFloat(seconds, 64); err == nil {
+				reading.Seconds = max(int(n), 0)
+			}
+		}
+		if progress, ok := eventValue(attributes, "progress"); ok {
+			if percent, err := strconv.ParseFloat(progress, 64); err == nil {
+				percent = min(max(percent, 0), 100)
+				reading.Percent = &percent
+			}
+		}
+
+		if reading.Seconds > 0 || reading.Percent != nil {
+			book.Readings = append(book.Readings, reading)
+		}
+	}
+
+	return rows.Err()
+}
+
+// eventValue returns the value of key, matched regardless of case, in the
+// JSON object of an analytics event, as text.
+func eventValue(object, key string) (string, bool) {
+	var values map[string]any
+	if err := json.Unmarshal([]byte(object), &values); err != nil {
+		return "", false
+	}
+
+	for name, value := range values {
+		if !strings.EqualFold(name, key) {
+			continue
+		}
+		switch value := value.(type) {
+		case string:
+			return strings.TrimSpace(value), true
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64), true
+		}
+	}
+	return "", false
+}
+
+// bookmarks reads the highlights and notes of the books, with the chapter
+// they were made in. Bookmarks without text are page markers and skipped.
+func (r *reader) bookmarks(index map[string]*Book) error {
+	columns, err := r.columns("Bookmark")
+	if err != nil {
+		return err
+	}
+	if !columns["VolumeID"] || !columns["Text"] {
+		// Devices that never highlighted anything may lack the table.
+		return nil
+	}
+
+	hidden := "0"
+	if columns["Hidden"] {
+		hidden = "coalesce(b.Hidden, 'false') in ('true', '1')"
+	}
+	kind := "''"
+	if columns["Type"] {
+		kind = "coalesce(b.Type, '')"
+	}
+
+	rows, err := r.db.QueryContext(r.ctx, fmt.Sprintf(`
+	select
+		b.VolumeID,
+		b.Text,
+		%s,
+		coalesce(c.Title, ''),
+		%s,
+		%s
+	from Bookmark b
+	left join content c on c.ContentID = b.ContentID and c.ContentType = %d
+	where
+		coalesce(trim(b.Text), '') <> ''
+		and not (%s)
+		and %s <> 'dogear'
+	order by b.VolumeID, %s, b.rowid
+	`,
+		optional(columns, "b.Annotation", "''"),
+		optional(columns, "b.Color", "0"),
+		optional(columns, "b.DateCreated", "''"),
+		contentChapter,
+		hidden,
+		kind,
+		optional(columns, "b.DateCreated", "''"),
+	))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotKobo, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var volume, created string
+		var color int
+		var clip clippings.Clipping
+		if err := rows.Scan(&volume, &clip.Text, &clip.Note, &clip.Chapter, &color, &created); err != nil {
+			return err
+		}
+
+		book, ok := index[volume]
+		if !ok {
+			continue
+		}
+
+		clip.Title = book.Title
+		clip.Author = book.Author
+		clip.Text = strings.TrimSpace(clip.Text)
+		clip.Note = strings.TrimSpace(clip.Note)
+		clip.Chapter = strings.TrimSpace(clip.Chapter)
+		clip.Color = highlightColors[color]
+		clip.AddedAt = parseTime(created)
+
+		book.Highlights = append(book.Highlights, clip)
+	}
+
+	return rows.Err()
+}
+
+// parseTime reads the timestamps of the database, which firmware versions
+// write with and without fractions and zones. Times without a zone are UTC.
+func parseTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.000", "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil && t.Year() > 2000 {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Package koreader holds what Bookwise shares with the progress sync plugin
+// of KOReader: the digests it identifies documents by and the errors of its
+// protocol.
+package koreader
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+)
+
+// sampleSize is the size of the samples DocumentHash reads.
+const sampleSize = 1024
+
+// DocumentHash returns the partial MD5 of a file, the digest KOReader syncs
+// progress under by default. It hashes samples of 1 KiB at offsets growing
+// by powers of four, so large files are hashed quickly.
+func DocumentHash(data []byte) string {
+	h := md5.New()
+
+	// KOReader computes the offsets as 1024 << 2i for i from -1 to 10 with
+	// 32 bit shifts, which makes the first offset 0 rather than 256.
+	for i := -1; i <= 10; i++ {
+		offset := 0
+		if i >= 0 {
+			offset = sampleSize << (2 * i)
+		}
+		if offset >= len(data) {
+			break
+		}
+
+		h.Write(data[offset:min(offset+sampleSize, len(data))])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FileNameHash returns the MD5 of a file name, the digest KOReader syncs
+// progress under when set to identify documents by name.
+func FileNameHash(name string) string {
+	sum := md5.Sum([]byte(name))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,24 @@
+package koreader
+
+import (
+	"bookwise/utils"
+	"net/http"
+)
+
+// Error codes of the sync protocol, which the plugin shows the message of.
+const (
+	CodeUnauthorized    = 2001
+	CodeUserExists      = 2002
+	CodeInvalidFields   = 2003
+	CodeDocumentMissing = 2004
+)
+
+// WriteError answers a request of the plugin with an error of the protocol.
+func WriteError(w http.ResponseWriter, status, code int, message string) {
+	utils.WriteJSON(w, status, utils.Envelope{"code": code, "message": message}, nil)
+}
+
+// Unauthorized answers a request whose credentials were wrong or missing.
+func Unauthorized(w http.ResponseWriter) {
+	WriteError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+}
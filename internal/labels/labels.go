@@ -0,0 +1,328 @@
+// Package labels lays out sheets of book labels as PDF documents. Each
+// label holds the title, author and location of a book next to a QR code
+// linking to it. Text is set in the standard Helvetica fonts, which PDF
+// readers provide, so no font is embedded.
+package labels
+
+import (
+	"bookwise/internal/qrcode"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var ErrUnknownPaper = errors.New("unknown label paper")
+
+// Paper names a sheet of labels.
+type Paper string
+
+const (
+	// PaperA4 is an A4 sheet of 3 by 7 labels of 70 by 42.3 mm, such as
+	// Avery 3652.
+	PaperA4 Paper = "a4"
+	// PaperLetter is a Letter sheet of 3 by 10 labels of 2.625 by 1
+	// inches, such as Avery 5160.
+	PaperLetter Paper = "letter"
+)
+
+// mm is a millimeter in points.
+const mm = 72 / 25.4
+
+// sheet is the layout of a sheet of labels, in points from its top left
+// corner.
+type sheet struct {
+	width, height  float64
+	columns, rows  int
+	left, top      float64
+	pitchX, pitchY float64
+	labelW, labelH float64
+	padding        float64
+}
+
+var sheets = map[Paper]sheet{
+	PaperA4: {
+		width: 210 * mm, height: 297 * mm,
+		columns: 3, rows: 7,
+		left: 0, top: 0.3 * mm,
+		pitchX: 70 * mm, pitchY: 42.3 * mm,
+		labelW: 70 * mm, labelH: 42.3 * mm,
+		padding: 4 * mm,
+	},
+	PaperLetter: {
+		width: 612, height: 792,
+		columns: 3, rows: 10,
+		left: 13.5, top: 36,
+		pitchX: 198, pitchY: 72,
+		labelW: 189, labelH: 72,
+		padding: 5,
+	},
+}
+
+// quietZone is the light margin around QR codes, in modules.
+const quietZone = 2
+
+// Label is the label of a book. URL is what its QR code links to.
+type Label struct {
+	Title    string
+	Author   string
+	Location string
+	URL      string
+}
+
+// ParsePaper returns the paper named name.
+func ParsePaper(name string) (Paper, error) {
+	paper := Paper(strings.ToLower(name))
+	if _, ok := sheets[paper]; !ok {
+		return "", ErrUnknownPaper
+	}
+	return paper, nil
+}
+
+// Write writes a PDF document of as many sheets of paper as labels takes.
+func Write(w io.Writer, paper Paper, labels []Label) error {
+	layout, ok := sheets[paper]
+	if !ok {
+		return ErrUnknownPaper
+	}
+
+	perPage := layout.columns * layout.rows
+	pages := [][]byte{}
+	for start := 0; start < len(labels); start += perPage {
+		end := min(start+perPage, len(labels))
+
+		var content bytes.Buffer
+		for i, label := range labels[start:end] {
+			x := layout.left + float64(i%layout.columns)*layout.pitchX
+			top := layout.top + float64(i/layout.columns)*layout.pitchY
+			if err := layout.drawLabel(&content, label, x, layout.height-top-layout.labelH); err != nil {
+				return err
+			}
+		}
+		pages = append(pages, content.Bytes())
+	}
+
+	return writeDocument(w, layout, pages)
+}
+
+// drawLabel draws label with its bottom left corner at x and y: the QR code
+// on the left and the text on the right.
+func (s sheet) drawLabel(w *bytes.Buffer, label Label, x, y float64) error {
+	code, err := qrcode.Encode(label.URL)
+	if err != nil {
+		return err
+	}
+
+	side := s.labelH - 2*s.padding
+	module := side / float64(code.Size+2*quietZone)
+	originX := x + s.padding + quietZone*module
+	originY := y + s.padding + quietZone*module
+
+	// Runs of dark modules are drawn as one rectangle.
+	w.WriteString("0 g\n")
+	for row := range code.Size {
+		for col := 0; col < code.Size; col++ {
+			if !code.Dark(col, row) {
+				continue
+			}
+			run := 1
+			for col+run < code.Size && code.Dark(col+run, row) {
+				run++
+			}
+			fmt.Fprintf(w, "%s %s %s %s re\n",
+				number(originX+float64(col)*module),
+				number(originY+float64(code.Size-1-row)*module),
+				number(float64(run)*module),
+				number(module),
+			)
+			col += run - 1
+		}
+	}
+	w.WriteString("f\n")
+
+	textX := x + s.padding + side + s.padding/2
+	width := x + s.labelW - s.padding - textX
+	titleSize, smallSize := 10.0, 7.5
+	if s.labelH < 100 {
+		titleSize, smallSize = 8.5, 6.5
+	}
+
+	lines := []line{}
+	for _, text := range wrap(label.Title, width, titleSize, 2) {
+		lines = append(lines, line{"F2", titleSize, text})
+	}
+	if label.Author != "" {
+		lines = append(lines, line{"F1", smallSize, truncate(label.Author, width, smallSize)})
+	}
+	if label.Location != "" {
+		lines = append(lines, line{"F1", smallSize, truncate(label.Location, width, smallSize)})
+	}
+
+	baseline := y + s.labelH - s.padding
+	for _, l := range lines {
+		baseline -= l.size * 1.2
+		fmt.Fprintf(w, "BT /%s %s Tf %s %s Td (%s) Tj ET\n",
+			l.font, number(l.size), number(textX), number(baseline), escape(l.text))
+	}
+
+	return nil
+}
+
+type line struct {
+	font string
+	size float64
+	text string
+}
+
+// wrap breaks text into at most lines lines that fit width at size, the
+// last one truncated.
+func wrap(text string, width, size float64, lines int) []string {
+	words := strings.Fields(text)
+	result := []string{}
+	current := ""
+	for i, word := range words {
+		candidate := strings.TrimSpace(current + " " + word)
+		if current == "" || textWidth(candidate, size) <= width {
+			current = candidate
+			continue
+		}
+		if len(result) == lines-1 {
+			current = strings.Join(append([]string{current}, words[i:]...), " ")
+			break
+		}
+		result = append(result, current)
+		current = word
+	}
+	if current != "" {
+		result = append(result, truncate(current, width, size))
+	}
+	return result
+}
+
+// truncate shortens text to fit width at size, ending it with an ellipsis.
+func truncate(text string, width, size float64) string {
+	if textWidth(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && textWidth(string(runes)+"…", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "…"
+}
+
+// textWidth measures text in Helvetica at size. Bold text runs slightly
+// wider, which the padding of labels absorbs.
+func textWidth(text string, size float64) float64 {
+	total := 0
+	for _, r := range text {
+		if r >= ' ' && r <= '~' {
+			total += helveticaWidths[r-' ']
+		} else if r == '…' {
+			total += 1000
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// helveticaWidths are the advance widths of the printable ASCII characters
+// in Helvetica, in thousandths of the font size.
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// escape encodes text as the body of a PDF string in WinAnsiEncoding, the
+// encoding of the fonts. Characters it lacks are replaced by question marks.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			c = '?'
+		}
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// number formats a coordinate with two decimals, without trailing zeros.
+func number(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "" || s == "-" {
+		return "0"
+	}
+	return s
+}
+
+// writeDocument writes the pages, given their content streams, as a PDF
+// document with the catalog, the page tree and the two fonts.
+func writeDocument(w io.Writer, layout sheet, pages [][]byte) error {
+	var out bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 to 4 are the catalog, the page tree and the fonts; every
+	// page is followed by its content stream.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, content := range pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			number(layout.width), number(layout.height), 6+2*i,
+		))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(content); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := out.WriteTo(w)
+	return err
+}
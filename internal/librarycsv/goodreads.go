@@ -0,0 +1,252 @@
+package librarycsv
+
+import (
+	"bookwise/utils/isbn"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goodreadsSeriesRX matches the series Goodreads appends to titles, as in
+// "The Fellowship of the Ring (The Lord of the Rings, #1)".
+var goodreadsSeriesRX = regexp.MustCompile(`^(.+?)\s*\(([^()]+?),?\s+#(\d+(?:\.\d+)?)\)$`)
+
+var goodreadsBreakRX = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+// goodreadsExclusiveShelves are the shelves every Goodreads book is on
+// exactly one of.
+var goodreadsExclusiveShelves = []string{StatusRead, "currently-reading", "to-read"}
+
+func parseGoodreads(r row) Entry {
+	entry := Entry{
+		ExternalID: r.get("Book Id"),
+		Title:      r.get("Title"),
+		ISBN:       goodreadsISBN(r.get("ISBN13")),
+		Format:     bookFormat(r.get("Binding")),
+		Publisher:  r.get("Publisher"),
+		Review:     strings.TrimSpace(goodreadsBreakRX.ReplaceAllString(r.get("My Review"), "\n")),
+		Spoiler:    strings.EqualFold(r.get("Spoiler"), "true"),
+		Status:     r.get("Exclusive Shelf"),
+		DateAdded:  r.date("Date Added"),
+		DateRead:   r.date("Date Read"),
+	}
+
+	if entry.ISBN == "" {
+		entry.ISBN = goodreadsISBN(r.get("ISBN"))
+	}
+
+	if m := goodreadsSeriesRX.FindStringSubmatch(entry.Title); m != nil {
+		position, _ := strconv.ParseFloat(m[3], 64)
+		entry.Title, entry.SeriesName, entry.SeriesPosition = m[1], m[2], &position
+	}
+
+	authors := []string{r.get("Author")}
+	authors = append(authors, splitList(r.get("Additional Authors"))...)
+	entry.Author = joinAuthors(authors)
+
+	entry.Pages, _ = strconv.Atoi(r.get("Number of Pages"))
+	entry.PublishedYear, _ = strconv.Atoi(r.get("Year Published"))
+	entry.ReadCount, _ = strconv.Atoi(r.get("Read Count"))
+
+	if rating, err := strconv.ParseFloat(r.get("My Rating"), 64); err == nil && rating > 0 {
+		entry.Rating = &rating
+	}
+
+	// Books are on their exclusive shelf too, which older exports leave out
+	// of the Bookshelves column. Read books become completed reading plans
+	// instead of a shelf.
+	entry.Shelves = splitList(r.get("Bookshelves") + "," + entry.Status)
+	for _, shelf := range goodreadsExclusiveShelves {
+		if shelf != entry.Status || shelf == StatusRead {
+			entry.Shelves = removeShelf(entry.Shelves, shelf)
+		}
+	}
+
+	return entry
+}
+
+// goodreadsISBN unwraps the ="0439023483" form Goodreads writes ISBNs in to
+// keep spreadsheets from reading them as numbers.
+func goodreadsISBN(s string) string {
+	return strings.Trim(s, `="`)
+}
+
+func joinAuthors(names []string) string {
+	authors := []string{}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			authors = append(authors, name)
+		}
+	}
+	return strings.Join(authors, " & ")
+}
+
+func removeShelf(shelves []string, name string) []string {
+	kept := shelves[:0]
+	for _, shelf := range shelves {
+		if shelf != name {
+			kept = append(kept, shelf)
+		}
+	}
+	return kept
+}
+
+// goodreadsHeader is the header of a Goodreads library export, which
+// Goodreads and most other services import.
+var goodreadsHeader = []string{
+	"Book Id", "Title", "Author", "Author l-f", "Additional Authors", "ISBN", "ISBN13",
+	"My Rating", "Average Rating", "Publisher", "Binding", "Number of Pages",
+	"Year Published", "Original Publication Year", "Date Read", "Date Added",
+	"Bookshelves", "Bookshelves with positions", "Exclusive Shelf", "My Review",
+	"Spoiler", "Private Notes", "Read Count", "Owned Copies",
+}
+
+var goodreadsBindings = map[string]string{
+	"paperback": "Paperback",
+	"hardcover": "Hardcover",
+	"ebook":     "ebook",
+	"audiobook": "Audiobook",
+}
+
+// GoodreadsWriter writes entries in the layout of a Goodreads export.
+type GoodreadsWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func NewGoodreadsWriter(w io.Writer) *GoodreadsWriter {
+	return &GoodreadsWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes entry, preceded by the header when it is the first one.
+// Ratings are rounded to the whole stars Goodreads supports.
+func (gw *GoodreadsWriter) Write(entry Entry) error {
+	if err := gw.writeHeader(); err != nil {
+		return err
+	}
+
+	title := entry.Title
+	if entry.SeriesName != "" && entry.SeriesPosition != nil {
+		title += fmt.Sprintf(" (%s, #%s)", entry.SeriesName, strconv.FormatFloat(*entry.SeriesPosition, 'f', -1, 64))
+	}
+
+	authors := splitAuthors(entry.Author)
+	author, additional := "", ""
+	if len(authors) > 0 {
+		author, additional = authors[0], strings.Join(authors[1:], ", ")
+	}
+
+	isbn10 := ""
+	if entry.ISBN != "" {
+		isbn10, _ = isbn.To10(entry.ISBN)
+	}
+
+	rating := "0"
+	if entry.Rating != nil {
+		rating = strconv.Itoa(int(math.Round(*entry.Rating)))
+	}
+
+	shelves := slices.Clone(entry.Shelves)
+	status := entry.Status
+	if status == "" {
+		status = "to-read"
+	}
+	if !slices.Contains(shelves, status) {
+		shelves = append(shelves, status)
+	}
+
+	spoiler := ""
+	if entry.Spoiler {
+		spoiler = "true"
+	}
+
+	return gw.w.Write([]string{
+		entry.ExternalID,
+		title,
+		author,
+		lastFirst(author),
+		additional,
+		goodreadsQuote(isbn10),
+		goodreadsQuote(entry.ISBN),
+		rating,
+		"",
+		entry.Publisher,
+		goodreadsBindings[entry.Format],
+		formatInt(entry.Pages),
+		formatInt(entry.PublishedYear),
+		"",
+		formatDate(entry.DateRead),
+		formatDate(entry.DateAdded),
+		strings.Join(shelves, ", "),
+		"",
+		status,
+		strings.ReplaceAll(entry.Review, "\n", "<br/>"),
+		spoiler,
+		"",
+		strconv.Itoa(entry.ReadCount),
+		strconv.Itoa(entry.OwnedCopies),
+	})
+}
+
+// Flush writes any buffered entries, and the header when there were none.
+func (gw *GoodreadsWriter) Flush() error {
+	if err := gw.writeHeader(); err != nil {
+		return err
+	}
+
+	gw.w.Flush()
+	return gw.w.Error()
+}
+
+func (gw *GoodreadsWriter) writeHeader() error {
+	if gw.wroteHeader {
+		return nil
+	}
+
+	gw.wroteHeader = true
+	return gw.w.Write(goodreadsHeader)
+}
+
+func goodreadsQuote(s string) string {
+	return `="` + s + `"`
+}
+
+// lastFirst turns "Ursula K. Le Guin" into the "Le Guin, Ursula K." form of
+// the Author l-f column, as well as a name can be split on its last space.
+func lastFirst(name string) string {
+	i := strings.LastIndex(name, " ")
+	if i < 0 {
+		return name
+	}
+	return name[i+1:] + ", " + name[:i]
+}
+
+func splitAuthors(credit string) []string {
+	names := []string{}
+	for _, name := range strings.Split(credit, "&") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func formatInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
@@ -0,0 +1,225 @@
+// Package librarycsv reads the library exports of Goodreads and StoryGraph.
+package librarycsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+type Source string
+
+const (
+	SourceGoodreads  Source = "goodreads"
+	SourceStoryGraph Source = "storygraph"
+	// SourceCalibre libraries are SQLite databases rather than CSV exports,
+	// read into entries by package calibre.
+	SourceCalibre Source = "calibre"
+)
+
+// StatusRead is the reading status of finished books in both exports.
+const StatusRead = "read"
+
+var ErrUnknownSource = errors.New("unrecognized library export")
+
+const bom = "\ufeff"
+
+// dateLayout is the layout both exports write dates in.
+const dateLayout = "2006/01/02"
+
+var dateLayouts = []string{dateLayout, "2006-01-02", "2006/1/2"}
+
+// Entry is a book of an exported library as the source describes it.
+type Entry struct {
+	// Line is the line of the entry in the file, counting the header, or
+	// the ID of the book in a Calibre library.
+	Line           int
+	ExternalID     string
+	Title          string
+	Author         string
+	ISBN           string
+	Pages          int
+	Format         string
+	Publisher      string
+	PublishedYear  int
+	PublishedDate  *time.Time
+	Language       string
+	Description    string
+	SeriesName     string
+	SeriesPosition *float64
+	Rating         *float64
+	Review         string
+	Spoiler        bool
+	Status         string
+	Shelves        []string
+	ReadCount      int
+	OwnedCopies    int
+	DateAdded      *time.Time
+	DateStarted    *time.Time
+	DateRead       *time.Time
+	// Identifiers are the IDs other services know the book by, by service.
+	Identifiers map[string]string
+}
+
+// Detect tells the source of an export from its header.
+func Detect(data []byte) (Source, error) {
+	header, err := newReader(bytes.NewReader(data)).Read()
+	if err != nil {
+		return "", ErrUnknownSource
+	}
+
+	columns := columnIndex(header)
+	switch {
+	case columns.has("Book Id", "Exclusive Shelf"):
+		return SourceGoodreads, nil
+	case columns.has("ISBN/UID", "Read Status"):
+		return SourceStoryGraph, nil
+	}
+
+	return "", ErrUnknownSource
+}
+
+// Parse reads an export of the given source.
+func Parse(source Source, r io.Reader) ([]Entry, error) {
+	cr := newReader(r)
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := columnIndex(header)
+
+	var parse func(row) Entry
+	switch source {
+	case SourceGoodreads:
+		if !columns.has("Book Id", "Title", "Author") {
+			return nil, ErrUnknownSource
+		}
+		parse = parseGoodreads
+	case SourceStoryGraph:
+		if !columns.has("ISBN/UID", "Title", "Authors") {
+			return nil, ErrUnknownSource
+		}
+		parse = parseStoryGraph
+	default:
+		return nil, ErrUnknownSource
+	}
+
+	entries := []Entry{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+		if isBlank(record) {
+			continue
+		}
+
+		entry := parse(row{columns: columns, record: record})
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func newReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	return cr
+}
+
+type columns map[string]int
+
+func columnIndex(header []string) columns {
+	index := columns{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, bom))
+		index[name] = i
+	}
+	return index
+}
+
+func (c columns) has(names ...string) bool {
+	for _, name := range names {
+		if _, ok := c[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// row reads the fields of a record by column name.
+type row struct {
+	columns columns
+	record  []string
+}
+
+func (r row) get(name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func (r row) date(name string) *time.Time {
+	return parseDate(r.get(name))
+}
+
+func parseDate(s string) *time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+func isBlank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// splitList splits a comma separated list such as the shelves of a book.
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" && !slices.Contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// bookFormat maps the binding or format an export names to the formats of a
+// book, leaving unknown ones empty.
+func bookFormat(s string) string {
+	s = strings.ToLower(s)
+	switch {
+	case strings.Contains(s, "audio"):
+		return "audiobook"
+	case strings.Contains(s, "kindle"), strings.Contains(s, "ebook"),
+		strings.Contains(s, "digital"), strings.Contains(s, "nook"):
+		return "ebook"
+	case strings.Contains(s, "hardcover"), strings.Contains(s, "hardback"):
+		return "hardcover"
+	case strings.Contains(s, "paperback"), strings.Contains(s, "mass market"):
+		return "paperback"
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package librarycsv
+
+import (
+	"bookwise/utils/isbn"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func parseStoryGraph(r row) Entry {
+	entry := Entry{
+		ExternalID: r.get("ISBN/UID"),
+		Title:      r.get("Title"),
+		Author:     joinAuthors(splitList(r.get("Authors"))),
+		Format:     bookFormat(r.get("Format")),
+		Review:     r.get("Review"),
+		Status:     r.get("Read Status"),
+		DateAdded:  r.date("Date Added"),
+		DateRead:   r.date("Last Date Read"),
+	}
+
+	entry.ReadCount, _ = strconv.Atoi(r.get("Read Count"))
+
+	// The UID column holds an ISBN only for books StoryGraph knows one of.
+	if _, err := isbn.Normalize(entry.ExternalID); err == nil {
+		entry.ISBN = entry.ExternalID
+	}
+
+	if rating, err := strconv.ParseFloat(r.get("Star Rating"), 64); err == nil && rating > 0 {
+		entry.Rating = &rating
+	}
+
+	// Like Goodreads shelves, the status becomes a shelf unless the book
+	// was read.
+	entry.Shelves = splitList(r.get("Tags"))
+	if entry.Status != "" && entry.Status != StatusRead && !slices.Contains(entry.Shelves, entry.Status) {
+		entry.Shelves = append(entry.Shelves, entry.Status)
+	}
+
+	entry.DateStarted, entry.DateRead = storyGraphReadDates(r.get("Dates Read"), entry.DateRead)
+
+	return entry
+}
+
+// storyGraphReadDates returns the start and end of the latest read of
+// "2023/01/20-2023/02/01, 2024/03/02-2024/03/09", falling back to last for
+// the end.
+func storyGraphReadDates(s string, last *time.Time) (start, end *time.Time) {
+	reads := splitList(s)
+	if len(reads) == 0 {
+		return nil, last
+	}
+
+	from, to, ok := strings.Cut(reads[len(reads)-1], "-")
+	if !ok {
+		return nil, parseDateOr(from, last)
+	}
+	return parseDate(from), parseDateOr(to, last)
+}
+
+func parseDateOr(s string, fallback *time.Time) *time.Time {
+	if t := parseDate(s); t != nil {
+		return t
+	}
+	return fallback
+}
@@ -0,0 +1,261 @@
+package metadata
+
+import (
+	"bookwise/utils/isbn"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxLineLength bounds a single record of the dump.
+const maxLineLength = 4 << 20
+
+type dumpProvider struct {
+	file  *os.File
+	index map[string]int64
+}
+
+// NewDumpProvider indexes an Open Library editions dump at path. Each line
+// holds one edition as JSON, either on its own or as the last tab-separated
+// column of the official dump format. Only the byte offset of every ISBN is
+// kept in memory; records are read back from the file on lookup.
+func NewDumpProvider(path string) (*dumpProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &dumpProvider{
+		file:  file,
+		index: map[string]int64{},
+	}
+
+	if err := p.buildIndex(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Len returns the number of indexed ISBNs.
+func (p *dumpProvider) Len() int {
+	return len(p.index)
+}
+
+func (p *dumpProvider) buildIndex() error {
+	reader := bufio.NewReaderSize(p.file, 64<<10)
+	var offset int64
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var ids struct {
+				ISBN10 []string `json:"isbn_10"`
+				ISBN13 []string `json:"isbn_13"`
+			}
+
+			if json.Unmarshal(recordJSON(line), &ids) == nil {
+				for _, raw := range append(ids.ISBN13, ids.ISBN10...) {
+					if normalized, err := isbn.Normalize(raw); err == nil {
+						if _, ok := p.index[normalized]; !ok {
+							p.index[normalized] = offset
+						}
+					}
+				}
+			}
+
+			offset += int64(len(line))
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *dumpProvider) Lookup(isbn string) (*Metadata, error) {
+	offset, ok := p.index[isbn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(p.file, offset, maxLineLength))
+	line, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var record dumpRecord
+	if err := json.Unmarshal(recordJSON(line), &record); err != nil {
+		return nil, fmt.Errorf("metadata dump record at offset %d: %w", offset, err)
+	}
+
+	metadata := record.toMetadata()
+	metadata.ISBN = isbn
+	return metadata, nil
+}
+
+// recordJSON strips the leading tab-separated columns of the official dump
+// format, leaving the JSON document.
+func recordJSON(line []byte) []byte {
+	if i := bytes.LastIndexByte(line, '\t'); i >= 0 {
+		line = line[i+1:]
+	}
+	return bytes.TrimSpace(line)
+}
+
+type dumpRecord struct {
+	Title         string            `json:"title"`
+	Subtitle      string            `json:"subtitle"`
+	NumberOfPages int               `json:"number_of_pages"`
+	Publishers    []string          `json:"publishers"`
+	PublishDate   string            `json:"publish_date"`
+	Description   json.RawMessage   `json:"description"`
+	Authors       []json.RawMessage `json:"authors"`
+	ByStatement   string            `json:"by_statement"`
+	Covers        []int64           `json:"covers"`
+	Languages     []struct {
+		Key string `json:"key"`
+	} `json:"languages"`
+}
+
+func (r dumpRecord) toMetadata() *Metadata {
+	metadata := &Metadata{
+		Title:         strings.TrimSpace(r.Title),
+		Authors:       []string{},
+		Pages:         r.NumberOfPages,
+		Description:   textValue(r.Description),
+		PublishedDate: parsePublishDate(r.PublishDate),
+	}
+
+	if subtitle := strings.TrimSpace(r.Subtitle); subtitle != "" && metadata.Title != "" {
+		metadata.Title += ": " + subtitle
+	}
+
+	for _, raw := range r.Authors {
+		if name := authorName(raw); name != "" {
+			metadata.Authors = append(metadata.Authors, name)
+		}
+	}
+
+	if len(metadata.Authors) == 0 {
+		if by := strings.TrimRight(strings.TrimSpace(r.ByStatement), "."); by != "" {
+			metadata.Authors = append(metadata.Authors, by)
+		}
+	}
+
+	if len(r.Publishers) > 0 {
+		metadata.Publisher = strings.TrimSpace(r.Publishers[0])
+	}
+
+	if len(r.Languages) > 0 {
+		metadata.Language = languageCode(r.Languages[0].Key)
+	}
+
+	for _, cover := range r.Covers {
+		if cover > 0 {
+			metadata.CoverURL = fmt.Sprintf("https://covers.openlibrary.org/b/id/%d-L.jpg", cover)
+			break
+		}
+	}
+
+	return metadata
+}
+
+// textValue reads Open Library text fields, which are either a plain string
+// or an object with a "value" key.
+func textValue(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+
+	var text struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &text) == nil {
+		return strings.TrimSpace(text.Value)
+	}
+
+	return ""
+}
+
+// authorName accepts authors given as plain names or as objects carrying a
+// "name". Bare references to author records have no name and are skipped.
+func authorName(raw json.RawMessage) string {
+	var name string
+	if json.Unmarshal(raw, &name) == nil {
+		return strings.TrimSpace(name)
+	}
+
+	var author struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(raw, &author) == nil {
+		return strings.TrimSpace(author.Name)
+	}
+
+	return ""
+}
+
+var publishDateLayouts = []string{
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"January 2006",
+	"Jan 2006",
+	"2006-01",
+	"2006",
+}
+
+// parsePublishDate understands the most common spellings of the free-text
+// publish_date field. Unknown days and months default to the first.
+func parsePublishDate(raw string) *time.Time {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range publishDateLayouts {
+		if date, err := time.Parse(layout, raw); err == nil {
+			return &date
+		}
+	}
+	return nil
+}
+
+var marcLanguages = map[string]string{
+	"eng": "en",
+	"por": "pt",
+	"spa": "es",
+	"fre": "fr",
+	"ger": "de",
+	"ita": "it",
+	"dut": "nl",
+	"rus": "ru",
+	"jpn": "ja",
+	"chi": "zh",
+}
+
+// languageCode turns an Open Library language key such as "/languages/eng"
+// into a two-letter code where one is known. Keys that do not look like a
+// language code are dropped.
+func languageCode(key string) string {
+	code := key[strings.LastIndexByte(key, '/')+1:]
+	if short, ok := marcLanguages[code]; ok {
+		return short
+	}
+
+	if len(code) != 3 || strings.Trim(code, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return ""
+	}
+
+	return code
+}
@@ -0,0 +1,96 @@
+package metadata
+
+import (
+	"bookwise/internal/jsonlog"
+	e "bookwise/utils/errors"
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("no metadata found for this isbn")
+
+// Metadata is the bibliographic information a provider knows about an
+// edition. Empty fields are unknown.
+type Metadata struct {
+	ISBN          string     `json:"isbn"`
+	Title         string     `json:"title"`
+	Authors       []string   `json:"authors"`
+	Pages         int        `json:"pages"`
+	Description   string     `json:"description"`
+	Publisher     string     `json:"publisher"`
+	PublishedDate *time.Time `json:"publishedDate"`
+	Language      string     `json:"language"`
+	CoverURL      string     `json:"coverUrl"`
+}
+
+// Provider looks up an edition by its ISBN-13. Lookups that find nothing
+// return ErrNotFound.
+type Provider interface {
+	Lookup(isbn string) (*Metadata, error)
+}
+
+// Cache stores provider results. Get returns e.ErrRecordNotFound when isbn
+// has no entry younger than maxAge.
+type Cache interface {
+	Get(isbn string, maxAge time.Duration) (*Metadata, error)
+	Put(isbn string, metadata *Metadata) error
+}
+
+type nopProvider struct{}
+
+// NewNopProvider returns a Provider that never finds anything, used when no
+// metadata source is configured.
+func NewNopProvider() *nopProvider {
+	return &nopProvider{}
+}
+
+func (p *nopProvider) Lookup(isbn string) (*Metadata, error) {
+	return nil, ErrNotFound
+}
+
+type cachedProvider struct {
+	next   Provider
+	cache  Cache
+	ttl    time.Duration
+	logger jsonlog.Logger
+}
+
+// NewCachedProvider wraps next so results are served from cache while they
+// are younger than ttl. Failures to write the cache are logged and otherwise
+// ignored.
+func NewCachedProvider(
+	next Provider,
+	cache Cache,
+	ttl time.Duration,
+	logger jsonlog.Logger,
+) *cachedProvider {
+	return &cachedProvider{
+		next:   next,
+		cache:  cache,
+		ttl:    ttl,
+		logger: logger,
+	}
+}
+
+func (p *cachedProvider) Lookup(isbn string) (*Metadata, error) {
+	metadata, err := p.cache.Get(isbn, p.ttl)
+	switch {
+	case err == nil:
+		return metadata, nil
+	case !errors.Is(err, e.ErrRecordNotFound):
+		return nil, err
+	}
+
+	metadata, err = p.next.Lookup(isbn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.cache.Put(isbn, metadata); err != nil {
+		p.logger.PrintError(err, map[string]string{
+			"isbn": isbn,
+		})
+	}
+
+	return metadata, nil
+}
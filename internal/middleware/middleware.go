@@ -3,10 +3,12 @@ package middleware
 import (
 	"bookwise/internal/config"
 	"bookwise/internal/contexts"
+	"bookwise/internal/koreader"
 	"bookwise/internal/models"
 	"bookwise/internal/services"
-	"bookwise/utils/errors"
+	e "bookwise/utils/errors"
 	"bookwise/utils/validator"
+	"errors"
 	"expvar"
 	"fmt"
 	"net"
@@ -16,6 +18,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go-chi/chi"
 	"golang.org/x/time/rate"
 )
 
@@ -27,9 +30,10 @@ var (
 )
 
 type Middleware struct {
-	errRsp      errors.ErrorResponseInterface
+	errRsp      e.ErrorResponseInterface
 	userService services.UserService
 	authService services.AuthServiceInterface
+	preferences services.PreferencesService
 	config      config.Config
 }
 
@@ -39,20 +43,24 @@ type MiddlewareInterface interface {
 	RequireAuthenticatedUser(next http.Handler) http.Handler
 	RequireActivatedUser(next http.Handler) http.Handler
 	Authenticate(next http.Handler) http.Handler
+	AuthenticateFeed(next http.Handler) http.Handler
+	AuthenticateKOReader(next http.Handler) http.Handler
 	RateLimit(next http.Handler) http.Handler
 	RecoverPanic(next http.Handler) http.Handler
 }
 
 func New(
-	errRsp errors.ErrorResponseInterface,
+	errRsp e.ErrorResponseInterface,
 	userService services.UserService,
 	authService services.AuthServiceInterface,
+	preferences services.PreferencesService,
 	config config.Config,
 ) *Middleware {
 	return &Middleware{
 		errRsp:      errRsp,
 		userService: userService,
 		authService: authService,
+		preferences: preferences,
 		config:      config,
 	}
 }
@@ -159,11 +167,22 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 
 		if authorizationHeader == "" {
 			r = contexts.ContextSetUser(r, models.AnonymousUser)
+			r = contexts.ContextSetPreferences(r, models.DefaultPreferences(0))
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		headerParts := strings.Split(authorizationHeader, " ")
+
+		// Basic credentials are only accepted by the routes behind
+		// AuthenticateFeed, which check them.
+		if len(headerParts) == 2 && headerParts[0] == "Basic" {
+			r = contexts.ContextSetUser(r, models.AnonymousUser)
+			r = contexts.ContextSetPreferences(r, models.DefaultPreferences(0))
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
 			m.errRsp.InvalidCredentialsResponse(w, r)
 			return
@@ -183,11 +202,91 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		r = contexts.ContextSetUser(r, user)
-		next.ServeHTTP(w, r)
+		m.serveAs(w, r, next, user)
 	})
 }
 
+// basicChallenge asks clients for HTTP Basic credentials.
+const basicChallenge = `Basic realm="Bookwise", charset="UTF-8"`
+
+// AuthenticateFeed authenticates the e-reader apps reading a catalog feed,
+// which cannot send bearer tokens, by the feed token in the {token} path
+// variable or by HTTP Basic credentials. Users already authenticated by a
+// bearer token pass through. Anonymous requests are asked for Basic
+// credentials, which makes apps prompt for them.
+func (m *Middleware) AuthenticateFeed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !contexts.ContextGetUser(r).IsAnonymous() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var user *models.User
+		var err error
+
+		token := chi.URLParam(r, "token")
+		if token != "" {
+			user, err = m.authService.AuthenticateFeedToken(token)
+		} else if email, password, ok := r.BasicAuth(); ok {
+			user, err = m.authService.AuthenticateBasic(email, password)
+		} else {
+			w.Header().Set("WWW-Authenticate", basicChallenge)
+			m.errRsp.AuthenticationRequiredResponse(w, r)
+			return
+		}
+
+		switch {
+		case err == nil:
+			m.serveAs(w, r, next, user)
+		case errors.Is(err, e.ErrInvalidCredentials):
+			if token == "" {
+				w.Header().Set("WWW-Authenticate", basicChallenge)
+			}
+			m.errRsp.InvalidCredentialsResponse(w, r)
+		case errors.Is(err, e.ErrInactiveAccount):
+			m.errRsp.InactiveAccountResponse(w, r)
+		default:
+			m.errRsp.ServerErrorResponse(w, r, err)
+		}
+	})
+}
+
+// AuthenticateKOReader authenticates the sync plugin of KOReader by the
+// x-auth-user and x-auth-key headers it sends with every request, answering
+// failures the way the plugin understands.
+func (m *Middleware) AuthenticateKOReader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, key := r.Header.Get("x-auth-user"), r.Header.Get("x-auth-key")
+		if username == "" || key == "" {
+			koreader.Unauthorized(w)
+			return
+		}
+
+		user, err := m.authService.AuthenticateKOReader(username, key)
+		switch {
+		case err == nil:
+			m.serveAs(w, r, next, user)
+		case errors.Is(err, e.ErrInvalidCredentials), errors.Is(err, e.ErrInactiveAccount):
+			koreader.Unauthorized(w)
+		default:
+			m.errRsp.ServerErrorResponse(w, r, err)
+		}
+	})
+}
+
+// serveAs serves the request as the user, with the user's preferences.
+func (m *Middleware) serveAs(w http.ResponseWriter, r *http.Request, next http.Handler, user *models.User) {
+	preferences, err := m.preferences.FindByUserID(user.ID)
+	if err != nil {
+		m.errRsp.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	r = contexts.ContextSetUser(r, user)
+	r = contexts.ContextSetPreferences(r, preferences)
+	next.ServeHTTP(w, r)
+}
+
 func (m *Middleware) RateLimit(next http.Handler) http.Handler {
 	type client struct {
 		limiter  *rate.Limiter
@@ -245,6 +344,12 @@ func (m *Middleware) RecoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				// Handlers abort responses already under way, which net/http
+				// does by closing the connection.
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				w.Header().Set("Connection", "close")
 				m.errRsp.ServerErrorResponse(w, r, fmt.Errorf("%s", err))
 			}
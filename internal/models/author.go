@@ -0,0 +1,205 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type AuthorRole string
+
+const (
+	AuthorRoleAuthor      AuthorRole = "author"
+	AuthorRoleTranslator  AuthorRole = "translator"
+	AuthorRoleEditor      AuthorRole = "editor"
+	AuthorRoleIllustrator AuthorRole = "illustrator"
+)
+
+var authorSeparatorRX = regexp.MustCompile(`\s*(;|&|\s+and\s+)\s*`)
+
+type Author struct {
+	ID       int64  `db:"id"`
+	Name     string `db:"name"`
+	SortName string `db:"sort_name"`
+	Aliases  []string
+	BaseModel
+}
+
+type AuthorDTO struct {
+	ID       *int64   `json:"id"`
+	Name     *string  `json:"name"`
+	SortName *string  `json:"sortName"`
+	Aliases  []string `json:"aliases"`
+	Version  *int     `json:"version"`
+}
+
+type BookAuthor struct {
+	Author   Author
+	Role     AuthorRole
+	Position int
+}
+
+type BookAuthorDTO struct {
+	ID       *int64      `json:"id"`
+	Name     *string     `json:"name"`
+	SortName *string     `json:"sortName,omitempty"`
+	Role     *AuthorRole `json:"role"`
+}
+
+func (m AuthorDTO) ToModel() *Author {
+	var model Author
+
+	if m.ID != nil {
+		model.ID = *m.ID
+	}
+
+	if m.Name != nil {
+		model.Name = *m.Name
+	}
+
+	if m.SortName != nil {
+		model.SortName = *m.SortName
+	}
+
+	if m.Aliases != nil {
+		model.Aliases = m.Aliases
+	}
+
+	if m.Version != nil {
+		model.Version = *m.Version
+	}
+
+	return &model
+}
+
+func (m Author) ToDTO() *AuthorDTO {
+	aliases := m.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	return &AuthorDTO{
+		ID:       &m.ID,
+		Name:     &m.Name,
+		SortName: &m.SortName,
+		Aliases:  aliases,
+		Version:  &m.Version,
+	}
+}
+
+func (m BookAuthorDTO) ToModel() BookAuthor {
+	model := BookAuthor{
+		Role: AuthorRoleAuthor,
+	}
+
+	if m.ID != nil {
+		model.Author.ID = *m.ID
+	}
+
+	if m.Name != nil {
+		model.Author.Name, model.Author.SortName = ParseAuthorName(*m.Name)
+	}
+
+	if m.SortName != nil {
+		model.Author.SortName = *m.SortName
+	}
+
+	if m.Role != nil {
+		model.Role = *m.Role
+	}
+
+	return model
+}
+
+func (m BookAuthor) ToDTO() *BookAuthorDTO {
+	return &BookAuthorDTO{
+		ID:       &m.Author.ID,
+		Name:     &m.Author.Name,
+		SortName: &m.Author.SortName,
+		Role:     &m.Role,
+	}
+}
+
+func (m *Author) ValidateAuthor(v *validator.Validator) {
+	v.Check(strings.TrimSpace(m.Name) != "", "name", "must be provided")
+	v.Check(len(m.Name) <= 500, "name", "must not be more than 500 bytes long")
+	v.Check(AuthorNameKey(m.Name) != "", "name", "must contain letters or digits")
+	v.Check(len(m.Aliases) <= 50, "aliases", "must not contain more than 50 aliases")
+
+	for _, alias := range m.Aliases {
+		v.Check(strings.TrimSpace(alias) != "", "aliases", "must not contain empty values")
+	}
+}
+
+func ValidateBookAuthors(v *validator.Validator, authors []BookAuthor) {
+	for _, author := range authors {
+		v.Check(
+			validator.In(
+				string(author.Role),
+				string(AuthorRoleAuthor),
+				string(AuthorRoleTranslator),
+				string(AuthorRoleEditor),
+				string(AuthorRoleIllustrator),
+			),
+			"authors",
+			"role must be author, translator, editor or illustrator",
+		)
+		v.Check(author.Author.ID != 0 || AuthorNameKey(author.Author.Name) != "", "authors", "must have an id or a name")
+	}
+}
+
+// SplitAuthors splits a free-text credit such as "Neil Gaiman & Terry
+// Pratchett" into the individual names.
+func SplitAuthors(credit string) []string {
+	names := []string{}
+	for _, part := range authorSeparatorRX.Split(credit, -1) {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+	return names
+}
+
+// ParseAuthorName returns the display name and the sort name for raw, which
+// may be written either as "First Last" or as "Last, First".
+func ParseAuthorName(raw string) (name, sortName string) {
+	raw = strings.Join(strings.Fields(raw), " ")
+
+	if last, first, ok := strings.Cut(raw, ","); ok && !strings.Contains(first, ",") {
+		last, first = strings.TrimSpace(last), strings.TrimSpace(first)
+		if last != "" && first != "" {
+			return first + " " + last, last + ", " + first
+		}
+	}
+
+	i := strings.LastIndex(raw, " ")
+	if i < 0 {
+		return raw, raw
+	}
+
+	return raw, raw[i+1:] + ", " + raw[:i]
+}
+
+// AuthorNameKey reduces a name to lower-case letters and digits so spelling
+// variants like "J.R.R. Tolkien" and "J. R. R. Tolkien" compare equal.
+func AuthorNameKey(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return sb.String()
+}
+
+// AuthorCredit renders the main authors of a book as a single display string.
+func AuthorCredit(authors []BookAuthor) string {
+	names := []string{}
+	for _, author := range authors {
+		if author.Role == AuthorRoleAuthor {
+			names = append(names, author.Author.Name)
+		}
+	}
+	return strings.Join(names, " & ")
+}
@@ -5,6 +5,7 @@ import "time"
 type BaseModel struct {
 	Version   int        `db:"version"`
 	Deleted   bool       `db:"deleted"`
+	DeletedAt *time.Time `db:"deleted_at"`
 	CreatedAt time.Time  `db:"created_at"`
 	CreatedBy *int64     `db:"created_by"`
 	UpdatedAt *time.Time `db:"updated_at"`
@@ -1,28 +1,118 @@
 package models
 
-import "bookwise/utils/validator"
+import (
+	"bookwise/utils/isbn"
+	"bookwise/utils/validator"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type BookFormat string
+
+const (
+	BookFormatPaperback BookFormat = "paperback"
+	BookFormatHardcover BookFormat = "hardcover"
+	BookFormatEbook     BookFormat = "ebook"
+	BookFormatAudiobook BookFormat = "audiobook"
+)
+
+// AcquisitionStatus tells how the user has a book: a copy they own, one they
+// borrowed or took from a library, one they wish for, or one they no longer
+// have since they sold or donated it.
+type AcquisitionStatus string
+
+const (
+	AcquisitionOwned    AcquisitionStatus = "owned"
+	AcquisitionBorrowed AcquisitionStatus = "borrowed"
+	AcquisitionLibrary  AcquisitionStatus = "library"
+	AcquisitionWishlist AcquisitionStatus = "wishlist"
+	AcquisitionSold     AcquisitionStatus = "sold"
+	AcquisitionDonated  AcquisitionStatus = "donated"
+)
+
+// AcquisitionStatuses lists every acquisition status.
+var AcquisitionStatuses = []string{
+	string(AcquisitionOwned),
+	string(AcquisitionBorrowed),
+	string(AcquisitionLibrary),
+	string(AcquisitionWishlist),
+	string(AcquisitionSold),
+	string(AcquisitionDonated),
+}
+
+// currencyRX matches ISO 4217 currency codes such as BRL or EUR.
+var currencyRX = regexp.MustCompile(`^[A-Z]{3}$`)
 
 type Book struct {
-	ID          int64  `db:"id" dto:"ID"`
-	Title       string `db:"title" dto:"Title" `
-	Author      string `db:"author" dto:"Author"`
-	Pages       int    `db:"pages" dto:"Pages"`
-	Description string `db:"description" dto:"Description"`
+	ID              int64             `db:"id" dto:"ID"`
+	Title           string            `db:"title" dto:"Title" `
+	Author          string            `db:"author" dto:"Author"`
+	Pages           int               `db:"pages" dto:"Pages"`
+	Description     string            `db:"description" dto:"Description"`
+	ISBN            string            `db:"isbn"`
+	Publisher       string            `db:"publisher"`
+	PublishedDate   *time.Time        `db:"published_date"`
+	Language        string            `db:"language"`
+	Format          BookFormat        `db:"format"`
+	DurationMinutes int               `db:"duration_minutes"`
+	CoverURL        string            `db:"cover_url"`
+	CoverKey        string            `db:"cover_key"`
+	SeriesID        *int64            `db:"series_id"`
+	SeriesPosition  *float64          `db:"series_position"`
+	Acquisition     AcquisitionStatus `db:"acquisition_status"`
+	PurchasedAt     *time.Time        `db:"purchased_at"`
+	Price           *float64          `db:"price"`
+	Currency        string            `db:"currency"`
+	Store           string            `db:"store"`
+	LocationID      *int64            `db:"location_id"`
+	SeriesName      string
+	SeriesTotal     *int
+	Rating          *float64
+	Authors         []BookAuthor
+	Loans           []*Loan
+	LocationPath    []string
+	Chapters        []*Chapter
 	BaseModel
 	User *User `db:"-" dto:"User"`
 }
 
 type BookDTO struct {
-	ID          *int64   `json:"id" dto:"ID"`
-	Title       *string  `json:"title" dto:"Title"`
-	Author      *string  `json:"author" dto:"Author"`
-	Pages       *int     `json:"pages" dto:"Pages"`
-	Description *string  `json:"description" dto:"Description"`
-	User        *UserDTO `json:"user" dto:"User"`
+	ID              *int64             `json:"id" dto:"ID"`
+	Title           *string            `json:"title" dto:"Title"`
+	Author          *string            `json:"author" dto:"Author"`
+	Pages           *int               `json:"pages" dto:"Pages"`
+	Description     *string            `json:"description" dto:"Description"`
+	ISBN            *string            `json:"isbn"`
+	ISBN10          *string            `json:"isbn10,omitempty"`
+	Publisher       *string            `json:"publisher"`
+	PublishedDate   *time.Time         `json:"publishedDate"`
+	Language        *string            `json:"language"`
+	Format          *BookFormat        `json:"format"`
+	DurationMinutes *int               `json:"durationMinutes"`
+	CoverURL        *string            `json:"coverUrl"`
+	ThumbnailURL    *string            `json:"thumbnailUrl"`
+	Rating          *float64           `json:"rating"`
+	Authors         []*BookAuthorDTO   `json:"authors"`
+	Series          *BookSeriesDTO     `json:"series"`
+	Acquisition     *AcquisitionStatus `json:"acquisitionStatus"`
+	PurchasedAt     *time.Time         `json:"purchasedAt"`
+	Price           *float64           `json:"price"`
+	Currency        *string            `json:"currency"`
+	Store           *string            `json:"store"`
+	Location        *BookLocationDTO   `json:"location"`
+	Loan            *BookLoanDTO       `json:"loan"`
+	Version         *int               `json:"version"`
+	User            *UserDTO           `json:"user" dto:"User"`
 }
 
 func (m BookDTO) ToModel() *Book {
-	var model Book
+	model := Book{
+		Format:      BookFormatPaperback,
+		Acquisition: AcquisitionOwned,
+	}
 
 	if m.ID != nil {
 		model.ID = *m.ID
@@ -44,6 +134,71 @@ func (m BookDTO) ToModel() *Book {
 		model.Description = *m.Description
 	}
 
+	if m.ISBN != nil {
+		model.ISBN = *m.ISBN
+	}
+
+	if m.Publisher != nil {
+		model.Publisher = strings.TrimSpace(*m.Publisher)
+	}
+
+	model.PublishedDate = m.PublishedDate
+
+	if m.Language != nil {
+		model.Language = strings.TrimSpace(*m.Language)
+	}
+
+	if m.Format != nil {
+		model.Format = *m.Format
+	}
+
+	if m.DurationMinutes != nil {
+		model.DurationMinutes = *m.DurationMinutes
+	}
+
+	// Relative cover URLs point at an uploaded cover and are derived from
+	// the stored cover key, so they are not taken back from clients.
+	if m.CoverURL != nil && !strings.HasPrefix(*m.CoverURL, "/") {
+		model.CoverURL = strings.TrimSpace(*m.CoverURL)
+	}
+
+	for _, author := range m.Authors {
+		model.Authors = append(model.Authors, author.ToModel())
+	}
+
+	if m.Series != nil {
+		model.SeriesID = m.Series.ID
+		model.SeriesPosition = m.Series.Position
+		model.SeriesTotal = m.Series.TotalCount
+
+		if m.Series.Name != nil {
+			model.SeriesName = strings.TrimSpace(*m.Series.Name)
+		}
+	}
+
+	if m.Acquisition != nil {
+		model.Acquisition = *m.Acquisition
+	}
+
+	model.PurchasedAt = m.PurchasedAt
+	model.Price = m.Price
+
+	if m.Currency != nil {
+		model.Currency = strings.ToUpper(strings.TrimSpace(*m.Currency))
+	}
+
+	if m.Store != nil {
+		model.Store = strings.TrimSpace(*m.Store)
+	}
+
+	if m.Location != nil {
+		model.LocationID = m.Location.ID
+	}
+
+	if m.Version != nil {
+		model.Version = *m.Version
+	}
+
 	if m.User != nil {
 		model.User = m.User.ToModel()
 	}
@@ -52,19 +207,251 @@ func (m BookDTO) ToModel() *Book {
 }
 
 func (m Book) ToDTO() *BookDTO {
-	return &BookDTO{
-		ID:          &m.ID,
-		Title:       &m.Title,
-		Author:      &m.Author,
-		Pages:       &m.Pages,
-		Description: &m.Description,
-		User:        m.User.ToDTO(),
+	authors := make([]*BookAuthorDTO, 0, len(m.Authors))
+	for _, author := range m.Authors {
+		authors = append(authors, author.ToDTO())
+	}
+
+	dto := &BookDTO{
+		ID:              &m.ID,
+		Title:           &m.Title,
+		Author:          &m.Author,
+		Pages:           &m.Pages,
+		Description:     &m.Description,
+		ISBN:            &m.ISBN,
+		Publisher:       &m.Publisher,
+		PublishedDate:   m.PublishedDate,
+		Language:        &m.Language,
+		Format:          &m.Format,
+		DurationMinutes: &m.DurationMinutes,
+		CoverURL:        &m.CoverURL,
+		Rating:          m.Rating,
+		Authors:         authors,
+		Acquisition:     &m.Acquisition,
+		PurchasedAt:     m.PurchasedAt,
+		Price:           m.Price,
+		Currency:        &m.Currency,
+		Store:           &m.Store,
+		Version:         &m.Version,
+		User:            m.User.ToDTO(),
+	}
+
+	if m.CoverKey != "" {
+		cover := fmt.Sprintf("/v1/books/%d/cover", m.ID)
+		thumbnail := cover + "/thumbnail"
+		dto.CoverURL = &cover
+		dto.ThumbnailURL = &thumbnail
+	}
+
+	dto.Location = m.location()
+
+	for _, loan := range m.Loans {
+		if loan.Open() {
+			dto.Loan = loan.ToBookDTO()
+		}
+	}
+
+	if isbn10, err := isbn.To10(m.ISBN); err == nil {
+		dto.ISBN10 = &isbn10
+	}
+
+	if m.SeriesID != nil {
+		dto.Series = &BookSeriesDTO{
+			ID:         m.SeriesID,
+			Name:       &m.SeriesName,
+			Position:   m.SeriesPosition,
+			TotalCount: m.SeriesTotal,
+		}
+	}
+
+	return dto
+}
+
+// ToLocatedDTO returns the book as the "where is it" search shows it.
+func (m Book) ToLocatedDTO() *LocatedBookDTO {
+	return &LocatedBookDTO{
+		ID:       &m.ID,
+		Title:    &m.Title,
+		Author:   &m.Author,
+		Format:   &m.Format,
+		Location: m.location(),
 	}
 }
 
+// location returns the location the book is kept in, nil when it has none.
+func (m Book) location() *BookLocationDTO {
+	if m.LocationID == nil {
+		return nil
+	}
+
+	location := &BookLocationDTO{
+		ID:   m.LocationID,
+		Path: m.LocationPath,
+	}
+	if len(m.LocationPath) > 0 {
+		location.Name = &m.LocationPath[len(m.LocationPath)-1]
+	}
+	return location
+}
+
 func (m *Book) ValidateBook(v *validator.Validator) {
 	v.Check(m.Title != "", "Title", "must be provided")
-	v.Check(m.Author != "", "Author", "must be provided")
-	v.Check(m.Pages != 0, "Pages", "must be provided")
+	v.Check(m.Author != "" || len(m.Authors) > 0, "Author", "must be provided")
 	v.Check(m.Description != "", "Description", "must be provided")
+	v.Check(
+		validator.In(
+			string(m.Format),
+			string(BookFormatPaperback),
+			string(BookFormatHardcover),
+			string(BookFormatEbook),
+			string(BookFormatAudiobook),
+		),
+		"format",
+		"must be paperback, hardcover, ebook or audiobook",
+	)
+
+	if m.Format == BookFormatAudiobook {
+		v.Check(m.Pages >= 0, "Pages", "must not be negative")
+		v.Check(m.DurationMinutes > 0, "durationMinutes", "must be provided for audiobooks")
+	} else {
+		v.Check(m.Pages != 0, "Pages", "must be provided")
+		v.Check(m.DurationMinutes == 0, "durationMinutes", "must only be provided for audiobooks")
+	}
+
+	v.Check(m.DurationMinutes <= 100000, "durationMinutes", "must not be more than 100000")
+	v.Check(m.ISBN == "" || isbn.Valid13(m.ISBN), "isbn", "must be a valid ISBN-10 or ISBN-13")
+	v.Check(
+		m.CoverURL == "" || strings.HasPrefix(m.CoverURL, "https://") || strings.HasPrefix(m.CoverURL, "http://"),
+		"coverUrl",
+		"must be an http or https URL",
+	)
+	v.Check(len(m.CoverURL) <= 2000, "coverUrl", "must not be more than 2000 bytes long")
+	v.Check(len(m.Publisher) <= 500, "publisher", "must not be more than 500 bytes long")
+	v.Check(m.Language == "" || validator.Matches(m.Language, localeRX), "language", "must be a language code such as en or pt-BR")
+
+	if m.PublishedDate != nil {
+		v.Check(m.PublishedDate.Year() >= 1000, "publishedDate", "must be after the year 1000")
+		v.Check(m.PublishedDate.Before(time.Now().AddDate(5, 0, 0)), "publishedDate", "must not be that far in the future")
+	}
+	ValidateBookAuthors(v, m.Authors)
+	ValidateSeriesPosition(v, m.SeriesPosition)
+	m.validateAcquisition(v)
+
+	if m.SeriesPosition != nil {
+		v.Check(m.SeriesID != nil || m.SeriesName != "", "series", "name must be provided with a position")
+	}
+
+	if m.SeriesTotal != nil {
+		v.Check(*m.SeriesTotal > 0, "series", "totalCount must be greater than zero")
+	}
+}
+
+// validateAcquisition checks the acquisition status and the purchase. Books
+// on the wishlist were not bought yet, so they may carry the price they are
+// wished for at but no purchase date.
+func (m *Book) validateAcquisition(v *validator.Validator) {
+	v.Check(
+		validator.In(string(m.Acquisition), AcquisitionStatuses...),
+		"acquisitionStatus",
+		"must be owned, borrowed, library, wishlist, sold or donated",
+	)
+
+	if m.Price != nil {
+		v.Check(*m.Price >= 0, "price", "must not be negative")
+		v.Check(*m.Price < 1e10, "price", "must be less than 10000000000")
+		v.Check(m.Currency != "", "currency", "must be provided with a price")
+	}
+
+	v.Check(m.Currency == "" || validator.Matches(m.Currency, currencyRX), "currency", "must be a currency code such as EUR or BRL")
+	v.Check(len(m.Store) <= 200, "store", "must not be more than 200 bytes long")
+
+	if m.PurchasedAt != nil {
+		v.Check(m.Acquisition != AcquisitionWishlist, "purchasedAt", "must not be provided for wishlist books")
+		v.Check(m.PurchasedAt.Year() >= 1000, "purchasedAt", "must be after the year 1000")
+		v.Check(m.PurchasedAt.Before(time.Now().AddDate(0, 0, 1)), "purchasedAt", "must not be in the future")
+	}
+}
+
+// Acquired reports whether the user got hold of the book, which books on the
+// wishlist are still to be.
+func (m *Book) Acquired() bool {
+	return m.Acquisition != AcquisitionWishlist
+}
+
+// ValidateImportedBook validates a book read from another service's export.
+// Exports carry no descriptions, so unlike ValidateBook it does not require
+// one.
+func (m *Book) ValidateImportedBook(v *validator.Validator) {
+	check := validator.New()
+	m.ValidateBook(check)
+	delete(check.Errors, "Description")
+
+	for key, message := range check.Errors {
+		v.AddError(key, message)
+	}
+}
+
+// CoverThumbnailKey returns the blob key of the thumbnail stored next to the
+// cover at key.
+func CoverThumbnailKey(key string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "-thumb.jpg"
+}
+
+// NormalizeISBN rewrites ISBN in its canonical ISBN-13 form. Values that do
+// not parse are left untouched for ValidateBook to report.
+func (m *Book) NormalizeISBN() {
+	if m.ISBN == "" {
+		return
+	}
+
+	if normalized, err := isbn.Normalize(m.ISBN); err == nil {
+		m.ISBN = normalized
+	}
+}
+
+// NormalizeAuthors keeps the free-text Author credit and the structured
+// Authors list in sync, deriving whichever one the client left out.
+func (m *Book) NormalizeAuthors() {
+	if len(m.Authors) == 0 {
+		for i, name := range SplitAuthors(m.Author) {
+			var author BookAuthor
+			author.Author.Name, author.Author.SortName = ParseAuthorName(name)
+			author.Role = AuthorRoleAuthor
+			author.Position = i + 1
+			m.Authors = append(m.Authors, author)
+		}
+		return
+	}
+
+	for i := range m.Authors {
+		m.Authors[i].Position = i + 1
+	}
+
+	if m.Author == "" {
+		m.Author = AuthorCredit(m.Authors)
+	}
+}
+
+// Spending is what a user spent on books, by month of purchase and by store.
+type Spending struct {
+	ByMonth []*SpendingMonth `json:"byMonth"`
+	ByStore []*SpendingStore `json:"byStore"`
+}
+
+// SpendingMonth is the amount spent on Books books bought in a month,
+// formatted as 2006-01, in one currency.
+type SpendingMonth struct {
+	Month    string  `json:"month"`
+	Currency string  `json:"currency"`
+	Total    float64 `json:"total"`
+	Books    int     `json:"books"`
+}
+
+// SpendingStore is the amount spent on Books books bought in a store, in one
+// currency. Store is empty for the books bought in none in particular.
+type SpendingStore struct {
+	Store    string  `json:"store"`
+	Currency string  `json:"currency"`
+	Total    float64 `json:"total"`
+	Books    int     `json:"books"`
 }
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// BookFile is an ebook file a user uploaded. Files without a book are
+// uploads that have not been confirmed yet. DocumentHash is the partial MD5
+// KOReader identifies the file by, empty for files stored before it was kept.
+type BookFile struct {
+	ID           int64  `db:"id"`
+	UserID       int64  `db:"user_id"`
+	BookID       *int64 `db:"book_id"`
+	Format       string `db:"format"`
+	FileName     string `db:"file_name"`
+	Size         int64  `db:"size"`
+	ContentHash  string `db:"content_hash"`
+	BlobKey      string `db:"blob_key"`
+	DocumentHash string `db:"document_hash"`
+	BaseModel
+}
+
+type BookFileDTO struct {
+	ID          *int64     `json:"id"`
+	BookID      *int64     `json:"bookId"`
+	Format      *string    `json:"format"`
+	FileName    *string    `json:"fileName"`
+	Size        *int64     `json:"size"`
+	ContentHash *string    `json:"contentHash"`
+	DownloadURL *string    `json:"downloadUrl,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt"`
+}
+
+func (m BookFile) ToDTO() *BookFileDTO {
+	dto := &BookFileDTO{
+		ID:          &m.ID,
+		BookID:      m.BookID,
+		Format:      &m.Format,
+		FileName:    &m.FileName,
+		Size:        &m.Size,
+		ContentHash: &m.ContentHash,
+		CreatedAt:   &m.CreatedAt,
+	}
+
+	if m.BookID != nil {
+		url := fmt.Sprintf("/v1/books/%d/files/%d", *m.BookID, m.ID)
+		dto.DownloadURL = &url
+	}
+
+	return dto
+}
+
+// BookUpload is an uploaded file together with the book its metadata
+// describes, for the user to complete and confirm. Missing holds what
+// ValidateBook still finds wrong with the book.
+type BookUpload struct {
+	File     *BookFile
+	Book     *Book
+	HasCover bool
+	Missing  map[string]string
+}
+
+type BookUploadDTO struct {
+	File     *BookFileDTO      `json:"file"`
+	Book     *BookDTO          `json:"book"`
+	HasCover bool              `json:"hasCover"`
+	Missing  map[string]string `json:"missing"`
+}
+
+func (m BookUpload) ToDTO() *BookUploadDTO {
+	dto := &BookUploadDTO{
+		File:     m.File.ToDTO(),
+		Book:     m.Book.ToDTO(),
+		HasCover: m.HasCover,
+		Missing:  m.Missing,
+	}
+	dto.Book.User = nil
+
+	return dto
+}
+
+// Outcomes of the files of an EbookImport.
+const (
+	EbookImportCreated   = "created"
+	EbookImportLinked    = "linked"
+	EbookImportDuplicate = "duplicate"
+	EbookImportFailed    = "failed"
+)
+
+// EbookImport summarizes an import of a directory of ebook files. Files whose
+// content was imported before are duplicates, files of a book already in the
+// library, matched by ISBN, are linked to it, and the others create a book.
+type EbookImport struct {
+	Scanned    int                `json:"scanned"`
+	Created    int                `json:"created"`
+	Linked     int                `json:"linked"`
+	Duplicates int                `json:"duplicates"`
+	Failed     int                `json:"failed"`
+	Files      []*EbookImportFile `json:"files"`
+}
+
+// EbookImportFile is a file of an import. Files created in a dry run have no
+// BookID; failed files list what was wrong with them in Errors.
+type EbookImportFile struct {
+	Path    string            `json:"path"`
+	Format  string            `json:"format,omitempty"`
+	Outcome string            `json:"outcome"`
+	BookID  *int64            `json:"bookId,omitempty"`
+	Title   string            `json:"title,omitempty"`
+	ISBN    string            `json:"isbn,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// Add records file in the totals of the import.
+func (m *EbookImport) Add(file *EbookImportFile) {
+	m.Scanned++
+	m.Files = append(m.Files, file)
+
+	switch file.Outcome {
+	case EbookImportCreated:
+		m.Created++
+	case EbookImportLinked:
+		m.Linked++
+	case EbookImportDuplicate:
+		m.Duplicates++
+	case EbookImportFailed:
+		m.Failed++
+	}
+}
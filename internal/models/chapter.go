@@ -0,0 +1,110 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"fmt"
+	"strings"
+)
+
+// maxChapters caps the table of contents of a book.
+const maxChapters = 1000
+
+// Chapter is an entry of the table of contents of a book, numbered by
+// Position from 1. It runs from StartPage to EndPage, which is filled in by
+// NumberChapters from the start of the next chapter. Minutes is an optional
+// estimate of the time it takes to read.
+type Chapter struct {
+	ID        int64  `db:"id"`
+	BookID    int64  `db:"book_id"`
+	Position  int    `db:"position"`
+	Title     string `db:"title"`
+	StartPage int    `db:"start_page"`
+	Minutes   *int   `db:"minutes"`
+	EndPage   int
+}
+
+// ChapterDTO is a chapter as the API shows it. Number and endPage are only
+// written: chapters are numbered in the order they are sent.
+type ChapterDTO struct {
+	Number    *int    `json:"number"`
+	Title     *string `json:"title"`
+	StartPage *int    `json:"startPage"`
+	EndPage   *int    `json:"endPage"`
+	Minutes   *int    `json:"minutes"`
+}
+
+func (m ChapterDTO) ToModel() *Chapter {
+	var model Chapter
+
+	if m.Title != nil {
+		model.Title = strings.TrimSpace(*m.Title)
+	}
+
+	if m.StartPage != nil {
+		model.StartPage = *m.StartPage
+	}
+
+	model.Minutes = m.Minutes
+
+	return &model
+}
+
+func (m Chapter) ToDTO() *ChapterDTO {
+	return &ChapterDTO{
+		Number:    &m.Position,
+		Title:     &m.Title,
+		StartPage: &m.StartPage,
+		EndPage:   &m.EndPage,
+		Minutes:   m.Minutes,
+	}
+}
+
+// ValidateChapters checks the table of contents of a book of pages pages, or
+// of unknown length when pages is 0: chapters start on pages of the book, in
+// order.
+func ValidateChapters(v *validator.Validator, chapters []*Chapter, pages int) {
+	v.Check(len(chapters) <= maxChapters, "chapters", fmt.Sprintf("must not contain more than %d chapters", maxChapters))
+
+	for i, chapter := range chapters {
+		key := fmt.Sprintf("chapters[%d]", i)
+
+		v.Check(chapter.Title != "", key+".title", "must be provided")
+		v.Check(len(chapter.Title) <= 500, key+".title", "must not be more than 500 bytes long")
+		v.Check(chapter.StartPage > 0, key+".startPage", "must be greater than zero")
+		v.Check(pages == 0 || chapter.StartPage <= pages, key+".startPage", "must not be after the last page of the book")
+		v.Check(chapter.Minutes == nil || *chapter.Minutes > 0, key+".minutes", "must be greater than zero")
+
+		if i > 0 {
+			v.Check(chapter.StartPage >= chapters[i-1].StartPage, key+".startPage", "must not be before the start of the previous chapter")
+		}
+	}
+}
+
+// NumberChapters numbers the chapters of a book of pages pages in their
+// order and sets where each one ends: before the next one starts, or on the
+// last page. Chapters sharing their first page with the next one end there.
+func NumberChapters(chapters []*Chapter, pages int) {
+	for i, chapter := range chapters {
+		chapter.Position = i + 1
+
+		switch {
+		case i+1 < len(chapters):
+			chapter.EndPage = max(chapters[i+1].StartPage-1, chapter.StartPage)
+		default:
+			chapter.EndPage = max(pages, chapter.StartPage)
+		}
+	}
+}
+
+// ChapterAt returns the number of the chapter page falls in, or nil when it
+// falls before the first one or the book has none.
+func ChapterAt(chapters []*Chapter, page int) *int {
+	var position *int
+	for _, chapter := range chapters {
+		if chapter.StartPage > page {
+			break
+		}
+		position = &chapter.Position
+	}
+	return position
+}
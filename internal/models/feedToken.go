@@ -0,0 +1,66 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+)
+
+// FeedToken lets the e-reader apps of a user read their catalog feed without
+// logging in. Only the hash of the token is stored; Plaintext is only known
+// right after the token is created.
+type FeedToken struct {
+	UserID     int64      `db:"user_id"`
+	Hash       []byte     `db:"token_hash"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	Plaintext  string
+}
+
+type FeedTokenDTO struct {
+	Token      *string    `json:"token,omitempty"`
+	CatalogURL *string    `json:"catalogUrl,omitempty"`
+	CreatedAt  *time.Time `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// NewFeedToken generates a random token for the user.
+func NewFeedToken(userID int64) (*FeedToken, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	token := &FeedToken{
+		UserID:    userID,
+		Plaintext: base64.RawURLEncoding.EncodeToString(b),
+		CreatedAt: time.Now(),
+	}
+	token.Hash = HashFeedToken(token.Plaintext)
+
+	return token, nil
+}
+
+// HashFeedToken returns the hash a token is stored and looked up by.
+func HashFeedToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+// ToDTO describes the token, with the plaintext and the URL of the OPDS
+// catalog it opens when it was just created.
+func (m FeedToken) ToDTO() *FeedTokenDTO {
+	dto := &FeedTokenDTO{
+		CreatedAt:  &m.CreatedAt,
+		LastUsedAt: m.LastUsedAt,
+	}
+
+	if m.Plaintext != "" {
+		url := "/v1/feeds/" + m.Plaintext + "/opds"
+		dto.Token = &m.Plaintext
+		dto.CatalogURL = &url
+	}
+
+	return dto
+}
@@ -65,3 +65,73 @@ func (f Filters) SortDirection() string {
 	}
 	return "ASC"
 }
+
+// BookFilters narrows a user's book list. ShelfIDs are combined with OR
+// semantics unless ShelfMatchAll is set, in which case a book must be on
+// every listed shelf. MinRating and MaxRating only match reviewed books.
+// AuthorID and SeriesID are zero when not filtering by them.
+type BookFilters struct {
+	Title         string
+	Author        string
+	AuthorID      int64
+	SeriesID      int64
+	ISBN          string
+	ShelfIDs      []int64
+	ShelfMatchAll bool
+	MinRating     *float64
+	MaxRating     *float64
+	Acquisition   []string
+	LocationID    int64
+	IDs           []int64
+}
+
+func ValidateBookFilters(v *validator.Validator, f BookFilters) {
+	v.Check(len(f.ShelfIDs) <= 20, "shelves", "must not contain more than 20 shelves")
+	v.Check(f.AuthorID >= 0, "author_id", "must not be negative")
+	v.Check(f.SeriesID >= 0, "series_id", "must not be negative")
+	v.Check(f.LocationID >= 0, "location_id", "must not be negative")
+	v.Check(len(f.IDs) <= 500, "ids", "must not contain more than 500 books")
+
+	for _, status := range f.Acquisition {
+		v.Check(
+			validator.In(status, "owned", "borrowed", "library", "wishlist", "sold", "donated"),
+			"acquisition",
+			"must be a list of owned, borrowed, library, wishlist, sold or donated",
+		)
+	}
+
+	if f.MinRating != nil {
+		v.Check(*f.MinRating >= 0 && *f.MinRating <= 5, "min_rating", "must be between 0 and 5")
+	}
+
+	if f.MaxRating != nil {
+		v.Check(*f.MaxRating >= 0 && *f.MaxRating <= 5, "max_rating", "must be between 0 and 5")
+	}
+
+	if f.MinRating != nil && f.MaxRating != nil {
+		v.Check(*f.MinRating <= *f.MaxRating, "min_rating", "must not be greater than max_rating")
+	}
+}
+
+// HighlightFilters narrows a user's highlights. Query is matched with full
+// text search against the passage, its chapter and the note.
+type HighlightFilters struct {
+	Query  string
+	BookID int64
+	Color  string
+}
+
+// LoanFilters narrows a user's loans. Status is open, overdue or returned,
+// or empty for all loans; overdue loans are the open ones due before Today,
+// the date in the user's time zone. Query matches the borrower's name.
+type LoanFilters struct {
+	Query  string
+	BookID int64
+	Status string
+	Today  string
+}
+
+func ValidateLoanFilters(v *validator.Validator, f LoanFilters) {
+	v.Check(f.BookID >= 0, "book_id", "must not be negative")
+	v.Check(validator.In(f.Status, "", "open", "overdue", "returned"), "status", "must be open, overdue or returned")
+}
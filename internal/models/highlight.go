@@ -0,0 +1,223 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type HighlightColor string
+
+const (
+	HighlightColorYellow HighlightColor = "yellow"
+	HighlightColorGreen  HighlightColor = "green"
+	HighlightColorBlue   HighlightColor = "blue"
+	HighlightColorPink   HighlightColor = "pink"
+	HighlightColorPurple HighlightColor = "purple"
+)
+
+type Highlight struct {
+	ID         int64          `db:"id"`
+	SessionID  *int64         `db:"session_id"`
+	Text       string         `db:"text"`
+	Page       int            `db:"page"`
+	Chapter    string         `db:"chapter"`
+	Note       string         `db:"note"`
+	Color      HighlightColor `db:"color"`
+	Source     string         `db:"source"`
+	SourceHash string         `db:"source_hash"`
+	BaseModel
+	Book *Book `db:"-"`
+}
+
+type HighlightDTO struct {
+	ID        *int64          `json:"id"`
+	Book      *BookDTO        `json:"book"`
+	SessionID *int64          `json:"sessionId"`
+	Text      *string         `json:"text"`
+	Page      *int            `json:"page"`
+	Chapter   *string         `json:"chapter"`
+	Note      *string         `json:"note"`
+	Color     *HighlightColor `json:"color"`
+	Source    *string         `json:"source"`
+	CreatedAt *time.Time      `json:"createdAt"`
+	Version   *int            `json:"version"`
+}
+
+func (m HighlightDTO) ToModel() *Highlight {
+	model := Highlight{
+		Color: HighlightColorYellow,
+	}
+
+	if m.ID != nil {
+		model.ID = *m.ID
+	}
+
+	if m.Book != nil {
+		model.Book = m.Book.ToModel()
+	}
+
+	model.SessionID = m.SessionID
+
+	if m.Text != nil {
+		model.Text = strings.TrimSpace(*m.Text)
+	}
+
+	if m.Page != nil {
+		model.Page = *m.Page
+	}
+
+	if m.Chapter != nil {
+		model.Chapter = strings.TrimSpace(*m.Chapter)
+	}
+
+	if m.Note != nil {
+		model.Note = strings.TrimSpace(*m.Note)
+	}
+
+	if m.Color != nil {
+		model.Color = *m.Color
+	}
+
+	if m.Version != nil {
+		model.Version = *m.Version
+	}
+
+	return &model
+}
+
+func (m Highlight) ToDTO() *HighlightDTO {
+	dto := &HighlightDTO{
+		ID:        &m.ID,
+		SessionID: m.SessionID,
+		Text:      &m.Text,
+		Page:      &m.Page,
+		Chapter:   &m.Chapter,
+		Note:      &m.Note,
+		Color:     &m.Color,
+		Source:    &m.Source,
+		CreatedAt: &m.CreatedAt,
+		Version:   &m.Version,
+	}
+
+	if m.Book != nil {
+		dto.Book = m.Book.ToDTO()
+	}
+
+	return dto
+}
+
+func (m *Highlight) ValidateHighlight(v *validator.Validator) {
+	v.Check(m.Book != nil && m.Book.ID != 0, "book", "must be provided")
+	v.Check(m.Text != "", "text", "must be provided")
+	v.Check(len(m.Text) <= 20_000, "text", "must not be more than 20000 bytes long")
+	v.Check(m.Page >= 0, "page", "must not be negative")
+	v.Check(len(m.Chapter) <= 500, "chapter", "must not be more than 500 bytes long")
+	v.Check(len(m.Note) <= 20_000, "note", "must not be more than 20000 bytes long")
+	v.Check(
+		validator.In(
+			string(m.Color),
+			string(HighlightColorYellow),
+			string(HighlightColorGreen),
+			string(HighlightColorBlue),
+			string(HighlightColorPink),
+			string(HighlightColorPurple),
+		),
+		"color",
+		"must be yellow, green, blue, pink or purple",
+	)
+}
+
+// HighlightColorFrom maps the color names used by e-readers onto the
+// supported colors, falling back to yellow.
+func HighlightColorFrom(name string) HighlightColor {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "green", "olive":
+		return HighlightColorGreen
+	case "blue", "cyan":
+		return HighlightColorBlue
+	case "pink", "red":
+		return HighlightColorPink
+	case "purple":
+		return HighlightColorPurple
+	default:
+		return HighlightColorYellow
+	}
+}
+
+// ValidateLocation checks the page against book: audiobooks have no pages,
+// every other format needs a page within the book.
+func (m *Highlight) ValidateLocation(v *validator.Validator, book *Book) {
+	if book.Format == BookFormatAudiobook {
+		v.Check(m.Page == 0, "page", "must not be provided for audiobooks")
+		return
+	}
+
+	v.Check(m.Page > 0, "page", "must be provided")
+	v.Check(book.Pages == 0 || m.Page <= book.Pages, "page", "must not exceed the number of pages of the book")
+}
+
+// WriteHighlightsMarkdown renders highlights as a Markdown document with one
+// section per book. Highlights are expected to be ordered by book.
+func WriteHighlightsMarkdown(w io.Writer, highlights []*Highlight) error {
+	var sb strings.Builder
+	sb.WriteString("# Highlights\n")
+
+	var bookID int64
+	for _, h := range highlights {
+		if h.Book != nil && h.Book.ID != bookID {
+			bookID = h.Book.ID
+			fmt.Fprintf(&sb, "\n## %s\n", h.Book.Title)
+			if h.Book.Author != "" {
+				fmt.Fprintf(&sb, "\n*%s*\n", h.Book.Author)
+			}
+		}
+
+		sb.WriteString("\n")
+		for _, line := range strings.Split(h.Text, "\n") {
+			sb.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+		}
+
+		location := []string{}
+		if h.Page > 0 {
+			location = append(location, fmt.Sprintf("p. %d", h.Page))
+		}
+		if h.Chapter != "" {
+			location = append(location, h.Chapter)
+		}
+		if len(location) > 0 {
+			fmt.Fprintf(&sb, "\n— %s\n", strings.Join(location, ", "))
+		}
+
+		if h.Note != "" {
+			fmt.Fprintf(&sb, "\n**Note:** %s\n", h.Note)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// HighlightImport summarizes an import of e-reader highlights.
+type HighlightImport struct {
+	Imported   int                    `json:"imported"`
+	Duplicates int                    `json:"duplicates"`
+	Skipped    int                    `json:"skipped"`
+	Matched    []*HighlightImportBook `json:"matched"`
+	Created    []*HighlightImportBook `json:"created"`
+	Unmatched  []*HighlightImportBook `json:"unmatched"`
+}
+
+// HighlightImportBook is a book named in an import. Unmatched books have no
+// BookID; their title and author can be used to create them before importing
+// again, and Errors tells why they could not be created when that was asked.
+// Books created by a dry run have no BookID either.
+type HighlightImportBook struct {
+	BookID     *int64            `json:"bookId,omitempty"`
+	Title      string            `json:"title"`
+	Author     string            `json:"author"`
+	Highlights int               `json:"highlights"`
+	Errors     map[string]string `json:"errors,omitempty"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// KoboProgress is the reading time and progress last imported for a book of
+// a Kobo database. Imports record the difference with it, so the same
+// reading is never counted twice. SecondsRead and PercentRead are the
+// device's totals, PercentRead from 0 to 100.
+type KoboProgress struct {
+	UserID      int64      `db:"user_id"`
+	ContentID   string     `db:"content_id"`
+	BookID      *int64     `db:"book_id"`
+	SecondsRead int        `db:"seconds_read"`
+	PercentRead float64    `db:"percent_read"`
+	ReadStatus  int        `db:"read_status"`
+	LastRead    *time.Time `db:"last_read"`
+	UpdatedAt   time.Time  `db:"updated_at"`
+}
+
+// KoboImport summarizes an import of a Kobo database: the reading sessions
+// recorded and the highlights imported, as HighlightImport counts them.
+type KoboImport struct {
+	Sessions   int               `json:"sessions"`
+	Minutes    int               `json:"minutes"`
+	PagesRead  int               `json:"pagesRead"`
+	Completed  int               `json:"completed"`
+	Highlights HighlightImport   `json:"highlights"`
+	Matched    []*KoboImportBook `json:"matched"`
+	Unmatched  []*KoboImportBook `json:"unmatched"`
+}
+
+// KoboImportBook is a book of the database. Minutes and PagesRead are what
+// the import recorded for it, nothing for books without a reading plan to
+// record them in.
+type KoboImportBook struct {
+	BookID     *int64  `json:"bookId,omitempty"`
+	Title      string  `json:"title"`
+	Author     string  `json:"author"`
+	Percent    float64 `json:"percent"`
+	Minutes    int     `json:"minutes"`
+	PagesRead  int     `json:"pagesRead"`
+	Highlights int     `json:"highlights"`
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// KOReaderAccount is the account the progress sync plugin of KOReader logs
+// in with. The plugin sends the MD5 of the password rather than the password,
+// so Key holds the hash of that MD5.
+type KOReaderAccount struct {
+	UserID    int64  `db:"user_id"`
+	Username  string `db:"username"`
+	Key       password
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+	User      *User      `db:"-"`
+}
+
+type KOReaderAccountDTO struct {
+	Username  *string    `json:"username"`
+	Password  *string    `json:"password,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// KOReaderKey returns the key KOReader sends for password.
+func KOReaderKey(password string) string {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func (dto KOReaderAccountDTO) ToModel() *KOReaderAccount {
+	var model KOReaderAccount
+
+	if dto.Username != nil {
+		model.Username = strings.TrimSpace(*dto.Username)
+	}
+
+	if dto.Password != nil {
+		model.Key.Plaintext = dto.Password
+	}
+
+	return &model
+}
+
+func (m KOReaderAccount) ToDTO() *KOReaderAccountDTO {
+	return &KOReaderAccountDTO{
+		Username:  &m.Username,
+		CreatedAt: &m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (m *KOReaderAccount) Validate(v *validator.Validator) {
+	v.Check(m.Username != "", "username", "must be provided")
+	v.Check(len(m.Username) <= 100, "username", "must not be more than 100 bytes long")
+	v.Check(m.Key.Plaintext != nil, "password", "must be provided")
+
+	if m.Key.Plaintext != nil {
+		ValidatePasswordPlaintext(v, *m.Key.Plaintext)
+	}
+}
+
+// KOReaderProgress is the last position KOReader synced for a document.
+// Progress is opaque to Bookwise: an XPointer for reflowable documents and a
+// page number for fixed layout ones. BookID is nil while the document matches
+// no file of the library.
+type KOReaderProgress struct {
+	UserID     int64     `db:"user_id"`
+	Document   string    `db:"document"`
+	BookID     *int64    `db:"book_id"`
+	Progress   string    `db:"progress"`
+	Percentage float64   `db:"percentage"`
+	Device     string    `db:"device"`
+	DeviceID   string    `db:"device_id"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+// KOReaderProgressDTO is a position as the sync protocol sends it.
+type KOReaderProgressDTO struct {
+	Document   *string  `json:"document"`
+	Progress   *string  `json:"progress"`
+	Percentage *float64 `json:"percentage"`
+	Device     *string  `json:"device"`
+	DeviceID   *string  `json:"device_id"`
+}
+
+func (dto KOReaderProgressDTO) ToModel() *KOReaderProgress {
+	var model KOReaderProgress
+
+	if dto.Document != nil {
+		model.Document = strings.TrimSpace(*dto.Document)
+	}
+
+	if dto.Progress != nil {
+		model.Progress = *dto.Progress
+	}
+
+	if dto.Percentage != nil {
+		model.Percentage = *dto.Percentage
+	}
+
+	if dto.Device != nil {
+		model.Device = *dto.Device
+	}
+
+	if dto.DeviceID != nil {
+		model.DeviceID = *dto.DeviceID
+	}
+
+	return &model
+}
+
+func (m *KOReaderProgress) Validate(v *validator.Validator) {
+	v.Check(m.Document != "", "document", "must be provided")
+	v.Check(len(m.Document) <= 255, "document", "must not be more than 255 bytes long")
+	v.Check(m.Percentage >= 0 && m.Percentage <= 1, "percentage", "must be between 0 and 1")
+	v.Check(len(m.Progress) <= 1000, "progress", "must not be more than 1000 bytes long")
+}
+
+// PagesAt returns the page of a book of pages a position at percentage is
+// on, 0 when the book has no page count.
+func (m *KOReaderProgress) PagesAt(pages int) int {
+	return int(m.Percentage*float64(pages) + 0.5)
+}
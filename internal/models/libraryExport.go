@@ -0,0 +1,260 @@
+package models
+
+import (
+	"bookwise/internal/librarycsv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LibraryDataset string
+
+const (
+	LibraryDatasetBooks    LibraryDataset = "books"
+	LibraryDatasetPlans    LibraryDataset = "plans"
+	LibraryDatasetSessions LibraryDataset = "sessions"
+)
+
+// LibraryEntry is a book of a library export together with everything the
+// user recorded about it. Sessions are keyed by the ID of their plan.
+type LibraryEntry struct {
+	Book     *Book
+	Review   *Review
+	Shelves  []string
+	Plans    []*ReadingPlan
+	Sessions map[int64][]*ReadingSession
+}
+
+type LibraryEntryDTO struct {
+	Book    *BookDTO          `json:"book"`
+	Review  *ReviewDTO        `json:"review"`
+	Shelves []string          `json:"shelves"`
+	Plans   []*LibraryPlanDTO `json:"plans"`
+}
+
+type LibraryPlanDTO struct {
+	*ReadingPlanDTO
+	Sessions []*ReadingSessionDTO `json:"sessions"`
+}
+
+// ToDTO leaves out the owner and the book of plans and sessions, which the
+// entry already holds.
+func (m LibraryEntry) ToDTO() *LibraryEntryDTO {
+	dto := &LibraryEntryDTO{
+		Book:    m.Book.ToDTO(),
+		Shelves: m.Shelves,
+		Plans:   make([]*LibraryPlanDTO, 0, len(m.Plans)),
+	}
+	dto.Book.User = nil
+
+	if dto.Shelves == nil {
+		dto.Shelves = []string{}
+	}
+
+	if m.Review != nil {
+		dto.Review = m.Review.ToDTO()
+	}
+
+	for _, plan := range m.Plans {
+		bare := *plan
+		bare.Book, bare.User = nil, nil
+
+		planDTO := &LibraryPlanDTO{
+			ReadingPlanDTO: bare.ToDTO(),
+			Sessions:       []*ReadingSessionDTO{},
+		}
+
+		for _, session := range m.Sessions[plan.ID] {
+			sessionDTO := session.ToDTO()
+			sessionDTO.ReadingPlan = nil
+			planDTO.Sessions = append(planDTO.Sessions, sessionDTO)
+		}
+
+		dto.Plans = append(dto.Plans, planDTO)
+	}
+
+	return dto
+}
+
+// LibraryCSVHeader returns the header row of a dataset of a CSV export.
+func LibraryCSVHeader(dataset LibraryDataset) []string {
+	switch dataset {
+	case LibraryDatasetPlans:
+		return []string{
+			"id", "book_id", "title", "status", "priority", "start_date", "target_date",
+			"pages_per_day", "minutes_per_day", "created_at",
+		}
+	case LibraryDatasetSessions:
+		return []string{
+			"id", "plan_id", "book_id", "title", "date", "pages_read", "minutes", "chapter", "notes",
+		}
+	default:
+		return []string{
+			"id", "title", "author", "isbn", "pages", "format", "duration_minutes",
+			"publisher", "published_date", "language", "series", "series_position",
+			"shelves", "rating", "review", "spoiler", "reviewed_at",
+			"acquisition_status", "purchased_at", "price", "currency", "store", "created_at",
+		}
+	}
+}
+
+// CSVRecords returns the rows the entry adds to a dataset of a CSV export,
+// in the order of LibraryCSVHeader.
+func (m LibraryEntry) CSVRecords(dataset LibraryDataset) [][]string {
+	book := m.Book
+	bookID := strconv.FormatInt(book.ID, 10)
+	records := [][]string{}
+
+	switch dataset {
+	case LibraryDatasetPlans:
+		for _, plan := range m.Plans {
+			records = append(records, []string{
+				strconv.FormatInt(plan.ID, 10),
+				bookID,
+				book.Title,
+				plan.Status.String(),
+				strconv.Itoa(int(plan.Priority)),
+				csvDate(plan.StartDate),
+				csvDate(plan.TargetDate),
+				strconv.Itoa(plan.PagesPerDay),
+				strconv.Itoa(plan.MinutesPerDay),
+				plan.CreatedAt.Format(time.RFC3339),
+			})
+		}
+
+	case LibraryDatasetSessions:
+		for _, plan := range m.Plans {
+			for _, session := range m.Sessions[plan.ID] {
+				chapter := ""
+				if session.Chapter != nil {
+					chapter = strconv.Itoa(*session.Chapter)
+				}
+
+				records = append(records, []string{
+					strconv.FormatInt(session.ID, 10),
+					strconv.FormatInt(plan.ID, 10),
+					bookID,
+					book.Title,
+					csvDate(&session.Date),
+					strconv.Itoa(session.PagesRead),
+					strconv.Itoa(session.Minutes),
+					chapter,
+					session.Notes,
+				})
+			}
+		}
+
+	default:
+		series, position := "", ""
+		if book.SeriesID != nil {
+			series = book.SeriesName
+		}
+		if book.SeriesPosition != nil {
+			position = strconv.FormatFloat(*book.SeriesPosition, 'f', -1, 64)
+		}
+
+		price := ""
+		if book.Price != nil {
+			price = strconv.FormatFloat(*book.Price, 'f', 2, 64)
+		}
+
+		rating, body, spoiler, reviewedAt := "", "", "", ""
+		if m.Review != nil {
+			rating = strconv.FormatFloat(m.Review.Rating, 'f', -1, 64)
+			body = m.Review.Body
+			spoiler = strconv.FormatBool(m.Review.Spoiler)
+			reviewedAt = csvDate(&m.Review.ReviewedAt)
+		}
+
+		records = append(records, []string{
+			bookID,
+			book.Title,
+			book.Author,
+			book.ISBN,
+			strconv.Itoa(book.Pages),
+			string(book.Format),
+			strconv.Itoa(book.DurationMinutes),
+			book.Publisher,
+			csvDate(book.PublishedDate),
+			book.Language,
+			series,
+			position,
+			strings.Join(m.Shelves, "; "),
+			rating,
+			body,
+			spoiler,
+			reviewedAt,
+			string(book.Acquisition),
+			csvDate(book.PurchasedAt),
+			price,
+			book.Currency,
+			book.Store,
+			book.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return records
+}
+
+// GoodreadsEntry describes the entry the way a Goodreads export would. The
+// book counts as read when a plan was completed, dated by the latest one,
+// and as currently reading while a plan is in progress.
+func (m LibraryEntry) GoodreadsEntry() librarycsv.Entry {
+	book := m.Book
+	entry := librarycsv.Entry{
+		ExternalID: strconv.FormatInt(book.ID, 10),
+		Title:      book.Title,
+		Author:     book.Author,
+		ISBN:       book.ISBN,
+		Pages:      book.Pages,
+		Format:     string(book.Format),
+		Publisher:  book.Publisher,
+		Shelves:    m.Shelves,
+		Status:     "to-read",
+		DateAdded:  &book.CreatedAt,
+	}
+
+	if book.Acquisition == AcquisitionOwned {
+		entry.OwnedCopies = 1
+	}
+
+	if book.SeriesID != nil {
+		entry.SeriesName = book.SeriesName
+		entry.SeriesPosition = book.SeriesPosition
+	}
+
+	if book.PublishedDate != nil {
+		entry.PublishedYear = book.PublishedDate.Year()
+	}
+
+	if m.Review != nil {
+		entry.Rating = &m.Review.Rating
+		entry.Review = m.Review.Body
+		entry.Spoiler = m.Review.Spoiler
+	}
+
+	for _, plan := range m.Plans {
+		switch plan.Status {
+		case ReadingStatusCompleted:
+			entry.ReadCount++
+			if entry.DateRead == nil || (plan.TargetDate != nil && plan.TargetDate.After(*entry.DateRead)) {
+				entry.DateStarted, entry.DateRead = plan.StartDate, plan.TargetDate
+			}
+		case ReadingStatusReading:
+			entry.Status = "currently-reading"
+		}
+	}
+
+	if entry.ReadCount > 0 && entry.Status != "currently-reading" {
+		entry.Status = librarycsv.StatusRead
+	}
+
+	return entry
+}
+
+func csvDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
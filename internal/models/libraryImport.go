@@ -0,0 +1,105 @@
+package models
+
+import "time"
+
+type LibraryImportStatus string
+
+const (
+	LibraryImportPending   LibraryImportStatus = "pending"
+	LibraryImportRunning   LibraryImportStatus = "running"
+	LibraryImportCompleted LibraryImportStatus = "completed"
+	LibraryImportFailed    LibraryImportStatus = "failed"
+)
+
+// LibraryImport is a Goodreads or StoryGraph export or a Calibre library being
+// added to a user's library in the background. Created counts new books,
+// Linked books already in the library and Skipped rows imported by an earlier
+// upload.
+type LibraryImport struct {
+	ID         int64               `db:"id"`
+	UserID     int64               `db:"user_id"`
+	Source     string              `db:"source"`
+	Status     LibraryImportStatus `db:"status"`
+	DryRun     bool                `db:"dry_run"`
+	Total      int                 `db:"total"`
+	Processed  int                 `db:"processed"`
+	Created    int                 `db:"created"`
+	Linked     int                 `db:"linked"`
+	Skipped    int                 `db:"skipped"`
+	Failed     int                 `db:"failed"`
+	Error      string              `db:"error"`
+	StartedAt  *time.Time          `db:"started_at"`
+	FinishedAt *time.Time          `db:"finished_at"`
+	Data       []byte
+	Errors     []*LibraryImportError
+	BaseModel
+}
+
+// LibraryImportError is a validation message for a row that could not be
+// imported.
+type LibraryImportError struct {
+	Line    int    `db:"line"`
+	Title   string `db:"title"`
+	Field   string `db:"field"`
+	Message string `db:"message"`
+}
+
+type LibraryImportDTO struct {
+	ID         *int64                   `json:"id"`
+	Source     *string                  `json:"source"`
+	Status     *LibraryImportStatus     `json:"status"`
+	DryRun     *bool                    `json:"dryRun"`
+	Total      *int                     `json:"total"`
+	Processed  *int                     `json:"processed"`
+	Created    *int                     `json:"created"`
+	Linked     *int                     `json:"linked"`
+	Skipped    *int                     `json:"skipped"`
+	Failed     *int                     `json:"failed"`
+	Error      *string                  `json:"error,omitempty"`
+	StartedAt  *time.Time               `json:"startedAt"`
+	FinishedAt *time.Time               `json:"finishedAt"`
+	CreatedAt  *time.Time               `json:"createdAt"`
+	Errors     []*LibraryImportErrorDTO `json:"errors"`
+}
+
+type LibraryImportErrorDTO struct {
+	Line    *int    `json:"line"`
+	Title   *string `json:"title"`
+	Field   *string `json:"field"`
+	Message *string `json:"message"`
+}
+
+func (m LibraryImport) ToDTO() *LibraryImportDTO {
+	errors := make([]*LibraryImportErrorDTO, 0, len(m.Errors))
+	for _, err := range m.Errors {
+		errors = append(errors, &LibraryImportErrorDTO{
+			Line:    &err.Line,
+			Title:   &err.Title,
+			Field:   &err.Field,
+			Message: &err.Message,
+		})
+	}
+
+	dto := &LibraryImportDTO{
+		ID:         &m.ID,
+		Source:     &m.Source,
+		Status:     &m.Status,
+		DryRun:     &m.DryRun,
+		Total:      &m.Total,
+		Processed:  &m.Processed,
+		Created:    &m.Created,
+		Linked:     &m.Linked,
+		Skipped:    &m.Skipped,
+		Failed:     &m.Failed,
+		StartedAt:  m.StartedAt,
+		FinishedAt: m.FinishedAt,
+		CreatedAt:  &m.CreatedAt,
+		Errors:     errors,
+	}
+
+	if m.Error != "" {
+		dto.Error = &m.Error
+	}
+
+	return dto
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"strings"
+	"time"
+)
+
+// Loan is a book lent to someone, named by BorrowerName. BorrowerEmail is
+// the borrower's email as the lender gave it, and BorrowerID the user with
+// that email, if any; the lender is never told whether there is one. A loan
+// is open until ReturnedAt is set, and overdue while
+// open past DueAt. Overdue is filled in by the service, as it depends on the
+// lender's time zone. The lender is the user of Book.
+type Loan struct {
+	ID            int64      `db:"id"`
+	BorrowerName  string     `db:"borrower_name"`
+	BorrowerID    *int64     `db:"borrower_id"`
+	BorrowerEmail string     `db:"borrower_email"`
+	LentAt        time.Time  `db:"lent_at"`
+	DueAt         *time.Time `db:"due_at"`
+	ReturnedAt    *time.Time `db:"returned_at"`
+	Notes         string     `db:"notes"`
+	RemindedAt    *time.Time `db:"reminded_at"`
+	Overdue       bool
+	BaseModel
+	Book *Book `db:"-"`
+}
+
+// LoanDTO is a loan as the API reads and writes it.
+type LoanDTO struct {
+	ID            *int64     `json:"id"`
+	Book          *BookDTO   `json:"book"`
+	BorrowerName  *string    `json:"borrowerName"`
+	BorrowerEmail *string    `json:"borrowerEmail"`
+	LentAt        *time.Time `json:"lentAt"`
+	DueAt         *time.Time `json:"dueAt"`
+	ReturnedAt    *time.Time `json:"returnedAt"`
+	Notes         *string    `json:"notes"`
+	Overdue       *bool      `json:"overdue"`
+	CreatedAt     *time.Time `json:"createdAt"`
+	Version       *int       `json:"version"`
+}
+
+// BookLoanDTO is the open loan of a book, as shown with the book.
+type BookLoanDTO struct {
+	ID           *int64     `json:"id"`
+	BorrowerName *string    `json:"borrowerName"`
+	LentAt       *time.Time `json:"lentAt"`
+	DueAt        *time.Time `json:"dueAt"`
+	Overdue      *bool      `json:"overdue"`
+}
+
+func (m LoanDTO) ToModel() *Loan {
+	var model Loan
+
+	if m.ID != nil {
+		model.ID = *m.ID
+	}
+
+	if m.Book != nil {
+		model.Book = m.Book.ToModel()
+	}
+
+	if m.BorrowerName != nil {
+		model.BorrowerName = strings.TrimSpace(*m.BorrowerName)
+	}
+
+	if m.BorrowerEmail != nil {
+		model.BorrowerEmail = strings.TrimSpace(*m.BorrowerEmail)
+	}
+
+	if m.LentAt != nil {
+		model.LentAt = *m.LentAt
+	}
+
+	model.DueAt = m.DueAt
+	model.ReturnedAt = m.ReturnedAt
+
+	if m.Notes != nil {
+		model.Notes = strings.TrimSpace(*m.Notes)
+	}
+
+	if m.Version != nil {
+		model.Version = *m.Version
+	}
+
+	return &model
+}
+
+func (m Loan) ToDTO() *LoanDTO {
+	dto := &LoanDTO{
+		ID:            &m.ID,
+		BorrowerName:  &m.BorrowerName,
+		BorrowerEmail: &m.BorrowerEmail,
+		LentAt:        &m.LentAt,
+		DueAt:         m.DueAt,
+		ReturnedAt:    m.ReturnedAt,
+		Notes:         &m.Notes,
+		Overdue:       &m.Overdue,
+		CreatedAt:     &m.CreatedAt,
+		Version:       &m.Version,
+	}
+
+	if m.Book != nil {
+		dto.Book = m.Book.ToDTO()
+	}
+
+	return dto
+}
+
+// ToBookDTO returns the loan as shown with its book.
+func (m Loan) ToBookDTO() *BookLoanDTO {
+	return &BookLoanDTO{
+		ID:           &m.ID,
+		BorrowerName: &m.BorrowerName,
+		LentAt:       &m.LentAt,
+		DueAt:        m.DueAt,
+		Overdue:      &m.Overdue,
+	}
+}
+
+// Open tells whether the book was not returned yet.
+func (m *Loan) Open() bool {
+	return m.ReturnedAt == nil
+}
+
+// MarkOverdue sets Overdue for the lender's date today.
+func (m *Loan) MarkOverdue(today time.Time) {
+	m.Overdue = m.Open() && m.DueAt != nil && dateAfter(today, *m.DueAt)
+}
+
+// ValidateLoan checks the loan against today, the lender's date, as loans
+// are dated in the lender's time zone.
+func (m *Loan) ValidateLoan(v *validator.Validator, today time.Time) {
+	v.Check(m.Book != nil && m.Book.ID != 0, "book", "must be provided")
+	v.Check(m.BorrowerName != "", "borrowerName", "must be provided")
+	v.Check(len(m.BorrowerName) <= 200, "borrowerName", "must not be more than 200 bytes long")
+	v.Check(m.BorrowerEmail == "" || validator.Matches(m.BorrowerEmail, validator.EmailRX), "borrowerEmail", "must be a valid email address")
+	v.Check(len(m.Notes) <= 10_000, "notes", "must not be more than 10000 bytes long")
+	v.Check(!m.LentAt.IsZero(), "lentAt", "must be provided")
+	v.Check(!dateAfter(m.LentAt, today), "lentAt", "must not be in the future")
+
+	if m.DueAt != nil {
+		v.Check(!dateAfter(m.LentAt, *m.DueAt), "dueAt", "must not be before lentAt")
+	}
+
+	if m.ReturnedAt != nil {
+		v.Check(!dateAfter(m.LentAt, *m.ReturnedAt), "returnedAt", "must not be before lentAt")
+		v.Check(!dateAfter(*m.ReturnedAt, today), "returnedAt", "must not be in the future")
+	}
+}
+
+// dateAfter tells whether the date of a is after the date of b.
+func dateAfter(a, b time.Time) bool {
+	return a.Format(time.DateOnly) > b.Format(time.DateOnly)
+}
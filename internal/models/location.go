@@ -0,0 +1,177 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type LocationKind string
+
+// Kinds of locations, from the outermost in. A location holds locations of
+// the kinds after its own: rooms and bookcases may be in a house, shelves in
+// any of the others.
+const (
+	LocationHouse    LocationKind = "house"
+	LocationRoom     LocationKind = "room"
+	LocationBookcase LocationKind = "bookcase"
+	LocationShelf    LocationKind = "shelf"
+)
+
+var locationKinds = []LocationKind{LocationHouse, LocationRoom, LocationBookcase, LocationShelf}
+
+// Location is a place books are kept in, inside its parent. Path holds the
+// names from the outermost location down to this one, and BookCount the
+// books kept directly in it.
+type Location struct {
+	ID        int64        `db:"id"`
+	ParentID  *int64       `db:"parent_id"`
+	Name      string       `db:"name"`
+	Kind      LocationKind `db:"kind"`
+	Path      []string
+	BookCount int
+	BaseModel
+}
+
+type LocationDTO struct {
+	ID        *int64        `json:"id"`
+	ParentID  *int64        `json:"parentId"`
+	Name      *string       `json:"name"`
+	Kind      *LocationKind `json:"kind"`
+	Path      []string      `json:"path"`
+	BookCount *int          `json:"bookCount"`
+	Version   *int          `json:"version"`
+}
+
+// BookLocationDTO is the location a book is kept in, as shown with the book.
+// Only its ID is read.
+type BookLocationDTO struct {
+	ID   *int64   `json:"id"`
+	Name *string  `json:"name,omitempty"`
+	Path []string `json:"path,omitempty"`
+}
+
+// LocatedBookDTO is a book found by the "where is it" search, with the
+// location it is kept in, if any.
+type LocatedBookDTO struct {
+	ID       *int64           `json:"id"`
+	Title    *string          `json:"title"`
+	Author   *string          `json:"author"`
+	Format   *BookFormat      `json:"format"`
+	Location *BookLocationDTO `json:"location"`
+}
+
+func (m LocationDTO) ToModel() *Location {
+	var model Location
+
+	if m.ID != nil {
+		model.ID = *m.ID
+	}
+
+	model.ParentID = m.ParentID
+
+	if m.Name != nil {
+		model.Name = strings.TrimSpace(*m.Name)
+	}
+
+	if m.Kind != nil {
+		model.Kind = *m.Kind
+	}
+
+	if m.Version != nil {
+		model.Version = *m.Version
+	}
+
+	return &model
+}
+
+func (m Location) ToDTO() *LocationDTO {
+	path := m.Path
+	if path == nil {
+		path = []string{m.Name}
+	}
+
+	return &LocationDTO{
+		ID:        &m.ID,
+		ParentID:  m.ParentID,
+		Name:      &m.Name,
+		Kind:      &m.Kind,
+		Path:      path,
+		BookCount: &m.BookCount,
+		Version:   &m.Version,
+	}
+}
+
+func (m *Location) ValidateLocation(v *validator.Validator) {
+	v.Check(m.Name != "", "name", "must be provided")
+	v.Check(len(m.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(slices.Contains(locationKinds, m.Kind), "kind", "must be house, room, bookcase or shelf")
+}
+
+// ValidatePlacement checks where the location goes among the other
+// locations of the user, by ID: its parent must exist and be of an outer
+// kind, must not be the location itself or inside it, and the locations
+// inside it must remain of inner kinds.
+func (m *Location) ValidatePlacement(v *validator.Validator, locations map[int64]*Location) {
+	if m.ParentID != nil {
+		parent, ok := locations[*m.ParentID]
+		switch {
+		case !ok:
+			v.AddError("parentId", "not found")
+		case m.ID != 0 && m.insideOf(parent, locations):
+			v.AddError("parentId", "must not be the location itself or one inside it")
+		case !parent.Kind.holds(m.Kind):
+			v.AddError("parentId", fmt.Sprintf("a %s cannot be inside a %s", m.Kind, parent.Kind))
+		}
+	}
+
+	for _, child := range locations {
+		if child.ParentID != nil && *child.ParentID == m.ID && m.ID != 0 && !m.Kind.holds(child.Kind) {
+			v.AddError("kind", fmt.Sprintf("must hold the %s %q inside it", child.Kind, child.Name))
+			return
+		}
+	}
+}
+
+// insideOf tells whether location is m or one of the locations inside it.
+func (m *Location) insideOf(location *Location, locations map[int64]*Location) bool {
+	for depth := 0; location != nil && depth <= len(locations); depth++ {
+		if location.ID == m.ID {
+			return true
+		}
+		if location.ParentID == nil {
+			return false
+		}
+		location = locations[*location.ParentID]
+	}
+	return false
+}
+
+// holds tells whether a location of kind k may hold one of kind inner.
+func (k LocationKind) holds(inner LocationKind) bool {
+	return slices.Index(locationKinds, k) < slices.Index(locationKinds, inner)
+}
+
+// IndexLocations sets the Path of the locations and returns them by ID.
+func IndexLocations(locations []*Location) map[int64]*Location {
+	index := make(map[int64]*Location, len(locations))
+	for _, location := range locations {
+		index[location.ID] = location
+	}
+
+	for _, location := range locations {
+		path := []string{}
+		for current, depth := location, 0; current != nil && depth <= len(locations); depth++ {
+			path = append(path, current.Name)
+			if current.ParentID == nil {
+				break
+			}
+			current = index[*current.ParentID]
+		}
+		slices.Reverse(path)
+		location.Path = path
+	}
+
+	return index
+}
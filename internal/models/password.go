@@ -0,0 +1,183 @@
+package models
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordParams holds the argon2id cost parameters used for new hashes.
+type PasswordParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+var passwordParams = PasswordParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// SetPasswordParams sets the parameters of new hashes. It returns an error,
+// leaving the current ones, when argon2id could not hash with p.
+func SetPasswordParams(p PasswordParams) error {
+	switch {
+	case p.Iterations < 1:
+		return errors.New("argon2 iterations must be at least 1")
+	case p.Parallelism < 1:
+		return errors.New("argon2 parallelism must be at least 1")
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("argon2 memory must be at least %d KiB for a parallelism of %d", 8*uint32(p.Parallelism), p.Parallelism)
+	case p.SaltLength < 8:
+		return errors.New("argon2 salt length must be at least 8 bytes")
+	case p.KeyLength < 16:
+		return errors.New("argon2 key length must be at least 16 bytes")
+	}
+
+	passwordParams = p
+	return nil
+}
+
+type password struct {
+	Plaintext *string
+	Hash      []byte `db:"password_hash"`
+}
+
+// Set hashes the password with argon2id and stores it in the encoded
+// form $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+func (p *password) Set(plaintextPassword string) error {
+	params := passwordParams
+
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	key := argon2.IDKey(
+		[]byte(plaintextPassword),
+		salt,
+		params.Iterations,
+		params.Memory,
+		params.Parallelism,
+		params.KeyLength,
+	)
+
+	hash := fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	p.Plaintext = &plaintextPassword
+	p.Hash = []byte(hash)
+	return nil
+}
+
+func (p *password) Matches(plaintextPassword string) (bool, error) {
+	switch {
+	case bytes.HasPrefix(p.Hash, []byte("$argon2id$")):
+		params, salt, key, err := decodeArgon2Hash(string(p.Hash))
+		if err != nil {
+			return false, err
+		}
+
+		otherKey := argon2.IDKey(
+			[]byte(plaintextPassword),
+			salt,
+			params.Iterations,
+			params.Memory,
+			params.Parallelism,
+			params.KeyLength,
+		)
+
+		return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+
+	case bytes.HasPrefix(p.Hash, []byte("$2")):
+		err := bcrypt.CompareHashAndPassword(p.Hash, []byte(plaintextPassword))
+		if err != nil {
+			switch {
+			case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+				return false, nil
+			default:
+				return false, err
+			}
+		}
+		return true, nil
+
+	default:
+		return false, ErrUnknownPasswordHash
+	}
+}
+
+// NeedsRehash reports whether the stored hash uses a legacy algorithm or
+// argon2id parameters different from the configured ones.
+func (p *password) NeedsRehash() bool {
+	params, _, _, err := decodeArgon2Hash(string(p.Hash))
+	if err != nil {
+		return true
+	}
+
+	return params != passwordParams
+}
+
+func decodeArgon2Hash(hash string) (PasswordParams, []byte, []byte, error) {
+	var params PasswordParams
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+
+	if version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	_, err := fmt.Sscanf(
+		parts[3],
+		"m=%d,t=%d,p=%d",
+		&params.Memory,
+		&params.Iterations,
+		&params.Parallelism,
+	)
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
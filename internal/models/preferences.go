@@ -0,0 +1,112 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"regexp"
+	"time"
+)
+
+type ProgressUnit string
+
+const (
+	ProgressUnitPages   ProgressUnit = "pages"
+	ProgressUnitMinutes ProgressUnit = "minutes"
+)
+
+var localeRX = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+type Preferences struct {
+	UserID       int64        `db:"user_id"`
+	Timezone     string       `db:"timezone"`
+	Locale       string       `db:"locale"`
+	ProgressUnit ProgressUnit `db:"progress_unit"`
+	WeekStart    time.Weekday `db:"week_start"`
+	PageSize     int          `db:"page_size"`
+	BaseModel
+}
+
+type PreferencesDTO struct {
+	Timezone     *string       `json:"timezone"`
+	Locale       *string       `json:"locale"`
+	ProgressUnit *ProgressUnit `json:"progressUnit"`
+	WeekStart    *time.Weekday `json:"weekStart"`
+	PageSize     *int          `json:"pageSize"`
+	Version      *int          `json:"version"`
+}
+
+func DefaultPreferences(userID int64) *Preferences {
+	return &Preferences{
+		UserID:       userID,
+		Timezone:     "UTC",
+		Locale:       "en-US",
+		ProgressUnit: ProgressUnitPages,
+		WeekStart:    time.Monday,
+		PageSize:     20,
+	}
+}
+
+// Location returns the user's time zone, falling back to UTC when the
+// stored name is not known to the system tz database.
+func (m *Preferences) Location() *time.Location {
+	loc, err := time.LoadLocation(m.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ApplyTo overwrites the fields of m with the ones present in the DTO.
+func (dto PreferencesDTO) ApplyTo(m *Preferences) {
+	if dto.Timezone != nil {
+		m.Timezone = *dto.Timezone
+	}
+
+	if dto.Locale != nil {
+		m.Locale = *dto.Locale
+	}
+
+	if dto.ProgressUnit != nil {
+		m.ProgressUnit = *dto.ProgressUnit
+	}
+
+	if dto.WeekStart != nil {
+		m.WeekStart = *dto.WeekStart
+	}
+
+	if dto.PageSize != nil {
+		m.PageSize = *dto.PageSize
+	}
+
+	if dto.Version != nil {
+		m.Version = *dto.Version
+	}
+}
+
+func (m Preferences) ToDTO() *PreferencesDTO {
+	return &PreferencesDTO{
+		Timezone:     &m.Timezone,
+		Locale:       &m.Locale,
+		ProgressUnit: &m.ProgressUnit,
+		WeekStart:    &m.WeekStart,
+		PageSize:     &m.PageSize,
+		Version:      &m.Version,
+	}
+}
+
+func (m *Preferences) ValidatePreferences(v *validator.Validator) {
+	_, err := time.LoadLocation(m.Timezone)
+	v.Check(m.Timezone != "", "timezone", "must be provided")
+	v.Check(err == nil, "timezone", "must be a valid IANA time zone")
+
+	v.Check(validator.Matches(m.Locale, localeRX), "locale", "must be a valid locale such as pt-BR")
+
+	v.Check(
+		validator.In(string(m.ProgressUnit), string(ProgressUnitPages), string(ProgressUnitMinutes)),
+		"progressUnit",
+		"must be pages or minutes",
+	)
+
+	v.Check(m.WeekStart >= time.Sunday && m.WeekStart <= time.Saturday, "weekStart", "must be between 0 (Sunday) and 6 (Saturday)")
+	v.Check(m.PageSize > 0, "pageSize", "must be greater than zero")
+	v.Check(m.PageSize <= 100, "pageSize", "must be a maximum of 100")
+}
@@ -29,17 +29,21 @@ type ReadingPlan struct {
 	Priority      ReadingPriority `db:"priority"`
 	PagesPerDay   int             `db:"pages_per_day"`
 	MinutesPerDay int             `db:"minutes_per_day"`
-	Book          *Book           `db:"book_id"`
-	User          *User           `db:"user_id"`
+	ReviewPending bool
 
 	BaseModel
+	Book *Book `db:"-"`
+	User *User `db:"-"`
 }
 
+// ReadingSession is the reading of a day in a plan. Chapter is the number
+// of the chapter of the book reached, when known.
 type ReadingSession struct {
-	ID          string
+	ID          int64
 	ReadingPlan ReadingPlan
 	PagesRead   int
 	Minutes     int
+	Chapter     *int
 	Notes       string
 	Date        time.Time
 	BaseModel
@@ -55,13 +59,16 @@ type ReadingPlanDTO struct {
 	MinutesPerDay *int             `json:"minutesPerDay" dto:"MinutesPerDay"`
 	Book          *BookDTO         `json:"book" dto:"Book"`
 	User          *UserDTO         `json:"user" dto:"User"`
+	ReviewPending *bool            `json:"reviewPending"`
+	Version       *int             `json:"version"`
 }
 
 type ReadingSessionDTO struct {
-	ID          *string         `json:"id" dto:"ID"`
+	ID          *int64          `json:"id" dto:"ID"`
 	ReadingPlan *ReadingPlanDTO `json:"readingPlan" dto:"ReadingPlan"`
 	PagesRead   *int            `json:"pagesRead" dto:"PagesRead"`
 	Minutes     *int            `json:"minutes" dto:"Minutes"`
+	Chapter     *int            `json:"chapter" dto:"Chapter"`
 	Notes       *string         `json:"notes" dto:"Notes"`
 	Date        *time.Time      `json:"date" dto:"Date"`
 }
@@ -85,6 +92,8 @@ func (dto ReadingSessionDTO) ToModel() *ReadingSession {
 		model.Minutes = *dto.Minutes
 	}
 
+	model.Chapter = dto.Chapter
+
 	if dto.Notes != nil {
 		model.Notes = *dto.Notes
 	}
@@ -102,6 +111,7 @@ func (m ReadingSession) ToDTO() *ReadingSessionDTO {
 		ReadingPlan: m.ReadingPlan.ToDTO(),
 		PagesRead:   &m.PagesRead,
 		Minutes:     &m.Minutes,
+		Chapter:     m.Chapter,
 		Notes:       &m.Notes,
 		Date:        &m.Date,
 	}
@@ -141,11 +151,15 @@ func (dto ReadingPlanDTO) ToModel() *ReadingPlan {
 		model.User = dto.User.ToModel()
 	}
 
+	if dto.Version != nil {
+		model.Version = *dto.Version
+	}
+
 	return &model
 }
 
 func (m ReadingPlan) ToDTO() *ReadingPlanDTO {
-	return &ReadingPlanDTO{
+	dto := &ReadingPlanDTO{
 		ID:            &m.ID,
 		Status:        &m.Status,
 		StartDate:     m.StartDate,
@@ -153,9 +167,19 @@ func (m ReadingPlan) ToDTO() *ReadingPlanDTO {
 		Priority:      &m.Priority,
 		PagesPerDay:   &m.PagesPerDay,
 		MinutesPerDay: &m.MinutesPerDay,
-		Book:          m.Book.ToDTO(),
-		User:          m.User.ToDTO(),
+		ReviewPending: &m.ReviewPending,
+		Version:       &m.Version,
+	}
+
+	if m.Book != nil {
+		dto.Book = m.Book.ToDTO()
+	}
+
+	if m.User != nil {
+		dto.User = m.User.ToDTO()
 	}
+
+	return dto
 }
 
 func (m *ReadingSession) ValidateReadingSession(v *validator.Validator) {
@@ -165,6 +189,7 @@ func (m *ReadingSession) ValidateReadingSession(v *validator.Validator) {
 		v.Check(false, "Session", "either pagesRead or minutes must be provided")
 	}
 
+	v.Check(m.Chapter == nil || *m.Chapter > 0, "Chapter", "must be greater than zero")
 	v.Check(!m.Date.IsZero(), "Date", "must be provided")
 }
 
@@ -172,8 +197,8 @@ func (m *ReadingPlan) ValidateReadingPlan(v *validator.Validator) {
 	v.Check(m.Status > 0, "Status", "must be provided")
 	v.Check(m.Priority > 0, "Priority", "must be provided")
 
-	v.Check(m.Book.ID != 0, "Book", "must be provided")
-	v.Check(m.User.ID != 0, "User", "must be provided")
+	v.Check(m.Book != nil && m.Book.ID != 0, "Book", "must be provided")
+	v.Check(m.User != nil && m.User.ID != 0, "User", "must be provided")
 
 	if m.PagesPerDay == 0 && m.MinutesPerDay == 0 {
 		v.Check(false, "Plan", "either pagesPerDay or minutesPerDay must be provided")
@@ -188,6 +213,23 @@ func (m *ReadingPlan) ValidateReadingPlan(v *validator.Validator) {
 	}
 }
 
+// ValidatePace checks that the daily goal fits the format of book: audiobooks
+// have no pages, so their plans are paced in minutes.
+func (m *ReadingPlan) ValidatePace(v *validator.Validator, book *Book) {
+	if book.Format != BookFormatAudiobook {
+		return
+	}
+
+	v.Check(m.MinutesPerDay > 0, "MinutesPerDay", "must be provided for audiobooks")
+	v.Check(m.PagesPerDay == 0, "PagesPerDay", "must not be provided for audiobooks")
+}
+
+// NeedsReview reports whether the plan is completed while its book has no
+// review yet, so the reader can be prompted to write one.
+func (m *ReadingPlan) NeedsReview(reviewed bool) bool {
+	return m.Status == ReadingStatusCompleted && !reviewed
+}
+
 func (s ReadingStatus) String() string {
 	switch s {
 	case ReadingStatusPlanned:
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"math"
+	"time"
+)
+
+type Review struct {
+	ID         int64     `db:"id"`
+	BookID     int64     `db:"book_id"`
+	Rating     float64   `db:"rating"`
+	Body       string    `db:"body"`
+	Spoiler    bool      `db:"spoiler"`
+	ReviewedAt time.Time `db:"reviewed_at"`
+	BaseModel
+}
+
+type ReviewDTO struct {
+	ID         *int64     `json:"id"`
+	BookID     *int64     `json:"bookId"`
+	Rating     *float64   `json:"rating"`
+	Body       *string    `json:"body"`
+	Spoiler    *bool      `json:"spoiler"`
+	ReviewedAt *time.Time `json:"reviewedAt"`
+	CreatedAt  *time.Time `json:"createdAt"`
+	UpdatedAt  *time.Time `json:"updatedAt"`
+	Version    *int       `json:"version"`
+}
+
+func (m ReviewDTO) ToModel() *Review {
+	var model Review
+
+	if m.ID != nil {
+		model.ID = *m.ID
+	}
+
+	if m.Rating != nil {
+		model.Rating = *m.Rating
+	}
+
+	if m.Body != nil {
+		model.Body = *m.Body
+	}
+
+	if m.Spoiler != nil {
+		model.Spoiler = *m.Spoiler
+	}
+
+	if m.ReviewedAt != nil {
+		model.ReviewedAt = *m.ReviewedAt
+	}
+
+	if m.Version != nil {
+		model.Version = *m.Version
+	}
+
+	return &model
+}
+
+func (m Review) ToDTO() *ReviewDTO {
+	return &ReviewDTO{
+		ID:         &m.ID,
+		BookID:     &m.BookID,
+		Rating:     &m.Rating,
+		Body:       &m.Body,
+		Spoiler:    &m.Spoiler,
+		ReviewedAt: &m.ReviewedAt,
+		CreatedAt:  &m.CreatedAt,
+		UpdatedAt:  m.UpdatedAt,
+		Version:    &m.Version,
+	}
+}
+
+func (m *Review) ValidateReview(v *validator.Validator) {
+	v.Check(m.BookID != 0, "bookId", "must be provided")
+	v.Check(m.Rating >= 0.5 && m.Rating <= 5, "rating", "must be between 0.5 and 5")
+	v.Check(m.Rating*2 == math.Trunc(m.Rating*2), "rating", "must be a multiple of 0.5")
+	v.Check(len(m.Body) <= 100_000, "body", "must not be more than 100000 bytes long")
+	v.Check(!m.ReviewedAt.After(time.Now()), "reviewedAt", "must not be in the future")
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// maxScheduleDays bounds the schedule of a slow plan for a long book, about
+// three years.
+const maxScheduleDays = 1100
+
+// Schedule is the pages of a plan's book left to read, spread over days.
+type Schedule struct {
+	PagesRead         int            `json:"pagesRead"`
+	AlignedToChapters bool           `json:"alignedToChapters"`
+	Days              []*ScheduleDay `json:"days"`
+}
+
+// ScheduleDay is what to read on Date: the pages from FromPage to ToPage,
+// through Chapters. Minutes is estimated from the minutes of the chapters,
+// when all of them have some.
+type ScheduleDay struct {
+	Date     time.Time          `json:"date"`
+	FromPage int                `json:"fromPage"`
+	ToPage   int                `json:"toPage"`
+	Pages    int                `json:"pages"`
+	Minutes  *int               `json:"minutes"`
+	Chapters []*ScheduleChapter `json:"chapters"`
+}
+
+// ScheduleChapter is a chapter read on a day of a schedule. Finished tells
+// whether the day reaches its end.
+type ScheduleChapter struct {
+	Number   int    `json:"number"`
+	Title    string `json:"title"`
+	Finished bool   `json:"finished"`
+}
+
+// BuildSchedule spreads the pages of a book of pages pages left after
+// pagesRead over the days from from on: evenly up to the target date of
+// plan, when it has one that is not past, or else pagesPerDay a day. With
+// align, days end where chapters do whenever one ends within half a day's
+// reading of the day's target; chapters longer than that still end days
+// mid-chapter. Days a schedule is ahead of its pace are left free. The
+// chapters must be numbered by NumberChapters.
+func BuildSchedule(
+	plan *ReadingPlan,
+	pages int,
+	chapters []*Chapter,
+	pagesRead int,
+	from time.Time,
+	align bool,
+) *Schedule {
+	pagesRead = min(max(pagesRead, 0), pages)
+	schedule := &Schedule{
+		PagesRead:         pagesRead,
+		AlignedToChapters: align && len(chapters) > 0,
+		Days:              []*ScheduleDay{},
+	}
+
+	remaining := pages - pagesRead
+	days, daily := 0, plan.PagesPerDay
+	if plan.TargetDate != nil {
+		target := dateOf(*plan.TargetDate)
+		if !target.Before(from) {
+			days = int(target.Sub(from).Hours()/24) + 1
+			daily = (remaining + days - 1) / days
+		}
+	}
+
+	// ideal is how far the reading should be after day k.
+	ideal := func(k int) int {
+		if days > 0 {
+			return pagesRead + int(math.Ceil(float64(remaining)*float64(k)/float64(days)))
+		}
+		return pagesRead + k*daily
+	}
+
+	if daily <= 0 {
+		return schedule
+	}
+
+	end := pagesRead
+	for k := 1; end < pages && k <= maxScheduleDays; k++ {
+		target := min(ideal(k), pages)
+		if target <= end {
+			continue
+		}
+
+		if schedule.AlignedToChapters {
+			target = alignToChapter(chapters, end, target, daily)
+		}
+
+		schedule.Days = append(schedule.Days, scheduleDay(from.AddDate(0, 0, k-1), end+1, target, chapters))
+		end = target
+	}
+
+	return schedule
+}
+
+// alignToChapter moves target, the last page of a day starting after end, to
+// the nearest end of a chapter within half of daily of it, if any.
+func alignToChapter(chapters []*Chapter, end, target, daily int) int {
+	slack := max(daily/2, 1)
+	best, distance := target, slack+1
+	for _, chapter := range chapters {
+		if chapter.EndPage <= end || chapter.EndPage < target-slack {
+			continue
+		}
+		if chapter.EndPage > target+slack {
+			break
+		}
+		if d := abs(chapter.EndPage - target); d < distance || (d == distance && chapter.EndPage > best) {
+			best, distance = chapter.EndPage, d
+		}
+	}
+	return best
+}
+
+func scheduleDay(date time.Time, fromPage, toPage int, chapters []*Chapter) *ScheduleDay {
+	day := &ScheduleDay{
+		Date:     date,
+		FromPage: fromPage,
+		ToPage:   toPage,
+		Pages:    toPage - fromPage + 1,
+		Chapters: []*ScheduleChapter{},
+	}
+
+	minutes, estimated := 0.0, true
+	for _, chapter := range chapters {
+		if chapter.EndPage < fromPage || chapter.StartPage > toPage {
+			continue
+		}
+
+		day.Chapters = append(day.Chapters, &ScheduleChapter{
+			Number:   chapter.Position,
+			Title:    chapter.Title,
+			Finished: chapter.EndPage <= toPage,
+		})
+
+		if chapter.Minutes == nil {
+			estimated = false
+			continue
+		}
+		read := min(chapter.EndPage, toPage) - max(chapter.StartPage, fromPage) + 1
+		minutes += float64(*chapter.Minutes) * float64(read) / float64(chapter.EndPage-chapter.StartPage+1)
+	}
+
+	if estimated && len(day.Chapters) > 0 {
+		rounded := max(int(math.Round(minutes)), 1)
+		day.Minutes = &rounded
+	}
+
+	return day
+}
+
+// dateOf returns the day of t as midnight UTC, the way dates are compared.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
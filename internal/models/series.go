@@ -0,0 +1,170 @@
+package models
+
+import (
+	"bookwise/utils/validator"
+	"math"
+	"strings"
+)
+
+type Series struct {
+	ID         int64  `db:"id"`
+	Name       string `db:"name"`
+	TotalCount *int   `db:"total_count"`
+	BaseModel
+	Entries []SeriesEntry
+}
+
+// SeriesEntry is one of the user's books within a series.
+type SeriesEntry struct {
+	BookID   int64
+	Title    string
+	Position float64
+	Read     bool
+}
+
+type SeriesDTO struct {
+	ID         *int64            `json:"id"`
+	Name       *string           `json:"name"`
+	TotalCount *int              `json:"totalCount"`
+	Version    *int              `json:"version"`
+	Books      []*SeriesEntryDTO `json:"books,omitempty"`
+	Missing    []int             `json:"missing,omitempty"`
+	Unread     []*SeriesEntryDTO `json:"unread,omitempty"`
+	Next       *SeriesEntryDTO   `json:"next,omitempty"`
+}
+
+type SeriesEntryDTO struct {
+	BookID   int64   `json:"bookId"`
+	Title    string  `json:"title"`
+	Position float64 `json:"position"`
+	Read     bool    `json:"read"`
+}
+
+// BookSeriesDTO is the series information carried by a book.
+type BookSeriesDTO struct {
+	ID         *int64   `json:"id"`
+	Name       *string  `json:"name"`
+	Position   *float64 `json:"position"`
+	TotalCount *int     `json:"totalCount"`
+}
+
+func (m SeriesDTO) ToModel() *Series {
+	var model Series
+
+	if m.ID != nil {
+		model.ID = *m.ID
+	}
+
+	if m.Name != nil {
+		model.Name = strings.TrimSpace(*m.Name)
+	}
+
+	model.TotalCount = m.TotalCount
+
+	if m.Version != nil {
+		model.Version = *m.Version
+	}
+
+	return &model
+}
+
+func (m Series) ToDTO() *SeriesDTO {
+	dto := &SeriesDTO{
+		ID:         &m.ID,
+		Name:       &m.Name,
+		TotalCount: m.TotalCount,
+		Version:    &m.Version,
+	}
+
+	if m.Entries == nil {
+		return dto
+	}
+
+	dto.Books = []*SeriesEntryDTO{}
+	dto.Unread = []*SeriesEntryDTO{}
+	dto.Missing = m.MissingPositions()
+
+	for _, entry := range m.Entries {
+		dto.Books = append(dto.Books, entry.ToDTO())
+		if !entry.Read {
+			dto.Unread = append(dto.Unread, entry.ToDTO())
+		}
+	}
+
+	if next := m.Next(); next != nil {
+		dto.Next = next.ToDTO()
+	}
+
+	return dto
+}
+
+func (m SeriesEntry) ToDTO() *SeriesEntryDTO {
+	return &SeriesEntryDTO{
+		BookID:   m.BookID,
+		Title:    m.Title,
+		Position: m.Position,
+		Read:     m.Read,
+	}
+}
+
+// MissingPositions lists the whole-number positions between 1 and the
+// series total (or the highest owned position when the total is unknown)
+// that the user has no book for. Fractional entries such as novellas at 2.5
+// never count as missing.
+func (m *Series) MissingPositions() []int {
+	owned := map[int]bool{}
+	last := 0
+
+	for _, entry := range m.Entries {
+		if entry.Position == math.Trunc(entry.Position) {
+			owned[int(entry.Position)] = true
+		}
+		last = max(last, int(entry.Position))
+	}
+
+	if m.TotalCount != nil {
+		last = *m.TotalCount
+	}
+
+	missing := []int{}
+	for position := 1; position <= last; position++ {
+		if !owned[position] {
+			missing = append(missing, position)
+		}
+	}
+
+	return missing
+}
+
+// Next returns the first owned book in reading order that has not been read.
+func (m *Series) Next() *SeriesEntry {
+	for i := range m.Entries {
+		if !m.Entries[i].Read {
+			return &m.Entries[i]
+		}
+	}
+	return nil
+}
+
+func (m *Series) ValidateSeries(v *validator.Validator) {
+	v.Check(m.Name != "", "name", "must be provided")
+	v.Check(len(m.Name) <= 500, "name", "must not be more than 500 bytes long")
+
+	if m.TotalCount != nil {
+		v.Check(*m.TotalCount > 0, "totalCount", "must be greater than zero")
+	}
+}
+
+func ValidateSeriesPosition(v *validator.Validator, position *float64) {
+	if position == nil {
+		return
+	}
+
+	v.Check(*position > 0, "series", "position must be greater than zero")
+	v.Check(*position < 100000, "series", "position must be less than 100000")
+	v.Check(
+		math.Abs(math.Round(*position*100)-*position*100) < 1e-6,
+		"series",
+		"position must have at most two decimal places",
+	)
+}
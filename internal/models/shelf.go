@@ -0,0 +1,54 @@
+package models
+
+import "bookwise/utils/validator"
+
+type Shelf struct {
+	ID        int64  `db:"id" dto:"ID"`
+	Name      string `db:"name" dto:"Name"`
+	BookCount int
+	BaseModel
+}
+
+type ShelfDTO struct {
+	ID        *int64  `json:"id" dto:"ID"`
+	Name      *string `json:"name" dto:"Name"`
+	BookCount *int    `json:"bookCount,omitempty"`
+	Version   *int    `json:"version" dto:"Version"`
+}
+
+func (m ShelfDTO) ToModel() *Shelf {
+	var model Shelf
+
+	if m.ID != nil {
+		model.ID = *m.ID
+	}
+
+	if m.Name != nil {
+		model.Name = *m.Name
+	}
+
+	if m.Version != nil {
+		model.Version = *m.Version
+	}
+
+	return &model
+}
+
+func (m Shelf) ToDTO() *ShelfDTO {
+	return &ShelfDTO{
+		ID:        &m.ID,
+		Name:      &m.Name,
+		BookCount: &m.BookCount,
+		Version:   &m.Version,
+	}
+}
+
+func (m *Shelf) ValidateShelf(v *validator.Validator) {
+	v.Check(m.Name != "", "name", "must be provided")
+	v.Check(len(m.Name) <= 100, "name", "must not be more than 100 bytes long")
+}
+
+func ValidateBookIDs(v *validator.Validator, ids []int64) {
+	v.Check(len(ids) > 0, "bookIds", "must contain at least one book")
+	v.Check(len(ids) <= 500, "bookIds", "must not contain more than 500 books")
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+type TrashItemType string
+
+const (
+	TrashItemBook TrashItemType = "book"
+	TrashItemPlan TrashItemType = "plan"
+)
+
+// TrashItem is a soft-deleted book or reading plan. Plans are titled after
+// their book.
+type TrashItem struct {
+	Type      TrashItemType `db:"type"`
+	ID        int64         `db:"id"`
+	Title     string        `db:"title"`
+	BookID    int64         `db:"book_id"`
+	DeletedAt time.Time     `db:"deleted_at"`
+}
+
+type TrashItemDTO struct {
+	Type      TrashItemType `json:"type"`
+	ID        int64         `json:"id"`
+	Title     string        `json:"title"`
+	BookID    int64         `json:"bookId"`
+	DeletedAt time.Time     `json:"deletedAt"`
+	PurgeAt   time.Time     `json:"purgeAt"`
+}
+
+// ToDTO describes the item, including when it will be purged given the
+// configured retention.
+func (m TrashItem) ToDTO(retention time.Duration) *TrashItemDTO {
+	return &TrashItemDTO{
+		Type:      m.Type,
+		ID:        m.ID,
+		Title:     m.Title,
+		BookID:    m.BookID,
+		DeletedAt: m.DeletedAt,
+		PurgeAt:   m.DeletedAt.Add(retention),
+	}
+}
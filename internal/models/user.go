@@ -2,41 +2,46 @@ package models
 
 import (
 	"bookwise/utils/validator"
-	"errors"
-
-	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 var AnonymousUser = &User{}
 
+type ActivationChannel string
+
+const (
+	ActivationChannelEmail ActivationChannel = "email"
+	ActivationChannelSMS   ActivationChannel = "sms"
+)
+
 type User struct {
-	ID        int64  `db:"id" dto:"ID"`
-	Name      string `db:"name" dto:"Name"`
-	Email     string `db:"email" dto:"Email"`
-	Phone     string `db:"phone" dto:"Phone"`
-	Cod       int    `db:"cod"`
-	Password  password
-	Activated bool `db:"activated"`
+	ID            int64             `db:"id" dto:"ID"`
+	Name          string            `db:"name" dto:"Name"`
+	Email         string            `db:"email" dto:"Email"`
+	Phone         string            `db:"phone" dto:"Phone"`
+	PhoneVerified bool              `db:"phone_verified" dto:"PhoneVerified"`
+	Cod           int               `db:"cod"`
+	CodChannel    ActivationChannel `db:"cod_channel"`
+	CodSentAt     *time.Time        `db:"cod_sent_at"`
+	Password      password
+	Activated     bool `db:"activated"`
 	BaseModel
 }
 
 type UserDTO struct {
-	ID    int64  `json:"user_id" dto:"ID"`
-	Name  string `json:"name" dto:"Name"`
-	Email string `json:"email" dto:"Email"`
-	Phone string `json:"phone" dto:"Phone"`
+	ID            int64  `json:"user_id" dto:"ID"`
+	Name          string `json:"name" dto:"Name"`
+	Email         string `json:"email" dto:"Email"`
+	Phone         string `json:"phone" dto:"Phone"`
+	PhoneVerified bool   `json:"phone_verified" dto:"PhoneVerified"`
 }
 
 type UserSaveDTO struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Phone    string `json:"phone"`
-	Password string `json:"password"`
-}
-
-type password struct {
-	Plaintext *string
-	Hash      []byte `db:"password_hash"`
+	Name              string            `json:"name"`
+	Email             string            `json:"email"`
+	Phone             string            `json:"phone"`
+	Password          string            `json:"password"`
+	ActivationChannel ActivationChannel `json:"activation_channel"`
 }
 
 func (u *User) IsAnonymous() bool {
@@ -45,10 +50,11 @@ func (u *User) IsAnonymous() bool {
 
 func (u *User) ToDTO() *UserDTO {
 	return &UserDTO{
-		ID:    u.ID,
-		Name:  u.Name,
-		Email: u.Email,
-		Phone: u.Phone,
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Phone:         u.Phone,
+		PhoneVerified: u.PhoneVerified,
 	}
 }
 
@@ -63,9 +69,14 @@ func (u *UserDTO) ToModel() *User {
 
 func (u *UserSaveDTO) ToModel() (*User, error) {
 	user := &User{
-		Name:  u.Name,
-		Email: u.Email,
-		Phone: u.Phone,
+		Name:       u.Name,
+		Email:      u.Email,
+		Phone:      u.Phone,
+		CodChannel: u.ActivationChannel,
+	}
+
+	if user.CodChannel == "" {
+		user.CodChannel = ActivationChannelEmail
 	}
 
 	err := user.Password.Set(u.Password)
@@ -76,45 +87,31 @@ func (u *UserSaveDTO) ToModel() (*User, error) {
 	return user, nil
 }
 
-func (p *password) Set(plaintextPassword string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
-	if err != nil {
-		return err
-	}
-
-	p.Plaintext = &plaintextPassword
-	p.Hash = hash
-	return nil
-}
-
-func (p *password) Matches(plaintextPassword string) (bool, error) {
-	err := bcrypt.CompareHashAndPassword(p.Hash, []byte(plaintextPassword))
-	if err != nil {
-		switch {
-		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			return false, nil
-		default:
-			return false, err
-		}
-	}
-	return true, nil
-}
-
 func ValidateEmail(v *validator.Validator, email string) {
 	v.Check(email != "", "email", "must be provided")
 	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
 }
 
+func ValidateActivationChannel(v *validator.Validator, channel ActivationChannel) {
+	v.Check(
+		validator.In(string(channel), string(ActivationChannelEmail), string(ActivationChannelSMS)),
+		"activation_channel",
+		"must be email or sms",
+	)
+}
+
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
 	v.Check(password != "", "password", "must be provided")
 	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
-	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+	v.Check(len(password) <= 1024, "password", "must not be more than 1024 bytes long")
 }
 
 func (m *User) ValidateUser(v *validator.Validator) {
 	v.Check(m.Name != "", "name", "must be provided")
 	v.Check(len(m.Name) <= 500, "name", "must not be more than 500 bytes long")
 	v.Check(m.Phone != "", "phone", "must be provided")
+	v.Check(validator.Matches(m.Phone, validator.PhoneRX), "phone", "must be a valid phone number")
+	ValidateActivationChannel(v, m.CodChannel)
 
 	ValidateEmail(v, m.Email)
 
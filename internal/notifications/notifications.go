@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"bookwise/internal/jsonlog"
+)
+
+type SMSSender interface {
+	SendSMS(to, message string) error
+}
+
+type Mailer interface {
+	SendMail(to, subject, body string) error
+}
+
+type logSMSSender struct {
+	logger jsonlog.Logger
+}
+
+// NewLogSMSSender returns an SMSSender that only writes messages to the log.
+// It is meant for development until a real SMS provider is configured.
+func NewLogSMSSender(logger jsonlog.Logger) *logSMSSender {
+	return &logSMSSender{
+		logger: logger,
+	}
+}
+
+func (s *logSMSSender) SendSMS(to, message string) error {
+	s.logger.PrintInfo("sms sent", map[string]string{
+		"to":      to,
+		"message": message,
+	})
+	return nil
+}
+
+type logMailer struct {
+	logger jsonlog.Logger
+}
+
+// NewLogMailer returns a Mailer that only writes messages to the log.
+func NewLogMailer(logger jsonlog.Logger) *logMailer {
+	return &logMailer{
+		logger: logger,
+	}
+}
+
+func (m *logMailer) SendMail(to, subject, body string) error {
+	m.logger.PrintInfo("mail sent", map[string]string{
+		"to":      to,
+		"subject": subject,
+		"body":    body,
+	})
+	return nil
+}
@@ -0,0 +1,461 @@
+// Package opds writes catalog feeds for e-reader apps, as the Atom feeds of
+// OPDS 1.2 and the JSON documents of OPDS 2.0.
+package opds
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+type Version int
+
+const (
+	Version1 Version = 1
+	Version2 Version = 2
+)
+
+// Kind tells navigation feeds, which list other feeds, from acquisition
+// feeds, which list publications.
+type Kind string
+
+const (
+	KindNavigation  Kind = "navigation"
+	KindAcquisition Kind = "acquisition"
+)
+
+// Link relations of feeds and publications.
+const (
+	RelSelf        = "self"
+	RelStart       = "start"
+	RelUp          = "up"
+	RelNext        = "next"
+	RelPrevious    = "previous"
+	RelFirst       = "first"
+	RelLast        = "last"
+	RelSubsection  = "subsection"
+	RelAcquisition = "http://opds-spec.org/acquisition"
+	RelImage       = "http://opds-spec.org/image"
+	RelThumbnail   = "http://opds-spec.org/image/thumbnail"
+)
+
+const jsonContentType = "application/opds+json"
+
+// ContentType returns the media type of a feed of the kind in version.
+func ContentType(version Version, kind Kind) string {
+	if version == Version2 {
+		return jsonContentType
+	}
+	return "application/atom+xml;profile=opds-catalog;kind=" + string(kind)
+}
+
+// Link points at another resource. Types left empty are filled in for the
+// feeds a feed links to.
+type Link struct {
+	Rel   string
+	Href  string
+	Type  string
+	Title string
+}
+
+// Feed is a page of a catalog. Paged feeds have a non-zero ItemsPerPage.
+type Feed struct {
+	ID           string
+	Title        string
+	Kind         Kind
+	Updated      time.Time
+	Links        []Link
+	Navigation   []Navigation
+	Publications []Publication
+	TotalResults int
+	ItemsPerPage int
+	CurrentPage  int
+}
+
+// Navigation is an entry of a navigation feed, leading to a feed of Kind.
+type Navigation struct {
+	ID      string
+	Title   string
+	Summary string
+	Href    string
+	Kind    Kind
+	Count   int
+	Updated time.Time
+}
+
+// Publication is a book of an acquisition feed. Image and Thumbnail are the
+// hrefs of its cover, empty when it has none.
+type Publication struct {
+	ID             string
+	Title          string
+	Authors        []string
+	Summary        string
+	Language       string
+	Publisher      string
+	Published      *time.Time
+	ISBN           string
+	Series         string
+	SeriesPosition *float64
+	Updated        time.Time
+	Image          string
+	Thumbnail      string
+	Acquisitions   []Acquisition
+}
+
+// Acquisition is a file a publication can be downloaded as.
+type Acquisition struct {
+	Href   string
+	Type   string
+	Title  string
+	Length int64
+}
+
+// Write writes feed in the format of version.
+func (f *Feed) Write(w io.Writer, version Version) error {
+	if version == Version2 {
+		return f.writeJSON(w)
+	}
+	return f.writeAtom(w)
+}
+
+// linkType fills in the type of the feeds of the catalog a link points at.
+func (f *Feed) linkType(version Version, link Link) string {
+	if link.Type != "" {
+		return link.Type
+	}
+
+	switch link.Rel {
+	case RelSelf, RelNext, RelPrevious, RelFirst, RelLast:
+		return ContentType(version, f.Kind)
+	case RelStart, RelUp:
+		return ContentType(version, KindNavigation)
+	}
+	return ""
+}
+
+type atomFeed struct {
+	XMLName      xml.Name    `xml:"feed"`
+	Namespace    string      `xml:"xmlns,attr"`
+	DC           string      `xml:"xmlns:dc,attr"`
+	OPDS         string      `xml:"xmlns:opds,attr"`
+	OpenSearch   string      `xml:"xmlns:opensearch,attr"`
+	Thread       string      `xml:"xmlns:thr,attr"`
+	ID           string      `xml:"id"`
+	Title        string      `xml:"title"`
+	Updated      string      `xml:"updated"`
+	Author       atomAuthor  `xml:"author"`
+	TotalResults int         `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage int         `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   int         `xml:"opensearch:startIndex,omitempty"`
+	Links        []atomLink  `xml:"link"`
+	Entries      []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+	Type   string `xml:"type,attr,omitempty"`
+	Title  string `xml:"title,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+	Count  int    `xml:"thr:count,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title      string       `xml:"title"`
+	ID         string       `xml:"id"`
+	Updated    string       `xml:"updated"`
+	Authors    []atomAuthor `xml:"author"`
+	Language   string       `xml:"dc:language,omitempty"`
+	Publisher  string       `xml:"dc:publisher,omitempty"`
+	Issued     string       `xml:"dc:issued,omitempty"`
+	Identifier string       `xml:"dc:identifier,omitempty"`
+	Summary    *atomText    `xml:"summary"`
+	Content    *atomText    `xml:"content"`
+	Links      []atomLink   `xml:"link"`
+}
+
+func (f *Feed) writeAtom(w io.Writer) error {
+	feed := atomFeed{
+		Namespace:    "http://www.w3.org/2005/Atom",
+		DC:           "http://purl.org/dc/terms/",
+		OPDS:         "http://opds-spec.org/2010/catalog",
+		OpenSearch:   "http://a9.com/-/spec/opensearch/1.1/",
+		Thread:       "http://purl.org/syndication/thread/1.0",
+		ID:           f.ID,
+		Title:        f.Title,
+		Updated:      f.Updated.UTC().Format(time.RFC3339),
+		Author:       atomAuthor{Name: "Bookwise"},
+		TotalResults: f.TotalResults,
+		ItemsPerPage: f.ItemsPerPage,
+		Entries:      []atomEntry{},
+	}
+
+	if f.ItemsPerPage > 0 {
+		feed.StartIndex = (max(f.CurrentPage, 1)-1)*f.ItemsPerPage + 1
+	}
+
+	for _, link := range f.Links {
+		feed.Links = append(feed.Links, atomLink{
+			Rel:   link.Rel,
+			Href:  link.Href,
+			Type:  f.linkType(Version1, link),
+			Title: link.Title,
+		})
+	}
+
+	for _, nav := range f.Navigation {
+		entry := atomEntry{
+			Title:   nav.Title,
+			ID:      nav.ID,
+			Updated: nav.Updated.UTC().Format(time.RFC3339),
+			Links: []atomLink{{
+				Rel:   RelSubsection,
+				Href:  nav.Href,
+				Type:  ContentType(Version1, nav.Kind),
+				Count: nav.Count,
+			}},
+		}
+		if nav.Summary != "" {
+			entry.Content = &atomText{Type: "text", Text: nav.Summary}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	for _, pub := range f.Publications {
+		feed.Entries = append(feed.Entries, pub.atomEntry())
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	if err := encoder.Encode(feed); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func (p Publication) atomEntry() atomEntry {
+	entry := atomEntry{
+		Title:     p.Title,
+		ID:        p.ID,
+		Updated:   p.Updated.UTC().Format(time.RFC3339),
+		Language:  p.Language,
+		Publisher: p.Publisher,
+	}
+
+	for _, name := range p.Authors {
+		entry.Authors = append(entry.Authors, atomAuthor{Name: name})
+	}
+
+	if p.Published != nil {
+		entry.Issued = p.Published.Format("2006-01-02")
+	}
+
+	if p.ISBN != "" {
+		entry.Identifier = "urn:isbn:" + p.ISBN
+	}
+
+	// OPDS 1.2 has no place for series, so they lead the summary the way
+	// other catalogs show them.
+	summary := p.Summary
+	if p.Series != "" {
+		series := p.Series
+		if p.SeriesPosition != nil {
+			series += " #" + strconv.FormatFloat(*p.SeriesPosition, 'f', -1, 64)
+		}
+		if summary != "" {
+			series += "\n\n"
+		}
+		summary = series + summary
+	}
+	if summary != "" {
+		entry.Summary = &atomText{Type: "text", Text: summary}
+	}
+
+	if p.Image != "" {
+		entry.Links = append(entry.Links, atomLink{Rel: RelImage, Href: p.Image})
+	}
+	if p.Thumbnail != "" {
+		entry.Links = append(entry.Links, atomLink{Rel: RelThumbnail, Href: p.Thumbnail, Type: "image/jpeg"})
+	}
+
+	for _, acquisition := range p.Acquisitions {
+		entry.Links = append(entry.Links, atomLink{
+			Rel:    RelAcquisition,
+			Href:   acquisition.Href,
+			Type:   acquisition.Type,
+			Title:  acquisition.Title,
+			Length: acquisition.Length,
+		})
+	}
+
+	return entry
+}
+
+type jsonLink struct {
+	Rel        string          `json:"rel,omitempty"`
+	Href       string          `json:"href"`
+	Type       string          `json:"type,omitempty"`
+	Title      string          `json:"title,omitempty"`
+	Properties *jsonProperties `json:"properties,omitempty"`
+}
+
+type jsonProperties struct {
+	NumberOfItems int   `json:"numberOfItems,omitempty"`
+	Length        int64 `json:"length,omitempty"`
+}
+
+type jsonFeedMetadata struct {
+	Title         string `json:"title"`
+	Identifier    string `json:"identifier,omitempty"`
+	Modified      string `json:"modified,omitempty"`
+	NumberOfItems int    `json:"numberOfItems,omitempty"`
+	ItemsPerPage  int    `json:"itemsPerPage,omitempty"`
+	CurrentPage   int    `json:"currentPage,omitempty"`
+}
+
+type jsonContributor struct {
+	Name string `json:"name"`
+}
+
+type jsonSeries struct {
+	Name     string   `json:"name"`
+	Position *float64 `json:"position,omitempty"`
+}
+
+type jsonPublicationMetadata struct {
+	Type        string            `json:"@type"`
+	Identifier  string            `json:"identifier,omitempty"`
+	Title       string            `json:"title"`
+	Authors     []jsonContributor `json:"author,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Publisher   string            `json:"publisher,omitempty"`
+	Published   string            `json:"published,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Modified    string            `json:"modified,omitempty"`
+	BelongsTo   map[string]any    `json:"belongsTo,omitempty"`
+}
+
+type jsonPublication struct {
+	Metadata jsonPublicationMetadata `json:"metadata"`
+	Links    []jsonLink              `json:"links"`
+	Images   []jsonLink              `json:"images,omitempty"`
+}
+
+func (f *Feed) writeJSON(w io.Writer) error {
+	doc := map[string]any{
+		"metadata": jsonFeedMetadata{
+			Title:         f.Title,
+			Identifier:    f.ID,
+			Modified:      f.Updated.UTC().Format(time.RFC3339),
+			NumberOfItems: f.TotalResults,
+			ItemsPerPage:  f.ItemsPerPage,
+			CurrentPage:   f.CurrentPage,
+		},
+	}
+
+	links := []jsonLink{}
+	for _, link := range f.Links {
+		links = append(links, jsonLink{
+			Rel:   link.Rel,
+			Href:  link.Href,
+			Type:  f.linkType(Version2, link),
+			Title: link.Title,
+		})
+	}
+	doc["links"] = links
+
+	// Every feed needs a collection, even an empty one.
+	if f.Kind == KindNavigation {
+		navigation := []jsonLink{}
+		for _, nav := range f.Navigation {
+			link := jsonLink{
+				Rel:   RelSubsection,
+				Href:  nav.Href,
+				Type:  jsonContentType,
+				Title: nav.Title,
+			}
+			if nav.Count > 0 {
+				link.Properties = &jsonProperties{NumberOfItems: nav.Count}
+			}
+			navigation = append(navigation, link)
+		}
+		doc["navigation"] = navigation
+	} else {
+		publications := []jsonPublication{}
+		for _, pub := range f.Publications {
+			publications = append(publications, pub.jsonPublication())
+		}
+		doc["publications"] = publications
+	}
+
+	return json.NewEncoder(w).Encode(doc)
+}
+
+func (p Publication) jsonPublication() jsonPublication {
+	pub := jsonPublication{
+		Metadata: jsonPublicationMetadata{
+			Type:        "http://schema.org/Book",
+			Identifier:  p.ID,
+			Title:       p.Title,
+			Language:    p.Language,
+			Publisher:   p.Publisher,
+			Description: p.Summary,
+			Modified:    p.Updated.UTC().Format(time.RFC3339),
+		},
+		Links: []jsonLink{},
+	}
+
+	if p.ISBN != "" {
+		pub.Metadata.Identifier = "urn:isbn:" + p.ISBN
+	}
+
+	for _, name := range p.Authors {
+		pub.Metadata.Authors = append(pub.Metadata.Authors, jsonContributor{Name: name})
+	}
+
+	if p.Published != nil {
+		pub.Metadata.Published = p.Published.Format("2006-01-02")
+	}
+
+	if p.Series != "" {
+		pub.Metadata.BelongsTo = map[string]any{
+			"series": []jsonSeries{{Name: p.Series, Position: p.SeriesPosition}},
+		}
+	}
+
+	for _, acquisition := range p.Acquisitions {
+		link := jsonLink{
+			Rel:   RelAcquisition,
+			Href:  acquisition.Href,
+			Type:  acquisition.Type,
+			Title: acquisition.Title,
+		}
+		if acquisition.Length > 0 {
+			link.Properties = &jsonProperties{Length: acquisition.Length}
+		}
+		pub.Links = append(pub.Links, link)
+	}
+
+	if p.Image != "" {
+		pub.Images = append(pub.Images, jsonLink{Href: p.Image})
+	}
+	if p.Thumbnail != "" {
+		pub.Images = append(pub.Images, jsonLink{Href: p.Thumbnail, Type: "image/jpeg"})
+	}
+
+	return pub
+}
@@ -0,0 +1,480 @@
+// Package qrcode encodes short texts such as URLs as QR codes. It writes
+// byte mode symbols of versions 1 to 10 at error correction level M, which
+// holds up to 213 bytes and survives about 15% of the symbol being damaged.
+package qrcode
+
+import (
+	"errors"
+)
+
+var ErrTooLong = errors.New("text too long for a QR code")
+
+// maxVersion is the largest version encoded.
+const maxVersion = 10
+
+// Penalty weights of the mask evaluation rules.
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// blockLayout is how the codewords of a version are split into blocks at
+// level M: every block has ecLength error correction codewords, and the
+// blocks carry the data codewords listed.
+type blockLayout struct {
+	ecLength int
+	data     []int
+}
+
+var layouts = [maxVersion + 1]blockLayout{
+	1:  {10, []int{16}},
+	2:  {16, []int{28}},
+	3:  {26, []int{44}},
+	4:  {18, []int{32, 32}},
+	5:  {24, []int{43, 43}},
+	6:  {16, []int{27, 27, 27, 27}},
+	7:  {18, []int{31, 31, 31, 31}},
+	8:  {22, []int{38, 38, 39, 39}},
+	9:  {22, []int{36, 36, 36, 37, 37}},
+	10: {26, []int{43, 43, 43, 43, 44}},
+}
+
+// alignments are the centers of the alignment patterns of each version, on
+// both axes.
+var alignments = [maxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// Code is a QR code symbol, Size modules wide and high, without the quiet
+// zone readers need around it.
+type Code struct {
+	Size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// Dark tells whether the module at column x and row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode encodes text in the smallest version it fits in.
+func Encode(text string) (*Code, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v <= maxVersion; v++ {
+		if 4+countBits(v)+8*len(data) <= 8*dataCapacity(v) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	size := 17 + 4*version
+	c := &Code{
+		Size:     size,
+		modules:  grid(size),
+		function: grid(size),
+	}
+
+	c.drawFunctionPatterns(version)
+	c.drawCodewords(codewords(data, version))
+
+	best, lowest := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if penalty := c.penalty(); lowest < 0 || penalty < lowest {
+			best, lowest = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+
+	c.applyMask(best)
+	c.drawFormatBits(best)
+	return c, nil
+}
+
+func grid(size int) [][]bool {
+	rows := make([][]bool, size)
+	for y := range rows {
+		rows[y] = make([]bool, size)
+	}
+	return rows
+}
+
+// countBits is the length of the character count of byte mode.
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+func dataCapacity(version int) int {
+	total := 0
+	for _, n := range layouts[version].data {
+		total += n
+	}
+	return total
+}
+
+// codewords returns the data and error correction codewords of data,
+// interleaved across the blocks of version.
+func codewords(data []byte, version int) []byte {
+	capacity := dataCapacity(version)
+
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	bits.append(0, min(4, 8*capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < 8*capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+	padded := bits.bytes()
+
+	layout := layouts[version]
+	divisor := rsDivisor(layout.ecLength)
+
+	blocks := make([][]byte, len(layout.data))
+	ecc := make([][]byte, len(layout.data))
+	longest := 0
+	for i, n := range layout.data {
+		blocks[i], padded = padded[:n], padded[n:]
+		ecc[i] = rsRemainder(blocks[i], divisor)
+		longest = max(longest, n)
+	}
+
+	result := []byte{}
+	for i := range longest {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := range layout.ecLength {
+		for _, block := range ecc {
+			result = append(result, block[i])
+		}
+	}
+
+	return result
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	result := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			result[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return result
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns(version int) {
+	for i := range c.Size {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+
+	c.drawFinder(3, 3)
+	c.drawFinder(c.Size-4, 3)
+	c.drawFinder(3, c.Size-4)
+
+	centers := alignments[version]
+	last := len(centers) - 1
+	for i, x := range centers {
+		for j, y := range centers {
+			// The corners with finder patterns have none.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve the format areas, written once the mask is chosen.
+	c.drawFormatBits(0)
+	c.drawVersion(version)
+}
+
+// drawFinder draws a finder pattern centered on x and y, with the separator
+// around it.
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			if x+dx < 0 || x+dx >= c.Size || y+dy < 0 || y+dy >= c.Size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			c.set(x+dx, y+dy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+// drawFormatBits writes both copies of the level and mask, protected by a
+// BCH code.
+func (c *Code) drawFormatBits(mask int) {
+	// Level M is 00.
+	data := mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := range 8 {
+		c.set(c.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.Size-15+i, bit(i))
+	}
+	c.set(8, c.Size-8, true)
+}
+
+// drawVersion writes both copies of the version, which versions from 7 on
+// carry.
+func (c *Code) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+
+	rem := version
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	bits := version<<12 | rem
+
+	for i := range 18 {
+		dark := bits>>i&1 == 1
+		a, b := c.Size-11+i%3, i/3
+		c.set(a, b, dark)
+		c.set(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag of two module wide
+// columns, from the bottom right corner, around the function patterns.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// The vertical timing pattern takes a whole column.
+			right = 5
+		}
+		for vert := range c.Size {
+			for j := range 2 {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if c.function[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.modules[y][x] = data[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules mask selects; applying it twice undoes
+// it.
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !c.function[y][x] {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// finderLike is the dark and light sequence of a finder pattern next to four
+// light modules, which the mask should avoid imitating.
+var finderLike = []bool{true, false, true, true, true, false, true, false, false, false, false}
+
+// penalty scores the symbol by the rules masks are chosen with: runs of one
+// color, 2x2 blocks, finder-like sequences and an unbalanced dark ratio all
+// make it harder to read.
+func (c *Code) penalty() int {
+	result := 0
+
+	lines := make([][]bool, 0, 2*c.Size)
+	for y := range c.Size {
+		lines = append(lines, c.modules[y])
+	}
+	for x := range c.Size {
+		column := make([]bool, c.Size)
+		for y := range c.Size {
+			column[y] = c.modules[y][x]
+		}
+		lines = append(lines, column)
+	}
+
+	for _, line := range lines {
+		run := 1
+		for i := 1; i <= len(line); i++ {
+			if i < len(line) && line[i] == line[i-1] {
+				run++
+				continue
+			}
+			if run >= 5 {
+				result += penaltyRun + run - 5
+			}
+			run = 1
+		}
+
+		for i := 0; i+len(finderLike) <= len(line); i++ {
+			forward, backward := true, true
+			for j, dark := range finderLike {
+				forward = forward && line[i+j] == dark
+				backward = backward && line[i+len(finderLike)-1-j] == dark
+			}
+			if forward {
+				result += penaltyFinder
+			}
+			if backward {
+				result += penaltyFinder
+			}
+		}
+	}
+
+	dark := 0
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				color := c.modules[y][x]
+				if color == c.modules[y-1][x] && color == c.modules[y][x-1] && color == c.modules[y-1][x-1] {
+					result += penaltyBlock
+				}
+			}
+		}
+	}
+
+	total := c.Size * c.Size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	result += k * penaltyBalance
+
+	return result
+}
+
+// rsDivisor returns the generator polynomial of degree, without its leading
+// term, highest power first.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords of data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11D
+		z ^= int(y>>i&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
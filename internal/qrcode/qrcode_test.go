@@ -0,0 +1,286 @@
+package qrcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// formatStrings are the format information of level M for masks 0 to 7, from
+// the table of ISO/IEC 18004 annex C.
+var formatStrings = [8]int{
+	0b101010000010010,
+	0b101000100100101,
+	0b101111001111100,
+	0b101101101001011,
+	0b100010111111001,
+	0b100000011001110,
+	0b100111110010111,
+	0b100101010100000,
+}
+
+// versionStrings are the version information of versions 7 to 10, from the
+// table of ISO/IEC 18004 annex D.
+var versionStrings = map[int]int{
+	7:  0b000111110010010100,
+	8:  0b001000010110111100,
+	9:  0b001001101010011001,
+	10: 0b001010010011010011,
+}
+
+func TestEncodeVersion(t *testing.T) {
+	// The byte mode capacities of level M.
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{1, 1},
+		{14, 1},
+		{15, 2},
+		{26, 2},
+		{27, 3},
+		{42, 3},
+		{62, 4},
+		{84, 5},
+		{106, 6},
+		{122, 7},
+		{152, 8},
+		{180, 9},
+		{213, 10},
+	}
+
+	for _, tt := range tests {
+		c, err := Encode(strings.Repeat("a", tt.length))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", tt.length, err)
+		}
+		if want := 17 + 4*tt.version; c.Size != want {
+			t.Errorf("Encode(%d bytes) is %d modules wide, want %d (version %d)", tt.length, c.Size, want, tt.version)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("a", 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("Encode(214 bytes) = %v, want ErrTooLong", err)
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" at 1-M, as worked out in the Thonky QR code tutorial.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := rsRemainder(data, rsDivisor(len(want)))
+	if string(got) != string(want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		version int
+	}{
+		{"version 2", "https://bw.to/1", 2},
+		{"short", "hello", 1},
+		{"version 4", "https://bookwise.example.com/books/1234567890?ref=label", 4},
+		{"version 7", strings.Repeat("0123456789", 11), 7},
+		{"version 9", strings.Repeat("Bookwise ", 20), 9},
+		{"version 10", strings.Repeat("x", 213), 10},
+		{"binary", "\x00\xff\x10\x80", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := 17 + 4*tt.version; c.Size != want {
+				t.Fatalf("size = %d, want %d", c.Size, want)
+			}
+
+			checkFinders(t, c)
+			mask := readFormat(t, c)
+			if tt.version >= 7 {
+				if got := readVersion(c); got != versionStrings[tt.version] {
+					t.Errorf("version information = %018b, want %018b", got, versionStrings[tt.version])
+				}
+			}
+
+			if got := decode(t, c, tt.version, mask); got != tt.text {
+				t.Errorf("decoded %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+// checkFinders checks the finder patterns in three corners and the timing
+// patterns between them.
+func checkFinders(t *testing.T, c *Code) {
+	t.Helper()
+
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		for dy := range 7 {
+			for dx := range 7 {
+				ring := max(abs(dx-3), abs(dy-3))
+				if want := ring != 2; c.Dark(corner[0]+dx, corner[1]+dy) != want {
+					t.Fatalf("finder at %v: module %d,%d is wrong", corner, dx, dy)
+				}
+			}
+		}
+	}
+
+	for i := 8; i < c.Size-8; i++ {
+		if c.Dark(i, 6) != (i%2 == 0) || c.Dark(6, i) != (i%2 == 0) {
+			t.Fatalf("timing module %d is wrong", i)
+		}
+	}
+}
+
+// readFormat reads both copies of the format information, checks them
+// against the level M table and returns the mask.
+func readFormat(t *testing.T, c *Code) int {
+	t.Helper()
+
+	bit := func(x, y int) int {
+		if c.Dark(x, y) {
+			return 1
+		}
+		return 0
+	}
+
+	first, second := 0, 0
+	for i := 0; i <= 5; i++ {
+		first |= bit(8, i) << i
+	}
+	first |= bit(8, 7)<<6 | bit(8, 8)<<7 | bit(7, 8)<<8
+	for i := 9; i < 15; i++ {
+		first |= bit(14-i, 8) << i
+	}
+	for i := range 8 {
+		second |= bit(c.Size-1-i, 8) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(8, c.Size-15+i) << i
+	}
+
+	if first != second {
+		t.Fatalf("format copies differ: %015b and %015b", first, second)
+	}
+	if !c.Dark(8, c.Size-8) {
+		t.Fatal("dark module is light")
+	}
+
+	for mask, format := range formatStrings {
+		if format == first {
+			return mask
+		}
+	}
+	t.Fatalf("format information %015b is not one of level M", first)
+	return 0
+}
+
+func readVersion(c *Code) int {
+	first, second := 0, 0
+	for i := range 18 {
+		a, b := c.Size-11+i%3, i/3
+		if c.Dark(a, b) {
+			first |= 1 << i
+		}
+		if c.Dark(b, a) {
+			second |= 1 << i
+		}
+	}
+	if first != second {
+		return -1
+	}
+	return first
+}
+
+// decode reads the codewords of c back, checks every block with the
+// Reed-Solomon syndromes and returns the text of its byte mode segment.
+func decode(t *testing.T, c *Code, version, mask int) string {
+	t.Helper()
+
+	c.applyMask(mask)
+	defer c.applyMask(mask)
+
+	var bits bitBuffer
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for _, x := range []int{right, right - 1} {
+				if !c.function[y][x] {
+					bits = append(bits, c.Dark(x, y))
+				}
+			}
+		}
+	}
+	raw := bits.bytes()
+
+	layout := layouts[version]
+	blocks := make([][]byte, len(layout.data))
+	i := 0
+	for column := 0; ; column++ {
+		added := false
+		for b, n := range layout.data {
+			if column < n {
+				blocks[b] = append(blocks[b], raw[i])
+				i++
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	for range layout.ecLength {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], raw[i])
+			i++
+		}
+	}
+
+	var data []byte
+	for b, block := range blocks {
+		root := byte(1)
+		for range layout.ecLength {
+			syndrome := byte(0)
+			for _, codeword := range block {
+				syndrome = gfMultiply(syndrome, root) ^ codeword
+			}
+			if syndrome != 0 {
+				t.Fatalf("block %d has a nonzero syndrome", b)
+			}
+			root = gfMultiply(root, 0x02)
+		}
+		data = append(data, block[:layout.data[b]]...)
+	}
+
+	read := func(offset, length int) int {
+		value := 0
+		for j := offset; j < offset+length; j++ {
+			value = value<<1 | int(data[j/8]>>(7-j%8)&1)
+		}
+		return value
+	}
+
+	if mode := read(0, 4); mode != 0b0100 {
+		t.Fatalf("mode = %04b, want byte mode", mode)
+	}
+	length := read(4, countBits(version))
+
+	text := make([]byte, length)
+	for j := range text {
+		text[j] = byte(read(4+countBits(version)+8*j, 8))
+	}
+	return string(text)
+}
@@ -0,0 +1,418 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type authorRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type AuthorRepository interface {
+	GetAll(name string, userID int64, f filters.Filters) ([]*models.Author, filters.Metadata, error)
+	GetByID(id, userID int64) (*models.Author, error)
+	GetByBookIDs(bookIDs []int64) (map[int64][]models.BookAuthor, error)
+	Resolve(tx *sql.Tx, author *models.Author, userID int64) error
+	Update(tx *sql.Tx, author *models.Author, userID int64) error
+	SetBookAuthors(tx *sql.Tx, bookID int64, authors []models.BookAuthor) error
+}
+
+func NewAuthorRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *authorRepository {
+	return &authorRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func parseAuthorConstraintError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "unique_author_per_user":
+			return e.ErrDuplicateName
+		}
+	}
+	return err
+}
+
+// authorSearchCondition matches authors whose name or any alias contains the
+// words of :author. It expects the author to be aliased as a.
+const authorSearchCondition = `(
+	to_tsvector('simple', a.name) @@ plainto_tsquery('simple', :author)
+	OR exists (
+		select 1
+		from author_aliases aa
+		where aa.author_id = a.id
+			and to_tsvector('simple', aa.alias) @@ plainto_tsquery('simple', :author)
+	)
+)`
+
+// bookAuthorCondition restricts books aliased as b to the ones credited to an
+// author matching :author.
+const bookAuthorCondition = `(
+	:author = ''
+	OR exists (
+		select 1
+		from book_authors ba
+		join authors a on a.id = ba.author_id and a.deleted = false
+		where ba.book_id = b.id
+			and ` + authorSearchCondition + `
+	)
+)`
+
+func (r *authorRepository) GetAll(
+	name string,
+	userID int64,
+	f filters.Filters,
+) ([]*models.Author, filters.Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		count(*) OVER(),
+		%s
+	FROM authors a
+	WHERE
+		(:author = '' OR %s)
+		AND a.user_id = :userID
+		AND a.deleted = false
+	ORDER BY
+		a.%s %s,
+		a.id ASC
+	LIMIT :limit
+	OFFSET :offset
+	`, selectColumns(models.Author{}, "a"), authorSearchCondition, f.SortColumn(), f.SortDirection())
+
+	params := map[string]any{
+		"author": name,
+		"userID": userID,
+		"limit":  f.Limit(),
+		"offset": f.Offset(),
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	authors, metadata, err := paginatedQuery(
+		r.db,
+		query,
+		args,
+		f,
+		func() *models.Author {
+			return &models.Author{}
+		},
+	)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	if err := r.loadAliases(authors); err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	return authors, metadata, nil
+}
+
+func (r *authorRepository) GetByID(id, userID int64) (*models.Author, error) {
+	query := fmt.Sprintf(`
+	select
+		%s
+	from authors a
+	where
+		a.id = :id
+		and a.user_id = :userID
+		and a.deleted = false
+	`, selectColumns(models.Author{}, "a"))
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	author, err := getByQuery[models.Author](r.db, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.loadAliases([]*models.Author{author}); err != nil {
+		return nil, err
+	}
+
+	return author, nil
+}
+
+func (r *authorRepository) loadAliases(authors []*models.Author) error {
+	if len(authors) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(authors))
+	byID := make(map[int64]*models.Author, len(authors))
+	for _, author := range authors {
+		ids = append(ids, author.ID)
+		byID[author.ID] = author
+		author.Aliases = []string{}
+	}
+
+	query := `
+	select author_id, alias
+	from author_aliases
+	where author_id = any($1)
+	order by alias
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var authorID int64
+		var alias string
+		if err := rows.Scan(&authorID, &alias); err != nil {
+			return err
+		}
+		byID[authorID].Aliases = append(byID[authorID].Aliases, alias)
+	}
+
+	return rows.Err()
+}
+
+func (r *authorRepository) GetByBookIDs(bookIDs []int64) (map[int64][]models.BookAuthor, error) {
+	result := make(map[int64][]models.BookAuthor, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+	select
+		ba.book_id,
+		ba.role,
+		ba.position,
+		a.id,
+		a.name,
+		a.sort_name
+	from book_authors ba
+	join authors a on a.id = ba.author_id
+	where ba.book_id = any($1)
+	order by ba.book_id, ba.position, ba.role
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(bookIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int64
+		var author models.BookAuthor
+		err := rows.Scan(
+			&bookID,
+			&author.Role,
+			&author.Position,
+			&author.Author.ID,
+			&author.Author.Name,
+			&author.Author.SortName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result[bookID] = append(result[bookID], author)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Resolve fills in the ID of author. Authors referenced by ID must belong to
+// the user; authors given by name are matched against existing names and
+// aliases and created when no match exists.
+func (r *authorRepository) Resolve(tx *sql.Tx, author *models.Author, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if author.ID != 0 {
+		query := `
+		select name, sort_name
+		from authors
+		where id = $1 and user_id = $2 and deleted = false
+		`
+		r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+		err := tx.QueryRowContext(ctx, query, author.ID, userID).Scan(&author.Name, &author.SortName)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return e.ErrAuthorNotFound
+			}
+			return err
+		}
+		return nil
+	}
+
+	key := models.AuthorNameKey(author.Name)
+
+	query := `
+	select a.id, a.name, a.sort_name
+	from authors a
+	where
+		a.user_id = :userID
+		and a.deleted = false
+		and (
+			a.name_key = :key
+			or exists (select 1 from author_aliases aa where aa.author_id = a.id and aa.alias_key = :key)
+		)
+	order by (a.name_key = :key) desc, a.id
+	limit 1
+	`
+	params := map[string]any{
+		"userID": userID,
+		"key":    key,
+	}
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&author.ID, &author.Name, &author.SortName)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	query = `
+	insert into authors (name, sort_name, name_key, user_id, created_by)
+	values (:name, :sort_name, :key, :userID, :userID)
+	returning id, created_at, version
+	`
+	params = map[string]any{
+		"name":      author.Name,
+		"sort_name": author.SortName,
+		"key":       key,
+		"userID":    userID,
+	}
+	query, args = namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&author.ID, &author.CreatedAt, &author.Version)
+	if err != nil {
+		return parseAuthorConstraintError(err)
+	}
+
+	return nil
+}
+
+func (r *authorRepository) Update(tx *sql.Tx, author *models.Author, userID int64) error {
+	query := `
+	update authors set
+		name = :name,
+		sort_name = :sort_name,
+		name_key = :key,
+		updated_at = now(),
+		updated_by = :user_id,
+		version = version + 1
+	where
+		id = :id
+		and version = :version
+		and deleted = false
+		and user_id = :user_id
+	returning version
+	`
+
+	params := map[string]any{
+		"id":        author.ID,
+		"name":      author.Name,
+		"sort_name": author.SortName,
+		"key":       models.AuthorNameKey(author.Name),
+		"user_id":   userID,
+		"version":   author.Version,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&author.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrEditConflict
+		}
+		return parseAuthorConstraintError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from author_aliases where author_id = $1`, author.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, alias := range author.Aliases {
+		_, err = tx.ExecContext(
+			ctx,
+			`insert into author_aliases (author_id, alias, alias_key) values ($1, $2, $3) on conflict do nothing`,
+			author.ID,
+			alias,
+			models.AuthorNameKey(alias),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *authorRepository) SetBookAuthors(tx *sql.Tx, bookID int64, authors []models.BookAuthor) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `delete from book_authors where book_id = $1`, bookID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	insert into book_authors (book_id, author_id, role, position)
+	values ($1, $2, $3, $4)
+	on conflict do nothing
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	for _, author := range authors {
+		_, err := tx.ExecContext(ctx, query, bookID, author.Author.ID, author.Role, author.Position)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
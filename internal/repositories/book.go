@@ -32,14 +32,20 @@ func NewBookRepository(
 }
 
 type BookRepository interface {
-	GetAll(title, author string,
+	GetAll(
+		search filters.BookFilters,
 		userID int64,
 		f filters.Filters,
 	) ([]*models.Book, filters.Metadata, error)
 	GetByID(bookID, userID int64) (*models.Book, error)
+	GetByISBN(isbn string, userID int64) (*models.Book, error)
+	GetTitles(userID int64) ([]*models.Book, error)
+	GetBatch(search filters.BookFilters, userID int64, afterID int64, limit int) ([]*models.Book, error)
 	Insert(tx *sql.Tx, book *models.Book) error
 	Update(tx *sql.Tx, book *models.Book, userID int64) error
+	SetCover(tx *sql.Tx, bookID, userID int64, key string) (string, error)
 	Delete(tx *sql.Tx, bookID, userID int64) error
+	GetSpending(userID int64, from, to *time.Time) (*models.Spending, error)
 }
 
 func parseBookConstraintError(err error) error {
@@ -47,14 +53,99 @@ func parseBookConstraintError(err error) error {
 		switch pqErr.Constraint {
 		case "unique_title_per_user":
 			return e.ErrBookTitle
+		case "unique_isbn_per_user":
+			return e.ErrBookISBN
 		case "chk_books_pages_positive":
 			return e.ErrBookPages
+		case "chk_books_duration_minutes":
+			return e.ErrBookDuration
+		case "chk_books_series_position_positive":
+			return e.ErrSeriesPosition
 		}
 	}
 	return err
 }
 
-func (r *bookRepository) GetAll(title, author string,
+// bookCreditCondition narrows books aliased as b to those of an author and
+// of a series, by ID.
+const bookCreditCondition = `(
+	:byAuthorID::bigint = 0
+	OR exists (
+		select 1
+		from book_authors bba
+		where bba.book_id = b.id and bba.author_id = :byAuthorID::bigint
+	)
+)
+AND (b.series_id = :bySeriesID::bigint OR :bySeriesID::bigint = 0)`
+
+// bookFilterCondition applies the BookFilters parameters of
+// bookSearchParams but the shelf selection to the books of a user aliased
+// as b.
+const bookFilterCondition = `
+	(to_tsvector('simple', b.title) @@ plainto_tsquery('simple', :title) OR :title = '')
+	AND ` + bookAuthorCondition + `
+	AND (b.isbn = :isbn OR :isbn = '')
+	AND (
+		coalesce(cardinality(:acquisition::text[]), 0) = 0
+		OR b.acquisition_status = any(:acquisition::text[])
+	)
+	AND (coalesce(cardinality(:bookIDs::bigint[]), 0) = 0 OR b.id = any(:bookIDs::bigint[]))
+	AND (
+		:locationID::bigint = 0
+		OR b.location_id IN (
+			WITH RECURSIVE inside AS (
+				SELECT id FROM locations
+				WHERE id = :locationID::bigint AND user_id = :userID AND deleted = false
+				UNION ALL
+				SELECT l.id FROM locations l
+				JOIN inside i ON l.parent_id = i.id
+				WHERE l.deleted = false
+			)
+			SELECT id FROM inside
+		)
+	)
+	AND ` + bookCreditCondition + `
+	AND ` + bookRatingCondition + `
+	AND b.deleted = false
+	AND b.user_id = :userID`
+
+// bookShelfCondition applies the shelf selection of bookSearchParams,
+// :shelfIDs and :shelfMatchAll, to books aliased as b.
+const bookShelfCondition = `(
+	cardinality(:shelfIDs::bigint[]) = 0
+	OR (
+		select count(distinct bs.shelf_id)
+		from book_shelves bs
+		join shelves s on s.id = bs.shelf_id and s.deleted = false
+		where bs.book_id = b.id and bs.shelf_id = any(:shelfIDs::bigint[])
+	) >= case when :shelfMatchAll::bool then cardinality(:shelfIDs::bigint[]) else 1 end
+)`
+
+// bookSearchCondition applies the BookFilters parameters of
+// bookSearchParams to the books of a user aliased as b.
+const bookSearchCondition = bookFilterCondition + `
+	AND ` + bookShelfCondition
+
+func bookSearchParams(search filters.BookFilters, userID int64) map[string]any {
+	return map[string]any{
+		"title":         search.Title,
+		"author":        search.Author,
+		"isbn":          search.ISBN,
+		"byAuthorID":    search.AuthorID,
+		"bySeriesID":    search.SeriesID,
+		"minRating":     search.MinRating,
+		"maxRating":     search.MaxRating,
+		"userID":        userID,
+		"shelfIDs":      pq.Array(search.ShelfIDs),
+		"shelfMatchAll": search.ShelfMatchAll,
+		"acquisition":   pq.Array(search.Acquisition),
+		"bookIDs":       pq.Array(search.IDs),
+		"locationID":    search.LocationID,
+	}
+}
+
+func (r *bookRepository) GetAll(
+	search filters.BookFilters,
 	userID int64,
 	f filters.Filters,
 ) ([]*models.Book, filters.Metadata, error) {
@@ -68,25 +159,19 @@ func (r *bookRepository) GetAll(title, author string,
            	%s
         FROM books b
         LEFT JOIN users u ON u.id = b.user_id
+        LEFT JOIN reviews rv ON rv.book_id = b.id AND rv.deleted = false
         WHERE
-            (to_tsvector('simple', b.title) @@ plainto_tsquery('simple', :title) OR :title = '')
-            AND (to_tsvector('simple', b.author) @@ plainto_tsquery('simple', :author) OR :author = '')
-            AND b.deleted = false
-			and b.user_id = :userID
+            %s
         ORDER BY
-            b.%s %s,
+            %s %s NULLS LAST,
             b.id ASC
         LIMIT :limit
         OFFSET :offset
-    `, cols, f.SortColumn(), f.SortDirection())
+    `, cols, bookSearchCondition, bookSortExpression(f), f.SortDirection())
 
-	params := map[string]any{
-		"title":  title,
-		"author": author,
-		"userID": userID,
-		"limit":  f.Limit(),
-		"offset": f.Offset(),
-	}
+	params := bookSearchParams(search, userID)
+	params["limit"] = f.Limit()
+	params["offset"] = f.Offset()
 
 	query, args := namedQuery(query, params)
 	r.logger.PrintInfo(utils.MinifySQL(query), nil)
@@ -104,6 +189,54 @@ func (r *bookRepository) GetAll(title, author string,
 	)
 }
 
+// bookSortExpression qualifies the sort column of f, which is a book column
+// except for the rating kept on the joined review.
+func bookSortExpression(f filters.Filters) string {
+	if f.SortColumn() == "rating" {
+		return "rv.rating"
+	}
+	return "b." + f.SortColumn()
+}
+
+// GetBatch returns up to limit matching books with an ID greater than
+// afterID in ID order, so callers can walk through every match without
+// holding them all at once.
+func (r *bookRepository) GetBatch(
+	search filters.BookFilters,
+	userID int64,
+	afterID int64,
+	limit int,
+) ([]*models.Book, error) {
+	cols := strings.Join([]string{
+		selectColumns(models.Book{}, "b"),
+		selectColumns(models.User{}, "u"),
+	}, ", ")
+	query := fmt.Sprintf(`
+	select
+		%s
+	from books b
+	left join users u on u.id = b.user_id
+	where
+		b.id > :afterID
+		and %s
+	order by b.id
+	limit :limit
+	`, cols, bookSearchCondition)
+
+	params := bookSearchParams(search, userID)
+	params["afterID"] = afterID
+	params["limit"] = limit
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return listQuery(r.db, query, args, func() *models.Book {
+		return &models.Book{
+			User: &models.User{},
+		}
+	})
+}
+
 func (r *bookRepository) GetByID(bookID, userID int64) (*models.Book, error) {
 	cols := strings.Join([]string{
 		selectColumns(models.Book{}, "b"),
@@ -131,6 +264,70 @@ func (r *bookRepository) GetByID(bookID, userID int64) (*models.Book, error) {
 	return getByQuery[models.Book](r.db, query, args)
 }
 
+func (r *bookRepository) GetByISBN(isbn string, userID int64) (*models.Book, error) {
+	cols := strings.Join([]string{
+		selectColumns(models.Book{}, "b"),
+		selectColumns(models.User{}, "u"),
+	}, ", ")
+
+	query := fmt.Sprintf(`
+    select
+        %s
+    from books b
+    left join users u on u.id = b.user_id
+    where
+        b.isbn = :isbn
+        and b.user_id = :userID
+        and b.deleted = false
+`, cols)
+
+	params := map[string]any{
+		"isbn":   isbn,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.Book](r.db, query, args)
+}
+
+// GetTitles returns the ID, title, author credit, pages, format and ISBN of
+// every book of the user, for matching books named by other sources.
+func (r *bookRepository) GetTitles(userID int64) ([]*models.Book, error) {
+	query := `
+	select id, title, author, pages, format, isbn
+	from books
+	where user_id = $1 and deleted = false
+	order by id
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []*models.Book{}
+	for rows.Next() {
+		var book models.Book
+		err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.Pages, &book.Format, &book.ISBN)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, &book)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
 func (r *bookRepository) Insert(tx *sql.Tx, book *models.Book) error {
 	query := `
 	insert into books(
@@ -138,6 +335,21 @@ func (r *bookRepository) Insert(tx *sql.Tx, book *models.Book) error {
 		author,
 		pages,
 		description,
+		isbn,
+		publisher,
+		published_date,
+		language,
+		format,
+		duration_minutes,
+		cover_url,
+		series_id,
+		series_position,
+		acquisition_status,
+		purchased_at,
+		price,
+		currency,
+		store,
+		location_id,
 		user_id,
 		created_by
 	)
@@ -146,6 +358,21 @@ func (r *bookRepository) Insert(tx *sql.Tx, book *models.Book) error {
 		:author,
 		:pages,
 		:description,
+		:isbn,
+		:publisher,
+		:published_date,
+		:language,
+		:format,
+		:duration_minutes,
+		:cover_url,
+		:series_id,
+		:series_position,
+		:acquisition_status,
+		:purchased_at,
+		:price,
+		:currency,
+		:store,
+		:location_id,
 		:user_id,
 		:user_id
 	)
@@ -153,11 +380,26 @@ func (r *bookRepository) Insert(tx *sql.Tx, book *models.Book) error {
 	`
 
 	params := map[string]any{
-		"title":       book.Title,
-		"author":      book.Author,
-		"pages":       book.Pages,
-		"description": book.Description,
-		"user_id":     book.User.ID,
+		"title":              book.Title,
+		"author":             book.Author,
+		"pages":              book.Pages,
+		"description":        book.Description,
+		"isbn":               book.ISBN,
+		"publisher":          book.Publisher,
+		"published_date":     book.PublishedDate,
+		"language":           book.Language,
+		"format":             book.Format,
+		"duration_minutes":   book.DurationMinutes,
+		"cover_url":          book.CoverURL,
+		"series_id":          book.SeriesID,
+		"series_position":    book.SeriesPosition,
+		"acquisition_status": book.Acquisition,
+		"purchased_at":       book.PurchasedAt,
+		"price":              book.Price,
+		"currency":           book.Currency,
+		"store":              book.Store,
+		"location_id":        book.LocationID,
+		"user_id":            book.User.ID,
 	}
 	query, args := namedQuery(query, params)
 	r.logger.PrintInfo(utils.MinifySQL(query), nil)
@@ -189,6 +431,21 @@ func (r *bookRepository) Update(tx *sql.Tx, book *models.Book, userID int64) err
 		author = :author,
 		pages = :pages,
 		description = :description,
+		isbn = :isbn,
+		publisher = :publisher,
+		published_date = :published_date,
+		language = :language,
+		format = :format,
+		duration_minutes = :duration_minutes,
+		cover_url = :cover_url,
+		series_id = :series_id,
+		series_position = :series_position,
+		acquisition_status = :acquisition_status,
+		purchased_at = :purchased_at,
+		price = :price,
+		currency = :currency,
+		store = :store,
+		location_id = :location_id,
 		updated_at = now(),
 		updated_by = :user_id,
 		version = version + 1
@@ -201,13 +458,28 @@ func (r *bookRepository) Update(tx *sql.Tx, book *models.Book, userID int64) err
 	`
 
 	params := map[string]any{
-		"title":       book.Title,
-		"author":      book.Author,
-		"pages":       book.Pages,
-		"description": book.Description,
-		"user_id":     book.User.ID,
-		"version":     book.Version,
-		"id":          book.ID,
+		"title":              book.Title,
+		"author":             book.Author,
+		"pages":              book.Pages,
+		"description":        book.Description,
+		"isbn":               book.ISBN,
+		"publisher":          book.Publisher,
+		"published_date":     book.PublishedDate,
+		"language":           book.Language,
+		"format":             book.Format,
+		"duration_minutes":   book.DurationMinutes,
+		"cover_url":          book.CoverURL,
+		"series_id":          book.SeriesID,
+		"series_position":    book.SeriesPosition,
+		"acquisition_status": book.Acquisition,
+		"purchased_at":       book.PurchasedAt,
+		"price":              book.Price,
+		"currency":           book.Currency,
+		"store":              book.Store,
+		"location_id":        book.LocationID,
+		"user_id":            book.User.ID,
+		"version":            book.Version,
+		"id":                 book.ID,
 	}
 
 	query, args := namedQuery(query, params)
@@ -230,13 +502,60 @@ func (r *bookRepository) Update(tx *sql.Tx, book *models.Book, userID int64) err
 	return nil
 }
 
+// SetCover stores the blob key of the book's uploaded cover and returns the
+// key it replaced.
+func (r *bookRepository) SetCover(tx *sql.Tx, bookID, userID int64, key string) (string, error) {
+	query := `
+	update books b set
+		cover_key = :key,
+		updated_at = now(),
+		updated_by = :userID,
+		version = b.version + 1
+	from (
+		select id, cover_key
+		from books
+		where id = :bookID and user_id = :userID and deleted = false
+		for update
+	) old
+	where b.id = old.id
+	returning old.cover_key
+	`
+
+	params := map[string]any{
+		"key":    key,
+		"bookID": bookID,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var previous string
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", e.ErrRecordNotFound
+		}
+		return "", err
+	}
+
+	return previous, nil
+}
+
 func (r *bookRepository) Delete(tx *sql.Tx, bookID, userID int64) error {
 	query := `
 	update books set
-		deleted = false
-	where 
+		deleted = true,
+		deleted_at = now(),
+		updated_at = now(),
+		updated_by = :userID
+	where
 		id = :id
 		and user_id = :userID
+		and deleted = false
 	`
 
 	params := map[string]any{
@@ -250,7 +569,7 @@ func (r *bookRepository) Delete(tx *sql.Tx, bookID, userID int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := tx.ExecContext(ctx, query, args)
+	result, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -266,3 +585,104 @@ func (r *bookRepository) Delete(tx *sql.Tx, bookID, userID int64) error {
 
 	return nil
 }
+
+// spendingCondition narrows the books of :userID to those bought, with a
+// price, between the dates :from and :to when given. Books without a
+// purchase date only count when no dates are.
+const spendingCondition = `
+	user_id = :userID
+	AND deleted = false
+	AND price IS NOT NULL
+	AND acquisition_status IN ('owned', 'sold', 'donated')
+	AND (:from::date IS NULL OR purchased_at >= :from::date)
+	AND (:to::date IS NULL OR purchased_at <= :to::date)`
+
+// GetSpending sums the price of the books the user bought by month of
+// purchase and by store, in each currency, as prices in different
+// currencies are not added up. Books without a purchase date are only
+// counted by store.
+func (r *bookRepository) GetSpending(userID int64, from, to *time.Time) (*models.Spending, error) {
+	params := map[string]any{
+		"userID": userID,
+		"from":   from,
+		"to":     to,
+	}
+
+	byMonth, err := spendingTotals(r, `
+	select
+		to_char(purchased_at, 'YYYY-MM'),
+		currency,
+		sum(price),
+		count(*)
+	from books
+	where
+		`+spendingCondition+`
+		and purchased_at is not null
+	group by 1, 2
+	order by 1, 2
+	`, params, func(total *models.SpendingMonth) []any {
+		return []any{&total.Month, &total.Currency, &total.Total, &total.Books}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byStore, err := spendingTotals(r, `
+	select
+		store,
+		currency,
+		sum(price),
+		count(*)
+	from books
+	where
+		`+spendingCondition+`
+	group by 1, 2
+	order by 3 desc, 1, 2
+	`, params, func(total *models.SpendingStore) []any {
+		return []any{&total.Store, &total.Currency, &total.Total, &total.Books}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Spending{
+		ByMonth: byMonth,
+		ByStore: byStore,
+	}, nil
+}
+
+// spendingTotals runs a spending query, scanning each row into the fields
+// of a total dest returns.
+func spendingTotals[T any](
+	r *bookRepository,
+	query string,
+	params map[string]any,
+	dest func(total *T) []any,
+) ([]*T, error) {
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []*T{}
+	for rows.Next() {
+		var total T
+		if err := rows.Scan(dest(&total)...); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
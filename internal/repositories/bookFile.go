@@ -0,0 +1,400 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type bookFileRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type BookFileRepository interface {
+	GetByID(id, userID int64) (*models.BookFile, error)
+	GetByHash(hash string, userID int64) (*models.BookFile, error)
+	GetByBookID(bookID, userID int64) ([]*models.BookFile, error)
+	GetByBookIDs(bookIDs []int64, userID int64) (map[int64][]*models.BookFile, error)
+	GetByDocument(document string, userID int64) (*models.BookFile, error)
+	GetWithoutDocumentHash(userID int64) ([]*models.BookFile, error)
+	SetDocumentHash(tx *sql.Tx, id int64, hash string) error
+	Insert(tx *sql.Tx, file *models.BookFile) error
+	Touch(tx *sql.Tx, file *models.BookFile) error
+	Attach(tx *sql.Tx, id, userID, bookID int64) error
+	Delete(tx *sql.Tx, id, userID int64) (string, error)
+	DeleteUnattached(tx *sql.Tx, before time.Time) ([]string, error)
+}
+
+func NewBookFileRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *bookFileRepository {
+	return &bookFileRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func parseBookFileConstraintError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "unique_book_files_content_hash":
+			return e.ErrBookFileExists
+		}
+	}
+	return err
+}
+
+func (r *bookFileRepository) GetByID(id, userID int64) (*models.BookFile, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from book_files bf
+	where
+		bf.id = :id
+		and bf.user_id = :userID
+		and bf.deleted = false
+	`, selectColumns(models.BookFile{}, "bf"))
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return getByQuery[models.BookFile](r.db, query, args)
+}
+
+// GetByHash returns the file of the user with the given content, whether it
+// belongs to a book or not.
+func (r *bookFileRepository) GetByHash(hash string, userID int64) (*models.BookFile, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from book_files bf
+	where
+		bf.content_hash = :hash
+		and bf.user_id = :userID
+		and bf.deleted = false
+	`, selectColumns(models.BookFile{}, "bf"))
+
+	params := map[string]any{
+		"hash":   hash,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return getByQuery[models.BookFile](r.db, query, args)
+}
+
+func (r *bookFileRepository) GetByBookID(bookID, userID int64) ([]*models.BookFile, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from book_files bf
+	join books b on b.id = bf.book_id
+	where
+		bf.book_id = :bookID
+		and bf.user_id = :userID
+		and bf.deleted = false
+		and b.deleted = false
+	order by bf.id
+	`, selectColumns(models.BookFile{}, "bf"))
+
+	params := map[string]any{
+		"bookID": bookID,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return listQuery(r.db, query, args, func() *models.BookFile {
+		return &models.BookFile{}
+	})
+}
+
+// GetByBookIDs returns the files of several books at once, by book.
+func (r *bookFileRepository) GetByBookIDs(bookIDs []int64, userID int64) (map[int64][]*models.BookFile, error) {
+	result := make(map[int64][]*models.BookFile, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	query := fmt.Sprintf(`
+	select %s
+	from book_files bf
+	where
+		bf.book_id = any(:bookIDs)
+		and bf.user_id = :userID
+		and bf.deleted = false
+	order by bf.id
+	`, selectColumns(models.BookFile{}, "bf"))
+
+	params := map[string]any{
+		"bookIDs": pq.Array(bookIDs),
+		"userID":  userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	files, err := listQuery(r.db, query, args, func() *models.BookFile {
+		return &models.BookFile{}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		result[*file.BookID] = append(result[*file.BookID], file)
+	}
+
+	return result, nil
+}
+
+// GetByDocument returns the attached file KOReader knows as document, by
+// its partial MD5 or the MD5 of its name.
+func (r *bookFileRepository) GetByDocument(document string, userID int64) (*models.BookFile, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from book_files bf
+	join books b on b.id = bf.book_id
+	where
+		(bf.document_hash = :document or md5(bf.file_name) = :document)
+		and bf.user_id = :userID
+		and bf.deleted = false
+		and b.deleted = false
+	order by bf.document_hash = :document desc, bf.id
+	limit 1
+	`, selectColumns(models.BookFile{}, "bf"))
+
+	params := map[string]any{
+		"document": document,
+		"userID":   userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return getByQuery[models.BookFile](r.db, query, args)
+}
+
+// GetWithoutDocumentHash returns the attached files of the user stored
+// before document hashes were kept.
+func (r *bookFileRepository) GetWithoutDocumentHash(userID int64) ([]*models.BookFile, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from book_files bf
+	where
+		bf.document_hash = ''
+		and bf.book_id is not null
+		and bf.user_id = :userID
+		and bf.deleted = false
+	order by bf.id
+	`, selectColumns(models.BookFile{}, "bf"))
+
+	params := map[string]any{
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return listQuery(r.db, query, args, func() *models.BookFile {
+		return &models.BookFile{}
+	})
+}
+
+func (r *bookFileRepository) SetDocumentHash(tx *sql.Tx, id int64, hash string) error {
+	query := `
+	update book_files set
+		document_hash = $1
+	where id = $2
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, hash, id)
+	return err
+}
+
+func (r *bookFileRepository) Insert(tx *sql.Tx, file *models.BookFile) error {
+	query := `
+	insert into book_files (
+		user_id,
+		format,
+		file_name,
+		size,
+		content_hash,
+		blob_key,
+		document_hash,
+		created_by
+	)
+	values (
+		:userID,
+		:format,
+		:file_name,
+		:size,
+		:content_hash,
+		:blob_key,
+		:document_hash,
+		:userID
+	)
+	returning id, created_at, version
+	`
+
+	params := map[string]any{
+		"userID":        file.UserID,
+		"format":        file.Format,
+		"file_name":     file.FileName,
+		"size":          file.Size,
+		"content_hash":  file.ContentHash,
+		"blob_key":      file.BlobKey,
+		"document_hash": file.DocumentHash,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&file.ID,
+		&file.CreatedAt,
+		&file.Version,
+	)
+	return parseBookFileConstraintError(err)
+}
+
+// Touch renews an unattached file uploaded again, so DeleteUnattached counts
+// its age from the new upload. The file name is taken from the new upload.
+func (r *bookFileRepository) Touch(tx *sql.Tx, file *models.BookFile) error {
+	query := `
+	update book_files set
+		file_name = $1,
+		updated_at = now(),
+		updated_by = user_id,
+		version = version + 1
+	where
+		id = $2
+		and book_id is null
+	returning updated_at, version
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, file.FileName, file.ID).Scan(&file.UpdatedAt, &file.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e.ErrEditConflict
+	}
+	return err
+}
+
+// Attach gives an unattached file to a book. It returns e.ErrEditConflict
+// when the file was attached in the meantime.
+func (r *bookFileRepository) Attach(tx *sql.Tx, id, userID, bookID int64) error {
+	query := `
+	update book_files set
+		book_id = $1,
+		updated_at = now(),
+		updated_by = $3,
+		version = version + 1
+	where
+		id = $2
+		and user_id = $3
+		and book_id is null
+		and deleted = false
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, bookID, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return e.ErrEditConflict
+	}
+
+	return nil
+}
+
+// Delete removes an unattached file and returns its blob key.
+func (r *bookFileRepository) Delete(tx *sql.Tx, id, userID int64) (string, error) {
+	query := `
+	delete from book_files
+	where
+		id = $1
+		and user_id = $2
+		and book_id is null
+	returning blob_key
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var key string
+	err := tx.QueryRowContext(ctx, query, id, userID).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", e.ErrRecordNotFound
+	}
+	return key, err
+}
+
+// DeleteUnattached removes the files that have been without a book since
+// before and returns their blob keys.
+func (r *bookFileRepository) DeleteUnattached(tx *sql.Tx, before time.Time) ([]string, error) {
+	query := `
+	delete from book_files
+	where
+		book_id is null
+		and coalesce(updated_at, created_at) < $1
+	returning blob_key
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := tx.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type chapterRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type ChapterRepository interface {
+	GetByBookID(bookID int64) ([]*models.Chapter, error)
+	Replace(tx *sql.Tx, bookID int64, chapters []*models.Chapter) error
+}
+
+func NewChapterRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *chapterRepository {
+	return &chapterRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetByBookID returns the chapters of a book in order. The caller checks
+// the book belongs to the user.
+func (r *chapterRepository) GetByBookID(bookID int64) ([]*models.Chapter, error) {
+	query := `
+	select id, book_id, position, title, start_page, minutes
+	from book_chapters
+	where book_id = $1
+	order by position
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chapters := []*models.Chapter{}
+	for rows.Next() {
+		var chapter models.Chapter
+		err := rows.Scan(
+			&chapter.ID,
+			&chapter.BookID,
+			&chapter.Position,
+			&chapter.Title,
+			&chapter.StartPage,
+			&chapter.Minutes,
+		)
+		if err != nil {
+			return nil, err
+		}
+		chapters = append(chapters, &chapter)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return chapters, nil
+}
+
+// Replace makes chapters, numbered in their order, the table of contents of
+// the book.
+func (r *chapterRepository) Replace(tx *sql.Tx, bookID int64, chapters []*models.Chapter) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, `delete from book_chapters where book_id = $1`, bookID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	insert into book_chapters (book_id, position, title, start_page, minutes)
+	values ($1, $2, $3, $4, $5)
+	returning id
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	for i, chapter := range chapters {
+		chapter.BookID = bookID
+		chapter.Position = i + 1
+
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			bookID,
+			chapter.Position,
+			chapter.Title,
+			chapter.StartPage,
+			chapter.Minutes,
+		).Scan(&chapter.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
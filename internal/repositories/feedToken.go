@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type feedTokenRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type FeedTokenRepository interface {
+	GetByUserID(userID int64) (*models.FeedToken, error)
+	GetUserByHash(hash []byte) (*models.User, error)
+	Replace(tx *sql.Tx, token *models.FeedToken) error
+	Delete(tx *sql.Tx, userID int64) error
+	Touch(userID int64) error
+}
+
+func NewFeedTokenRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *feedTokenRepository {
+	return &feedTokenRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *feedTokenRepository) GetByUserID(userID int64) (*models.FeedToken, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from feed_tokens ft
+	where ft.user_id = :userID
+	`, selectColumns(models.FeedToken{}, "ft"))
+
+	params := map[string]any{
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.FeedToken](r.db, query, args)
+}
+
+// GetUserByHash returns the user a token belongs to.
+func (r *feedTokenRepository) GetUserByHash(hash []byte) (*models.User, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from feed_tokens ft
+	join users u on u.id = ft.user_id
+	where
+		ft.token_hash = :hash
+		and u.deleted = false
+	`, selectColumns(models.User{}, "u"))
+
+	params := map[string]any{
+		"hash": hash,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.User](r.db, query, args)
+}
+
+// Replace saves token as the only token of its user, revoking the one it had.
+func (r *feedTokenRepository) Replace(tx *sql.Tx, token *models.FeedToken) error {
+	query := `
+	insert into feed_tokens (user_id, token_hash)
+	values ($1, $2)
+	on conflict (user_id) do update set
+		token_hash = excluded.token_hash,
+		created_at = now(),
+		last_used_at = null
+	returning created_at
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return tx.QueryRowContext(ctx, query, token.UserID, token.Hash).Scan(&token.CreatedAt)
+}
+
+func (r *feedTokenRepository) Delete(tx *sql.Tx, userID int64) error {
+	query := `
+	delete from feed_tokens
+	where user_id = $1
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return e.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Touch records that the token of the user was used. Apps fetch many pages
+// and covers in a row, so it is only written once a minute.
+func (r *feedTokenRepository) Touch(userID int64) error {
+	query := `
+	update feed_tokens set
+		last_used_at = now()
+	where
+		user_id = $1
+		and (last_used_at is null or last_used_at < now() - interval '1 minute')
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
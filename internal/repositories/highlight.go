@@ -0,0 +1,472 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type highlightRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type HighlightRepository interface {
+	GetAll(
+		search filters.HighlightFilters,
+		userID int64,
+		f filters.Filters,
+	) ([]*models.Highlight, filters.Metadata, error)
+	GetByID(id, userID int64) (*models.Highlight, error)
+	Random(search filters.HighlightFilters, userID int64, seed string) (*models.Highlight, error)
+	Export(search filters.HighlightFilters, userID int64) ([]*models.Highlight, error)
+	SessionOfBook(sessionID, bookID, userID int64) (bool, error)
+	Insert(tx *sql.Tx, highlight *models.Highlight, userID int64) error
+	Import(tx *sql.Tx, highlight *models.Highlight, addedAt *time.Time, userID int64) (bool, error)
+	Update(tx *sql.Tx, highlight *models.Highlight, userID int64) error
+	Delete(tx *sql.Tx, id, userID int64) error
+}
+
+func NewHighlightRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *highlightRepository {
+	return &highlightRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// highlightColumns lists the columns in the order a Highlight is scanned: the
+// highlight, its book and the book's owner.
+func highlightColumns() string {
+	return strings.Join([]string{
+		selectColumns(models.Highlight{}, "h"),
+		selectColumns(models.Book{}, "b"),
+		selectColumns(models.User{}, "u"),
+	}, ", ")
+}
+
+// highlightSearchCondition applies the HighlightFilters parameters :search,
+// :bookID and :color to highlights aliased as h.
+const highlightSearchCondition = `(
+	(:search = '' OR to_tsvector('simple', h.text || ' ' || h.chapter || ' ' || h.note) @@ plainto_tsquery('simple', :search))
+	AND (:bookID = 0 OR h.book_id = :bookID)
+	AND (:color = '' OR h.color = :color)
+)`
+
+func highlightSearchParams(search filters.HighlightFilters, userID int64) map[string]any {
+	return map[string]any{
+		"search": search.Query,
+		"bookID": search.BookID,
+		"color":  search.Color,
+		"userID": userID,
+	}
+}
+
+func (r *highlightRepository) GetAll(
+	search filters.HighlightFilters,
+	userID int64,
+	f filters.Filters,
+) ([]*models.Highlight, filters.Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		count(*) OVER(),
+		%s
+	FROM highlights h
+	JOIN books b ON b.id = h.book_id AND b.deleted = false
+	LEFT JOIN users u ON u.id = b.user_id
+	WHERE
+		%s
+		AND h.user_id = :userID
+		AND h.deleted = false
+	ORDER BY
+		h.%s %s,
+		h.id ASC
+	LIMIT :limit
+	OFFSET :offset
+	`, highlightColumns(), highlightSearchCondition, f.SortColumn(), f.SortDirection())
+
+	params := highlightSearchParams(search, userID)
+	params["limit"] = f.Limit()
+	params["offset"] = f.Offset()
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return paginatedQuery(
+		r.db,
+		query,
+		args,
+		f,
+		func() *models.Highlight {
+			return &models.Highlight{
+				Book: &models.Book{},
+			}
+		},
+	)
+}
+
+func (r *highlightRepository) GetByID(id, userID int64) (*models.Highlight, error) {
+	query := fmt.Sprintf(`
+	select
+		%s
+	from highlights h
+	join books b on b.id = h.book_id and b.deleted = false
+	left join users u on u.id = b.user_id
+	where
+		h.id = :id
+		and h.user_id = :userID
+		and h.deleted = false
+	`, highlightColumns())
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.Highlight](r.db, query, args)
+}
+
+// Random picks one matching highlight. With a seed the pick is stable for as
+// long as the seed and the highlights stay the same, which lets callers
+// resurface the same passage for a whole day.
+func (r *highlightRepository) Random(search filters.HighlightFilters, userID int64, seed string) (*models.Highlight, error) {
+	params := highlightSearchParams(search, userID)
+
+	order := "random()"
+	if seed != "" {
+		order = "md5(h.id::text || :seed)"
+		params["seed"] = seed
+	}
+
+	query := fmt.Sprintf(`
+	select
+		%s
+	from highlights h
+	join books b on b.id = h.book_id and b.deleted = false
+	left join users u on u.id = b.user_id
+	where
+		%s
+		and h.user_id = :userID
+		and h.deleted = false
+	order by %s
+	limit 1
+	`, highlightColumns(), highlightSearchCondition, order)
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.Highlight](r.db, query, args)
+}
+
+// Export returns every matching highlight ordered by book and location.
+func (r *highlightRepository) Export(search filters.HighlightFilters, userID int64) ([]*models.Highlight, error) {
+	query := fmt.Sprintf(`
+	select
+		%s
+	from highlights h
+	join books b on b.id = h.book_id and b.deleted = false
+	left join users u on u.id = b.user_id
+	where
+		%s
+		and h.user_id = :userID
+		and h.deleted = false
+	order by b.title, b.id, h.page, h.id
+	`, highlightColumns(), highlightSearchCondition)
+
+	query, args := namedQuery(query, highlightSearchParams(search, userID))
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	highlights := []*models.Highlight{}
+	for rows.Next() {
+		highlight := &models.Highlight{}
+
+		fields, err := collectFields(highlight)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		highlights = append(highlights, highlight)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return highlights, nil
+}
+
+// SessionOfBook reports whether the reading session belongs to a plan of the
+// user for the given book.
+func (r *highlightRepository) SessionOfBook(sessionID, bookID, userID int64) (bool, error) {
+	query := `
+	select exists (
+		select 1
+		from reading_sessions rs
+		join reading_plans rp on rp.id = rs.reading_plan_id
+		where
+			rs.id = :sessionID
+			and rs.deleted = false
+			and rp.book_id = :bookID
+			and rp.user_id = :userID
+	)
+	`
+
+	params := map[string]any{
+		"sessionID": sessionID,
+		"bookID":    bookID,
+		"userID":    userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var exists bool
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&exists)
+	return exists, err
+}
+
+func (r *highlightRepository) Insert(tx *sql.Tx, highlight *models.Highlight, userID int64) error {
+	query := `
+	insert into highlights (
+		book_id,
+		session_id,
+		text,
+		page,
+		chapter,
+		note,
+		color,
+		source,
+		source_hash,
+		user_id,
+		created_by
+	)
+	values (
+		:book_id,
+		:session_id,
+		:text,
+		:page,
+		:chapter,
+		:note,
+		:color,
+		:origin,
+		:hash,
+		:user_id,
+		:user_id
+	)
+	returning id, created_at, version
+	`
+
+	params := map[string]any{
+		"book_id":    highlight.Book.ID,
+		"session_id": highlight.SessionID,
+		"text":       highlight.Text,
+		"page":       highlight.Page,
+		"chapter":    highlight.Chapter,
+		"note":       highlight.Note,
+		"color":      highlight.Color,
+		"origin":     highlight.Source,
+		"hash":       highlight.SourceHash,
+		"user_id":    userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(
+		&highlight.ID,
+		&highlight.CreatedAt,
+		&highlight.Version,
+	)
+}
+
+// Import inserts an imported highlight dated addedAt, or now when unknown. It
+// reports false when the book already has a highlight with the same
+// SourceHash, including deleted ones.
+func (r *highlightRepository) Import(
+	tx *sql.Tx,
+	highlight *models.Highlight,
+	addedAt *time.Time,
+	userID int64,
+) (bool, error) {
+	query := `
+	insert into highlights (
+		book_id,
+		text,
+		page,
+		chapter,
+		note,
+		color,
+		source,
+		source_hash,
+		user_id,
+		created_at,
+		created_by
+	)
+	values (
+		:book_id,
+		:text,
+		:page,
+		:chapter,
+		:note,
+		:color,
+		:origin,
+		:hash,
+		:user_id,
+		coalesce(:added_at::timestamptz, now()),
+		:user_id
+	)
+	on conflict (user_id, book_id, source_hash) where source_hash <> '' do nothing
+	returning id, created_at, version
+	`
+
+	params := map[string]any{
+		"book_id":  highlight.Book.ID,
+		"text":     highlight.Text,
+		"page":     highlight.Page,
+		"chapter":  highlight.Chapter,
+		"note":     highlight.Note,
+		"color":    highlight.Color,
+		"origin":   highlight.Source,
+		"hash":     highlight.SourceHash,
+		"user_id":  userID,
+		"added_at": addedAt,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&highlight.ID,
+		&highlight.CreatedAt,
+		&highlight.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (r *highlightRepository) Update(tx *sql.Tx, highlight *models.Highlight, userID int64) error {
+	query := `
+	update highlights set
+		book_id = :book_id,
+		session_id = :session_id,
+		text = :text,
+		page = :page,
+		chapter = :chapter,
+		note = :note,
+		color = :color,
+		updated_at = now(),
+		updated_by = :user_id,
+		version = version + 1
+	where
+		id = :id
+		and version = :version
+		and deleted = false
+		and user_id = :user_id
+	returning created_at, version
+	`
+
+	params := map[string]any{
+		"id":         highlight.ID,
+		"book_id":    highlight.Book.ID,
+		"session_id": highlight.SessionID,
+		"text":       highlight.Text,
+		"page":       highlight.Page,
+		"chapter":    highlight.Chapter,
+		"note":       highlight.Note,
+		"color":      highlight.Color,
+		"user_id":    userID,
+		"version":    highlight.Version,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&highlight.CreatedAt, &highlight.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrEditConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *highlightRepository) Delete(tx *sql.Tx, id, userID int64) error {
+	query := `
+	update highlights set
+		deleted = true,
+		deleted_at = now(),
+		updated_at = now(),
+		updated_by = :user_id
+	where
+		id = :id
+		and user_id = :user_id
+		and deleted = false
+	`
+
+	params := map[string]any{
+		"id":      id,
+		"user_id": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return e.ErrRecordNotFound
+	}
+
+	return nil
+}
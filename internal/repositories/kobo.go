@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type koboRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type KoboRepository interface {
+	GetProgress(userID int64) ([]*models.KoboProgress, error)
+	SaveProgress(tx *sql.Tx, progress *models.KoboProgress) error
+}
+
+func NewKoboRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *koboRepository {
+	return &koboRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetProgress returns the progress last imported for every book of the
+// user's Kobo databases.
+func (r *koboRepository) GetProgress(userID int64) ([]*models.KoboProgress, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from kobo_progress kp
+	where kp.user_id = $1
+	order by kp.content_id
+	`, selectColumns(models.KoboProgress{}, "kp"))
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return listQuery(r.db, query, []any{userID}, func() *models.KoboProgress {
+		return &models.KoboProgress{}
+	})
+}
+
+// SaveProgress saves progress as the last imported for its book.
+func (r *koboRepository) SaveProgress(tx *sql.Tx, progress *models.KoboProgress) error {
+	query := `
+	insert into kobo_progress (
+		user_id,
+		content_id,
+		book_id,
+		seconds_read,
+		percent_read,
+		read_status,
+		last_read
+	)
+	values (:userID, :contentID, :bookID, :secondsRead, :percentRead, :readStatus, :lastRead)
+	on conflict (user_id, content_id) do update set
+		book_id = excluded.book_id,
+		seconds_read = excluded.seconds_read,
+		percent_read = excluded.percent_read,
+		read_status = excluded.read_status,
+		last_read = excluded.last_read,
+		updated_at = now()
+	returning updated_at
+	`
+
+	params := map[string]any{
+		"userID":      progress.UserID,
+		"contentID":   progress.ContentID,
+		"bookID":      progress.BookID,
+		"secondsRead": progress.SecondsRead,
+		"percentRead": progress.PercentRead,
+		"readStatus":  progress.ReadStatus,
+		"lastRead":    progress.LastRead,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&progress.UpdatedAt)
+}
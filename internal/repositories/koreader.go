@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type koreaderRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type KOReaderRepository interface {
+	GetAccount(userID int64) (*models.KOReaderAccount, error)
+	GetAccountByUsername(username string) (*models.KOReaderAccount, error)
+	SaveAccount(tx *sql.Tx, account *models.KOReaderAccount) error
+	DeleteAccount(tx *sql.Tx, userID int64) error
+	GetProgress(userID int64, document string) (*models.KOReaderProgress, error)
+	SaveProgress(tx *sql.Tx, progress *models.KOReaderProgress) error
+}
+
+func NewKOReaderRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *koreaderRepository {
+	return &koreaderRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func parseKOReaderConstraintError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "unique_koreader_accounts_username":
+			return e.ErrDuplicateUser
+		}
+	}
+	return err
+}
+
+// koreaderAccountColumns lists the columns of an account followed by those of
+// its user.
+func koreaderAccountColumns() string {
+	return strings.Join([]string{
+		selectColumns(models.KOReaderAccount{}, "ka"),
+		selectColumns(models.User{}, "u"),
+	}, ", ")
+}
+
+func (r *koreaderRepository) GetAccount(userID int64) (*models.KOReaderAccount, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from koreader_accounts ka
+	join users u on u.id = ka.user_id
+	where
+		ka.user_id = :userID
+		and u.deleted = false
+	`, koreaderAccountColumns())
+
+	params := map[string]any{
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.KOReaderAccount](r.db, query, args)
+}
+
+func (r *koreaderRepository) GetAccountByUsername(username string) (*models.KOReaderAccount, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from koreader_accounts ka
+	join users u on u.id = ka.user_id
+	where
+		ka.username = :username
+		and u.deleted = false
+	`, koreaderAccountColumns())
+
+	params := map[string]any{
+		"username": username,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.KOReaderAccount](r.db, query, args)
+}
+
+// SaveAccount saves account as the only account of its user, replacing the
+// username and password it had.
+func (r *koreaderRepository) SaveAccount(tx *sql.Tx, account *models.KOReaderAccount) error {
+	query := `
+	insert into koreader_accounts (user_id, username, password_hash)
+	values ($1, $2, $3)
+	on conflict (user_id) do update set
+		username = excluded.username,
+		password_hash = excluded.password_hash,
+		updated_at = now()
+	returning created_at, updated_at
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, account.UserID, account.Username, account.Key.Hash).Scan(
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+	return parseKOReaderConstraintError(err)
+}
+
+func (r *koreaderRepository) DeleteAccount(tx *sql.Tx, userID int64) error {
+	query := `
+	delete from koreader_accounts
+	where user_id = $1
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return e.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *koreaderRepository) GetProgress(userID int64, document string) (*models.KOReaderProgress, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from koreader_progress kp
+	where
+		kp.user_id = :userID
+		and kp.document = :document
+	`, selectColumns(models.KOReaderProgress{}, "kp"))
+
+	params := map[string]any{
+		"userID":   userID,
+		"document": document,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.KOReaderProgress](r.db, query, args)
+}
+
+// SaveProgress saves progress as the last position of its document.
+func (r *koreaderRepository) SaveProgress(tx *sql.Tx, progress *models.KOReaderProgress) error {
+	query := `
+	insert into koreader_progress (
+		user_id,
+		document,
+		book_id,
+		progress,
+		percentage,
+		device,
+		device_id
+	)
+	values (:userID, :document, :bookID, :progress, :percentage, :deviceName, :deviceID)
+	on conflict (user_id, document) do update set
+		book_id = excluded.book_id,
+		progress = excluded.progress,
+		percentage = excluded.percentage,
+		device = excluded.device,
+		device_id = excluded.device_id,
+		updated_at = now()
+	returning updated_at
+	`
+
+	params := map[string]any{
+		"userID":     progress.UserID,
+		"document":   progress.Document,
+		"bookID":     progress.BookID,
+		"progress":   progress.Progress,
+		"percentage": progress.Percentage,
+		"deviceName": progress.Device,
+		"deviceID":   progress.DeviceID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(&progress.UpdatedAt)
+}
@@ -0,0 +1,325 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// staleImportAfter is how long a running import may go without progress
+// before another runner takes it over, as happens when the server stopped
+// in the middle of it.
+const staleImportAfter = 10 * time.Minute
+
+type libraryImportRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type LibraryImportRepository interface {
+	GetByID(id, userID int64) (*models.LibraryImport, error)
+	Insert(tx *sql.Tx, job *models.LibraryImport) error
+	Claim() (*models.LibraryImport, error)
+	SaveProgress(job *models.LibraryImport) error
+	AddErrors(importID int64, errs []*models.LibraryImportError) error
+	ExternalBookID(tx *sql.Tx, userID int64, source, externalID string) (int64, error)
+	LinkExternalID(tx *sql.Tx, userID int64, source, externalID string, bookID int64) error
+}
+
+func NewLibraryImportRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *libraryImportRepository {
+	return &libraryImportRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetByID returns the import together with the errors of its rows.
+func (r *libraryImportRepository) GetByID(id, userID int64) (*models.LibraryImport, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from library_imports li
+	where
+		li.id = :id
+		and li.user_id = :userID
+		and li.deleted = false
+	`, selectColumns(models.LibraryImport{}, "li"))
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	job, err := getByQuery[models.LibraryImport](r.db, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Errors, err = r.getErrors(job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (r *libraryImportRepository) getErrors(importID int64) ([]*models.LibraryImportError, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from library_import_errors lie
+	where lie.import_id = $1
+	order by lie.line, lie.id
+	`, selectColumns(models.LibraryImportError{}, "lie"))
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	errs := []*models.LibraryImportError{}
+	for rows.Next() {
+		var importErr models.LibraryImportError
+		fields, err := collectFields(&importErr)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		errs = append(errs, &importErr)
+	}
+
+	return errs, rows.Err()
+}
+
+func (r *libraryImportRepository) Insert(tx *sql.Tx, job *models.LibraryImport) error {
+	query := `
+	insert into library_imports (
+		user_id,
+		source,
+		dry_run,
+		data,
+		total,
+		created_by
+	)
+	values (
+		:userID,
+		:source,
+		:dry_run,
+		:data,
+		:total,
+		:userID
+	)
+	returning id, status, created_at, version
+	`
+
+	params := map[string]any{
+		"userID":  job.UserID,
+		"source":  job.Source,
+		"dry_run": job.DryRun,
+		"data":    job.Data,
+		"total":   job.Total,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return tx.QueryRowContext(ctx, query, args...).Scan(
+		&job.ID,
+		&job.Status,
+		&job.CreatedAt,
+		&job.Version,
+	)
+}
+
+// Claim marks the oldest pending import, or a running one that stopped making
+// progress, as running and returns it with its data. It returns
+// e.ErrRecordNotFound when there is nothing to do.
+func (r *libraryImportRepository) Claim() (*models.LibraryImport, error) {
+	query := fmt.Sprintf(`
+	update library_imports li set
+		status = 'running',
+		started_at = coalesce(li.started_at, now()),
+		updated_at = now(),
+		version = li.version + 1
+	where li.id = (
+		select id
+		from library_imports
+		where
+			deleted = false
+			and (
+				status = 'pending'
+				or (status = 'running' and updated_at < now() - make_interval(secs => :staleAfter))
+			)
+		order by id
+		limit 1
+		for update skip locked
+	)
+	returning %s, li.data
+	`, selectColumns(models.LibraryImport{}, "li"))
+
+	params := map[string]any{
+		"staleAfter": staleImportAfter.Seconds(),
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var job models.LibraryImport
+	fields, err := collectFields(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.QueryRowContext(ctx, query, args...).Scan(append(fields, &job.Data)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, e.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// SaveProgress stores the counters and status of job. The uploaded data is
+// dropped once the import has finished.
+func (r *libraryImportRepository) SaveProgress(job *models.LibraryImport) error {
+	query := `
+	update library_imports set
+		status = :status,
+		processed = :processed,
+		created = :created,
+		linked = :linked,
+		skipped = :skipped,
+		failed = :failed,
+		error = :error,
+		finished_at = case when :status in ('completed', 'failed') then now() end,
+		data = case when :status in ('completed', 'failed') then null else data end,
+		updated_at = now(),
+		version = version + 1
+	where id = :id
+	returning finished_at, version
+	`
+
+	params := map[string]any{
+		"id":        job.ID,
+		"status":    string(job.Status),
+		"processed": job.Processed,
+		"created":   job.Created,
+		"linked":    job.Linked,
+		"skipped":   job.Skipped,
+		"failed":    job.Failed,
+		"error":     job.Error,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&job.FinishedAt, &job.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return e.ErrRecordNotFound
+	}
+	return err
+}
+
+func (r *libraryImportRepository) AddErrors(importID int64, errs []*models.LibraryImportError) error {
+	query := `
+	insert into library_import_errors (import_id, line, title, field, message)
+	values ($1, $2, $3, $4, $5)
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return utils.RunInTx(r.db, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		for _, importErr := range errs {
+			_, err := tx.ExecContext(
+				ctx,
+				query,
+				importID,
+				importErr.Line,
+				importErr.Title,
+				importErr.Field,
+				importErr.Message,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ExternalBookID returns the book an earlier import made of the row the
+// source identifies as externalID, including books now in the trash.
+func (r *libraryImportRepository) ExternalBookID(tx *sql.Tx, userID int64, source, externalID string) (int64, error) {
+	query := `
+	select book_id
+	from book_external_ids
+	where
+		user_id = $1
+		and source = $2
+		and external_id = $3
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var bookID int64
+	err := tx.QueryRowContext(ctx, query, userID, source, externalID).Scan(&bookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, e.ErrRecordNotFound
+	}
+	return bookID, err
+}
+
+func (r *libraryImportRepository) LinkExternalID(
+	tx *sql.Tx,
+	userID int64,
+	source, externalID string,
+	bookID int64,
+) error {
+	query := `
+	insert into book_external_ids (user_id, source, external_id, book_id)
+	values ($1, $2, $3, $4)
+	on conflict (user_id, source, external_id) do update set
+		book_id = excluded.book_id
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, userID, source, externalID, bookID)
+	return err
+}
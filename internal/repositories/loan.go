@@ -0,0 +1,378 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type loanRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type LoanRepository interface {
+	GetAll(search filters.LoanFilters, userID int64, f filters.Filters) ([]*models.Loan, filters.Metadata, error)
+	GetByID(id, userID int64) (*models.Loan, error)
+	GetOpenByBookIDs(bookIDs []int64) (map[int64]*models.Loan, error)
+	GetUnremindedOverdue(limit int) ([]*models.Loan, error)
+	Insert(tx *sql.Tx, loan *models.Loan, userID int64) error
+	Update(tx *sql.Tx, loan *models.Loan, userID int64) error
+	MarkReminded(tx *sql.Tx, id int64) error
+	Delete(tx *sql.Tx, id, userID int64) error
+}
+
+func NewLoanRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *loanRepository {
+	return &loanRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func parseLoanConstraintError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "unique_open_loan_per_book":
+			return e.ErrBookOnLoan
+		case "chk_loans_due_at":
+			return e.ErrLoanDueAt
+		case "chk_loans_returned_at":
+			return e.ErrLoanReturnedAt
+		}
+	}
+	return err
+}
+
+// loanColumns lists the columns in the order a Loan is scanned: the loan, its
+// book and the book's owner, who lent it.
+func loanColumns() string {
+	return strings.Join([]string{
+		selectColumns(models.Loan{}, "l"),
+		selectColumns(models.Book{}, "b"),
+		selectColumns(models.User{}, "u"),
+	}, ", ")
+}
+
+// loanSearchCondition applies the LoanFilters parameters :search, :bookID,
+// :status and :today to loans aliased as l.
+const loanSearchCondition = `(
+	(:search = '' OR l.borrower_name ILIKE '%' || :search || '%')
+	AND (:bookID = 0 OR l.book_id = :bookID)
+	AND (
+		:status = ''
+		OR (:status = 'open' AND l.returned_at IS NULL)
+		OR (:status = 'overdue' AND l.returned_at IS NULL AND l.due_at < :today::date)
+		OR (:status = 'returned' AND l.returned_at IS NOT NULL)
+	)
+)`
+
+func newLoan() *models.Loan {
+	return &models.Loan{
+		Book: &models.Book{},
+	}
+}
+
+func (r *loanRepository) GetAll(
+	search filters.LoanFilters,
+	userID int64,
+	f filters.Filters,
+) ([]*models.Loan, filters.Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		count(*) OVER(),
+		%s
+	FROM loans l
+	JOIN books b ON b.id = l.book_id AND b.deleted = false
+	LEFT JOIN users u ON u.id = b.user_id
+	WHERE
+		%s
+		AND l.user_id = :userID
+		AND l.deleted = false
+	ORDER BY
+		l.%s %s NULLS LAST,
+		l.id ASC
+	LIMIT :limit
+	OFFSET :offset
+	`, loanColumns(), loanSearchCondition, f.SortColumn(), f.SortDirection())
+
+	params := map[string]any{
+		"search": search.Query,
+		"bookID": search.BookID,
+		"status": search.Status,
+		"today":  search.Today,
+		"userID": userID,
+		"limit":  f.Limit(),
+		"offset": f.Offset(),
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return paginatedQuery(r.db, query, args, f, newLoan)
+}
+
+func (r *loanRepository) GetByID(id, userID int64) (*models.Loan, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from loans l
+	join books b on b.id = l.book_id and b.deleted = false
+	left join users u on u.id = b.user_id
+	where
+		l.id = :id
+		and l.user_id = :userID
+		and l.deleted = false
+	`, loanColumns())
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.Loan](r.db, query, args)
+}
+
+// GetOpenByBookIDs returns the open loan of each of the books that have one,
+// by book ID, without their books.
+func (r *loanRepository) GetOpenByBookIDs(bookIDs []int64) (map[int64]*models.Loan, error) {
+	result := make(map[int64]*models.Loan, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+	select book_id, id, borrower_name, lent_at, due_at, version
+	from loans
+	where book_id = any($1) and returned_at is null and deleted = false
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(bookIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int64
+		var loan models.Loan
+		err := rows.Scan(
+			&bookID,
+			&loan.ID,
+			&loan.BorrowerName,
+			&loan.LentAt,
+			&loan.DueAt,
+			&loan.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result[bookID] = &loan
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetUnremindedOverdue returns up to limit open loans of any user that are
+// overdue in the lender's time zone and were not reminded of yet, the
+// longest overdue first.
+func (r *loanRepository) GetUnremindedOverdue(limit int) ([]*models.Loan, error) {
+	query := fmt.Sprintf(`
+	select %s
+	from loans l
+	join books b on b.id = l.book_id and b.deleted = false
+	join users u on u.id = l.user_id and u.deleted = false
+	left join user_preferences p on p.user_id = l.user_id
+	where
+		l.deleted = false
+		and l.returned_at is null
+		and l.reminded_at is null
+		and l.due_at < (now() at time zone coalesce(p.timezone, 'UTC'))::date
+	order by l.due_at, l.id
+	limit $1
+	`, loanColumns())
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return listQuery(r.db, query, []any{limit}, newLoan)
+}
+
+func (r *loanRepository) Insert(tx *sql.Tx, loan *models.Loan, userID int64) error {
+	query := `
+	insert into loans (
+		book_id,
+		borrower_name,
+		borrower_id,
+		borrower_email,
+		lent_at,
+		due_at,
+		returned_at,
+		notes,
+		user_id,
+		created_by
+	)
+	values (
+		:book,
+		:borrowerName,
+		:borrowerID,
+		:borrowerEmail,
+		:lent,
+		:due,
+		:returned,
+		:notes,
+		:userID,
+		:userID
+	)
+	returning id, created_at, version
+	`
+
+	params := map[string]any{
+		"book":          loan.Book.ID,
+		"borrowerName":  loan.BorrowerName,
+		"borrowerID":    loan.BorrowerID,
+		"borrowerEmail": loan.BorrowerEmail,
+		"lent":          loan.LentAt,
+		"due":           loan.DueAt,
+		"returned":      loan.ReturnedAt,
+		"notes":         loan.Notes,
+		"userID":        userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&loan.ID,
+		&loan.CreatedAt,
+		&loan.Version,
+	)
+	return parseLoanConstraintError(err)
+}
+
+// Update saves loan, clearing the reminder sent for it when its due date
+// changes so a new one is sent once it is overdue again.
+func (r *loanRepository) Update(tx *sql.Tx, loan *models.Loan, userID int64) error {
+	query := `
+	update loans set
+		book_id = :book,
+		borrower_name = :borrowerName,
+		borrower_id = :borrowerID,
+		borrower_email = :borrowerEmail,
+		lent_at = :lent,
+		due_at = :due,
+		returned_at = :returned,
+		notes = :notes,
+		reminded_at = case when due_at is distinct from :due::date then null else reminded_at end,
+		updated_at = now(),
+		updated_by = :userID,
+		version = version + 1
+	where
+		id = :id
+		and version = :version
+		and deleted = false
+		and user_id = :userID
+	returning created_at, version
+	`
+
+	params := map[string]any{
+		"id":            loan.ID,
+		"book":          loan.Book.ID,
+		"borrowerName":  loan.BorrowerName,
+		"borrowerID":    loan.BorrowerID,
+		"borrowerEmail": loan.BorrowerEmail,
+		"lent":          loan.LentAt,
+		"due":           loan.DueAt,
+		"returned":      loan.ReturnedAt,
+		"notes":         loan.Notes,
+		"userID":        userID,
+		"version":       loan.Version,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&loan.CreatedAt, &loan.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrEditConflict
+		}
+		return parseLoanConstraintError(err)
+	}
+
+	return nil
+}
+
+func (r *loanRepository) MarkReminded(tx *sql.Tx, id int64) error {
+	query := `
+	update loans set
+		reminded_at = now()
+	where id = $1
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *loanRepository) Delete(tx *sql.Tx, id, userID int64) error {
+	query := `
+	update loans set
+		deleted = true,
+		deleted_at = now(),
+		updated_at = now(),
+		updated_by = $2
+	where
+		id = $1
+		and user_id = $2
+		and deleted = false
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return e.ErrRecordNotFound
+	}
+
+	return nil
+}
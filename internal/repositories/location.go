@@ -0,0 +1,416 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type locationRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type LocationRepository interface {
+	GetAll(userID int64) ([]*models.Location, error)
+	GetByID(id, userID int64) (*models.Location, error)
+	FindBooks(search string, userID int64, limit int) ([]*models.Book, error)
+	Insert(tx *sql.Tx, location *models.Location, userID int64) error
+	Update(tx *sql.Tx, location *models.Location, userID int64) error
+	Delete(tx *sql.Tx, id, userID int64) error
+	AddBooks(tx *sql.Tx, id, userID int64, bookIDs []int64) (int64, error)
+	RemoveBooks(tx *sql.Tx, id, userID int64, bookIDs []int64) (int64, error)
+}
+
+func NewLocationRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *locationRepository {
+	return &locationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func parseLocationConstraintError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "unique_location_name_per_parent":
+			return e.ErrDuplicateName
+		}
+	}
+	return err
+}
+
+// GetAll returns every location of the user with the number of books kept
+// directly in it, without their paths.
+func (r *locationRepository) GetAll(userID int64) ([]*models.Location, error) {
+	query := `
+	select
+		l.id,
+		l.parent_id,
+		l.name,
+		l.kind,
+		l.created_at,
+		l.version,
+		count(b.id)
+	from locations l
+	left join books b on b.location_id = l.id and b.deleted = false
+	where
+		l.user_id = :userID
+		and l.deleted = false
+	group by l.id
+	order by l.id
+	`
+
+	params := map[string]any{
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locations := []*models.Location{}
+	for rows.Next() {
+		var location models.Location
+		err := rows.Scan(
+			&location.ID,
+			&location.ParentID,
+			&location.Name,
+			&location.Kind,
+			&location.CreatedAt,
+			&location.Version,
+			&location.BookCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, &location)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+func (r *locationRepository) GetByID(id, userID int64) (*models.Location, error) {
+	query := `
+	select
+		id,
+		parent_id,
+		name,
+		kind,
+		created_at,
+		version
+	from locations
+	where
+		id = :id
+		and user_id = :userID
+		and deleted = false
+	`
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var location models.Location
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&location.ID,
+		&location.ParentID,
+		&location.Name,
+		&location.Kind,
+		&location.CreatedAt,
+		&location.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, e.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &location, nil
+}
+
+// FindBooks returns up to limit books of the user whose title or author
+// contains search, or whose ISBN is search, with their location IDs. Books
+// kept somewhere come first.
+func (r *locationRepository) FindBooks(search string, userID int64, limit int) ([]*models.Book, error) {
+	query := `
+	select
+		b.id,
+		b.title,
+		b.author,
+		b.format,
+		b.location_id
+	from books b
+	where
+		b.user_id = :userID
+		and b.deleted = false
+		and (
+			b.title ILIKE '%' || :search || '%'
+			OR b.author ILIKE '%' || :search || '%'
+			OR b.isbn = :search
+		)
+	order by
+		b.location_id is null,
+		b.title,
+		b.id
+	limit :limit
+	`
+
+	params := map[string]any{
+		"search": search,
+		"userID": userID,
+		"limit":  limit,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	books := []*models.Book{}
+	for rows.Next() {
+		var book models.Book
+		err := rows.Scan(&book.ID, &book.Title, &book.Author, &book.Format, &book.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, &book)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+func (r *locationRepository) Insert(tx *sql.Tx, location *models.Location, userID int64) error {
+	query := `
+	insert into locations (
+		parent_id,
+		name,
+		kind,
+		user_id,
+		created_by
+	)
+	values (
+		:parent,
+		:name,
+		:kind,
+		:userID,
+		:userID
+	)
+	returning id, created_at, version
+	`
+
+	params := map[string]any{
+		"parent": location.ParentID,
+		"name":   location.Name,
+		"kind":   location.Kind,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&location.ID,
+		&location.CreatedAt,
+		&location.Version,
+	)
+	return parseLocationConstraintError(err)
+}
+
+func (r *locationRepository) Update(tx *sql.Tx, location *models.Location, userID int64) error {
+	query := `
+	update locations set
+		parent_id = :parent,
+		name = :name,
+		kind = :kind,
+		updated_at = now(),
+		updated_by = :userID,
+		version = version + 1
+	where
+		id = :id
+		and version = :version
+		and deleted = false
+		and user_id = :userID
+	returning created_at, version
+	`
+
+	params := map[string]any{
+		"id":      location.ID,
+		"parent":  location.ParentID,
+		"name":    location.Name,
+		"kind":    location.Kind,
+		"userID":  userID,
+		"version": location.Version,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&location.CreatedAt, &location.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrEditConflict
+		}
+		return parseLocationConstraintError(err)
+	}
+
+	return nil
+}
+
+// Delete removes the location, moving the locations and books inside it to
+// its parent, or out of any location when it has none. Moved locations
+// whose names are taken in the parent fail the delete.
+func (r *locationRepository) Delete(tx *sql.Tx, id, userID int64) error {
+	query := `
+	update locations set
+		deleted = true,
+		deleted_at = now(),
+		updated_at = now(),
+		updated_by = :userID
+	where
+		id = :id
+		and user_id = :userID
+		and deleted = false
+	returning parent_id
+	`
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var parentID *int64
+	if err := tx.QueryRowContext(ctx, query, args...).Scan(&parentID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrRecordNotFound
+		}
+		return err
+	}
+
+	query = `
+	update locations set
+		parent_id = $2,
+		updated_at = now(),
+		version = version + 1
+	where parent_id = $1 and deleted = false
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	if _, err := tx.ExecContext(ctx, query, id, parentID); err != nil {
+		return parseLocationConstraintError(err)
+	}
+
+	query = `update books set location_id = $2 where location_id = $1`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	_, err := tx.ExecContext(ctx, query, id, parentID)
+	return err
+}
+
+// AddBooks keeps the given books of the user in the location, taking them
+// out of wherever they were, and returns how many were moved.
+func (r *locationRepository) AddBooks(tx *sql.Tx, id, userID int64, bookIDs []int64) (int64, error) {
+	query := `
+	update books set
+		location_id = :location,
+		updated_at = now(),
+		updated_by = :userID,
+		version = version + 1
+	where
+		id = any(:bookIDs)
+		and user_id = :userID
+		and deleted = false
+		and location_id is distinct from :location
+	`
+
+	return r.placeBooks(tx, query, id, userID, bookIDs)
+}
+
+// RemoveBooks takes the given books of the user out of the location and
+// returns how many were in it.
+func (r *locationRepository) RemoveBooks(tx *sql.Tx, id, userID int64, bookIDs []int64) (int64, error) {
+	query := `
+	update books set
+		location_id = null,
+		updated_at = now(),
+		updated_by = :userID,
+		version = version + 1
+	where
+		id = any(:bookIDs)
+		and user_id = :userID
+		and deleted = false
+		and location_id = :location
+	`
+
+	return r.placeBooks(tx, query, id, userID, bookIDs)
+}
+
+func (r *locationRepository) placeBooks(tx *sql.Tx, query string, id, userID int64, bookIDs []int64) (int64, error) {
+	params := map[string]any{
+		"location": id,
+		"userID":   userID,
+		"bookIDs":  pq.Array(bookIDs),
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
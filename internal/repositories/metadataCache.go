@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/metadata"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type metadataCacheRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type MetadataCacheRepository interface {
+	Get(isbn string, maxAge time.Duration) (*metadata.Metadata, error)
+	Put(isbn string, metadata *metadata.Metadata) error
+}
+
+func NewMetadataCacheRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *metadataCacheRepository {
+	return &metadataCacheRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *metadataCacheRepository) Get(isbn string, maxAge time.Duration) (*metadata.Metadata, error) {
+	query := `
+	select data
+	from book_metadata_cache
+	where
+		isbn = $1
+		and fetched_at > now() - make_interval(secs => $2)
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var data []byte
+	err := r.db.QueryRowContext(ctx, query, isbn, maxAge.Seconds()).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, e.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	var result metadata.Metadata
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *metadataCacheRepository) Put(isbn string, m *metadata.Metadata) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	insert into book_metadata_cache (isbn, data, fetched_at)
+	values ($1, $2, now())
+	on conflict (isbn) do update set
+		data = excluded.data,
+		fetched_at = excluded.fetched_at
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err = r.db.ExecContext(ctx, query, isbn, data)
+	return err
+}
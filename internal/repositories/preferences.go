@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type preferencesRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type PreferencesRepository interface {
+	GetByUserID(userID int64) (*models.Preferences, error)
+	Insert(tx *sql.Tx, preferences *models.Preferences) error
+	Update(tx *sql.Tx, preferences *models.Preferences) error
+}
+
+func NewPreferencesRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *preferencesRepository {
+	return &preferencesRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *preferencesRepository) GetByUserID(userID int64) (*models.Preferences, error) {
+	query := fmt.Sprintf(`
+	select
+		%s
+	from user_preferences p
+	where
+		p.user_id = :userID
+		and p.deleted = false
+	`, selectColumns(models.Preferences{}, "p"))
+
+	params := map[string]any{
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.Preferences](r.db, query, args)
+}
+
+func (r *preferencesRepository) Insert(tx *sql.Tx, preferences *models.Preferences) error {
+	query := `
+	insert into user_preferences (
+		user_id,
+		timezone,
+		locale,
+		progress_unit,
+		week_start,
+		page_size,
+		created_by
+	)
+	values (
+		:user_id,
+		:timezone,
+		:locale,
+		:progress_unit,
+		:week_start,
+		:page_size,
+		:user_id
+	)
+	returning created_at, version
+	`
+
+	params := map[string]any{
+		"user_id":       preferences.UserID,
+		"timezone":      preferences.Timezone,
+		"locale":        preferences.Locale,
+		"progress_unit": preferences.ProgressUnit,
+		"week_start":    preferences.WeekStart,
+		"page_size":     preferences.PageSize,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&preferences.CreatedAt,
+		&preferences.Version,
+	)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "user_preferences_pkey" {
+			return e.ErrEditConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *preferencesRepository) Update(tx *sql.Tx, preferences *models.Preferences) error {
+	query := `
+	update user_preferences set
+		timezone = :timezone,
+		locale = :locale,
+		progress_unit = :progress_unit,
+		week_start = :week_start,
+		page_size = :page_size,
+		updated_at = now(),
+		updated_by = :user_id,
+		version = version + 1
+	where
+		user_id = :user_id
+		and version = :version
+		and deleted = false
+	returning version
+	`
+
+	params := map[string]any{
+		"user_id":       preferences.UserID,
+		"timezone":      preferences.Timezone,
+		"locale":        preferences.Locale,
+		"progress_unit": preferences.ProgressUnit,
+		"week_start":    preferences.WeekStart,
+		"page_size":     preferences.PageSize,
+		"version":       preferences.Version,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&preferences.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrEditConflict
+		}
+		return err
+	}
+
+	return nil
+}
@@ -12,6 +12,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type readingPlanRepository struct {
@@ -51,6 +53,7 @@ type ReadingPlanRepository interface {
 		f filters.Filters,
 	) ([]*models.ReadingPlan, filters.Metadata, error)
 	GetByID(id, userID int64) (*models.ReadingPlan, error)
+	GetByBookIDs(bookIDs []int64, userID int64) ([]*models.ReadingPlan, error)
 	Insert(
 		tx *sql.Tx,
 		plan *models.ReadingPlan,
@@ -67,6 +70,23 @@ type ReadingPlanRepository interface {
 	) error
 }
 
+type ReadingSessionRepository interface {
+	GetByPlanIDs(planIDs []int64, userID int64) ([]*models.ReadingSession, error)
+	Record(tx *sql.Tx, session *models.ReadingSession, userID int64) error
+}
+
+// readingPlanColumns lists the columns in the order a ReadingPlan is scanned:
+// the plan itself, its book, the book's owner and the plan's owner, who is the
+// same user.
+func readingPlanColumns() string {
+	return strings.Join([]string{
+		selectColumns(models.ReadingPlan{}, "r"),
+		selectColumns(models.Book{}, "b"),
+		selectColumns(models.User{}, "u"),
+		selectColumns(models.User{}, "u"),
+	}, ", ")
+}
+
 func (r *readingPlanRepository) GetAll(
 	status *models.ReadingStatus,
 	startDate *time.Time,
@@ -74,11 +94,7 @@ func (r *readingPlanRepository) GetAll(
 	userID, bookID int64,
 	f filters.Filters,
 ) ([]*models.ReadingPlan, filters.Metadata, error) {
-	cols := strings.Join([]string{
-		selectColumns(models.ReadingPlan{}, "r"),
-		selectColumns(models.Book{}, "b"),
-		selectColumns(models.User{}, "u"),
-	}, ", ")
+	cols := readingPlanColumns()
 
 	query := fmt.Sprintf(`
         SELECT
@@ -88,10 +104,11 @@ func (r *readingPlanRepository) GetAll(
         LEFT JOIN users u ON u.id = r.user_id
         LEFT JOIN books b ON b.id = r.book_id
         WHERE
-            (:status is null or r.status = :status)
+            (:status::int IS NULL OR r.status = :status::int)
 			AND (:startDate::timestamptz IS NULL OR r.start_date >= :startDate::timestamptz)
 			AND (:targetDate::timestamptz IS NULL OR r.target_date <= :targetDate::timestamptz)
             AND b.deleted = false
+			and r.deleted = false
 			and r.user_id = :userID
 			and r.book_id = :bookID
         ORDER BY
@@ -114,7 +131,7 @@ func (r *readingPlanRepository) GetAll(
 	}
 
 	params := map[string]any{
-		"startDate":  startDate,
+		"startDate":  start,
 		"targetDate": target,
 		"userID":     userID,
 		"bookID":     bookID,
@@ -141,21 +158,18 @@ func (r *readingPlanRepository) GetAll(
 }
 
 func (r *readingPlanRepository) GetByID(id, userID int64) (*models.ReadingPlan, error) {
-	cols := strings.Join([]string{
-		selectColumns(models.ReadingPlan{}, "r"),
-		selectColumns(models.Book{}, "b"),
-		selectColumns(models.User{}, "u"),
-	}, ", ")
+	cols := readingPlanColumns()
 	query := fmt.Sprintf(`
 	select
 		%s
 	FROM reading_plans r
     LEFT JOIN users u ON u.id = r.user_id
-    LEFT JOIN books b ON b.id = r.book_id	
+    LEFT JOIN books b ON b.id = r.book_id
 	where
-		r.id = :id,
+		r.id = :id
 		and r.user_id = :userID
 		and r.deleted = false
+		and b.deleted = false
 	`, cols)
 
 	params := map[string]any{
@@ -168,6 +182,42 @@ func (r *readingPlanRepository) GetByID(id, userID int64) (*models.ReadingPlan,
 	return getByQuery[models.ReadingPlan](r.db, query, args)
 }
 
+// GetByBookIDs returns the plans of the given books ordered by book and
+// start.
+func (r *readingPlanRepository) GetByBookIDs(bookIDs []int64, userID int64) ([]*models.ReadingPlan, error) {
+	if len(bookIDs) == 0 {
+		return []*models.ReadingPlan{}, nil
+	}
+
+	query := fmt.Sprintf(`
+	select
+		%s
+	from reading_plans r
+	join books b on b.id = r.book_id
+	left join users u on u.id = r.user_id
+	where
+		r.book_id = any(:bookIDs)
+		and r.user_id = :userID
+		and r.deleted = false
+	order by r.book_id, r.start_date nulls first, r.id
+	`, readingPlanColumns())
+
+	params := map[string]any{
+		"bookIDs": pq.Array(bookIDs),
+		"userID":  userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return listQuery(r.db, query, args, func() *models.ReadingPlan {
+		return &models.ReadingPlan{
+			User: &models.User{},
+			Book: &models.Book{},
+		}
+	})
+}
+
 func (r *readingPlanRepository) Insert(
 	tx *sql.Tx,
 	plan *models.ReadingPlan,
@@ -294,6 +344,7 @@ func (r *readingPlanRepository) Delete(
 	query := `
 	update reading_plans set
 		deleted = true,
+		deleted_at = now(),
 		updated_at = now(),
 		updated_by = :user_id
 	where
@@ -329,3 +380,141 @@ func (r *readingPlanRepository) Delete(
 
 	return nil
 }
+
+// GetByPlanIDs returns the sessions logged against the given plans ordered by
+// plan and date.
+func (r *readingSession) GetByPlanIDs(planIDs []int64, userID int64) ([]*models.ReadingSession, error) {
+	sessions := []*models.ReadingSession{}
+	if len(planIDs) == 0 {
+		return sessions, nil
+	}
+
+	query := `
+	select id, reading_plan_id, pages_read, minutes, chapter, notes, date, created_at
+	from reading_sessions
+	where
+		reading_plan_id = any($1)
+		and user_id = $2
+		and deleted = false
+	order by reading_plan_id, date, id
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(planIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var session models.ReadingSession
+		err := rows.Scan(
+			&session.ID,
+			&session.ReadingPlan.ID,
+			&session.PagesRead,
+			&session.Minutes,
+			&session.Chapter,
+			&session.Notes,
+			&session.Date,
+			&session.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// Record adds the pages and minutes of session to the session of the same
+// plan, day and notes, creating that session when there is none yet. Syncs
+// from devices arrive in small steps and end up as one session a day, at
+// the last chapter they reached.
+func (r *readingSession) Record(tx *sql.Tx, session *models.ReadingSession, userID int64) error {
+	query := `
+	update reading_sessions set
+		pages_read = pages_read + :pagesRead,
+		minutes = minutes + :minutes,
+		chapter = coalesce(:chapter::integer, chapter),
+		updated_at = now(),
+		updated_by = :userID,
+		version = version + 1
+	where id = (
+		select id
+		from reading_sessions
+		where
+			reading_plan_id = :planID
+			and user_id = :userID
+			and date = :day::date
+			and notes = :notes
+			and deleted = false
+		order by id
+		limit 1
+	)
+	returning id, pages_read, minutes, chapter, created_at, version
+	`
+
+	params := map[string]any{
+		"planID":    session.ReadingPlan.ID,
+		"userID":    userID,
+		"pagesRead": session.PagesRead,
+		"minutes":   session.Minutes,
+		"chapter":   session.Chapter,
+		"notes":     session.Notes,
+		"day":       session.Date.Format("2006-01-02"),
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&session.ID,
+		&session.PagesRead,
+		&session.Minutes,
+		&session.Chapter,
+		&session.CreatedAt,
+		&session.Version,
+	)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	query = `
+	insert into reading_sessions (
+		reading_plan_id,
+		user_id,
+		pages_read,
+		minutes,
+		chapter,
+		notes,
+		date,
+		created_by
+	)
+	values ($1, $2, $3, $4, $5, $6, $7::date, $2)
+	returning id, created_at, version
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return tx.QueryRowContext(
+		ctx,
+		query,
+		session.ReadingPlan.ID,
+		userID,
+		session.PagesRead,
+		session.Minutes,
+		session.Chapter,
+		session.Notes,
+		session.Date.Format("2006-01-02"),
+	).Scan(&session.ID, &session.CreatedAt, &session.Version)
+}
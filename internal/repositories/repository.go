@@ -14,9 +14,26 @@ import (
 )
 
 type Repository struct {
-	User        UserRepositoryInterface
-	Book        BookRepository
-	ReadingPlan ReadingPlanRepository
+	User           UserRepositoryInterface
+	Book           BookRepository
+	ReadingPlan    ReadingPlanRepository
+	ReadingSession ReadingSessionRepository
+	Preferences    PreferencesRepository
+	Shelf          ShelfRepository
+	Author         AuthorRepository
+	Series         SeriesRepository
+	MetadataCache  MetadataCacheRepository
+	Trash          TrashRepository
+	Review         ReviewRepository
+	Highlight      HighlightRepository
+	LibraryImport  LibraryImportRepository
+	BookFile       BookFileRepository
+	FeedToken      FeedTokenRepository
+	KOReader       KOReaderRepository
+	Kobo           KoboRepository
+	Loan           LoanRepository
+	Location       LocationRepository
+	Chapter        ChapterRepository
 }
 
 type FactoryFunc[T any] func() *T
@@ -26,9 +43,26 @@ func NewRepository(
 	db *sql.DB,
 ) *Repository {
 	return &Repository{
-		User:        NewUserRepository(db, logger),
-		Book:        NewBookRepository(db, logger),
-		ReadingPlan: NewReadingPlanRepository(db, logger),
+		User:           NewUserRepository(db, logger),
+		Book:           NewBookRepository(db, logger),
+		ReadingPlan:    NewReadingPlanRepository(db, logger),
+		ReadingSession: NewReadingSessionRepository(db, logger),
+		Preferences:    NewPreferencesRepository(db, logger),
+		Shelf:          NewShelfRepository(db, logger),
+		Author:         NewAuthorRepository(db, logger),
+		Series:         NewSeriesRepository(db, logger),
+		MetadataCache:  NewMetadataCacheRepository(db, logger),
+		Trash:          NewTrashRepository(db, logger),
+		Review:         NewReviewRepository(db, logger),
+		Highlight:      NewHighlightRepository(db, logger),
+		LibraryImport:  NewLibraryImportRepository(db, logger),
+		BookFile:       NewBookFileRepository(db, logger),
+		FeedToken:      NewFeedTokenRepository(db, logger),
+		KOReader:       NewKOReaderRepository(db, logger),
+		Kobo:           NewKoboRepository(db, logger),
+		Loan:           NewLoanRepository(db, logger),
+		Location:       NewLocationRepository(db, logger),
+		Chapter:        NewChapterRepository(db, logger),
 	}
 }
 
@@ -168,6 +202,45 @@ func paginatedQuery[T any](
 	return models, metaData, nil
 }
 
+// listQuery runs a query that is not paginated and scans every row into a
+// model made by factory.
+func listQuery[T any](
+	db *sql.DB,
+	query string,
+	args []any,
+	factory FactoryFunc[T],
+) ([]*T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	models := []*T{}
+	for rows.Next() {
+		model := factory()
+
+		fields, err := collectFields(model)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := rows.Scan(fields...); err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return models, nil
+}
+
 func getByQuery[T any](
 	db *sql.DB,
 	query string,
@@ -0,0 +1,273 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type reviewRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type ReviewRepository interface {
+	GetByBookID(bookID, userID int64) (*models.Review, error)
+	GetByBookIDs(bookIDs []int64) (map[int64]*models.Review, error)
+	Insert(tx *sql.Tx, review *models.Review, userID int64) error
+	Update(tx *sql.Tx, review *models.Review, userID int64) error
+	Delete(tx *sql.Tx, bookID, userID int64) error
+}
+
+func NewReviewRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *reviewRepository {
+	return &reviewRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func parseReviewConstraintError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "unique_review_per_book":
+			return e.ErrReviewExists
+		case "chk_reviews_rating":
+			return e.ErrReviewRating
+		}
+	}
+	return err
+}
+
+// bookRatingCondition restricts books aliased as b to the ones whose review
+// rating lies between :minRating and :maxRating. Unreviewed books only match
+// when neither bound is set.
+const bookRatingCondition = `(
+	(:minRating::numeric IS NULL AND :maxRating::numeric IS NULL)
+	OR exists (
+		select 1
+		from reviews rr
+		where rr.book_id = b.id
+			and rr.deleted = false
+			and (:minRating::numeric IS NULL OR rr.rating >= :minRating::numeric)
+			and (:maxRating::numeric IS NULL OR rr.rating <= :maxRating::numeric)
+	)
+)`
+
+func (r *reviewRepository) GetByBookID(bookID, userID int64) (*models.Review, error) {
+	query := fmt.Sprintf(`
+	select
+		%s
+	from reviews rv
+	where
+		rv.book_id = :bookID
+		and rv.user_id = :userID
+		and rv.deleted = false
+	`, selectColumns(models.Review{}, "rv"))
+
+	params := map[string]any{
+		"bookID": bookID,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.Review](r.db, query, args)
+}
+
+func (r *reviewRepository) GetByBookIDs(bookIDs []int64) (map[int64]*models.Review, error) {
+	result := make(map[int64]*models.Review, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+	select id, book_id, rating, body, spoiler, reviewed_at
+	from reviews
+	where book_id = any($1) and deleted = false
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(bookIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review models.Review
+		err := rows.Scan(
+			&review.ID,
+			&review.BookID,
+			&review.Rating,
+			&review.Body,
+			&review.Spoiler,
+			&review.ReviewedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result[review.BookID] = &review
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *reviewRepository) Insert(tx *sql.Tx, review *models.Review, userID int64) error {
+	query := `
+	insert into reviews (
+		book_id,
+		rating,
+		body,
+		spoiler,
+		reviewed_at,
+		user_id,
+		created_by
+	)
+	values (
+		:book_id,
+		:rating,
+		:body,
+		:spoiler,
+		:reviewed_at,
+		:user_id,
+		:user_id
+	)
+	returning id, created_at, version
+	`
+
+	params := map[string]any{
+		"book_id":     review.BookID,
+		"rating":      review.Rating,
+		"body":        review.Body,
+		"spoiler":     review.Spoiler,
+		"reviewed_at": review.ReviewedAt,
+		"user_id":     userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&review.ID,
+		&review.CreatedAt,
+		&review.Version,
+	)
+
+	if err != nil {
+		return parseReviewConstraintError(err)
+	}
+
+	return nil
+}
+
+func (r *reviewRepository) Update(tx *sql.Tx, review *models.Review, userID int64) error {
+	query := `
+	update reviews set
+		rating = :rating,
+		body = :body,
+		spoiler = :spoiler,
+		reviewed_at = :reviewed_at,
+		updated_at = now(),
+		updated_by = :user_id,
+		version = version + 1
+	where
+		book_id = :book_id
+		and version = :version
+		and deleted = false
+		and user_id = :user_id
+	returning id, created_at, updated_at, version
+	`
+
+	params := map[string]any{
+		"book_id":     review.BookID,
+		"rating":      review.Rating,
+		"body":        review.Body,
+		"spoiler":     review.Spoiler,
+		"reviewed_at": review.ReviewedAt,
+		"user_id":     userID,
+		"version":     review.Version,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&review.ID,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+		&review.Version,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrEditConflict
+		}
+		return parseReviewConstraintError(err)
+	}
+
+	return nil
+}
+
+func (r *reviewRepository) Delete(tx *sql.Tx, bookID, userID int64) error {
+	query := `
+	update reviews set
+		deleted = true,
+		deleted_at = now(),
+		updated_at = now(),
+		updated_by = :user_id
+	where
+		book_id = :book_id
+		and user_id = :user_id
+		and deleted = false
+	`
+
+	params := map[string]any{
+		"book_id": bookID,
+		"user_id": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return e.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type seriesRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type SeriesRepository interface {
+	GetAll(name string, userID int64, f filters.Filters) ([]*models.Series, filters.Metadata, error)
+	GetByID(id, userID int64) (*models.Series, error)
+	GetByIDs(ids []int64) (map[int64]*models.Series, error)
+	LoadEntries(series []*models.Series, userID int64) error
+	Resolve(tx *sql.Tx, series *models.Series, userID int64) error
+	Update(tx *sql.Tx, series *models.Series, userID int64) error
+}
+
+func NewSeriesRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *seriesRepository {
+	return &seriesRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func parseSeriesConstraintError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "unique_series_name_per_user":
+			return e.ErrDuplicateName
+		}
+	}
+	return err
+}
+
+func (r *seriesRepository) GetAll(
+	name string,
+	userID int64,
+	f filters.Filters,
+) ([]*models.Series, filters.Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		count(*) OVER(),
+		%s
+	FROM series s
+	WHERE
+		(to_tsvector('simple', s.name) @@ plainto_tsquery('simple', :name) OR :name = '')
+		AND s.user_id = :userID
+		AND s.deleted = false
+	ORDER BY
+		s.%s %s,
+		s.id ASC
+	LIMIT :limit
+	OFFSET :offset
+	`, selectColumns(models.Series{}, "s"), f.SortColumn(), f.SortDirection())
+
+	params := map[string]any{
+		"name":   name,
+		"userID": userID,
+		"limit":  f.Limit(),
+		"offset": f.Offset(),
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return paginatedQuery(
+		r.db,
+		query,
+		args,
+		f,
+		func() *models.Series {
+			return &models.Series{}
+		},
+	)
+}
+
+func (r *seriesRepository) GetByID(id, userID int64) (*models.Series, error) {
+	query := fmt.Sprintf(`
+	select
+		%s
+	from series s
+	where
+		s.id = :id
+		and s.user_id = :userID
+		and s.deleted = false
+	`, selectColumns(models.Series{}, "s"))
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.Series](r.db, query, args)
+}
+
+func (r *seriesRepository) GetByIDs(ids []int64) (map[int64]*models.Series, error) {
+	result := make(map[int64]*models.Series, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	query := `
+	select id, name, total_count
+	from series
+	where id = any($1)
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var series models.Series
+		if err := rows.Scan(&series.ID, &series.Name, &series.TotalCount); err != nil {
+			return nil, err
+		}
+		result[series.ID] = &series
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LoadEntries fills the Entries of every series with the user's books in
+// reading order. A book counts as read when it has a completed reading plan.
+func (r *seriesRepository) LoadEntries(series []*models.Series, userID int64) error {
+	if len(series) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(series))
+	byID := make(map[int64]*models.Series, len(series))
+	for _, s := range series {
+		ids = append(ids, s.ID)
+		byID[s.ID] = s
+		s.Entries = []models.SeriesEntry{}
+	}
+
+	query := `
+	select
+		b.series_id,
+		b.id,
+		b.title,
+		coalesce(b.series_position, 0),
+		exists (
+			select 1
+			from reading_plans rp
+			where rp.book_id = b.id
+				and rp.status = :completed
+				and rp.deleted = false
+		)
+	from books b
+	where
+		b.series_id = any(:ids)
+		and b.user_id = :userID
+		and b.deleted = false
+	order by b.series_id, b.series_position asc nulls last, b.title
+	`
+
+	params := map[string]any{
+		"ids":       pq.Array(ids),
+		"userID":    userID,
+		"completed": models.ReadingStatusCompleted,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seriesID int64
+		var entry models.SeriesEntry
+		err := rows.Scan(
+			&seriesID,
+			&entry.BookID,
+			&entry.Title,
+			&entry.Position,
+			&entry.Read,
+		)
+		if err != nil {
+			return err
+		}
+		byID[seriesID].Entries = append(byID[seriesID].Entries, entry)
+	}
+
+	return rows.Err()
+}
+
+// Resolve finds the series by ID or, case-insensitively, by name, creating
+// it when it does not exist yet. A non-nil TotalCount overwrites the stored
+// one.
+func (r *seriesRepository) Resolve(tx *sql.Tx, series *models.Series, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `
+	select id, name, total_count, version
+	from series
+	where
+		user_id = :userID
+		and deleted = false
+		and (id = :id or (:id = 0 and lower(name) = lower(:name)))
+	`
+	params := map[string]any{
+		"id":     series.ID,
+		"name":   series.Name,
+		"userID": userID,
+	}
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	var stored models.Series
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&stored.ID,
+		&stored.Name,
+		&stored.TotalCount,
+		&stored.Version,
+	)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && series.ID != 0:
+		return e.ErrSeriesNotFound
+
+	case errors.Is(err, sql.ErrNoRows):
+		query = `
+		insert into series (name, total_count, user_id, created_by)
+		values (:name, :total_count, :userID, :userID)
+		returning id, created_at, version
+		`
+		params = map[string]any{
+			"name":        series.Name,
+			"total_count": series.TotalCount,
+			"userID":      userID,
+		}
+		query, args = namedQuery(query, params)
+		r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&series.ID, &series.CreatedAt, &series.Version)
+		if err != nil {
+			return parseSeriesConstraintError(err)
+		}
+		return nil
+
+	case err != nil:
+		return err
+	}
+
+	series.ID = stored.ID
+	series.Name = stored.Name
+	series.Version = stored.Version
+
+	if series.TotalCount == nil || (stored.TotalCount != nil && *stored.TotalCount == *series.TotalCount) {
+		series.TotalCount = stored.TotalCount
+		return nil
+	}
+
+	return r.Update(tx, series, userID)
+}
+
+func (r *seriesRepository) Update(tx *sql.Tx, series *models.Series, userID int64) error {
+	query := `
+	update series set
+		name = :name,
+		total_count = :total_count,
+		updated_at = now(),
+		updated_by = :user_id,
+		version = version + 1
+	where
+		id = :id
+		and version = :version
+		and deleted = false
+		and user_id = :user_id
+	returning version
+	`
+
+	params := map[string]any{
+		"id":          series.ID,
+		"name":        series.Name,
+		"total_count": series.TotalCount,
+		"user_id":     userID,
+		"version":     series.Version,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&series.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrEditConflict
+		}
+		return parseSeriesConstraintError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,430 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type shelfRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type ShelfRepository interface {
+	GetAll(userID int64) ([]*models.Shelf, error)
+	GetByID(id, userID int64) (*models.Shelf, error)
+	GetNamesByBookIDs(bookIDs []int64) (map[int64][]string, error)
+	CountBooks(search filters.BookFilters, userID int64) ([]*models.Shelf, error)
+	Insert(tx *sql.Tx, shelf *models.Shelf, userID int64) error
+	Resolve(tx *sql.Tx, shelf *models.Shelf, userID int64) error
+	Update(tx *sql.Tx, shelf *models.Shelf, userID int64) error
+	Delete(tx *sql.Tx, id, userID int64) error
+	AddBooks(tx *sql.Tx, id, userID int64, bookIDs []int64) (int64, error)
+	RemoveBooks(tx *sql.Tx, id, userID int64, bookIDs []int64) (int64, error)
+}
+
+func NewShelfRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *shelfRepository {
+	return &shelfRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func parseShelfConstraintError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Constraint {
+		case "unique_shelf_name_per_user":
+			return e.ErrDuplicateName
+		}
+	}
+	return err
+}
+
+func (r *shelfRepository) GetAll(userID int64) ([]*models.Shelf, error) {
+	query := `
+	select
+		s.id,
+		s.name,
+		s.version,
+		count(b.id)
+	from shelves s
+	left join book_shelves bs on bs.shelf_id = s.id
+	left join books b on b.id = bs.book_id and b.deleted = false
+	where
+		s.user_id = :userID
+		and s.deleted = false
+	group by s.id
+	order by s.name asc
+	`
+
+	params := map[string]any{
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return r.queryCounts(query, args)
+}
+
+// CountBooks returns, for every shelf of the user, how many books match
+// search. The shelf selection itself is ignored so the counts can be shown
+// next to a filtered list.
+func (r *shelfRepository) CountBooks(search filters.BookFilters, userID int64) ([]*models.Shelf, error) {
+	query := fmt.Sprintf(`
+	select
+		s.id,
+		s.name,
+		s.version,
+		count(b.id)
+	from shelves s
+	left join book_shelves bs on bs.shelf_id = s.id
+	left join books b on b.id = bs.book_id
+		and %s
+	where
+		s.user_id = :userID
+		and s.deleted = false
+	group by s.id
+	order by s.name asc
+	`, bookFilterCondition)
+
+	params := bookSearchParams(search, userID)
+	delete(params, "shelfIDs")
+	delete(params, "shelfMatchAll")
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return r.queryCounts(query, args)
+}
+
+func (r *shelfRepository) queryCounts(query string, args []any) ([]*models.Shelf, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shelves := []*models.Shelf{}
+	for rows.Next() {
+		var shelf models.Shelf
+		err := rows.Scan(
+			&shelf.ID,
+			&shelf.Name,
+			&shelf.Version,
+			&shelf.BookCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, &shelf)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return shelves, nil
+}
+
+func (r *shelfRepository) GetByID(id, userID int64) (*models.Shelf, error) {
+	query := `
+	select
+		s.id,
+		s.name,
+		s.version,
+		count(b.id)
+	from shelves s
+	left join book_shelves bs on bs.shelf_id = s.id
+	left join books b on b.id = bs.book_id and b.deleted = false
+	where
+		s.id = :id
+		and s.user_id = :userID
+		and s.deleted = false
+	group by s.id
+	`
+
+	params := map[string]any{
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	shelves, err := r.queryCounts(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shelves) == 0 {
+		return nil, e.ErrRecordNotFound
+	}
+
+	return shelves[0], nil
+}
+
+// GetNamesByBookIDs returns the names of the shelves each of the given books
+// is on.
+func (r *shelfRepository) GetNamesByBookIDs(bookIDs []int64) (map[int64][]string, error) {
+	result := make(map[int64][]string, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+	select bs.book_id, s.name
+	from book_shelves bs
+	join shelves s on s.id = bs.shelf_id and s.deleted = false
+	where bs.book_id = any($1)
+	order by bs.book_id, s.name
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(bookIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int64
+		var name string
+		if err := rows.Scan(&bookID, &name); err != nil {
+			return nil, err
+		}
+		result[bookID] = append(result[bookID], name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *shelfRepository) Insert(tx *sql.Tx, shelf *models.Shelf, userID int64) error {
+	query := `
+	insert into shelves (
+		name,
+		user_id,
+		created_by
+	)
+	values (
+		:name,
+		:user_id,
+		:user_id
+	)
+	returning id, created_at, version
+	`
+
+	params := map[string]any{
+		"name":    shelf.Name,
+		"user_id": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
+		&shelf.ID,
+		&shelf.CreatedAt,
+		&shelf.Version,
+	)
+
+	if err != nil {
+		return parseShelfConstraintError(err)
+	}
+
+	return nil
+}
+
+// Resolve finds the shelf case-insensitively by name, creating it when it
+// does not exist yet.
+func (r *shelfRepository) Resolve(tx *sql.Tx, shelf *models.Shelf, userID int64) error {
+	query := `
+	select id, name, version
+	from shelves
+	where
+		user_id = :userID
+		and deleted = false
+		and lower(name) = lower(:name)
+	`
+	params := map[string]any{
+		"name":   shelf.Name,
+		"userID": userID,
+	}
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&shelf.ID, &shelf.Name, &shelf.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.Insert(tx, shelf, userID)
+	}
+	return err
+}
+
+func (r *shelfRepository) Update(tx *sql.Tx, shelf *models.Shelf, userID int64) error {
+	query := `
+	update shelves set
+		name = :name,
+		updated_at = now(),
+		updated_by = :user_id,
+		version = version + 1
+	where
+		id = :id
+		and version = :version
+		and deleted = false
+		and user_id = :user_id
+	returning version
+	`
+
+	params := map[string]any{
+		"id":      shelf.ID,
+		"name":    shelf.Name,
+		"user_id": userID,
+		"version": shelf.Version,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&shelf.Version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.ErrEditConflict
+		}
+		return parseShelfConstraintError(err)
+	}
+
+	return nil
+}
+
+func (r *shelfRepository) Delete(tx *sql.Tx, id, userID int64) error {
+	query := `
+	update shelves set
+		deleted = true,
+		deleted_at = now(),
+		updated_at = now(),
+		updated_by = :user_id
+	where
+		id = :id
+		and user_id = :user_id
+		and deleted = false
+	`
+
+	params := map[string]any{
+		"id":      id,
+		"user_id": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return e.ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `delete from book_shelves where shelf_id = $1`, id)
+	return err
+}
+
+func (r *shelfRepository) AddBooks(tx *sql.Tx, id, userID int64, bookIDs []int64) (int64, error) {
+	query := `
+	insert into book_shelves (book_id, shelf_id)
+	select b.id, s.id
+	from books b
+	join shelves s on s.user_id = b.user_id
+	where
+		s.id = :id
+		and s.deleted = false
+		and b.id = any(:bookIDs)
+		and b.user_id = :userID
+		and b.deleted = false
+	on conflict do nothing
+	`
+
+	params := map[string]any{
+		"id":      id,
+		"userID":  userID,
+		"bookIDs": pq.Array(bookIDs),
+	}
+
+	return r.execBooks(tx, query, params)
+}
+
+func (r *shelfRepository) RemoveBooks(tx *sql.Tx, id, userID int64, bookIDs []int64) (int64, error) {
+	query := `
+	delete from book_shelves bs
+	using shelves s
+	where
+		s.id = bs.shelf_id
+		and s.id = :id
+		and s.user_id = :userID
+		and bs.book_id = any(:bookIDs)
+	`
+
+	params := map[string]any{
+		"id":      id,
+		"userID":  userID,
+		"bookIDs": pq.Array(bookIDs),
+	}
+
+	return r.execBooks(tx, query, params)
+}
+
+func (r *shelfRepository) execBooks(tx *sql.Tx, query string, params map[string]any) (int64, error) {
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,347 @@
+package repositories
+
+import (
+	"bookwise/internal/jsonlog"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type trashRepository struct {
+	db     *sql.DB
+	logger jsonlog.Logger
+}
+
+type TrashRepository interface {
+	GetAll(itemType models.TrashItemType, userID int64, f filters.Filters) ([]*models.TrashItem, filters.Metadata, error)
+	GetBook(bookID, userID int64) (*models.TrashItem, error)
+	GetPlan(planID, userID int64) (*models.TrashItem, error)
+	TitleTaken(tx *sql.Tx, title string, userID int64) (bool, error)
+	RestoreBook(tx *sql.Tx, bookID, userID int64, title string) error
+	RestorePlan(tx *sql.Tx, planID, userID int64) error
+	PurgeBook(tx *sql.Tx, bookID, userID int64) (string, error)
+	PurgePlan(tx *sql.Tx, planID, userID int64) error
+	Empty(tx *sql.Tx, userID int64) ([]string, error)
+	PurgeExpired(tx *sql.Tx, before time.Time) (int64, []string, error)
+}
+
+func NewTrashRepository(
+	db *sql.DB,
+	logger jsonlog.Logger,
+) *trashRepository {
+	return &trashRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// trashItems selects the user's deleted books and plans in the column order
+// of models.TrashItem.
+const trashItems = `
+	select 'book' as type, b.id, b.title, b.id as book_id, coalesce(b.deleted_at, b.created_at) as deleted_at
+	from books b
+	where b.user_id = :userID and b.deleted = true
+	union all
+	select 'plan' as type, r.id, b.title, r.book_id, coalesce(r.deleted_at, r.created_at) as deleted_at
+	from reading_plans r
+	join books b on b.id = r.book_id
+	where r.user_id = :userID and r.deleted = true
+`
+
+func (r *trashRepository) GetAll(
+	itemType models.TrashItemType,
+	userID int64,
+	f filters.Filters,
+) ([]*models.TrashItem, filters.Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT
+		count(*) OVER(),
+		t.type,
+		t.id,
+		t.title,
+		t.book_id,
+		t.deleted_at
+	FROM (%s) t
+	WHERE (t.type = :type OR :type = '')
+	ORDER BY
+		t.%s %s,
+		t.id ASC
+	LIMIT :limit
+	OFFSET :offset
+	`, trashItems, f.SortColumn(), f.SortDirection())
+
+	params := map[string]any{
+		"type":   itemType,
+		"userID": userID,
+		"limit":  f.Limit(),
+		"offset": f.Offset(),
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	return paginatedQuery(
+		r.db,
+		query,
+		args,
+		f,
+		func() *models.TrashItem {
+			return &models.TrashItem{}
+		},
+	)
+}
+
+func (r *trashRepository) GetBook(bookID, userID int64) (*models.TrashItem, error) {
+	return r.getItem(models.TrashItemBook, bookID, userID)
+}
+
+func (r *trashRepository) GetPlan(planID, userID int64) (*models.TrashItem, error) {
+	return r.getItem(models.TrashItemPlan, planID, userID)
+}
+
+func (r *trashRepository) getItem(itemType models.TrashItemType, id, userID int64) (*models.TrashItem, error) {
+	query := fmt.Sprintf(`
+	select t.type, t.id, t.title, t.book_id, t.deleted_at
+	from (%s) t
+	where t.type = :type and t.id = :id
+	`, trashItems)
+
+	params := map[string]any{
+		"type":   itemType,
+		"id":     id,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+	return getByQuery[models.TrashItem](r.db, query, args)
+}
+
+func (r *trashRepository) TitleTaken(tx *sql.Tx, title string, userID int64) (bool, error) {
+	query := `
+	select exists (
+		select 1 from books where user_id = $1 and title = $2 and deleted = false
+	)
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var taken bool
+	err := tx.QueryRowContext(ctx, query, userID, title).Scan(&taken)
+	return taken, err
+}
+
+func (r *trashRepository) RestoreBook(tx *sql.Tx, bookID, userID int64, title string) error {
+	query := `
+	update books set
+		title = :title,
+		deleted = false,
+		deleted_at = null,
+		updated_at = now(),
+		updated_by = :userID,
+		version = version + 1
+	where
+		id = :id
+		and user_id = :userID
+		and deleted = true
+	`
+
+	params := map[string]any{
+		"id":     bookID,
+		"title":  title,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return parseBookConstraintError(err)
+	}
+
+	return requireAffected(result)
+}
+
+// RestorePlan brings back a deleted plan whose book is not in the trash.
+func (r *trashRepository) RestorePlan(tx *sql.Tx, planID, userID int64) error {
+	query := `
+	update reading_plans r set
+		deleted = false,
+		deleted_at = null,
+		updated_at = now(),
+		updated_by = :userID,
+		version = r.version + 1
+	from books b
+	where
+		b.id = r.book_id
+		and b.deleted = false
+		and r.id = :id
+		and r.user_id = :userID
+		and r.deleted = true
+	`
+
+	params := map[string]any{
+		"id":     planID,
+		"userID": userID,
+	}
+
+	query, args := namedQuery(query, params)
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
+
+// PurgeBook permanently deletes a book from the trash and returns the key of
+// its uploaded cover, if any.
+func (r *trashRepository) PurgeBook(tx *sql.Tx, bookID, userID int64) (string, error) {
+	query := `
+	delete from books
+	where id = $1 and user_id = $2 and deleted = true
+	returning cover_key
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var coverKey string
+	err := tx.QueryRowContext(ctx, query, bookID, userID).Scan(&coverKey)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", e.ErrRecordNotFound
+		}
+		return "", err
+	}
+
+	return coverKey, nil
+}
+
+func (r *trashRepository) PurgePlan(tx *sql.Tx, planID, userID int64) error {
+	query := `
+	delete from reading_plans
+	where id = $1 and user_id = $2 and deleted = true
+	`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := tx.ExecContext(ctx, query, planID, userID)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result)
+}
+
+// Empty permanently deletes everything in the user's trash and returns the
+// cover keys of the purged books.
+func (r *trashRepository) Empty(tx *sql.Tx, userID int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `delete from reading_plans where user_id = $1 and deleted = true`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return nil, err
+	}
+
+	query = `delete from books where user_id = $1 and deleted = true returning cover_key`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanCoverKeys(rows)
+}
+
+// PurgeExpired permanently deletes every book and plan that went to the trash
+// before the given time. It returns the number of purged rows and the cover
+// keys of the purged books.
+func (r *trashRepository) PurgeExpired(tx *sql.Tx, before time.Time) (int64, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query := `delete from reading_plans where deleted = true and deleted_at < $1`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	result, err := tx.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	plans, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	query = `delete from books where deleted = true and deleted_at < $1 returning cover_key`
+	r.logger.PrintInfo(utils.MinifySQL(query), nil)
+
+	rows, err := tx.QueryContext(ctx, query, before)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	keys, err := scanCoverKeys(rows)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return plans + int64(len(keys)), keys, nil
+}
+
+func scanCoverKeys(rows *sql.Rows) ([]string, error) {
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func requireAffected(result sql.Result) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return e.ErrRecordNotFound
+	}
+
+	return nil
+}
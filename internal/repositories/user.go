@@ -8,6 +8,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/lib/pq"
@@ -64,20 +65,22 @@ func parseUserConstraintError(err error) error {
 			return e.ErrDuplicateEmail
 		case "users_phone_key":
 			return e.ErrDuplicatePhone
+		case "chk_users_phone_e164":
+			return e.ErrInvalidPhone
 		}
 	}
 	return err
 }
 
 func (r *UserRepository) GetByCodAndEmail(cod int, email string) (*models.User, error) {
-	query := `
-	select u.* 
+	query := fmt.Sprintf(`
+	select %s
 	from users u
 	WHERE
 		email = :email
 		AND deleted = false
 		AND cod = :cod
-	`
+	`, selectColumns(models.User{}, "u"))
 	params := map[string]any{
 		"email": email,
 		"cod":   cod,
@@ -89,13 +92,13 @@ func (r *UserRepository) GetByCodAndEmail(cod int, email string) (*models.User,
 }
 
 func (r *UserRepository) GetByID(id int64) (*models.User, error) {
-	query := `
-	select u.* 
+	query := fmt.Sprintf(`
+	select %s
 	from users u
 	WHERE
 		id = :id
 		AND deleted = false
-	`
+	`, selectColumns(models.User{}, "u"))
 
 	params := map[string]any{
 		"id": id,
@@ -107,13 +110,13 @@ func (r *UserRepository) GetByID(id int64) (*models.User, error) {
 }
 
 func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
-	query := `
-	select u.* 
+	query := fmt.Sprintf(`
+	select %s
 	from users u
 	WHERE
 		email = :email
 		AND deleted = false
-	`
+	`, selectColumns(models.User{}, "u"))
 	params := map[string]any{
 		"email": email,
 	}
@@ -126,15 +129,16 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 
 func (r *UserRepository) Insert(tx *sql.Tx, user *models.User) error {
 	query := `
-	INSERT INTO users (name, email, phone,cod, password_hash, activated,deleted)
-	VALUES ($1, $2, $3, $4, $5, $6,false)
-	RETURNING id, created_at, version
+	INSERT INTO users (name, email, phone,cod, cod_channel, cod_sent_at, password_hash, activated,deleted)
+	VALUES ($1, $2, $3, $4, $5, now(), $6, $7,false)
+	RETURNING id, created_at, cod_sent_at, version
 	`
 	args := []any{
 		user.Name,
 		user.Email,
 		user.Phone,
 		user.Cod,
+		user.CodChannel,
 		user.Password.Hash,
 		user.Activated,
 	}
@@ -147,6 +151,7 @@ func (r *UserRepository) Insert(tx *sql.Tx, user *models.User) error {
 	err := tx.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
 		&user.CreatedAt,
+		&user.CodSentAt,
 		&user.Version,
 	)
 
@@ -161,19 +166,24 @@ func (r *UserRepository) Insert(tx *sql.Tx, user *models.User) error {
 	return nil
 }
 
+// UpdateCodByEmail saves the new activation code of user as sent now.
 func (r *UserRepository) UpdateCodByEmail(tx *sql.Tx, user *models.User) error {
 	query := `
 	UPDATE users SET
-	cod = $1
-	WHERE id = $2 AND version = $3
-	RETURNING version`
+	cod = $1,
+	cod_channel = $2,
+	cod_sent_at = now(),
+	version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING cod_sent_at, version`
 
 	r.logger.PrintInfo(utils.MinifySQL(query), nil)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := tx.QueryRowContext(ctx, query, user.Cod, user.ID, user.Version).Scan(
+	err := tx.QueryRowContext(ctx, query, user.Cod, user.CodChannel, user.ID, user.Version).Scan(
+		&user.CodSentAt,
 		&user.Version,
 	)
 
@@ -195,20 +205,25 @@ func (r *UserRepository) Update(tx *sql.Tx, user *models.User) error {
 		name = $1,
 		email = $2,
 		cod = $3,
-		phone = $4,
-		password_hash = $5,
-		activated = $6,
+		cod_channel = $4,
+		phone = $5,
+		phone_legacy = phone_legacy AND phone = $5,
+		phone_verified = $6,
+		password_hash = $7,
+		activated = $8,
 		version = version + 1
 	WHERE
-		id = $7
-		AND version = $8
+		id = $9
+		AND version = $10
 	RETURNING version`
 
 	args := []any{
 		user.Name,
 		user.Email,
 		user.Cod,
+		user.CodChannel,
 		user.Phone,
+		user.PhoneVerified,
 		user.Password.Hash,
 		user.Activated,
 		user.ID,
@@ -237,7 +252,8 @@ func (r *UserRepository) Update(tx *sql.Tx, user *models.User) error {
 func (r *UserRepository) Delete(tx *sql.Tx, idUser int64) error {
 	query := `
 	UPDATE users set
-	deleted = true
+	deleted = true,
+	deleted_at = now()
 	where id = $1
 	`
 
@@ -0,0 +1,37 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type authorRouter struct {
+	author handlers.AuthorHandler
+	m      middleware.MiddlewareInterface
+}
+
+type AuthorRouter interface {
+	AuthorRoutes(r chi.Router)
+}
+
+func NewAuthorRouter(
+	author handlers.AuthorHandler,
+	m middleware.MiddlewareInterface,
+) *authorRouter {
+	return &authorRouter{
+		author: author,
+		m:      m,
+	}
+}
+
+func (a *authorRouter) AuthorRoutes(r chi.Router) {
+	r.Route("/authors", func(r chi.Router) {
+		r.Use(a.m.RequireActivatedUser)
+
+		r.Get("/", a.author.FindAll)
+		r.Get("/{id}", a.author.FindByID)
+		r.Put("/", a.author.Update)
+	})
+}
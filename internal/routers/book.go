@@ -8,8 +8,14 @@ import (
 )
 
 type bookRouter struct {
-	book handlers.BookHandler
-	m    middleware.MiddlewareInterface
+	book        handlers.BookHandler
+	readingPlan handlers.ReadingPlanHandler
+	review      handlers.ReviewHandler
+	highlight   handlers.HighlightHandler
+	bookFile    handlers.BookFileHandler
+	label       handlers.LabelHandler
+	chapter     handlers.ChapterHandler
+	m           middleware.MiddlewareInterface
 }
 
 type BookRouter interface {
@@ -18,12 +24,23 @@ type BookRouter interface {
 
 func NewBookRouter(
 	book handlers.BookHandler,
+	readingPlan handlers.ReadingPlanHandler,
+	review handlers.ReviewHandler,
+	highlight handlers.HighlightHandler,
+	bookFile handlers.BookFileHandler,
+	label handlers.LabelHandler,
+	chapter handlers.ChapterHandler,
 	m middleware.MiddlewareInterface,
-
 ) *bookRouter {
 	return &bookRouter{
-		book: book,
-		m:    m,
+		book:        book,
+		readingPlan: readingPlan,
+		review:      review,
+		highlight:   highlight,
+		bookFile:    bookFile,
+		label:       label,
+		chapter:     chapter,
+		m:           m,
 	}
 }
 
@@ -33,8 +50,35 @@ func (b *bookRouter) BookRoutes(r chi.Router) {
 
 		r.Get("/{id}", b.book.FindByID)
 		r.Get("/", b.book.FindAll)
+		r.Get("/spending", b.book.Spending)
+		r.Get("/labels", b.label.Labels)
 		r.Post("/", b.book.Save)
 		r.Put("/", b.book.Update)
 		r.Delete("/{id}", b.book.Delete)
+
+		r.Put("/{id}/cover", b.book.UploadCover)
+		r.Delete("/{id}/cover", b.book.DeleteCover)
+		r.Get("/{id}/cover", b.book.Cover)
+		r.Get("/{id}/cover/thumbnail", b.book.CoverThumbnail)
+
+		r.Get("/{id}/plans", b.readingPlan.FindAll)
+
+		r.Get("/{id}/review", b.review.FindByBookID)
+		r.Post("/{id}/review", b.review.Save)
+		r.Put("/{id}/review", b.review.Update)
+		r.Delete("/{id}/review", b.review.Delete)
+
+		r.Get("/{id}/highlights", b.highlight.FindByBook)
+
+		r.Get("/{id}/chapters", b.chapter.FindByBook)
+		r.Put("/{id}/chapters", b.chapter.Replace)
+
+		r.Post("/files", b.bookFile.Upload)
+		r.Get("/files/{id}", b.bookFile.FindUpload)
+		r.Delete("/files/{id}", b.bookFile.Discard)
+		r.Post("/files/{id}/confirm", b.bookFile.Confirm)
+		r.Get("/{id}/files", b.bookFile.FindByBook)
+		r.Get("/{id}/files/{fileID}", b.bookFile.Download)
+		r.Post("/{id}/files/{fileID}/chapters", b.chapter.ImportFromFile)
 	})
 }
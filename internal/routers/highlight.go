@@ -0,0 +1,42 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type highlightRouter struct {
+	highlight handlers.HighlightHandler
+	m         middleware.MiddlewareInterface
+}
+
+type HighlightRouter interface {
+	HighlightRoutes(r chi.Router)
+}
+
+func NewHighlightRouter(
+	highlight handlers.HighlightHandler,
+	m middleware.MiddlewareInterface,
+) *highlightRouter {
+	return &highlightRouter{
+		highlight: highlight,
+		m:         m,
+	}
+}
+
+func (h *highlightRouter) HighlightRoutes(r chi.Router) {
+	r.Route("/highlights", func(r chi.Router) {
+		r.Use(h.m.RequireActivatedUser)
+
+		r.Get("/", h.highlight.FindAll)
+		r.Get("/random", h.highlight.Random)
+		r.Get("/export", h.highlight.Export)
+		r.Get("/{id}", h.highlight.FindByID)
+		r.Post("/", h.highlight.Save)
+		r.Post("/import", h.highlight.Import)
+		r.Put("/", h.highlight.Update)
+		r.Delete("/{id}", h.highlight.Delete)
+	})
+}
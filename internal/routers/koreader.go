@@ -0,0 +1,44 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type koreaderRouter struct {
+	koreader handlers.KOReaderHandler
+	m        middleware.MiddlewareInterface
+}
+
+type KOReaderRouter interface {
+	KOReaderRoutes(r chi.Router)
+}
+
+func NewKOReaderRouter(
+	koreader handlers.KOReaderHandler,
+	m middleware.MiddlewareInterface,
+) *koreaderRouter {
+	return &koreaderRouter{
+		koreader: koreader,
+		m:        m,
+	}
+}
+
+// KOReaderRoutes serves the progress sync protocol at /koreader, the URL to
+// enter as custom sync server in KOReader.
+func (k *koreaderRouter) KOReaderRoutes(r chi.Router) {
+	r.Route("/koreader", func(r chi.Router) {
+		r.Get("/healthcheck", k.koreader.Healthcheck)
+		r.Post("/users/create", k.koreader.Register)
+
+		r.Group(func(r chi.Router) {
+			r.Use(k.m.AuthenticateKOReader)
+
+			r.Get("/users/auth", k.koreader.Authorize)
+			r.Put("/syncs/progress", k.koreader.UpdateProgress)
+			r.Get("/syncs/progress/{document}", k.koreader.FindProgress)
+		})
+	})
+}
@@ -0,0 +1,35 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type libraryExportRouter struct {
+	libraryExport handlers.LibraryExportHandler
+	m             middleware.MiddlewareInterface
+}
+
+type LibraryExportRouter interface {
+	LibraryExportRoutes(r chi.Router)
+}
+
+func NewLibraryExportRouter(
+	libraryExport handlers.LibraryExportHandler,
+	m middleware.MiddlewareInterface,
+) *libraryExportRouter {
+	return &libraryExportRouter{
+		libraryExport: libraryExport,
+		m:             m,
+	}
+}
+
+func (l *libraryExportRouter) LibraryExportRoutes(r chi.Router) {
+	r.Route("/export", func(r chi.Router) {
+		r.Use(l.m.RequireActivatedUser)
+
+		r.Get("/", l.libraryExport.Export)
+	})
+}
@@ -0,0 +1,40 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type libraryImportRouter struct {
+	libraryImport handlers.LibraryImportHandler
+	kobo          handlers.KoboHandler
+	m             middleware.MiddlewareInterface
+}
+
+type LibraryImportRouter interface {
+	LibraryImportRoutes(r chi.Router)
+}
+
+func NewLibraryImportRouter(
+	libraryImport handlers.LibraryImportHandler,
+	kobo handlers.KoboHandler,
+	m middleware.MiddlewareInterface,
+) *libraryImportRouter {
+	return &libraryImportRouter{
+		libraryImport: libraryImport,
+		kobo:          kobo,
+		m:             m,
+	}
+}
+
+func (l *libraryImportRouter) LibraryImportRoutes(r chi.Router) {
+	r.Route("/imports", func(r chi.Router) {
+		r.Use(l.m.RequireActivatedUser)
+
+		r.Post("/", l.libraryImport.Start)
+		r.Post("/kobo", l.kobo.Import)
+		r.Get("/{id}", l.libraryImport.FindByID)
+	})
+}
@@ -0,0 +1,40 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type loanRouter struct {
+	loan handlers.LoanHandler
+	m    middleware.MiddlewareInterface
+}
+
+type LoanRouter interface {
+	LoanRoutes(r chi.Router)
+}
+
+func NewLoanRouter(
+	loan handlers.LoanHandler,
+	m middleware.MiddlewareInterface,
+) *loanRouter {
+	return &loanRouter{
+		loan: loan,
+		m:    m,
+	}
+}
+
+func (l *loanRouter) LoanRoutes(r chi.Router) {
+	r.Route("/loans", func(r chi.Router) {
+		r.Use(l.m.RequireActivatedUser)
+
+		r.Get("/", l.loan.FindAll)
+		r.Get("/{id}", l.loan.FindByID)
+		r.Post("/", l.loan.Save)
+		r.Post("/{id}/return", l.loan.Return)
+		r.Put("/", l.loan.Update)
+		r.Delete("/{id}", l.loan.Delete)
+	})
+}
@@ -0,0 +1,42 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type locationRouter struct {
+	location handlers.LocationHandler
+	m        middleware.MiddlewareInterface
+}
+
+type LocationRouter interface {
+	LocationRoutes(r chi.Router)
+}
+
+func NewLocationRouter(
+	location handlers.LocationHandler,
+	m middleware.MiddlewareInterface,
+) *locationRouter {
+	return &locationRouter{
+		location: location,
+		m:        m,
+	}
+}
+
+func (l *locationRouter) LocationRoutes(r chi.Router) {
+	r.Route("/locations", func(r chi.Router) {
+		r.Use(l.m.RequireActivatedUser)
+
+		r.Get("/", l.location.FindAll)
+		r.Get("/where", l.location.Where)
+		r.Get("/{id}", l.location.FindByID)
+		r.Post("/", l.location.Save)
+		r.Put("/", l.location.Update)
+		r.Delete("/{id}", l.location.Delete)
+		r.Post("/{id}/books", l.location.AddBooks)
+		r.Delete("/{id}/books", l.location.RemoveBooks)
+	})
+}
@@ -0,0 +1,50 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type meRouter struct {
+	preferences handlers.PreferencesHandler
+	auth        handlers.AuthHandlerInterface
+	koreader    handlers.KOReaderHandler
+	m           middleware.MiddlewareInterface
+}
+
+type MeRouter interface {
+	MeRoutes(r chi.Router)
+}
+
+func NewMeRouter(
+	preferences handlers.PreferencesHandler,
+	auth handlers.AuthHandlerInterface,
+	koreader handlers.KOReaderHandler,
+	m middleware.MiddlewareInterface,
+) *meRouter {
+	return &meRouter{
+		preferences: preferences,
+		auth:        auth,
+		koreader:    koreader,
+		m:           m,
+	}
+}
+
+func (me *meRouter) MeRoutes(r chi.Router) {
+	r.Route("/me", func(r chi.Router) {
+		r.Use(me.m.RequireActivatedUser)
+
+		r.Get("/preferences", me.preferences.Find)
+		r.Put("/preferences", me.preferences.Update)
+
+		r.Get("/feed-token", me.auth.FindFeedToken)
+		r.Post("/feed-token", me.auth.CreateFeedToken)
+		r.Delete("/feed-token", me.auth.RevokeFeedToken)
+
+		r.Get("/koreader", me.koreader.FindAccount)
+		r.Put("/koreader", me.koreader.SaveAccount)
+		r.Delete("/koreader", me.koreader.DeleteAccount)
+	})
+}
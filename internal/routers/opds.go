@@ -0,0 +1,57 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type opdsRouter struct {
+	opds handlers.OPDSHandler
+	m    middleware.MiddlewareInterface
+}
+
+type OPDSRouter interface {
+	OPDSRoutes(r chi.Router)
+}
+
+func NewOPDSRouter(
+	opds handlers.OPDSHandler,
+	m middleware.MiddlewareInterface,
+) *opdsRouter {
+	return &opdsRouter{
+		opds: opds,
+		m:    m,
+	}
+}
+
+// OPDSRoutes serves the catalog to apps that log in with HTTP Basic at
+// /opds and to apps given a feed token at /feeds/{token}/opds. Both serve
+// OPDS 1.2, and OPDS 2.0 under /v2.
+func (o *opdsRouter) OPDSRoutes(r chi.Router) {
+	for _, pattern := range []string{"/opds", "/feeds/{token}/opds"} {
+		r.Route(pattern, func(r chi.Router) {
+			r.Use(o.m.AuthenticateFeed)
+			r.Use(o.m.RequireActivatedUser)
+
+			o.catalogRoutes(r)
+			r.Route("/v2", o.catalogRoutes)
+		})
+	}
+}
+
+func (o *opdsRouter) catalogRoutes(r chi.Router) {
+	r.Get("/", o.opds.Root)
+	r.Get("/recent", o.opds.Recent)
+	r.Get("/books", o.opds.Books)
+	r.Get("/shelves", o.opds.Shelves)
+	r.Get("/shelves/{id}", o.opds.Shelf)
+	r.Get("/authors", o.opds.Authors)
+	r.Get("/authors/{id}", o.opds.Author)
+	r.Get("/series", o.opds.SeriesList)
+	r.Get("/series/{id}", o.opds.Series)
+	r.Get("/books/{id}/cover", o.opds.Cover)
+	r.Get("/books/{id}/cover/thumbnail", o.opds.CoverThumbnail)
+	r.Get("/books/{id}/files/{fileID}", o.opds.Download)
+}
@@ -0,0 +1,39 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type readingPlanRouter struct {
+	readingPlan handlers.ReadingPlanHandler
+	m           middleware.MiddlewareInterface
+}
+
+type ReadingPlanRouter interface {
+	ReadingPlanRoutes(r chi.Router)
+}
+
+func NewReadingPlanRouter(
+	readingPlan handlers.ReadingPlanHandler,
+	m middleware.MiddlewareInterface,
+) *readingPlanRouter {
+	return &readingPlanRouter{
+		readingPlan: readingPlan,
+		m:           m,
+	}
+}
+
+func (p *readingPlanRouter) ReadingPlanRoutes(r chi.Router) {
+	r.Route("/plans", func(r chi.Router) {
+		r.Use(p.m.RequireActivatedUser)
+
+		r.Get("/{id}", p.readingPlan.FindByID)
+		r.Get("/{id}/schedule", p.readingPlan.Schedule)
+		r.Post("/", p.readingPlan.Save)
+		r.Put("/", p.readingPlan.Update)
+		r.Delete("/{id}", p.readingPlan.Delete)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"bookwise/internal/handlers"
 	"bookwise/internal/jsonlog"
 	"bookwise/internal/middleware"
+	"bookwise/internal/services"
 	"bookwise/utils/errors"
 	"database/sql"
 	"expvar"
@@ -14,11 +15,25 @@ import (
 )
 
 type Router struct {
-	errResp errors.ErrorResponseInterface
-	m       middleware.MiddlewareInterface
-	user    UserRoutesInterface
-	auth    AuthRoutesInterface
-	book    BookRouter
+	errResp   errors.ErrorResponseInterface
+	m         middleware.MiddlewareInterface
+	user      UserRoutesInterface
+	auth      AuthRoutesInterface
+	book      BookRouter
+	me        MeRouter
+	shelf     ShelfRouter
+	author    AuthorRouter
+	series    SeriesRouter
+	trash     TrashRouter
+	plan      ReadingPlanRouter
+	highlight HighlightRouter
+	imports   LibraryImportRouter
+	exports   LibraryExportRouter
+	opds      OPDSRouter
+	koreader  KOReaderRouter
+	loan      LoanRouter
+	location  LocationRouter
+	service   *services.Services
 }
 
 func NewRouter(
@@ -32,17 +47,37 @@ func NewRouter(
 		e,
 		h.Service.User,
 		h.Service.Auth,
+		h.Service.Preferences,
 		config,
 	)
 	return &Router{
-		errResp: e,
-		m:       m,
-		user:    NewUserRouter(h.User),
-		auth:    NewAuthRouter(h.Auth),
-		book:    NewBookRouter(h.Book, m),
+		errResp:   e,
+		m:         m,
+		user:      NewUserRouter(h.User),
+		auth:      NewAuthRouter(h.Auth),
+		book:      NewBookRouter(h.Book, h.ReadingPlan, h.Review, h.Highlight, h.BookFile, h.Label, h.Chapter, m),
+		me:        NewMeRouter(h.Preferences, h.Auth, h.KOReader, m),
+		shelf:     NewShelfRouter(h.Shelf, m),
+		author:    NewAuthorRouter(h.Author, m),
+		series:    NewSeriesRouter(h.Series, m),
+		trash:     NewTrashRouter(h.Trash, m),
+		plan:      NewReadingPlanRouter(h.ReadingPlan, m),
+		highlight: NewHighlightRouter(h.Highlight, m),
+		imports:   NewLibraryImportRouter(h.LibraryImport, h.Kobo, m),
+		exports:   NewLibraryExportRouter(h.LibraryExport, m),
+		opds:      NewOPDSRouter(h.OPDS, m),
+		koreader:  NewKOReaderRouter(h.KOReader, m),
+		loan:      NewLoanRouter(h.Loan, m),
+		location:  NewLocationRouter(h.Location, m),
+		service:   h.Service,
 	}
 }
 
+// Services exposes the services behind the routes to background jobs.
+func (router *Router) Services() *services.Services {
+	return router.service
+}
+
 func (router *Router) RegisterRoutes() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(router.m.RecoverPanic)
@@ -64,6 +99,19 @@ func (router *Router) RegisterRoutes() *chi.Mux {
 		router.user.UserRoutes(r)
 		router.auth.AuthRoutes(r)
 		router.book.BookRoutes(r)
+		router.me.MeRoutes(r)
+		router.shelf.ShelfRoutes(r)
+		router.author.AuthorRoutes(r)
+		router.series.SeriesRoutes(r)
+		router.trash.TrashRoutes(r)
+		router.plan.ReadingPlanRoutes(r)
+		router.highlight.HighlightRoutes(r)
+		router.imports.LibraryImportRoutes(r)
+		router.exports.LibraryExportRoutes(r)
+		router.opds.OPDSRoutes(r)
+		router.koreader.KOReaderRoutes(r)
+		router.loan.LoanRoutes(r)
+		router.location.LocationRoutes(r)
 	})
 
 	return r
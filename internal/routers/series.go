@@ -0,0 +1,37 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type seriesRouter struct {
+	series handlers.SeriesHandler
+	m      middleware.MiddlewareInterface
+}
+
+type SeriesRouter interface {
+	SeriesRoutes(r chi.Router)
+}
+
+func NewSeriesRouter(
+	series handlers.SeriesHandler,
+	m middleware.MiddlewareInterface,
+) *seriesRouter {
+	return &seriesRouter{
+		series: series,
+		m:      m,
+	}
+}
+
+func (s *seriesRouter) SeriesRoutes(r chi.Router) {
+	r.Route("/series", func(r chi.Router) {
+		r.Use(s.m.RequireActivatedUser)
+
+		r.Get("/", s.series.FindAll)
+		r.Get("/{id}", s.series.FindByID)
+		r.Put("/", s.series.Update)
+	})
+}
@@ -0,0 +1,41 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type shelfRouter struct {
+	shelf handlers.ShelfHandler
+	m     middleware.MiddlewareInterface
+}
+
+type ShelfRouter interface {
+	ShelfRoutes(r chi.Router)
+}
+
+func NewShelfRouter(
+	shelf handlers.ShelfHandler,
+	m middleware.MiddlewareInterface,
+) *shelfRouter {
+	return &shelfRouter{
+		shelf: shelf,
+		m:     m,
+	}
+}
+
+func (s *shelfRouter) ShelfRoutes(r chi.Router) {
+	r.Route("/shelves", func(r chi.Router) {
+		r.Use(s.m.RequireActivatedUser)
+
+		r.Get("/", s.shelf.FindAll)
+		r.Get("/{id}", s.shelf.FindByID)
+		r.Post("/", s.shelf.Save)
+		r.Put("/", s.shelf.Update)
+		r.Delete("/{id}", s.shelf.Delete)
+		r.Post("/{id}/books", s.shelf.AddBooks)
+		r.Delete("/{id}/books", s.shelf.RemoveBooks)
+	})
+}
@@ -0,0 +1,42 @@
+package routers
+
+import (
+	"bookwise/internal/handlers"
+	"bookwise/internal/middleware"
+
+	"github.com/go-chi/chi"
+)
+
+type trashRouter struct {
+	trash handlers.TrashHandler
+	m     middleware.MiddlewareInterface
+}
+
+type TrashRouter interface {
+	TrashRoutes(r chi.Router)
+}
+
+func NewTrashRouter(
+	trash handlers.TrashHandler,
+	m middleware.MiddlewareInterface,
+) *trashRouter {
+	return &trashRouter{
+		trash: trash,
+		m:     m,
+	}
+}
+
+func (t *trashRouter) TrashRoutes(r chi.Router) {
+	r.Route("/trash", func(r chi.Router) {
+		r.Use(t.m.RequireActivatedUser)
+
+		r.Get("/", t.trash.FindAll)
+		r.Delete("/", t.trash.Empty)
+
+		r.Post("/books/{id}/restore", t.trash.RestoreBook)
+		r.Delete("/books/{id}", t.trash.PurgeBook)
+
+		r.Post("/plans/{id}/restore", t.trash.RestorePlan)
+		r.Delete("/plans/{id}", t.trash.PurgePlan)
+	})
+}
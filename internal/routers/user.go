@@ -23,6 +23,7 @@ type UserRoutesInterface interface {
 func (u *UserRouter) UserRoutes(r chi.Router) {
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/activate", u.User.ActivateUserHandler)
+		r.Post("/activation-code", u.User.SendActivationCodeHandler)
 		r.Post("/", u.User.CreateUserHandler)
 	})
 }
@@ -2,29 +2,71 @@ package services
 
 import (
 	"bookwise/internal/config"
+	"bookwise/internal/jsonlog"
 	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
 	e "bookwise/utils/errors"
 	"bookwise/utils/validator"
+	"crypto/sha256"
+	"database/sql"
 	"errors"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// basicAuthTTL is how long a successful Basic login is remembered, so apps
+// sending credentials with every request do not pay for hashing the password
+// each time.
+const basicAuthTTL = 5 * time.Minute
+
 type AuthService struct {
-	user   UserService
-	config config.Config
+	user      UserService
+	feedToken repositories.FeedTokenRepository
+	koreader  repositories.KOReaderRepository
+	db        *sql.DB
+	config    config.Config
+	logger    jsonlog.Logger
+
+	mu         sync.Mutex
+	basicCache map[[sha256.Size]byte]basicLogin
+}
+
+type basicLogin struct {
+	userID  int64
+	expires time.Time
 }
 
 type AuthServiceInterface interface {
 	Login(v *validator.Validator, email, password string) (string, error)
 	ExtractUsername(tokenString string) (string, error)
+	AuthenticateBasic(email, password string) (*models.User, error)
+	AuthenticateFeedToken(token string) (*models.User, error)
+	AuthenticateKOReader(username, key string) (*models.User, error)
+	CreateFeedToken(userID int64) (*models.FeedToken, error)
+	FindFeedToken(userID int64) (*models.FeedToken, error)
+	RevokeFeedToken(userID int64) error
 }
 
-func NewAuthService(userService UserService, config config.Config) *AuthService {
+func NewAuthService(
+	userService UserService,
+	feedToken repositories.FeedTokenRepository,
+	koreader repositories.KOReaderRepository,
+	db *sql.DB,
+	config config.Config,
+	logger jsonlog.Logger,
+) *AuthService {
 	return &AuthService{
-		user:   userService,
-		config: config,
+		user:       userService,
+		feedToken:  feedToken,
+		koreader:   koreader,
+		db:         db,
+		config:     config,
+		logger:     logger,
+		basicCache: make(map[[sha256.Size]byte]basicLogin),
 	}
 }
 
@@ -40,37 +82,190 @@ func (s *AuthService) Login(
 		return "", e.ErrInvalidData
 	}
 
+	user, err := s.checkPassword(email, password, v)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := s.createToken(user.Email)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// checkPassword returns the activated user with the given credentials,
+// rehashing the password when its hash is outdated. A rehash that cannot be
+// saved is logged and tried again on the next login.
+func (s *AuthService) checkPassword(email, password string, v *validator.Validator) (*models.User, error) {
 	user, err := s.user.GetUserByEmail(email, v)
 	if err != nil {
 		switch {
 		case errors.Is(err, e.ErrRecordNotFound):
-			return "", e.ErrInvalidCredentials
+			return nil, e.ErrInvalidCredentials
 		default:
-			return "", err
+			return nil, err
 		}
 	}
 
 	if !user.Activated {
-		return "", e.ErrInactiveAccount
+		return nil, e.ErrInactiveAccount
 	}
 
 	match, err := user.Password.Matches(password)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if !match {
-		return "", e.ErrInvalidCredentials
+		return nil, e.ErrInvalidCredentials
 	}
 
-	token, err := s.createToken(user.Email)
+	if user.Password.NeedsRehash() {
+		if err := user.Password.Set(password); err != nil {
+			return nil, err
+		}
+
+		if err := s.user.Update(user, validator.New()); err != nil {
+			s.logger.PrintError(err, map[string]string{
+				"rehash": "password",
+				"user":   strconv.FormatInt(user.ID, 10),
+			})
+		}
+	}
+
+	return user, nil
+}
+
+// AuthenticateBasic returns the user of the email and password an app sent
+// as HTTP Basic credentials. Successful logins are remembered for a few
+// minutes, so a changed password may still be accepted for that long.
+func (s *AuthService) AuthenticateBasic(email, password string) (*models.User, error) {
+	key := sha256.Sum256([]byte(email + "\x00" + password))
+
+	v := validator.New()
+	if userID, ok := s.cachedLogin(key); ok {
+		user, err := s.user.GetUserByEmail(email, v)
+		if err == nil && user.ID == userID && user.Activated {
+			return user, nil
+		}
+	}
+
+	user, err := s.checkPassword(email, password, v)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	s.rememberLogin(key, user.ID)
+	return user, nil
+}
+
+// AuthenticateKOReader returns the user of the account the sync plugin of
+// KOReader logs in with, key being the MD5 of its password. Logins are
+// remembered like those of AuthenticateBasic.
+func (s *AuthService) AuthenticateKOReader(username, key string) (*models.User, error) {
+	account, err := s.koreader.GetAccountByUsername(username)
+	if err != nil {
+		if errors.Is(err, e.ErrRecordNotFound) {
+			return nil, e.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if !account.User.Activated {
+		return nil, e.ErrInactiveAccount
+	}
+
+	cacheKey := sha256.Sum256([]byte("koreader\x00" + username + "\x00" + key))
+	if userID, ok := s.cachedLogin(cacheKey); ok && userID == account.UserID {
+		return account.User, nil
+	}
+
+	match, err := account.Key.Matches(key)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		return nil, e.ErrInvalidCredentials
+	}
+
+	s.rememberLogin(cacheKey, account.UserID)
+	return account.User, nil
+}
+
+// cachedLogin returns the user a login was remembered for, if it has not
+// expired yet.
+func (s *AuthService) cachedLogin(key [sha256.Size]byte) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, ok := s.basicCache[key]
+	if !ok || time.Now().After(login.expires) {
+		return 0, false
+	}
+	return login.userID, true
+}
+
+// rememberLogin remembers a successful login, dropping the expired ones.
+func (s *AuthService) rememberLogin(key [sha256.Size]byte, userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, login := range s.basicCache {
+		if now.After(login.expires) {
+			delete(s.basicCache, k)
+		}
+	}
+	s.basicCache[key] = basicLogin{userID: userID, expires: now.Add(basicAuthTTL)}
+}
+
+// AuthenticateFeedToken returns the user a feed token belongs to.
+func (s *AuthService) AuthenticateFeedToken(token string) (*models.User, error) {
+	user, err := s.feedToken.GetUserByHash(models.HashFeedToken(token))
+	if err != nil {
+		if errors.Is(err, e.ErrRecordNotFound) {
+			return nil, e.ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := s.feedToken.Touch(user.ID); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// CreateFeedToken creates a new feed token for the user, revoking the one
+// the user had.
+func (s *AuthService) CreateFeedToken(userID int64) (*models.FeedToken, error) {
+	token, err := models.NewFeedToken(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.feedToken.Replace(tx, token)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return token, nil
 }
 
+func (s *AuthService) FindFeedToken(userID int64) (*models.FeedToken, error) {
+	return s.feedToken.GetByUserID(userID)
+}
+
+func (s *AuthService) RevokeFeedToken(userID int64) error {
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.feedToken.Delete(tx, userID)
+	})
+}
+
 func (s *AuthService) createToken(username string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256,
 		jwt.MapClaims{
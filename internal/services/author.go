@@ -0,0 +1,57 @@
+package services
+
+import (
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"strings"
+)
+
+type authorService struct {
+	author repositories.AuthorRepository
+	db     *sql.DB
+}
+
+type AuthorService interface {
+	FindAll(name string, userID int64, f filters.Filters) ([]*models.Author, filters.Metadata, error)
+	FindByID(id, userID int64) (*models.Author, error)
+	Update(author *models.Author, userID int64, v *validator.Validator) error
+}
+
+func NewAuthorService(
+	author repositories.AuthorRepository,
+	db *sql.DB,
+) *authorService {
+	return &authorService{
+		author: author,
+		db:     db,
+	}
+}
+
+func (s *authorService) FindAll(name string, userID int64, f filters.Filters) ([]*models.Author, filters.Metadata, error) {
+	return s.author.GetAll(name, userID, f)
+}
+
+func (s *authorService) FindByID(id, userID int64) (*models.Author, error) {
+	return s.author.GetByID(id, userID)
+}
+
+func (s *authorService) Update(author *models.Author, userID int64, v *validator.Validator) error {
+	if author.ValidateAuthor(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	name, sortName := models.ParseAuthorName(author.Name)
+	author.Name = name
+	if strings.TrimSpace(author.SortName) == "" {
+		author.SortName = sortName
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.author.Update(tx, author, userID)
+	})
+}
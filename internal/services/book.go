@@ -1,82 +1,583 @@
 package services
 
 import (
+	"bookwise/internal/config"
+	"bookwise/internal/metadata"
 	"bookwise/internal/models"
 	"bookwise/internal/models/filters"
 	"bookwise/internal/repositories"
+	"bookwise/internal/storage"
 	"bookwise/utils"
 	e "bookwise/utils/errors"
+	"bookwise/utils/imaging"
+	"bookwise/utils/isbn"
 	"bookwise/utils/validator"
+	"bytes"
+	"crypto/sha256"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 )
 
 type bookService struct {
-	book repositories.BookRepository
-	db   *sql.DB
+	book        repositories.BookRepository
+	author      repositories.AuthorRepository
+	series      repositories.SeriesRepository
+	review      repositories.ReviewRepository
+	loan        repositories.LoanRepository
+	location    repositories.LocationRepository
+	chapter     repositories.ChapterRepository
+	preferences PreferencesService
+	metadata    metadata.Provider
+	blobs       storage.BlobStore
+	db          *sql.DB
+	config      config.Config
 }
 
 type BookService interface {
 	FindAll(
-		title, author string,
+		search filters.BookFilters,
 		userID int64,
 		f filters.Filters,
 	) ([]*models.Book, filters.Metadata, error)
+	FindBatch(search filters.BookFilters, userID, afterID int64, limit int) ([]*models.Book, error)
 	Save(book *models.Book, userID int64, v *validator.Validator) error
+	Create(tx *sql.Tx, book *models.Book, userID int64, v *validator.Validator) error
+	Import(tx *sql.Tx, book *models.Book, userID int64, v *validator.Validator) error
+	Prefill(book *models.Book, userID int64) error
 	FindByID(id, userID int64) (*models.Book, error)
 	Update(book *models.Book, userID int64, v *validator.Validator) error
 	Delete(id, userID int64) error
+	SetCover(id, userID int64, data []byte, v *validator.Validator) (*models.Book, error)
+	RemoveCover(id, userID int64) error
+	Cover(id, userID int64, thumbnail bool) (io.ReadCloser, *storage.BlobInfo, error)
+	Spending(userID int64, from, to *time.Time) (*models.Spending, error)
 }
 
 func NewBookService(
 	book repositories.BookRepository,
+	author repositories.AuthorRepository,
+	series repositories.SeriesRepository,
+	review repositories.ReviewRepository,
+	loan repositories.LoanRepository,
+	location repositories.LocationRepository,
+	chapter repositories.ChapterRepository,
+	preferences PreferencesService,
+	metadata metadata.Provider,
+	blobs storage.BlobStore,
 	db *sql.DB,
+	config config.Config,
 ) *bookService {
 	return &bookService{
-		book: book,
-		db:   db,
+		book:        book,
+		author:      author,
+		series:      series,
+		review:      review,
+		loan:        loan,
+		location:    location,
+		chapter:     chapter,
+		preferences: preferences,
+		metadata:    metadata,
+		blobs:       blobs,
+		db:          db,
+		config:      config,
 	}
 }
 
 func (s *bookService) FindAll(
-	title, author string,
+	search filters.BookFilters,
 	userID int64,
 	f filters.Filters,
 ) ([]*models.Book, filters.Metadata, error) {
-	return s.book.GetAll(title, author, userID, f)
+	books, metadata, err := s.book.GetAll(search, userID, f)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	if err := s.loadRelations(userID, books...); err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	return books, metadata, nil
+}
+
+// FindBatch returns up to limit matching books with an ID greater than
+// afterID, in ID order.
+func (s *bookService) FindBatch(
+	search filters.BookFilters,
+	userID, afterID int64,
+	limit int,
+) ([]*models.Book, error) {
+	books, err := s.book.GetBatch(search, userID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadRelations(userID, books...); err != nil {
+		return nil, err
+	}
+
+	return books, nil
 }
 
 func (s *bookService) Save(book *models.Book, userID int64, v *validator.Validator) error {
-	if book.User == nil {
-		book.User = &models.User{
-			ID: userID,
-		}
+	if err := s.Prefill(book, userID); err != nil {
+		return err
 	}
 
 	if book.ValidateBook(v); !v.Valid() {
 		return e.ErrInvalidData
 	}
 
+	if err := s.checkLocation(book, userID, v); err != nil {
+		return err
+	}
+
 	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
-		return s.book.Insert(tx, book)
+		return s.insert(tx, book, userID)
 	})
 }
 
+// Create saves a book like Save does, but as part of tx.
+func (s *bookService) Create(tx *sql.Tx, book *models.Book, userID int64, v *validator.Validator) error {
+	if err := s.Prefill(book, userID); err != nil {
+		return err
+	}
+
+	if book.ValidateBook(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	if err := s.checkLocation(book, userID, v); err != nil {
+		return err
+	}
+
+	return s.insert(tx, book, userID)
+}
+
+// Import saves a book read from another service's export as part of tx.
+// Exports carry no descriptions, so unlike Save it does not require one.
+func (s *bookService) Import(tx *sql.Tx, book *models.Book, userID int64, v *validator.Validator) error {
+	if err := s.Prefill(book, userID); err != nil {
+		return err
+	}
+
+	if book.ValidateImportedBook(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return s.insert(tx, book, userID)
+}
+
+// Prefill normalizes a new book and completes it from the metadata provider
+// the way saving it would, without validating or saving it.
+func (s *bookService) Prefill(book *models.Book, userID int64) error {
+	book.User = &models.User{
+		ID: userID,
+	}
+
+	if book.Acquisition == "" {
+		book.Acquisition = models.AcquisitionOwned
+	}
+
+	book.NormalizeISBN()
+	if err := s.fillFromMetadata(book); err != nil {
+		return err
+	}
+
+	book.NormalizeAuthors()
+	return nil
+}
+
+func (s *bookService) insert(tx *sql.Tx, book *models.Book, userID int64) error {
+	if err := s.resolveAuthors(tx, book, userID); err != nil {
+		return err
+	}
+
+	if err := s.resolveSeries(tx, book, userID); err != nil {
+		return err
+	}
+
+	if err := s.book.Insert(tx, book); err != nil {
+		return err
+	}
+
+	if err := s.author.SetBookAuthors(tx, book.ID, book.Authors); err != nil {
+		return err
+	}
+
+	if len(book.Chapters) == 0 {
+		return nil
+	}
+	return s.chapter.Replace(tx, book.ID, book.Chapters)
+}
+
 func (s *bookService) FindByID(id, userID int64) (*models.Book, error) {
-	return s.book.GetByID(id, userID)
+	book, err := s.book.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadRelations(userID, book); err != nil {
+		return nil, err
+	}
+
+	return book, nil
 }
 
 func (s *bookService) Update(book *models.Book, userID int64, v *validator.Validator) error {
+	book.User = &models.User{
+		ID: userID,
+	}
+
+	book.NormalizeISBN()
+	book.NormalizeAuthors()
 	if book.ValidateBook(v); !v.Valid() {
 		return e.ErrInvalidData
 	}
 
+	if err := s.checkLocation(book, userID, v); err != nil {
+		return err
+	}
+
 	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
-		return s.book.Update(tx, book, userID)
+		if err := s.resolveAuthors(tx, book, userID); err != nil {
+			return err
+		}
+
+		if err := s.resolveSeries(tx, book, userID); err != nil {
+			return err
+		}
+
+		if err := s.book.Update(tx, book, userID); err != nil {
+			return err
+		}
+
+		return s.author.SetBookAuthors(tx, book.ID, book.Authors)
 	})
 }
 
+// checkLocation makes sure the location the book is kept in, if any, is one
+// of the user's.
+func (s *bookService) checkLocation(book *models.Book, userID int64, v *validator.Validator) error {
+	if book.LocationID == nil {
+		return nil
+	}
+
+	if _, err := s.location.GetByID(*book.LocationID, userID); err != nil {
+		if errors.Is(err, e.ErrRecordNotFound) {
+			v.AddError("location", "not found")
+			return e.ErrInvalidData
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Spending reports what the user spent on the books bought between from and
+// to, either of which may be nil.
+func (s *bookService) Spending(userID int64, from, to *time.Time) (*models.Spending, error) {
+	return s.book.GetSpending(userID, from, to)
+}
+
+// fillFromMetadata completes the fields the client left empty with what the
+// metadata provider knows about the book's ISBN. Values sent by the client
+// always win.
+func (s *bookService) fillFromMetadata(book *models.Book) error {
+	if !isbn.Valid13(book.ISBN) {
+		return nil
+	}
+
+	found, err := s.metadata.Lookup(book.ISBN)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if book.Title == "" {
+		book.Title = found.Title
+	}
+
+	if book.Author == "" && len(book.Authors) == 0 {
+		book.Author = strings.Join(found.Authors, " & ")
+	}
+
+	if book.Pages == 0 && book.Format != models.BookFormatAudiobook {
+		book.Pages = found.Pages
+	}
+
+	if book.Description == "" {
+		book.Description = found.Description
+	}
+
+	if book.Publisher == "" {
+		book.Publisher = found.Publisher
+	}
+
+	if book.PublishedDate == nil {
+		book.PublishedDate = found.PublishedDate
+	}
+
+	if book.Language == "" {
+		book.Language = found.Language
+	}
+
+	if book.CoverURL == "" {
+		book.CoverURL = found.CoverURL
+	}
+
+	return nil
+}
+
+func (s *bookService) resolveAuthors(tx *sql.Tx, book *models.Book, userID int64) error {
+	for i := range book.Authors {
+		if err := s.author.Resolve(tx, &book.Authors[i].Author, userID); err != nil {
+			return err
+		}
+	}
+
+	if book.Author == "" {
+		book.Author = models.AuthorCredit(book.Authors)
+	}
+
+	return nil
+}
+
+func (s *bookService) resolveSeries(tx *sql.Tx, book *models.Book, userID int64) error {
+	if book.SeriesID == nil && book.SeriesName == "" {
+		book.SeriesPosition = nil
+		return nil
+	}
+
+	series := &models.Series{
+		Name:       book.SeriesName,
+		TotalCount: book.SeriesTotal,
+	}
+
+	if book.SeriesID != nil {
+		series.ID = *book.SeriesID
+	}
+
+	if err := s.series.Resolve(tx, series, userID); err != nil {
+		return err
+	}
+
+	book.SeriesID = &series.ID
+	book.SeriesName = series.Name
+	book.SeriesTotal = series.TotalCount
+	return nil
+}
+
+// loadRelations fills the authors, series information, open loans and
+// location paths of the user's books, which live in their own tables.
+func (s *bookService) loadRelations(userID int64, books ...*models.Book) error {
+	ids := make([]int64, 0, len(books))
+	seriesIDs := []int64{}
+	for _, book := range books {
+		ids = append(ids, book.ID)
+		if book.SeriesID != nil {
+			seriesIDs = append(seriesIDs, *book.SeriesID)
+		}
+	}
+
+	authors, err := s.author.GetByBookIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	series, err := s.series.GetByIDs(seriesIDs)
+	if err != nil {
+		return err
+	}
+
+	reviews, err := s.review.GetByBookIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	loans, err := s.loan.GetOpenByBookIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	var locations map[int64]*models.Location
+	for _, book := range books {
+		if book.LocationID != nil {
+			all, err := s.location.GetAll(userID)
+			if err != nil {
+				return err
+			}
+			locations = models.IndexLocations(all)
+			break
+		}
+	}
+
+	var today time.Time
+	if len(loans) > 0 {
+		if today, err = userToday(s.preferences, userID); err != nil {
+			return err
+		}
+	}
+
+	for _, book := range books {
+		book.Authors = authors[book.ID]
+
+		if loan, ok := loans[book.ID]; ok {
+			loan.MarkOverdue(today)
+			book.Loans = []*models.Loan{loan}
+		}
+
+		if book.LocationID != nil {
+			if location, ok := locations[*book.LocationID]; ok {
+				book.LocationPath = location.Path
+			}
+		}
+
+		if review, ok := reviews[book.ID]; ok {
+			book.Rating = &review.Rating
+		}
+
+		if book.SeriesID != nil {
+			if info, ok := series[*book.SeriesID]; ok {
+				book.SeriesName = info.Name
+				book.SeriesTotal = info.TotalCount
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *bookService) Delete(id, userID int64) error {
 	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
 		return s.book.Delete(tx, id, userID)
 	})
 }
+
+// SetCover stores data as the book's cover together with a thumbnail and
+// returns the updated book. Blobs of the replaced cover are removed once the
+// new one is saved.
+func (s *bookService) SetCover(id, userID int64, data []byte, v *validator.Validator) (*models.Book, error) {
+	contentType, ext, err := imaging.Sniff(data)
+	if err != nil {
+		v.AddError("cover", "must be a JPEG, PNG or GIF image")
+		return nil, e.ErrInvalidData
+	}
+
+	img, err := imaging.Decode(data)
+	if err != nil {
+		v.AddError("cover", err.Error())
+		return nil, e.ErrInvalidData
+	}
+
+	thumbnail, err := imaging.Thumbnail(img, s.config.Covers.ThumbnailWidth, s.config.Covers.ThumbnailHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	book, err := s.book.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("covers/%d/%d/%x%s", userID, id, sum[:16], ext)
+	thumbnailKey := models.CoverThumbnailKey(key)
+
+	// Keys follow the content, so uploading the current cover again writes
+	// over the blobs the book points to: those must survive a failure.
+	discard := func() {
+		if key != book.CoverKey {
+			s.deleteCoverBlobs(key)
+		}
+	}
+
+	if err := s.blobs.Put(key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, err
+	}
+
+	if err := s.blobs.Put(thumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg"); err != nil {
+		discard()
+		return nil, err
+	}
+
+	var previous string
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		previous, err = s.book.SetCover(tx, id, userID, key)
+		return err
+	})
+	if err != nil {
+		discard()
+		return nil, err
+	}
+
+	if previous != key {
+		s.deleteCoverBlobs(previous)
+	}
+
+	return s.FindByID(id, userID)
+}
+
+func (s *bookService) RemoveCover(id, userID int64) error {
+	var previous string
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		previous, err = s.book.SetCover(tx, id, userID, "")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if previous == "" {
+		return e.ErrRecordNotFound
+	}
+
+	s.deleteCoverBlobs(previous)
+	return nil
+}
+
+// Cover opens the uploaded cover of the book, or its thumbnail. The caller
+// must close the returned reader.
+func (s *bookService) Cover(id, userID int64, thumbnail bool) (io.ReadCloser, *storage.BlobInfo, error) {
+	book, err := s.book.GetByID(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if book.CoverKey == "" {
+		return nil, nil, e.ErrRecordNotFound
+	}
+
+	key := book.CoverKey
+	if thumbnail {
+		key = models.CoverThumbnailKey(key)
+	}
+
+	body, info, err := s.blobs.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return nil, nil, e.ErrRecordNotFound
+		}
+		return nil, nil, err
+	}
+
+	return body, info, nil
+}
+
+// deleteCoverBlobs removes a cover and its thumbnail. Failures leave orphaned
+// blobs behind but never affect the book itself, so they are ignored.
+func (s *bookService) deleteCoverBlobs(key string) {
+	if key == "" {
+		return
+	}
+
+	s.blobs.Delete(key)
+	s.blobs.Delete(models.CoverThumbnailKey(key))
+}
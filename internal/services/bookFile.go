@@ -0,0 +1,382 @@
+package services
+
+import (
+	"bookwise/internal/config"
+	"bookwise/internal/ebook"
+	"bookwise/internal/koreader"
+	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/internal/storage"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+type bookFileService struct {
+	bookFile    repositories.BookFileRepository
+	book        repositories.BookRepository
+	bookService BookService
+	blobs       storage.BlobStore
+	db          *sql.DB
+	config      config.Config
+}
+
+type BookFileService interface {
+	Upload(data []byte, fileName string, userID int64, v *validator.Validator) (*models.BookUpload, error)
+	FindUpload(id, userID int64) (*models.BookUpload, error)
+	Confirm(id, userID int64, book *models.Book, v *validator.Validator) (*models.Book, error)
+	Discard(id, userID int64) error
+	FindByBook(bookID, userID int64) ([]*models.BookFile, error)
+	FindByBooks(books []*models.Book, userID int64) (map[int64][]*models.BookFile, error)
+	Open(bookID, id, userID int64) (*models.BookFile, io.ReadCloser, *storage.BlobInfo, error)
+	PurgeUnattached() (int, error)
+	ImportDir(fsys fs.FS, userID int64, dryRun bool) (*models.EbookImport, error)
+}
+
+func NewBookFileService(
+	bookFile repositories.BookFileRepository,
+	book repositories.BookRepository,
+	bookService BookService,
+	blobs storage.BlobStore,
+	db *sql.DB,
+	config config.Config,
+) *bookFileService {
+	return &bookFileService{
+		bookFile:    bookFile,
+		book:        book,
+		bookService: bookService,
+		blobs:       blobs,
+		db:          db,
+		config:      config,
+	}
+}
+
+// Upload stores an EPUB, PDF or MOBI file and returns the book its metadata
+// describes, for the user to confirm. Uploading a file again returns the
+// pending upload, unless it already belongs to a book.
+func (s *bookFileService) Upload(
+	data []byte,
+	fileName string,
+	userID int64,
+	v *validator.Validator,
+) (*models.BookUpload, error) {
+	metadata, format, err := s.read(data, v)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	file := &models.BookFile{
+		UserID:       userID,
+		Format:       string(format),
+		FileName:     path.Base(strings.ReplaceAll(fileName, `\`, "/")),
+		Size:         int64(len(data)),
+		ContentHash:  fmt.Sprintf("%x", sum),
+		DocumentHash: koreader.DocumentHash(data),
+	}
+	file.BlobKey = fmt.Sprintf("files/%d/%s%s", userID, file.ContentHash, format.Extension())
+
+	if file.FileName == "." || file.FileName == "/" {
+		file.FileName = ""
+	}
+
+	existing, err := s.bookFile.GetByHash(file.ContentHash, userID)
+	switch {
+	case err == nil && existing.BookID != nil:
+		v.AddError("file", fmt.Sprintf("is already in the library as book %d", *existing.BookID))
+		return nil, e.ErrInvalidData
+	case err == nil:
+		existing.FileName = file.FileName
+		err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+			return s.bookFile.Touch(tx, existing)
+		})
+		if err != nil {
+			return nil, err
+		}
+		file = existing
+	case errors.Is(err, e.ErrRecordNotFound):
+		if err := s.store(file, data, format); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	return s.upload(file, metadata, userID)
+}
+
+// store saves the blob and the row of a new file, removing the blob again
+// when the row cannot be saved.
+func (s *bookFileService) store(file *models.BookFile, data []byte, format ebook.Format) error {
+	if err := s.blobs.Put(file.BlobKey, bytes.NewReader(data), file.Size, format.ContentType()); err != nil {
+		return err
+	}
+
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.bookFile.Insert(tx, file)
+	})
+	if err != nil {
+		s.blobs.Delete(file.BlobKey)
+		return err
+	}
+
+	return nil
+}
+
+// FindUpload reads the metadata of an upload that has not been confirmed
+// yet again.
+func (s *bookFileService) FindUpload(id, userID int64) (*models.BookUpload, error) {
+	file, metadata, err := s.pending(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.upload(file, metadata, userID)
+}
+
+// Confirm creates book, as completed by the user, and gives it the uploaded
+// file and the table of contents found in it. The cover found in the file becomes the book's cover unless the user
+// picked one. The cover is best effort: a file whose cover is not a usable
+// image still makes a book.
+func (s *bookFileService) Confirm(
+	id, userID int64,
+	book *models.Book,
+	v *validator.Validator,
+) (*models.Book, error) {
+	file, metadata, err := s.pending(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pickedCover := book.CoverURL != ""
+	book.Chapters = ebookChapters(metadata, book.Pages)
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		if err := s.bookService.Create(tx, book, userID, v); err != nil {
+			return err
+		}
+
+		return s.bookFile.Attach(tx, file.ID, userID, book.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metadata.Cover) > 0 && !pickedCover {
+		s.bookService.SetCover(book.ID, userID, metadata.Cover, validator.New())
+	}
+
+	return s.bookService.FindByID(book.ID, userID)
+}
+
+// Discard removes an upload that has not been confirmed.
+func (s *bookFileService) Discard(id, userID int64) error {
+	var key string
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		key, err = s.bookFile.Delete(tx, id, userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.blobs.Delete(key)
+	return nil
+}
+
+func (s *bookFileService) FindByBook(bookID, userID int64) ([]*models.BookFile, error) {
+	if _, err := s.bookService.FindByID(bookID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.bookFile.GetByBookID(bookID, userID)
+}
+
+// FindByBooks returns the files of books, by book.
+func (s *bookFileService) FindByBooks(books []*models.Book, userID int64) (map[int64][]*models.BookFile, error) {
+	ids := make([]int64, 0, len(books))
+	for _, book := range books {
+		ids = append(ids, book.ID)
+	}
+
+	return s.bookFile.GetByBookIDs(ids, userID)
+}
+
+// Open opens a file of a book for download. The caller must close the
+// returned reader.
+func (s *bookFileService) Open(bookID, id, userID int64) (*models.BookFile, io.ReadCloser, *storage.BlobInfo, error) {
+	file, err := s.bookFile.GetByID(id, userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if file.BookID == nil || *file.BookID != bookID {
+		return nil, nil, nil, e.ErrRecordNotFound
+	}
+
+	if _, err := s.bookService.FindByID(bookID, userID); err != nil {
+		return nil, nil, nil, err
+	}
+
+	body, info, err := s.blobs.Get(file.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return nil, nil, nil, e.ErrRecordNotFound
+		}
+		return nil, nil, nil, err
+	}
+
+	return file, body, info, nil
+}
+
+// PurgeUnattached removes the uploads that were never confirmed and the files
+// of books purged from the trash once they are older than the configured
+// retention, and returns how many were removed.
+func (s *bookFileService) PurgeUnattached() (int, error) {
+	before := time.Now().Add(-s.config.Files.UploadRetention)
+
+	var keys []string
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		keys, err = s.bookFile.DeleteUnattached(tx, before)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Failures only leave orphaned blobs behind, so they are ignored.
+	for _, key := range keys {
+		s.blobs.Delete(key)
+	}
+
+	return len(keys), nil
+}
+
+// read detects the format of data and reads its metadata, reporting files
+// that are no ebook or cannot be read through v.
+func (s *bookFileService) read(data []byte, v *validator.Validator) (*ebook.Metadata, ebook.Format, error) {
+	metadata, format, err := ebook.Read(data)
+	switch {
+	case errors.Is(err, ebook.ErrUnsupportedFormat):
+		v.AddError("file", "must be an EPUB, PDF or MOBI file")
+		return nil, "", e.ErrInvalidData
+	case errors.Is(err, ebook.ErrMalformed):
+		v.AddError("file", fmt.Sprintf("could not be read as %s: %v", strings.ToUpper(string(format)), err))
+		return nil, "", e.ErrInvalidData
+	case err != nil:
+		return nil, "", err
+	}
+
+	return metadata, format, nil
+}
+
+// pending returns an unconfirmed upload with the metadata of its file.
+func (s *bookFileService) pending(id, userID int64) (*models.BookFile, *ebook.Metadata, error) {
+	file, err := s.bookFile.GetByID(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if file.BookID != nil {
+		return nil, nil, e.ErrRecordNotFound
+	}
+
+	body, _, err := s.blobs.Get(file.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return nil, nil, e.ErrRecordNotFound
+		}
+		return nil, nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata, _, err := ebook.Read(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, metadata, nil
+}
+
+// upload builds the book the metadata of file describes, completed from the
+// metadata provider, and lists what it still lacks.
+func (s *bookFileService) upload(file *models.BookFile, metadata *ebook.Metadata, userID int64) (*models.BookUpload, error) {
+	book := bookFromMetadata(metadata, file.FileName)
+	if err := s.bookService.Prefill(book, userID); err != nil {
+		return nil, err
+	}
+
+	check := validator.New()
+	book.ValidateBook(check)
+
+	return &models.BookUpload{
+		File:     file,
+		Book:     book,
+		HasCover: len(metadata.Cover) > 0,
+		Missing:  check.Errors,
+	}, nil
+}
+
+// ebookRoles maps the MARC relator codes of ebook creators to author roles.
+// Creators in other roles, such as narrators, are left out.
+var ebookRoles = map[string]models.AuthorRole{
+	"":    models.AuthorRoleAuthor,
+	"aut": models.AuthorRoleAuthor,
+	"trl": models.AuthorRoleTranslator,
+	"edt": models.AuthorRoleEditor,
+	"ill": models.AuthorRoleIllustrator,
+}
+
+// bookFromMetadata describes an ebook as a book. Files without a title are
+// named after the file.
+func bookFromMetadata(metadata *ebook.Metadata, fileName string) *models.Book {
+	book := &models.Book{
+		Title:         metadata.Title,
+		Pages:         metadata.Pages,
+		Description:   metadata.Description,
+		ISBN:          metadata.ISBN,
+		Publisher:     metadata.Publisher,
+		PublishedDate: metadata.PublishedDate,
+		Language:      strings.ReplaceAll(metadata.Language, "_", "-"),
+		Format:        models.BookFormatEbook,
+		Chapters:      ebookChapters(metadata, metadata.Pages),
+	}
+
+	if book.Title == "" {
+		book.Title = strings.TrimSuffix(fileName, path.Ext(fileName))
+	}
+
+	for _, creator := range metadata.Creators {
+		role, ok := ebookRoles[creator.Role]
+		if !ok {
+			continue
+		}
+
+		var author models.BookAuthor
+		author.Author.Name, author.Author.SortName = models.ParseAuthorName(creator.Name)
+		if creator.SortName != "" {
+			author.Author.SortName = creator.SortName
+		}
+		author.Role = role
+		book.Authors = append(book.Authors, author)
+	}
+
+	return book
+}
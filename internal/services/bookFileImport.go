@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bookwise/internal/koreader"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ebookExtensions are the file name extensions ImportDir picks up. AZW and
+// AZW3 files are Kindle books in the MOBI container.
+var ebookExtensions = map[string]bool{
+	".epub": true,
+	".pdf":  true,
+	".mobi": true,
+	".azw":  true,
+	".azw3": true,
+}
+
+// dirImport is the state of an ImportDir run, which de-duplicates the files
+// of the run among themselves as well as against the library.
+type dirImport struct {
+	userID int64
+	dryRun bool
+	hashes map[string]*models.EbookImportFile
+	isbns  map[string]*models.EbookImportFile
+}
+
+// ImportDir walks fsys for ebook files and adds them to the library of the
+// user, creating a book from the metadata of every file. Files imported
+// before are reported as duplicates and files whose ISBN is already in the
+// library are attached to that book. Hidden files and directories are
+// skipped. A dry run reports the same outcomes without saving anything.
+func (s *bookFileService) ImportDir(fsys fs.FS, userID int64, dryRun bool) (*models.EbookImport, error) {
+	run := &dirImport{
+		userID: userID,
+		dryRun: dryRun,
+		hashes: map[string]*models.EbookImportFile{},
+		isbns:  map[string]*models.EbookImportFile{},
+	}
+	result := &models.EbookImport{
+		Files: []*models.EbookImportFile{},
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			result.Add(&models.EbookImportFile{
+				Path:    name,
+				Outcome: models.EbookImportFailed,
+				Errors:  map[string]string{"file": err.Error()},
+			})
+			return nil
+		}
+
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !ebookExtensions[strings.ToLower(path.Ext(name))] {
+			return nil
+		}
+
+		file, err := s.importFile(fsys, name, run)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		result.Add(file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importFile imports a file in its own transaction, so a file that fails
+// leaves nothing behind. Only errors that should stop the whole run are
+// returned; files that cannot be read or make no valid book are reported
+// as failed.
+func (s *bookFileService) importFile(fsys fs.FS, name string, run *dirImport) (*models.EbookImportFile, error) {
+	report := &models.EbookImportFile{
+		Path:    name,
+		Outcome: models.EbookImportFailed,
+	}
+	v := validator.New()
+
+	data, err := readImportFile(fsys, name, s.config.Files.MaxBytes)
+	if err != nil {
+		v.AddError("file", err.Error())
+		report.Errors = v.Errors
+		return report, nil
+	}
+
+	metadata, format, err := s.read(data, v)
+	if err != nil {
+		if errors.Is(err, e.ErrInvalidData) {
+			report.Errors = v.Errors
+			return report, nil
+		}
+		return nil, err
+	}
+	report.Format = string(format)
+
+	sum := sha256.Sum256(data)
+	file := &models.BookFile{
+		UserID:       run.userID,
+		Format:       string(format),
+		FileName:     path.Base(name),
+		Size:         int64(len(data)),
+		ContentHash:  fmt.Sprintf("%x", sum),
+		DocumentHash: koreader.DocumentHash(data),
+	}
+	file.BlobKey = fmt.Sprintf("files/%d/%s%s", run.userID, file.ContentHash, format.Extension())
+
+	if earlier, ok := run.hashes[file.ContentHash]; ok {
+		report.Outcome = models.EbookImportDuplicate
+		report.BookID, report.Title, report.ISBN = earlier.BookID, earlier.Title, earlier.ISBN
+		return report, nil
+	}
+
+	// An upload waiting to be confirmed is taken over by the import rather
+	// than stored a second time.
+	existing, err := s.bookFile.GetByHash(file.ContentHash, run.userID)
+	switch {
+	case err == nil && existing.BookID != nil:
+		report.Outcome = models.EbookImportDuplicate
+		report.BookID = existing.BookID
+		run.hashes[file.ContentHash] = report
+		return report, nil
+	case err == nil:
+		file = existing
+	case !errors.Is(err, e.ErrRecordNotFound):
+		return nil, err
+	}
+
+	book := bookFromMetadata(metadata, file.FileName)
+	book.NormalizeISBN()
+	report.Title, report.ISBN = book.Title, book.ISBN
+
+	var target *int64
+	if book.ISBN != "" {
+		if earlier, ok := run.isbns[book.ISBN]; ok {
+			report.Outcome = models.EbookImportLinked
+			report.Title, target = earlier.Title, earlier.BookID
+		} else {
+			linked, err := s.book.GetByISBN(book.ISBN, run.userID)
+			switch {
+			case err == nil:
+				report.Outcome = models.EbookImportLinked
+				report.Title, target = linked.Title, &linked.ID
+			case !errors.Is(err, e.ErrRecordNotFound):
+				return nil, err
+			}
+		}
+	}
+
+	stored := file.ID == 0 && !run.dryRun
+	if stored {
+		if err := s.blobs.Put(file.BlobKey, bytes.NewReader(data), file.Size, format.ContentType()); err != nil {
+			return nil, err
+		}
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		if report.Outcome != models.EbookImportLinked {
+			if err := s.bookService.Import(tx, book, run.userID, v); err != nil {
+				return err
+			}
+			target = &book.ID
+		}
+
+		if file.ID == 0 {
+			if err := s.bookFile.Insert(tx, file); err != nil {
+				return err
+			}
+		}
+
+		// Files of a dry run link to books that were not saved.
+		if target != nil {
+			if err := s.bookFile.Attach(tx, file.ID, run.userID, *target); err != nil {
+				return err
+			}
+		}
+
+		if run.dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	var fieldErr e.ValidationFieldError
+	switch {
+	case err == nil, errors.Is(err, errDryRun):
+	case errors.As(err, &fieldErr):
+		v.AddError(fieldErr.Field, fieldErr.Message)
+	case errors.Is(err, e.ErrInvalidData):
+	default:
+		if stored {
+			s.blobs.Delete(file.BlobKey)
+		}
+		return nil, err
+	}
+
+	if !v.Valid() {
+		if stored {
+			s.blobs.Delete(file.BlobKey)
+		}
+		report.Outcome = models.EbookImportFailed
+		report.Errors = v.Errors
+		return report, nil
+	}
+
+	if report.Outcome != models.EbookImportLinked {
+		report.Outcome = models.EbookImportCreated
+		if !run.dryRun {
+			report.BookID = &book.ID
+
+			// The cover is best effort, as in Confirm.
+			if len(metadata.Cover) > 0 && book.CoverURL == "" {
+				s.bookService.SetCover(book.ID, run.userID, metadata.Cover, validator.New())
+			}
+		}
+	} else if !run.dryRun {
+		report.BookID = target
+	}
+
+	run.hashes[file.ContentHash] = report
+	if book.ISBN != "" && run.isbns[book.ISBN] == nil {
+		run.isbns[book.ISBN] = report
+	}
+
+	return report, nil
+}
+
+// readImportFile reads a file of an import, refusing files larger than
+// maxBytes.
+func readImportFile(fsys fs.FS, name string, maxBytes int64) ([]byte, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() > maxBytes {
+		return nil, fmt.Errorf("is larger than %d bytes", maxBytes)
+	}
+
+	return fs.ReadFile(fsys, name)
+}
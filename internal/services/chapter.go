@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bookwise/internal/ebook"
+	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/internal/storage"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type chapterService struct {
+	chapter  repositories.ChapterRepository
+	book     repositories.BookRepository
+	bookFile repositories.BookFileRepository
+	blobs    storage.BlobStore
+	db       *sql.DB
+}
+
+type ChapterService interface {
+	FindByBook(bookID, userID int64) ([]*models.Chapter, error)
+	Replace(bookID, userID int64, chapters []*models.Chapter, v *validator.Validator) ([]*models.Chapter, error)
+	ImportFromFile(bookID, fileID, userID int64, v *validator.Validator) ([]*models.Chapter, error)
+}
+
+func NewChapterService(
+	chapter repositories.ChapterRepository,
+	book repositories.BookRepository,
+	bookFile repositories.BookFileRepository,
+	blobs storage.BlobStore,
+	db *sql.DB,
+) *chapterService {
+	return &chapterService{
+		chapter:  chapter,
+		book:     book,
+		bookFile: bookFile,
+		blobs:    blobs,
+		db:       db,
+	}
+}
+
+// FindByBook returns the table of contents of a book of the user.
+func (s *chapterService) FindByBook(bookID, userID int64) ([]*models.Chapter, error) {
+	book, err := s.book.GetByID(bookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return bookChapters(s.chapter, book)
+}
+
+// Replace makes chapters the table of contents of a book of the user. An
+// empty list removes it.
+func (s *chapterService) Replace(
+	bookID, userID int64,
+	chapters []*models.Chapter,
+	v *validator.Validator,
+) ([]*models.Chapter, error) {
+	book, err := s.book.GetByID(bookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if models.ValidateChapters(v, chapters, book.Pages); !v.Valid() {
+		return nil, e.ErrInvalidData
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.chapter.Replace(tx, book.ID, chapters)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	models.NumberChapters(chapters, book.Pages)
+	return chapters, nil
+}
+
+// ImportFromFile replaces the table of contents of a book of the user with
+// the one of an EPUB file of the book.
+func (s *chapterService) ImportFromFile(bookID, fileID, userID int64, v *validator.Validator) ([]*models.Chapter, error) {
+	file, err := s.bookFile.GetByID(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.BookID == nil || *file.BookID != bookID {
+		return nil, e.ErrRecordNotFound
+	}
+
+	book, err := s.book.GetByID(bookID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := s.blobs.Get(file.BlobKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return nil, e.ErrRecordNotFound
+		}
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, format, err := ebook.Read(data)
+	switch {
+	case errors.Is(err, ebook.ErrMalformed):
+		v.AddError("file", fmt.Sprintf("could not be read as %s: %v", strings.ToUpper(string(format)), err))
+		return nil, e.ErrInvalidData
+	case err != nil:
+		return nil, err
+	}
+
+	chapters := ebookChapters(metadata, book.Pages)
+	if len(chapters) == 0 {
+		v.AddError("file", "has no table of contents")
+		return nil, e.ErrInvalidData
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.chapter.Replace(tx, book.ID, chapters)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	models.NumberChapters(chapters, book.Pages)
+	return chapters, nil
+}
+
+// bookChapters returns the numbered chapters of book.
+func bookChapters(chapter repositories.ChapterRepository, book *models.Book) ([]*models.Chapter, error) {
+	chapters, err := chapter.GetByBookID(book.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	models.NumberChapters(chapters, book.Pages)
+	return chapters, nil
+}
+
+// ebookChapters turns the table of contents of a file into the chapters of
+// a book of pages pages. Page numbers of the file are estimates that may
+// run past the pages of the book, so those chapters are left out.
+func ebookChapters(metadata *ebook.Metadata, pages int) []*models.Chapter {
+	chapters := []*models.Chapter{}
+	for _, entry := range metadata.Chapters {
+		if pages > 0 && entry.StartPage > pages {
+			break
+		}
+
+		title := entry.Title
+		if len(title) > 500 {
+			title = strings.ToValidUTF8(title[:500], "")
+		}
+
+		chapters = append(chapters, &models.Chapter{
+			Title:     title,
+			StartPage: entry.StartPage,
+		})
+	}
+	return chapters
+}
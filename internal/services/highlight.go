@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bookwise/internal/clippings"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"errors"
+)
+
+type highlightService struct {
+	highlight   repositories.HighlightRepository
+	book        repositories.BookRepository
+	bookService BookService
+	db          *sql.DB
+}
+
+type HighlightService interface {
+	FindAll(
+		search filters.HighlightFilters,
+		userID int64,
+		f filters.Filters,
+	) ([]*models.Highlight, filters.Metadata, error)
+	FindByID(id, userID int64) (*models.Highlight, error)
+	Random(search filters.HighlightFilters, userID int64, seed string) (*models.Highlight, error)
+	Export(search filters.HighlightFilters, userID int64) ([]*models.Highlight, error)
+	Save(highlight *models.Highlight, userID int64, v *validator.Validator) error
+	Update(highlight *models.Highlight, userID int64, v *validator.Validator) error
+	Delete(id, userID int64) error
+	Import(
+		data []byte,
+		format clippings.Format,
+		userID int64,
+		dryRun bool,
+		createMissing bool,
+		v *validator.Validator,
+	) (*models.HighlightImport, error)
+}
+
+func NewHighlightService(
+	highlight repositories.HighlightRepository,
+	book repositories.BookRepository,
+	bookService BookService,
+	db *sql.DB,
+) *highlightService {
+	return &highlightService{
+		highlight:   highlight,
+		book:        book,
+		bookService: bookService,
+		db:          db,
+	}
+}
+
+func (s *highlightService) FindAll(
+	search filters.HighlightFilters,
+	userID int64,
+	f filters.Filters,
+) ([]*models.Highlight, filters.Metadata, error) {
+	return s.highlight.GetAll(search, userID, f)
+}
+
+func (s *highlightService) FindByID(id, userID int64) (*models.Highlight, error) {
+	return s.highlight.GetByID(id, userID)
+}
+
+func (s *highlightService) Random(search filters.HighlightFilters, userID int64, seed string) (*models.Highlight, error) {
+	return s.highlight.Random(search, userID, seed)
+}
+
+func (s *highlightService) Export(search filters.HighlightFilters, userID int64) ([]*models.Highlight, error) {
+	return s.highlight.Export(search, userID)
+}
+
+func (s *highlightService) Save(highlight *models.Highlight, userID int64, v *validator.Validator) error {
+	if err := s.validate(highlight, userID, v); err != nil {
+		return err
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.highlight.Insert(tx, highlight, userID)
+	})
+}
+
+func (s *highlightService) Update(highlight *models.Highlight, userID int64, v *validator.Validator) error {
+	if err := s.validate(highlight, userID, v); err != nil {
+		return err
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.highlight.Update(tx, highlight, userID)
+	})
+}
+
+// validate checks the highlight against its book, which must belong to the
+// user, and against the reading session it is linked to, if any.
+func (s *highlightService) validate(highlight *models.Highlight, userID int64, v *validator.Validator) error {
+	if highlight.ValidateHighlight(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	book, err := s.book.GetByID(highlight.Book.ID, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrRecordNotFound) {
+			v.AddError("book", "not found")
+			return e.ErrInvalidData
+		}
+		return err
+	}
+	highlight.Book = book
+
+	if highlight.SessionID != nil {
+		ok, err := s.highlight.SessionOfBook(*highlight.SessionID, book.ID, userID)
+		if err != nil {
+			return err
+		}
+		v.Check(ok, "sessionId", "must be a reading session of this book")
+	}
+
+	if highlight.ValidateLocation(v, book); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return nil
+}
+
+func (s *highlightService) Delete(id, userID int64) error {
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.highlight.Delete(tx, id, userID)
+	})
+}
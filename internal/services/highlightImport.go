@@ -0,0 +1,304 @@
+package services
+
+import (
+	"bookwise/internal/clippings"
+	"bookwise/internal/models"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/fuzzy"
+	"bookwise/utils/validator"
+	"bytes"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// minBookMatch is the similarity an e-reader's title and author must reach to
+// be matched to one of the user's books.
+const minBookMatch = 0.85
+
+var trailingParensRX = regexp.MustCompile(`\s*\([^()]*\)\s*$`)
+
+// errDryRun rolls back the transaction of a dry-run import.
+var errDryRun = errors.New("dry run")
+
+type clippingGroup struct {
+	result    *models.HighlightImportBook
+	book      *models.Book
+	clippings []clippings.Clipping
+}
+
+// Import reads an e-reader export and adds its highlights to the matching
+// books of the user. Highlights imported before are counted as duplicates,
+// invalid ones, such as overlong passages, as skipped,
+// and books that match none of the user's books are reported so they can be
+// created, or are created along with their highlights when createMissing is
+// set. A dry run reports the same numbers without saving anything. An
+// empty format is detected from the data.
+func (s *highlightService) Import(
+	data []byte,
+	format clippings.Format,
+	userID int64,
+	dryRun bool,
+	createMissing bool,
+	v *validator.Validator,
+) (*models.HighlightImport, error) {
+	if format == "" {
+		detected, err := clippings.Detect(data)
+		if err != nil {
+			v.AddError("file", "must be a Kindle My Clippings.txt or a KOReader JSON export")
+			return nil, e.ErrInvalidData
+		}
+		format = detected
+	}
+
+	clips, err := clippings.Parse(format, bytes.NewReader(data))
+	if err != nil {
+		v.AddError("file", "could not be read as a "+string(format)+" export")
+		return nil, e.ErrInvalidData
+	}
+
+	books, err := s.book.GetTitles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupClippings(clips, books)
+	result := &models.HighlightImport{
+		Matched:   []*models.HighlightImportBook{},
+		Created:   []*models.HighlightImportBook{},
+		Unmatched: []*models.HighlightImportBook{},
+	}
+	created := map[*clippingGroup]bool{}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		for _, group := range groups {
+			if group.book == nil {
+				if !createMissing {
+					continue
+				}
+
+				book, err := s.createBook(tx, group, books, userID)
+				if err != nil {
+					return err
+				}
+				if book == nil {
+					continue
+				}
+
+				group.book = book
+				group.result.BookID = &book.ID
+				created[group] = true
+				books = append(books, book)
+			}
+
+			for _, clip := range group.clippings {
+				highlight := &models.Highlight{
+					Book:       group.book,
+					Text:       clip.Text,
+					Page:       clip.Page,
+					Chapter:    clip.Chapter,
+					Note:       clip.Note,
+					Color:      models.HighlightColorFrom(clip.Color),
+					Source:     string(format),
+					SourceHash: clip.Hash(),
+				}
+
+				check := validator.New()
+				if highlight.ValidateHighlight(check); !check.Valid() {
+					result.Skipped++
+					continue
+				}
+
+				inserted, err := s.highlight.Import(tx, highlight, clip.AddedAt, userID)
+				if err != nil {
+					return err
+				}
+
+				if inserted {
+					result.Imported++
+				} else {
+					result.Duplicates++
+				}
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		switch {
+		case created[group]:
+			if dryRun {
+				group.result.BookID = nil
+			}
+			result.Created = append(result.Created, group.result)
+		case group.book == nil:
+			result.Unmatched = append(result.Unmatched, group.result)
+		default:
+			result.Matched = append(result.Matched, group.result)
+		}
+	}
+
+	return result, nil
+}
+
+// createBook creates the ebook of an unmatched group as part of tx. E-readers
+// do not tell how long a book is, so it is given the highest page highlighted
+// in it. It returns nil, and the reasons in the group's result, when the book
+// cannot be created, such as when no page is known or another of books already
+// has its title.
+func (s *highlightService) createBook(
+	tx *sql.Tx,
+	group *clippingGroup,
+	books []*models.Book,
+	userID int64,
+) (*models.Book, error) {
+	book := &models.Book{
+		Title:  group.result.Title,
+		Author: group.result.Author,
+		Format: models.BookFormatEbook,
+	}
+	for _, clip := range group.clippings {
+		book.Pages = max(book.Pages, clip.Page)
+	}
+
+	v := validator.New()
+	for _, other := range books {
+		if other.Title == book.Title {
+			v.AddError(e.ErrBookTitle.Field, e.ErrBookTitle.Message)
+			group.result.Errors = v.Errors
+			return nil, nil
+		}
+	}
+
+	err := s.bookService.Import(tx, book, userID, v)
+	if errors.Is(err, e.ErrInvalidData) {
+		group.result.Errors = v.Errors
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return book, nil
+}
+
+// groupClippings groups the clippings by the book the e-reader named and
+// matches every group to the closest of books.
+func groupClippings(clips []clippings.Clipping, books []*models.Book) []*clippingGroup {
+	groups := []*clippingGroup{}
+	byKey := map[string]*clippingGroup{}
+
+	for _, clip := range clips {
+		key := fuzzy.Normalize(clip.Title) + "\x00" + fuzzy.Normalize(clip.Author)
+
+		group, ok := byKey[key]
+		if !ok {
+			title, author := clip.Title, importedAuthor(clip.Author)
+			group = &clippingGroup{
+				book: matchBook(books, title, author),
+				result: &models.HighlightImportBook{
+					Title:  title,
+					Author: author,
+				},
+			}
+			if group.book != nil {
+				group.result.BookID = &group.book.ID
+			}
+
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+
+		group.clippings = append(group.clippings, clip)
+		group.result.Highlights++
+	}
+
+	return groups
+}
+
+// importedAuthor turns an e-reader credit such as "Herbert, Frank;
+// Anderson, Kevin J." into display names.
+func importedAuthor(credit string) string {
+	names := []string{}
+	for _, name := range strings.Split(credit, ";") {
+		if name = strings.TrimSpace(name); name != "" {
+			display, _ := models.ParseAuthorName(name)
+			names = append(names, display)
+		}
+	}
+	return strings.Join(names, " & ")
+}
+
+// matchBook returns the book whose title and author are closest to the given
+// ones, or nil when none reaches minBookMatch. Titles are also compared
+// without subtitles and trailing parentheses, which e-readers often add for
+// the series.
+func matchBook(books []*models.Book, title, author string) *models.Book {
+	var best *models.Book
+	bestScore := 0.0
+
+	for _, book := range books {
+		score := titleSimilarity(book.Title, title)
+		if score < minBookMatch {
+			continue
+		}
+
+		if author != "" && book.Author != "" {
+			score = 0.8*score + 0.2*authorSimilarity(book.Author, author)
+		}
+
+		if score >= minBookMatch && score > bestScore {
+			best, bestScore = book, score
+		}
+	}
+
+	return best
+}
+
+func titleSimilarity(a, b string) float64 {
+	best := 0.0
+	for _, x := range titleVariants(a) {
+		for _, y := range titleVariants(b) {
+			best = max(best, fuzzy.Ratio(x, y))
+		}
+	}
+	return best
+}
+
+func titleVariants(title string) []string {
+	variants := []string{title}
+
+	short := title
+	for trailingParensRX.MatchString(short) {
+		short = trailingParensRX.ReplaceAllString(short, "")
+	}
+	if short != title && short != "" {
+		variants = append(variants, short)
+	}
+
+	if main, _, ok := strings.Cut(short, ":"); ok && strings.TrimSpace(main) != "" {
+		variants = append(variants, main)
+	}
+
+	return variants
+}
+
+func authorSimilarity(a, b string) float64 {
+	best := 0.0
+	for _, x := range models.SplitAuthors(a) {
+		for _, y := range models.SplitAuthors(b) {
+			best = max(best, fuzzy.Ratio(x, y))
+		}
+	}
+	return best
+}
@@ -0,0 +1,424 @@
+package services
+
+import (
+	"bookwise/internal/kobo"
+	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// koboSessionNotes marks the sessions Kobo imports record, so an import adds
+// to the session it recorded earlier that day rather than to one the user
+// logged.
+const koboSessionNotes = "Imported from Kobo"
+
+// koboSource is the source of the highlights Kobo imports add.
+const koboSource = "kobo"
+
+type koboService struct {
+	kobo           repositories.KoboRepository
+	book           repositories.BookRepository
+	highlight      repositories.HighlightRepository
+	readingPlan    repositories.ReadingPlanRepository
+	readingSession repositories.ReadingSessionRepository
+	chapter        repositories.ChapterRepository
+	db             *sql.DB
+}
+
+type KoboService interface {
+	Import(
+		data []byte,
+		userID int64,
+		loc *time.Location,
+		dryRun bool,
+		v *validator.Validator,
+	) (*models.KoboImport, error)
+}
+
+func NewKoboService(
+	kobo repositories.KoboRepository,
+	book repositories.BookRepository,
+	highlight repositories.HighlightRepository,
+	readingPlan repositories.ReadingPlanRepository,
+	readingSession repositories.ReadingSessionRepository,
+	chapter repositories.ChapterRepository,
+	db *sql.DB,
+) *koboService {
+	return &koboService{
+		kobo:           kobo,
+		book:           book,
+		highlight:      highlight,
+		readingPlan:    readingPlan,
+		readingSession: readingSession,
+		chapter:        chapter,
+		db:             db,
+	}
+}
+
+type koboBook struct {
+	device   *kobo.Book
+	book     *models.Book
+	plan     *models.ReadingPlan
+	chapters []*models.Chapter
+	previous *models.KoboProgress
+	result   *models.KoboImportBook
+}
+
+// Import reads a KoboReader.sqlite and records the reading time and pages
+// read since the last import of each book as reading sessions of the days
+// they were read on, in loc. Plans are started and completed as the
+// device reports, and the highlights of the books are imported. Books are
+// matched by ISBN or else by title and author; those matching none are
+// reported, and their reading is counted once they are created. A dry run
+// reports the same numbers without saving anything.
+func (s *koboService) Import(
+	data []byte,
+	userID int64,
+	loc *time.Location,
+	dryRun bool,
+	v *validator.Validator,
+) (*models.KoboImport, error) {
+	devices, err := kobo.Read(data)
+	if err != nil {
+		if errors.Is(err, kobo.ErrNotKobo) {
+			v.AddError("file", "must be a KoboReader.sqlite database")
+			return nil, e.ErrInvalidData
+		}
+		return nil, err
+	}
+
+	books, err := s.book.GetTitles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := s.kobo.GetProgress(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := map[string]*models.KoboProgress{}
+	for _, snapshot := range snapshots {
+		previous[snapshot.ContentID] = snapshot
+	}
+
+	result := &models.KoboImport{
+		Highlights: models.HighlightImport{
+			Matched:   []*models.HighlightImportBook{},
+			Unmatched: []*models.HighlightImportBook{},
+		},
+		Matched:   []*models.KoboImportBook{},
+		Unmatched: []*models.KoboImportBook{},
+	}
+
+	entries := []*koboBook{}
+	for _, device := range devices {
+		author := importedAuthor(device.Author)
+		entry := &koboBook{
+			device:   device,
+			book:     matchKoboBook(books, device, author),
+			previous: previous[device.ContentID],
+			result: &models.KoboImportBook{
+				Title:      device.Title,
+				Author:     author,
+				Percent:    device.Percent,
+				Highlights: len(device.Highlights),
+			},
+		}
+
+		highlights := &models.HighlightImportBook{
+			Title:      device.Title,
+			Author:     author,
+			Highlights: len(device.Highlights),
+		}
+
+		if entry.book == nil {
+			result.Unmatched = append(result.Unmatched, entry.result)
+			if len(device.Highlights) > 0 {
+				result.Highlights.Unmatched = append(result.Highlights.Unmatched, highlights)
+			}
+			continue
+		}
+
+		entry.result.BookID = &entry.book.ID
+		result.Matched = append(result.Matched, entry.result)
+		if len(device.Highlights) > 0 {
+			highlights.BookID = &entry.book.ID
+			result.Highlights.Matched = append(result.Highlights.Matched, highlights)
+		}
+
+		entry.plan, err = activePlan(s.readingPlan, entry.book.ID, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.plan != nil {
+			entry.chapters, err = s.chapter.GetByBookID(entry.book.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	today := time.Now().In(loc)
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		for _, entry := range entries {
+			if err := s.importReading(tx, entry, result, today, loc, userID); err != nil {
+				return err
+			}
+
+			if err := s.importHighlights(tx, entry, &result.Highlights, userID); err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// importReading records the reading of a book since its last import in its
+// plan, a session for each day koboDays finds, and saves the device's totals
+// for the next import. Totals lower than the last imported ones mean the
+// device was reset, so they are all new reading. Books without a plan only
+// have their totals saved.
+func (s *koboService) importReading(
+	tx *sql.Tx,
+	entry *koboBook,
+	result *models.KoboImport,
+	today time.Time,
+	loc *time.Location,
+	userID int64,
+) error {
+	device := entry.device
+	snapshot := &models.KoboProgress{
+		UserID:      userID,
+		ContentID:   device.ContentID,
+		BookID:      &entry.book.ID,
+		SecondsRead: device.Seconds,
+		PercentRead: device.Percent,
+		ReadStatus:  device.Status,
+		LastRead:    device.LastRead,
+	}
+
+	seconds, start := device.Seconds, 0.0
+	if previous := entry.previous; previous != nil {
+		if seconds >= previous.SecondsRead {
+			seconds -= previous.SecondsRead
+		}
+		if device.Percent >= previous.PercentRead {
+			start = previous.PercentRead
+		}
+	}
+
+	if entry.plan != nil {
+		page := func(percent float64) int {
+			return int(percent/100*float64(entry.book.Pages) + 0.5)
+		}
+
+		days := koboDays(device, entry.previous, seconds, start, today, loc)
+		var began *time.Time
+		elapsed, reached := 0, start
+		for _, day := range days {
+			// Minutes are counted on the running total, so the seconds of
+			// days short of a minute add up.
+			minutes := (elapsed+day.seconds)/60 - elapsed/60
+			elapsed += day.seconds
+
+			pages := 0
+			var chapter *int
+			if entry.book.Pages > 0 {
+				pages = page(day.percent) - page(reached)
+				chapter = models.ChapterAt(entry.chapters, page(day.percent))
+			}
+			reached = day.percent
+
+			if minutes <= 0 && pages <= 0 {
+				continue
+			}
+
+			session := &models.ReadingSession{
+				ReadingPlan: *entry.plan,
+				PagesRead:   pages,
+				Minutes:     minutes,
+				Chapter:     chapter,
+				Notes:       koboSessionNotes,
+				Date:        day.date,
+			}
+			if err := s.readingSession.Record(tx, session, userID); err != nil {
+				return err
+			}
+
+			if began == nil {
+				began = &day.date
+			}
+			result.Sessions++
+			result.Minutes += minutes
+			result.PagesRead += pages
+			entry.result.Minutes += minutes
+			entry.result.PagesRead += pages
+		}
+
+		// Seconds short of a minute are left for the next import.
+		snapshot.SecondsRead = device.Seconds - seconds%60
+
+		date := days[len(days)-1].date
+		if began != nil {
+			date = *began
+		}
+
+		finished := device.Status == kobo.StatusFinished
+		completed, err := advancePlan(tx, s.readingPlan, entry.plan, began != nil, finished, date, userID)
+		if err != nil {
+			return err
+		}
+		if completed {
+			result.Completed++
+		}
+	}
+
+	return s.kobo.SaveProgress(tx, snapshot)
+}
+
+// koboDay is the reading of a book on a day: for how many seconds, and how
+// far the book was read by its end, from 0 to 100.
+type koboDay struct {
+	date    time.Time
+	seconds int
+	percent float64
+}
+
+// koboDays spreads the seconds read and the progress from start made since
+// the last import of a book over the days, in loc, the device logged reading
+// on since. What the log does not cover, because the device dropped it when
+// it synced with the Kobo store, goes to the day the book was last read, or
+// else to today. There is always at least one day, the last one.
+func koboDays(
+	device *kobo.Book,
+	previous *models.KoboProgress,
+	seconds int,
+	start float64,
+	today time.Time,
+	loc *time.Location,
+) []*koboDay {
+	var since *time.Time
+	if previous != nil {
+		since = previous.LastRead
+	}
+
+	days := []*koboDay{}
+	remaining, reached := seconds, start
+	for _, reading := range device.Readings {
+		if since != nil && !reading.At.After(*since) {
+			continue
+		}
+
+		date := reading.At.In(loc)
+		if n := len(days); n == 0 || !sameDay(days[n-1].date, date) {
+			days = append(days, &koboDay{date: date, percent: reached})
+		}
+		day := days[len(days)-1]
+
+		read := min(reading.Seconds, remaining)
+		day.seconds += read
+		remaining -= read
+
+		if reading.Percent != nil && *reading.Percent > reached {
+			reached = min(*reading.Percent, device.Percent)
+			day.percent = reached
+		}
+	}
+
+	last := today
+	if device.LastRead != nil {
+		last = device.LastRead.In(loc)
+	}
+	if n := len(days); n == 0 || (last.After(days[n-1].date) && !sameDay(days[n-1].date, last)) {
+		days = append(days, &koboDay{date: last, percent: reached})
+	}
+
+	day := days[len(days)-1]
+	day.seconds += remaining
+	day.percent = max(day.percent, device.Percent)
+	return days
+}
+
+// sameDay tells whether a and b, in the same location, fall on the same day.
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// importHighlights adds the highlights of a book, counting those imported
+// before as duplicates and invalid ones as skipped.
+func (s *koboService) importHighlights(
+	tx *sql.Tx,
+	entry *koboBook,
+	result *models.HighlightImport,
+	userID int64,
+) error {
+	for _, clip := range entry.device.Highlights {
+		highlight := &models.Highlight{
+			Book:       entry.book,
+			Text:       clip.Text,
+			Page:       clip.Page,
+			Chapter:    clip.Chapter,
+			Note:       clip.Note,
+			Color:      models.HighlightColorFrom(clip.Color),
+			Source:     koboSource,
+			SourceHash: clip.Hash(),
+		}
+
+		check := validator.New()
+		if highlight.ValidateHighlight(check); !check.Valid() {
+			result.Skipped++
+			continue
+		}
+
+		inserted, err := s.highlight.Import(tx, highlight, clip.AddedAt, userID)
+		if err != nil {
+			return err
+		}
+
+		if inserted {
+			result.Imported++
+		} else {
+			result.Duplicates++
+		}
+	}
+
+	return nil
+}
+
+// matchKoboBook returns the book of the user with the ISBN of device, or
+// else the one closest to its title and author.
+func matchKoboBook(books []*models.Book, device *kobo.Book, author string) *models.Book {
+	isbn := &models.Book{ISBN: device.ISBN}
+	isbn.NormalizeISBN()
+
+	if isbn.ISBN != "" {
+		for _, book := range books {
+			if book.ISBN == isbn.ISBN {
+				return book
+			}
+		}
+	}
+
+	return matchBook(books, device.Title, author)
+}
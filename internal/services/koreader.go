@@ -0,0 +1,255 @@
+package services
+
+import (
+	"bookwise/internal/koreader"
+	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/internal/storage"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"errors"
+	"io"
+	"time"
+)
+
+// koreaderSessionNotes marks the sessions progress syncs record, so a sync
+// adds to the session it recorded earlier that day rather than to one the
+// user logged.
+const koreaderSessionNotes = "Synced from KOReader"
+
+type koreaderService struct {
+	koreader       repositories.KOReaderRepository
+	bookFile       repositories.BookFileRepository
+	book           repositories.BookRepository
+	readingPlan    repositories.ReadingPlanRepository
+	readingSession repositories.ReadingSessionRepository
+	chapter        repositories.ChapterRepository
+	blobs          storage.BlobStore
+	db             *sql.DB
+}
+
+type KOReaderService interface {
+	FindAccount(userID int64) (*models.KOReaderAccount, error)
+	SaveAccount(account *models.KOReaderAccount, userID int64, v *validator.Validator) error
+	DeleteAccount(userID int64) error
+	Register(username, key string) error
+	FindProgress(userID int64, document string) (*models.KOReaderProgress, error)
+	UpdateProgress(progress *models.KOReaderProgress, userID int64, today time.Time, v *validator.Validator) error
+}
+
+func NewKOReaderService(
+	koreader repositories.KOReaderRepository,
+	bookFile repositories.BookFileRepository,
+	book repositories.BookRepository,
+	readingPlan repositories.ReadingPlanRepository,
+	readingSession repositories.ReadingSessionRepository,
+	chapter repositories.ChapterRepository,
+	blobs storage.BlobStore,
+	db *sql.DB,
+) *koreaderService {
+	return &koreaderService{
+		koreader:       koreader,
+		bookFile:       bookFile,
+		book:           book,
+		readingPlan:    readingPlan,
+		readingSession: readingSession,
+		chapter:        chapter,
+		blobs:          blobs,
+		db:             db,
+	}
+}
+
+func (s *koreaderService) FindAccount(userID int64) (*models.KOReaderAccount, error) {
+	return s.koreader.GetAccount(userID)
+}
+
+// SaveAccount sets the username and password the user logs in to the sync
+// plugin with.
+func (s *koreaderService) SaveAccount(account *models.KOReaderAccount, userID int64, v *validator.Validator) error {
+	account.UserID = userID
+	if account.Validate(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	if err := account.Key.Set(models.KOReaderKey(*account.Key.Plaintext)); err != nil {
+		return err
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.koreader.SaveAccount(tx, account)
+	})
+}
+
+func (s *koreaderService) DeleteAccount(userID int64) error {
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.koreader.DeleteAccount(tx, userID)
+	})
+}
+
+// Register answers the registration of the sync plugin. Accounts are set up
+// in Bookwise, so registering only succeeds for an account that exists with
+// the same key, which lets the plugin's register button act as a login. It
+// returns e.ErrRecordNotFound for unknown usernames and e.ErrDuplicateUser
+// for usernames registered with another key.
+func (s *koreaderService) Register(username, key string) error {
+	account, err := s.koreader.GetAccountByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	match, err := account.Key.Matches(key)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return e.ErrDuplicateUser
+	}
+
+	return nil
+}
+
+func (s *koreaderService) FindProgress(userID int64, document string) (*models.KOReaderProgress, error) {
+	return s.koreader.GetProgress(userID, document)
+}
+
+// UpdateProgress saves the position of a document and, when the document is
+// a file of a book with a reading plan, turns the pages read since the last
+// sync into a reading session of today. The plan is started when it was
+// planned or paused and completed when the end of the book is reached.
+func (s *koreaderService) UpdateProgress(
+	progress *models.KOReaderProgress,
+	userID int64,
+	today time.Time,
+	v *validator.Validator,
+) error {
+	progress.UserID = userID
+	if progress.Validate(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	previous, err := s.koreader.GetProgress(userID, progress.Document)
+	if err != nil && !errors.Is(err, e.ErrRecordNotFound) {
+		return err
+	}
+
+	bookID, err := s.findBook(progress.Document, userID)
+	if err != nil {
+		return err
+	}
+	progress.BookID = bookID
+
+	var book *models.Book
+	var plan *models.ReadingPlan
+	var chapters []*models.Chapter
+	if bookID != nil {
+		book, err = s.book.GetByID(*bookID, userID)
+		if err != nil {
+			return err
+		}
+
+		plan, err = activePlan(s.readingPlan, book.ID, userID)
+		if err != nil {
+			return err
+		}
+
+		if plan != nil {
+			chapters, err = s.chapter.GetByBookID(book.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		if err := s.koreader.SaveProgress(tx, progress); err != nil {
+			return err
+		}
+
+		if plan == nil {
+			return nil
+		}
+
+		read := 0
+		if book.Pages > 0 {
+			read = progress.PagesAt(book.Pages)
+			if previous != nil && previous.BookID != nil && *previous.BookID == book.ID {
+				read -= previous.PagesAt(book.Pages)
+			}
+		}
+
+		if read > 0 {
+			session := &models.ReadingSession{
+				ReadingPlan: *plan,
+				PagesRead:   read,
+				Chapter:     models.ChapterAt(chapters, progress.PagesAt(book.Pages)),
+				Notes:       koreaderSessionNotes,
+				Date:        today,
+			}
+			if err := s.readingSession.Record(tx, session, userID); err != nil {
+				return err
+			}
+		}
+
+		_, err := advancePlan(tx, s.readingPlan, plan, read > 0, progress.Percentage >= 1, today, userID)
+		return err
+	})
+}
+
+// findBook returns the book of the file KOReader knows as document, or nil
+// when no file matches. Files stored before document hashes were kept get
+// theirs the first time a document matches none.
+func (s *koreaderService) findBook(document string, userID int64) (*int64, error) {
+	file, err := s.bookFile.GetByDocument(document, userID)
+	switch {
+	case err == nil:
+		return file.BookID, nil
+	case !errors.Is(err, e.ErrRecordNotFound):
+		return nil, err
+	}
+
+	files, err := s.bookFile.GetWithoutDocumentHash(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var bookID *int64
+	for _, file := range files {
+		hash, err := s.documentHash(file)
+		if err != nil {
+			if errors.Is(err, storage.ErrBlobNotFound) {
+				continue
+			}
+			return nil, err
+		}
+
+		err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+			return s.bookFile.SetDocumentHash(tx, file.ID, hash)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if hash == document && bookID == nil {
+			bookID = file.BookID
+		}
+	}
+
+	return bookID, nil
+}
+
+func (s *koreaderService) documentHash(file *models.BookFile) (string, error) {
+	body, _, err := s.blobs.Get(file.BlobKey)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	return koreader.DocumentHash(data), nil
+}
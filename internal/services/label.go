@@ -0,0 +1,88 @@
+package services
+
+import (
+	"bookwise/internal/config"
+	"bookwise/internal/labels"
+	"bookwise/internal/models/filters"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// maxLabels caps the labels printed at once, about two dozen sheets.
+const maxLabels = 500
+
+type labelService struct {
+	bookService BookService
+	config      config.Config
+}
+
+type LabelService interface {
+	Labels(search filters.BookFilters, userID int64, paper string, v *validator.Validator) ([]byte, error)
+}
+
+func NewLabelService(bookService BookService, config config.Config) *labelService {
+	return &labelService{
+		bookService: bookService,
+		config:      config,
+	}
+}
+
+// Labels lays out a PDF document of labels on paper for the books matching
+// search, in ID order, each with a QR code linking to the book in the app.
+func (s *labelService) Labels(
+	search filters.BookFilters,
+	userID int64,
+	paper string,
+	v *validator.Validator,
+) ([]byte, error) {
+	sheet, err := labels.ParsePaper(paper)
+	if err != nil {
+		v.AddError("paper", "must be a4 or letter")
+		return nil, e.ErrInvalidData
+	}
+
+	appURL := strings.TrimRight(s.config.App.URL, "/")
+	items := []labels.Label{}
+
+	var afterID int64
+	for {
+		books, err := s.bookService.FindBatch(search, userID, afterID, exportBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, book := range books {
+			items = append(items, labels.Label{
+				Title:    book.Title,
+				Author:   book.Author,
+				Location: strings.Join(book.LocationPath, " › "),
+				URL:      fmt.Sprintf("%s/books/%d", appURL, book.ID),
+			})
+		}
+
+		if len(items) > maxLabels {
+			v.AddError("books", fmt.Sprintf("must not be more than %d, narrow the search", maxLabels))
+			return nil, e.ErrInvalidData
+		}
+
+		if len(books) < exportBatchSize {
+			break
+		}
+		afterID = books[len(books)-1].ID
+	}
+
+	if len(items) == 0 {
+		v.AddError("books", "no book matches the search")
+		return nil, e.ErrInvalidData
+	}
+
+	var out bytes.Buffer
+	if err := labels.Write(&out, sheet, items); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/repositories"
+)
+
+// exportBatchSize is how many books an export loads at a time.
+const exportBatchSize = 200
+
+type libraryExportService struct {
+	bookService    BookService
+	review         repositories.ReviewRepository
+	shelf          repositories.ShelfRepository
+	readingPlan    repositories.ReadingPlanRepository
+	readingSession repositories.ReadingSessionRepository
+}
+
+type LibraryExportService interface {
+	Export(search filters.BookFilters, userID int64, fn func(*models.LibraryEntry) error) error
+}
+
+func NewLibraryExportService(
+	bookService BookService,
+	review repositories.ReviewRepository,
+	shelf repositories.ShelfRepository,
+	readingPlan repositories.ReadingPlanRepository,
+	readingSession repositories.ReadingSessionRepository,
+) *libraryExportService {
+	return &libraryExportService{
+		bookService:    bookService,
+		review:         review,
+		shelf:          shelf,
+		readingPlan:    readingPlan,
+		readingSession: readingSession,
+	}
+}
+
+// Export calls fn for every book matching search, in ID order, together with
+// its review, shelves, plans and sessions. Books are loaded in batches, so a
+// library of any size is exported without holding it in memory. An error
+// returned by fn stops the export and is returned.
+func (s *libraryExportService) Export(
+	search filters.BookFilters,
+	userID int64,
+	fn func(*models.LibraryEntry) error,
+) error {
+	var afterID int64
+	for {
+		books, err := s.bookService.FindBatch(search, userID, afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+
+		entries, err := s.entries(books, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if len(books) < exportBatchSize {
+			return nil
+		}
+		afterID = books[len(books)-1].ID
+	}
+}
+
+func (s *libraryExportService) entries(books []*models.Book, userID int64) ([]*models.LibraryEntry, error) {
+	if len(books) == 0 {
+		return nil, nil
+	}
+
+	bookIDs := make([]int64, 0, len(books))
+	for _, book := range books {
+		bookIDs = append(bookIDs, book.ID)
+	}
+
+	reviews, err := s.review.GetByBookIDs(bookIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	shelves, err := s.shelf.GetNamesByBookIDs(bookIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	plans, err := s.readingPlan.GetByBookIDs(bookIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	planIDs := make([]int64, 0, len(plans))
+	plansByBook := make(map[int64][]*models.ReadingPlan)
+	for _, plan := range plans {
+		planIDs = append(planIDs, plan.ID)
+		plansByBook[plan.Book.ID] = append(plansByBook[plan.Book.ID], plan)
+	}
+
+	sessions, err := s.readingSession.GetByPlanIDs(planIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsByPlan := make(map[int64][]*models.ReadingSession)
+	for _, session := range sessions {
+		sessionsByPlan[session.ReadingPlan.ID] = append(sessionsByPlan[session.ReadingPlan.ID], session)
+	}
+
+	entries := make([]*models.LibraryEntry, 0, len(books))
+	for _, book := range books {
+		entries = append(entries, &models.LibraryEntry{
+			Book:     book,
+			Review:   reviews[book.ID],
+			Shelves:  shelves[book.ID],
+			Plans:    plansByBook[book.ID],
+			Sessions: sessionsByPlan,
+		})
+	}
+
+	return entries, nil
+}
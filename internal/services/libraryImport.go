@@ -0,0 +1,484 @@
+package services
+
+import (
+	"bookwise/internal/calibre"
+	"bookwise/internal/librarycsv"
+	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/fuzzy"
+	"bookwise/utils/validator"
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// maxLibraryImportRows bounds the books of a single upload.
+const maxLibraryImportRows = 20000
+
+type libraryImportService struct {
+	libraryImport repositories.LibraryImportRepository
+	bookService   BookService
+	book          repositories.BookRepository
+	shelf         repositories.ShelfRepository
+	readingPlan   repositories.ReadingPlanRepository
+	review        repositories.ReviewRepository
+	db            *sql.DB
+}
+
+type LibraryImportService interface {
+	Start(
+		data []byte,
+		source librarycsv.Source,
+		userID int64,
+		dryRun bool,
+		v *validator.Validator,
+	) (*models.LibraryImport, error)
+	FindByID(id, userID int64) (*models.LibraryImport, error)
+	RunPending() (int, error)
+}
+
+func NewLibraryImportService(
+	libraryImport repositories.LibraryImportRepository,
+	bookService BookService,
+	book repositories.BookRepository,
+	shelf repositories.ShelfRepository,
+	readingPlan repositories.ReadingPlanRepository,
+	review repositories.ReviewRepository,
+	db *sql.DB,
+) *libraryImportService {
+	return &libraryImportService{
+		libraryImport: libraryImport,
+		bookService:   bookService,
+		book:          book,
+		shelf:         shelf,
+		readingPlan:   readingPlan,
+		review:        review,
+		db:            db,
+	}
+}
+
+// Start checks that data is a Goodreads or StoryGraph export or a Calibre
+// library and queues it for RunPending. An empty source is detected from the
+// header.
+func (s *libraryImportService) Start(
+	data []byte,
+	source librarycsv.Source,
+	userID int64,
+	dryRun bool,
+	v *validator.Validator,
+) (*models.LibraryImport, error) {
+	if source == "" && calibre.Detect(data) {
+		source = librarycsv.SourceCalibre
+	}
+	if source == "" {
+		detected, err := librarycsv.Detect(data)
+		if err != nil {
+			v.AddError("file", "must be a Goodreads or StoryGraph library export or a Calibre metadata.db")
+			return nil, e.ErrInvalidData
+		}
+		source = detected
+	}
+
+	entries, err := parseLibrary(source, data)
+	if err != nil {
+		v.AddError("file", "could not be read as a "+string(source)+" export")
+		return nil, e.ErrInvalidData
+	}
+
+	v.Check(len(entries) > 0, "file", "must contain at least one book")
+	v.Check(
+		len(entries) <= maxLibraryImportRows,
+		"file",
+		fmt.Sprintf("must not contain more than %d books", maxLibraryImportRows),
+	)
+	if !v.Valid() {
+		return nil, e.ErrInvalidData
+	}
+
+	job := &models.LibraryImport{
+		UserID: userID,
+		Source: string(source),
+		DryRun: dryRun,
+		Data:   data,
+		Total:  len(entries),
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.libraryImport.Insert(tx, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (s *libraryImportService) FindByID(id, userID int64) (*models.LibraryImport, error) {
+	return s.libraryImport.GetByID(id, userID)
+}
+
+// RunPending runs queued imports one after the other until none is left and
+// returns how many ran.
+func (s *libraryImportService) RunPending() (int, error) {
+	ran := 0
+	for {
+		job, err := s.libraryImport.Claim()
+		if err != nil {
+			if errors.Is(err, e.ErrRecordNotFound) {
+				return ran, nil
+			}
+			return ran, err
+		}
+
+		if err := s.run(job); err != nil {
+			return ran, err
+		}
+		ran++
+	}
+}
+
+// run imports the rows of job, saving the progress after each one. An import
+// taken over from a stopped runner resumes after the rows it had processed.
+func (s *libraryImportService) run(job *models.LibraryImport) error {
+	entries, err := parseLibrary(librarycsv.Source(job.Source), job.Data)
+	if err != nil {
+		return s.fail(job, err)
+	}
+
+	books, err := s.book.GetTitles(job.UserID)
+	if err != nil {
+		return s.fail(job, err)
+	}
+
+	for _, entry := range entries[min(job.Processed, len(entries)):] {
+		outcome, rowErrors, err := s.importEntry(job, entry, &books)
+		if err != nil {
+			return s.fail(job, fmt.Errorf("line %d: %w", entry.Line, err))
+		}
+
+		switch outcome {
+		case importCreated:
+			job.Created++
+		case importLinked:
+			job.Linked++
+		case importSkipped:
+			job.Skipped++
+		case importFailed:
+			job.Failed++
+			if err := s.libraryImport.AddErrors(job.ID, rowErrors); err != nil {
+				return s.fail(job, err)
+			}
+		}
+
+		job.Processed++
+		if err := s.libraryImport.SaveProgress(job); err != nil {
+			return err
+		}
+	}
+
+	job.Status = models.LibraryImportCompleted
+	return s.libraryImport.SaveProgress(job)
+}
+
+// parseLibrary reads the entries of an export of source.
+func parseLibrary(source librarycsv.Source, data []byte) ([]librarycsv.Entry, error) {
+	if source == librarycsv.SourceCalibre {
+		return calibre.Read(data)
+	}
+	return librarycsv.Parse(source, bytes.NewReader(data))
+}
+
+// fail marks job as failed because of err and returns err.
+func (s *libraryImportService) fail(job *models.LibraryImport, err error) error {
+	job.Status = models.LibraryImportFailed
+	job.Error = err.Error()
+
+	if saveErr := s.libraryImport.SaveProgress(job); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+type importOutcome int
+
+const (
+	importCreated importOutcome = iota + 1
+	importLinked
+	importSkipped
+	importFailed
+)
+
+// importEntry imports a row in its own transaction, so a row that fails
+// validation leaves nothing behind. Rows an earlier upload imported are
+// skipped, books already in the library are linked and only put on their
+// shelves, and new books are created together with their reading plan and
+// review. Invalid rows return their validation messages.
+func (s *libraryImportService) importEntry(
+	job *models.LibraryImport,
+	entry librarycsv.Entry,
+	books *[]*models.Book,
+) (importOutcome, []*models.LibraryImportError, error) {
+	v := validator.New()
+	externalID := entry.ExternalID
+	if externalID == "" {
+		externalID = fuzzy.Normalize(entry.Title) + "/" + fuzzy.Normalize(entry.Author)
+	}
+
+	var outcome importOutcome
+	var created *models.Book
+
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		_, err := s.libraryImport.ExternalBookID(tx, job.UserID, job.Source, externalID)
+		if err == nil {
+			outcome = importSkipped
+			return nil
+		}
+		if !errors.Is(err, e.ErrRecordNotFound) {
+			return err
+		}
+
+		book, err := s.identifiedBook(tx, job, entry)
+		if err != nil {
+			return err
+		}
+		if book == nil {
+			book = findImportedBook(*books, entry)
+		}
+
+		if book != nil {
+			outcome = importLinked
+		} else {
+			outcome = importCreated
+			book = importedBook(entry)
+			if err := s.bookService.Import(tx, book, job.UserID, v); err != nil {
+				return err
+			}
+
+			if err := s.addReading(tx, book, entry, job.UserID, v); err != nil {
+				return err
+			}
+			created = book
+		}
+
+		if err := s.addToShelves(tx, book, entry.Shelves, job.UserID, v); err != nil {
+			return err
+		}
+
+		if err := s.libraryImport.LinkExternalID(tx, job.UserID, job.Source, externalID, book.ID); err != nil {
+			return err
+		}
+
+		if job.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	var fieldErr e.ValidationFieldError
+	switch {
+	case err == nil, errors.Is(err, errDryRun):
+	case errors.As(err, &fieldErr):
+		v.AddError(fieldErr.Field, fieldErr.Message)
+		return importFailed, importErrors(entry, v), nil
+	case errors.Is(err, e.ErrInvalidData):
+		return importFailed, importErrors(entry, v), nil
+	default:
+		return 0, nil, err
+	}
+
+	// Later rows naming the same book, as exports list every edition
+	// separately, are linked to the one just created.
+	if created != nil {
+		*books = append(*books, created)
+	}
+
+	return outcome, nil, nil
+}
+
+// identifiedSources are the sources whose IDs libraries of other sources,
+// such as Calibre's, record for their books.
+var identifiedSources = []librarycsv.Source{librarycsv.SourceGoodreads, librarycsv.SourceStoryGraph}
+
+// identifiedBook returns the book an import of another source made of the
+// book that entry carries the ID of in that source, or nil when there is
+// none. The IDs are only looked up, not linked, so that a later export of
+// that source still imports its reading history for the book.
+func (s *libraryImportService) identifiedBook(
+	tx *sql.Tx,
+	job *models.LibraryImport,
+	entry librarycsv.Entry,
+) (*models.Book, error) {
+	for _, source := range identifiedSources {
+		id, ok := entry.Identifiers[string(source)]
+		if !ok || string(source) == job.Source {
+			continue
+		}
+
+		bookID, err := s.libraryImport.ExternalBookID(tx, job.UserID, string(source), id)
+		switch {
+		case err == nil:
+			return &models.Book{ID: bookID}, nil
+		case !errors.Is(err, e.ErrRecordNotFound):
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// findImportedBook returns the book of the library the entry describes,
+// matching it by ISBN or else by title and author.
+func findImportedBook(books []*models.Book, entry librarycsv.Entry) *models.Book {
+	book := &models.Book{ISBN: entry.ISBN}
+	book.NormalizeISBN()
+
+	if book.ISBN != "" {
+		for _, candidate := range books {
+			if candidate.ISBN == book.ISBN {
+				return candidate
+			}
+		}
+	}
+
+	return matchBook(books, entry.Title, entry.Author)
+}
+
+func importedBook(entry librarycsv.Entry) *models.Book {
+	book := &models.Book{
+		Title:          entry.Title,
+		Author:         entry.Author,
+		Pages:          entry.Pages,
+		ISBN:           entry.ISBN,
+		Publisher:      entry.Publisher,
+		PublishedDate:  entry.PublishedDate,
+		Language:       entry.Language,
+		Description:    entry.Description,
+		Format:         models.BookFormat(entry.Format),
+		SeriesName:     entry.SeriesName,
+		SeriesPosition: entry.SeriesPosition,
+	}
+
+	if book.Format == "" {
+		book.Format = models.BookFormatPaperback
+	}
+
+	return book
+}
+
+// addReading turns a read entry into a completed reading plan paced to the
+// dates it was read, and its rating into a review. Ratings are rounded to
+// the half stars reviews support.
+func (s *libraryImportService) addReading(
+	tx *sql.Tx,
+	book *models.Book,
+	entry librarycsv.Entry,
+	userID int64,
+	v *validator.Validator,
+) error {
+	if entry.Status == librarycsv.StatusRead {
+		plan := &models.ReadingPlan{
+			Status:     models.ReadingStatusCompleted,
+			Priority:   models.ReadingPriorityMedium,
+			StartDate:  entry.DateStarted,
+			TargetDate: entry.DateRead,
+			Book:       book,
+			User:       &models.User{ID: userID},
+		}
+
+		days := 1
+		if plan.StartDate != nil && plan.TargetDate != nil && plan.TargetDate.After(*plan.StartDate) {
+			days += int(plan.TargetDate.Sub(*plan.StartDate).Hours() / 24)
+		} else {
+			plan.StartDate = nil
+		}
+
+		if book.Format == models.BookFormatAudiobook {
+			plan.MinutesPerDay = max(1, int(math.Ceil(float64(book.DurationMinutes)/float64(days))))
+		} else {
+			plan.PagesPerDay = max(1, int(math.Ceil(float64(book.Pages)/float64(days))))
+		}
+
+		plan.ValidateReadingPlan(v)
+		if plan.ValidatePace(v, book); !v.Valid() {
+			return e.ErrInvalidData
+		}
+
+		if err := s.readingPlan.Insert(tx, plan); err != nil {
+			return err
+		}
+	}
+
+	if entry.Rating == nil {
+		return nil
+	}
+
+	review := &models.Review{
+		BookID:     book.ID,
+		Rating:     max(0.5, math.Round(*entry.Rating*2)/2),
+		Body:       entry.Review,
+		Spoiler:    entry.Spoiler,
+		ReviewedAt: time.Now().Truncate(24 * time.Hour),
+	}
+
+	if entry.DateRead != nil {
+		review.ReviewedAt = *entry.DateRead
+	}
+
+	if review.ValidateReview(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return s.review.Insert(tx, review, userID)
+}
+
+func (s *libraryImportService) addToShelves(
+	tx *sql.Tx,
+	book *models.Book,
+	names []string,
+	userID int64,
+	v *validator.Validator,
+) error {
+	for _, name := range names {
+		shelf := &models.Shelf{Name: name}
+		if shelf.ValidateShelf(v); !v.Valid() {
+			return e.ErrInvalidData
+		}
+
+		if err := s.shelf.Resolve(tx, shelf, userID); err != nil {
+			return err
+		}
+
+		if _, err := s.shelf.AddBooks(tx, shelf.ID, userID, []int64{book.ID}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importErrors lists the validation messages of v for the report of entry.
+func importErrors(entry librarycsv.Entry, v *validator.Validator) []*models.LibraryImportError {
+	fields := make([]string, 0, len(v.Errors))
+	for field := range v.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	errs := make([]*models.LibraryImportError, 0, len(fields))
+	for _, field := range fields {
+		errs = append(errs, &models.LibraryImportError{
+			Line:    entry.Line,
+			Title:   entry.Title,
+			Field:   field,
+			Message: v.Errors[field],
+		})
+	}
+
+	return errs
+}
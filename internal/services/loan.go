@@ -0,0 +1,292 @@
+package services
+
+import (
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/notifications"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// loanReminderBatch is how many overdue loans RemindOverdue reads at a time.
+const loanReminderBatch = 100
+
+type loanService struct {
+	loan        repositories.LoanRepository
+	book        repositories.BookRepository
+	user        repositories.UserRepositoryInterface
+	preferences PreferencesService
+	mailer      notifications.Mailer
+	db          *sql.DB
+}
+
+type LoanService interface {
+	FindAll(search filters.LoanFilters, userID int64, f filters.Filters) ([]*models.Loan, filters.Metadata, error)
+	FindByID(id, userID int64) (*models.Loan, error)
+	Save(loan *models.Loan, userID int64, v *validator.Validator) error
+	Update(loan *models.Loan, userID int64, v *validator.Validator) error
+	Return(id, userID int64, returnedAt *time.Time, v *validator.Validator) (*models.Loan, error)
+	Delete(id, userID int64) error
+	RemindOverdue() (int, error)
+}
+
+func NewLoanService(
+	loan repositories.LoanRepository,
+	book repositories.BookRepository,
+	user repositories.UserRepositoryInterface,
+	preferences PreferencesService,
+	mailer notifications.Mailer,
+	db *sql.DB,
+) *loanService {
+	return &loanService{
+		loan:        loan,
+		book:        book,
+		user:        user,
+		preferences: preferences,
+		mailer:      mailer,
+		db:          db,
+	}
+}
+
+// today returns the date in the user's time zone.
+func (s *loanService) today(userID int64) (time.Time, error) {
+	return userToday(s.preferences, userID)
+}
+
+// userToday returns the date in the time zone of a user.
+func userToday(preferences PreferencesService, userID int64) (time.Time, error) {
+	p, err := preferences.FindByUserID(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now().In(p.Location())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+func (s *loanService) FindAll(
+	search filters.LoanFilters,
+	userID int64,
+	f filters.Filters,
+) ([]*models.Loan, filters.Metadata, error) {
+	today, err := s.today(userID)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+	search.Today = today.Format(time.DateOnly)
+
+	loans, metadata, err := s.loan.GetAll(search, userID, f)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	for _, loan := range loans {
+		loan.MarkOverdue(today)
+	}
+
+	return loans, metadata, nil
+}
+
+func (s *loanService) FindByID(id, userID int64) (*models.Loan, error) {
+	loan, err := s.loan.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	today, err := s.today(userID)
+	if err != nil {
+		return nil, err
+	}
+	loan.MarkOverdue(today)
+
+	return loan, nil
+}
+
+func (s *loanService) Save(loan *models.Loan, userID int64, v *validator.Validator) error {
+	today, err := s.validate(loan, userID, v)
+	if err != nil {
+		return err
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.loan.Insert(tx, loan, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	loan.MarkOverdue(today)
+	return nil
+}
+
+func (s *loanService) Update(loan *models.Loan, userID int64, v *validator.Validator) error {
+	if _, err := s.loan.GetByID(loan.ID, userID); err != nil {
+		return err
+	}
+
+	today, err := s.validate(loan, userID, v)
+	if err != nil {
+		return err
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.loan.Update(tx, loan, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	loan.MarkOverdue(today)
+	return nil
+}
+
+// Return closes an open loan on returnedAt, or today in the user's time zone
+// when nil.
+func (s *loanService) Return(id, userID int64, returnedAt *time.Time, v *validator.Validator) (*models.Loan, error) {
+	loan, err := s.loan.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !loan.Open() {
+		v.AddError("returnedAt", "the book was already returned")
+		return nil, e.ErrInvalidData
+	}
+
+	today, err := s.today(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if returnedAt == nil {
+		returnedAt = &today
+	}
+	loan.ReturnedAt = returnedAt
+
+	if loan.ValidateLoan(v, today); !v.Valid() {
+		return nil, e.ErrInvalidData
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.loan.Update(tx, loan, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loan.MarkOverdue(today)
+	return loan, nil
+}
+
+// validate checks loan, lent today unless given, against its book, which must
+// be a physical copy of the user, and links the loan to the user with its
+// BorrowerEmail, if any. Whether there is one is not told, so the answer is
+// the same for any email. It returns the date in the user's time zone.
+func (s *loanService) validate(
+	loan *models.Loan,
+	userID int64,
+	v *validator.Validator,
+) (time.Time, error) {
+	today, err := s.today(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if loan.LentAt.IsZero() {
+		loan.LentAt = today
+	}
+
+	loan.BorrowerID = nil
+	if loan.BorrowerEmail != "" {
+		borrower, err := s.user.GetByEmail(loan.BorrowerEmail)
+		switch {
+		case errors.Is(err, e.ErrRecordNotFound):
+		case err != nil:
+			return time.Time{}, err
+		case borrower.ID == userID:
+			v.AddError("borrowerEmail", "must not be your own")
+		default:
+			loan.BorrowerID = &borrower.ID
+		}
+	}
+
+	if loan.ValidateLoan(v, today); !v.Valid() {
+		return time.Time{}, e.ErrInvalidData
+	}
+
+	book, err := s.book.GetByID(loan.Book.ID, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrRecordNotFound) {
+			v.AddError("book", "not found")
+			return time.Time{}, e.ErrInvalidData
+		}
+		return time.Time{}, err
+	}
+	loan.Book = book
+
+	v.Check(
+		book.Format != models.BookFormatEbook && book.Format != models.BookFormatAudiobook,
+		"book",
+		"must be a physical copy",
+	)
+	if !v.Valid() {
+		return time.Time{}, e.ErrInvalidData
+	}
+
+	return today, nil
+}
+
+func (s *loanService) Delete(id, userID int64) error {
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.loan.Delete(tx, id, userID)
+	})
+}
+
+// RemindOverdue mails the lenders of the loans that became overdue, once per
+// loan. Borrowers are not mailed: they never agreed to be reached through a
+// loan someone else wrote. A loan is marked as reminded after its mail went
+// out, so a failure leaves it to be reminded on the next run.
+func (s *loanService) RemindOverdue() (int, error) {
+	reminded := 0
+	for {
+		loans, err := s.loan.GetUnremindedOverdue(loanReminderBatch)
+		if err != nil {
+			return reminded, err
+		}
+
+		for _, loan := range loans {
+			if err := s.remind(loan); err != nil {
+				return reminded, err
+			}
+
+			err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+				return s.loan.MarkReminded(tx, loan.ID)
+			})
+			if err != nil {
+				return reminded, err
+			}
+			reminded++
+		}
+
+		if len(loans) < loanReminderBatch {
+			return reminded, nil
+		}
+	}
+}
+
+func (s *loanService) remind(loan *models.Loan) error {
+	message := fmt.Sprintf(
+		"%s borrowed \"%s\" on %s and was due to return it on %s.",
+		loan.BorrowerName,
+		loan.Book.Title,
+		loan.LentAt.Format("January 2, 2006"),
+		loan.DueAt.Format("January 2, 2006"),
+	)
+	return s.mailer.SendMail(loan.Book.User.Email, "Overdue loan: "+loan.Book.Title, message)
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"slices"
+	"strings"
+)
+
+// maxWhereResults caps the books a "where is it" search returns.
+const maxWhereResults = 50
+
+type locationService struct {
+	location repositories.LocationRepository
+	db       *sql.DB
+}
+
+type LocationService interface {
+	FindAll(userID int64) ([]*models.Location, error)
+	Where(search string, userID int64, v *validator.Validator) ([]*models.Book, error)
+	AddBooks(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error)
+	RemoveBooks(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error)
+	GenericServiceInterface[models.Location, models.LocationDTO]
+}
+
+func NewLocationService(
+	location repositories.LocationRepository,
+	db *sql.DB,
+) *locationService {
+	return &locationService{
+		location: location,
+		db:       db,
+	}
+}
+
+// FindAll returns the locations of the user with their paths, each one
+// followed by the locations inside it.
+func (s *locationService) FindAll(userID int64) ([]*models.Location, error) {
+	locations, err := s.location.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	models.IndexLocations(locations)
+	slices.SortFunc(locations, func(a, b *models.Location) int {
+		return slices.CompareFunc(a.Path, b.Path, func(x, y string) int {
+			return strings.Compare(strings.ToLower(x), strings.ToLower(y))
+		})
+	})
+
+	return locations, nil
+}
+
+func (s *locationService) FindByID(id, userID int64) (*models.Location, error) {
+	locations, err := s.location.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	location, ok := models.IndexLocations(locations)[id]
+	if !ok {
+		return nil, e.ErrRecordNotFound
+	}
+
+	return location, nil
+}
+
+// Where finds the books matching search and where each one is kept.
+func (s *locationService) Where(search string, userID int64, v *validator.Validator) ([]*models.Book, error) {
+	search = strings.TrimSpace(search)
+	if v.Check(search != "", "q", "must be provided"); !v.Valid() {
+		return nil, e.ErrInvalidData
+	}
+
+	books, err := s.location.FindBooks(search, userID, maxWhereResults)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := s.location.GetAll(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := models.IndexLocations(locations)
+	for _, book := range books {
+		if book.LocationID != nil {
+			if location, ok := index[*book.LocationID]; ok {
+				book.LocationPath = location.Path
+			}
+		}
+	}
+
+	return books, nil
+}
+
+func (s *locationService) Save(location *models.Location, userID int64, v *validator.Validator) error {
+	location.ID = 0
+	if err := s.validate(location, userID, v); err != nil {
+		return err
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.location.Insert(tx, location, userID)
+	})
+}
+
+func (s *locationService) Update(location *models.Location, userID int64, v *validator.Validator) error {
+	if err := s.validate(location, userID, v); err != nil {
+		return err
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.location.Update(tx, location, userID)
+	})
+}
+
+// validate checks the location itself and where it goes among the other
+// locations of the user.
+func (s *locationService) validate(location *models.Location, userID int64, v *validator.Validator) error {
+	if location.ValidateLocation(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	locations, err := s.location.GetAll(userID)
+	if err != nil {
+		return err
+	}
+
+	if location.ValidatePlacement(v, models.IndexLocations(locations)); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return nil
+}
+
+// Delete removes the location, keeping what was inside it in its parent.
+func (s *locationService) Delete(id, userID int64) error {
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.location.Delete(tx, id, userID)
+	})
+}
+
+// AddBooks keeps the books in the location, taking them out of wherever they
+// were before.
+func (s *locationService) AddBooks(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error) {
+	if models.ValidateBookIDs(v, bookIDs); !v.Valid() {
+		return 0, e.ErrInvalidData
+	}
+
+	var added int64
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		if _, err := s.location.GetByID(id, userID); err != nil {
+			return err
+		}
+
+		var err error
+		added, err = s.location.AddBooks(tx, id, userID, bookIDs)
+		return err
+	})
+
+	return added, err
+}
+
+// RemoveBooks takes the books out of the location. Books kept elsewhere are
+// left alone.
+func (s *locationService) RemoveBooks(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error) {
+	if models.ValidateBookIDs(v, bookIDs); !v.Valid() {
+		return 0, e.ErrInvalidData
+	}
+
+	var removed int64
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		if _, err := s.location.GetByID(id, userID); err != nil {
+			return err
+		}
+
+		var err error
+		removed, err = s.location.RemoveBooks(tx, id, userID, bookIDs)
+		return err
+	})
+
+	return removed, err
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"errors"
+)
+
+type preferencesService struct {
+	preferences repositories.PreferencesRepository
+	db          *sql.DB
+}
+
+type PreferencesService interface {
+	FindByUserID(userID int64) (*models.Preferences, error)
+	Update(preferences *models.Preferences, v *validator.Validator) error
+}
+
+func NewPreferencesService(
+	preferences repositories.PreferencesRepository,
+	db *sql.DB,
+) *preferencesService {
+	return &preferencesService{
+		preferences: preferences,
+		db:          db,
+	}
+}
+
+// FindByUserID returns the stored preferences, or the defaults with a zero
+// version when the user never saved any.
+func (s *preferencesService) FindByUserID(userID int64) (*models.Preferences, error) {
+	preferences, err := s.preferences.GetByUserID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, e.ErrRecordNotFound):
+			return models.DefaultPreferences(userID), nil
+		default:
+			return nil, err
+		}
+	}
+
+	return preferences, nil
+}
+
+func (s *preferencesService) Update(preferences *models.Preferences, v *validator.Validator) error {
+	if preferences.ValidatePreferences(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		if preferences.Version == 0 {
+			return s.preferences.Insert(tx, preferences)
+		}
+		return s.preferences.Update(tx, preferences)
+	})
+}
@@ -5,24 +5,40 @@ import (
 	"bookwise/internal/models/filters"
 	"bookwise/internal/repositories"
 	"bookwise/utils"
-	"bookwise/utils/errors"
+	e "bookwise/utils/errors"
 	"bookwise/utils/validator"
 	"database/sql"
+	"errors"
 	"time"
 )
 
 type readingPlanService struct {
-	readingPlan repositories.ReadingPlanRepository
-	db          *sql.DB
+	readingPlan    repositories.ReadingPlanRepository
+	readingSession repositories.ReadingSessionRepository
+	book           repositories.BookRepository
+	review         repositories.ReviewRepository
+	chapter        repositories.ChapterRepository
+	preferences    PreferencesService
+	db             *sql.DB
 }
 
 func NewReadingPlanService(
 	readingPlan repositories.ReadingPlanRepository,
+	readingSession repositories.ReadingSessionRepository,
+	book repositories.BookRepository,
+	review repositories.ReviewRepository,
+	chapter repositories.ChapterRepository,
+	preferences PreferencesService,
 	db *sql.DB,
 ) *readingPlanService {
 	return &readingPlanService{
-		readingPlan: readingPlan,
-		db:          db,
+		readingPlan:    readingPlan,
+		readingSession: readingSession,
+		book:           book,
+		review:         review,
+		chapter:        chapter,
+		preferences:    preferences,
+		db:             db,
 	}
 }
 
@@ -38,6 +54,7 @@ type ReadingPlanService interface {
 	FindByID(id, userID int64) (*models.ReadingPlan, error)
 	Update(model *models.ReadingPlan, userID int64, v *validator.Validator) error
 	Delete(id, userID int64) error
+	Schedule(id, userID int64, alignToChapters bool, v *validator.Validator) (*models.Schedule, error)
 }
 
 func (s *readingPlanService) FindAll(
@@ -47,37 +64,172 @@ func (s *readingPlanService) FindAll(
 	userID, bookID int64,
 	f filters.Filters,
 ) ([]*models.ReadingPlan, filters.Metadata, error) {
-	return s.readingPlan.GetAll(status, startDate, targetDate, userID, bookID, f)
+	plans, metadata, err := s.readingPlan.GetAll(status, startDate, targetDate, userID, bookID, f)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	if err := s.markReviewPending(plans...); err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	return plans, metadata, nil
 }
 
 func (s *readingPlanService) FindByID(id, userID int64) (*models.ReadingPlan, error) {
-	return s.readingPlan.GetByID(id, userID)
+	plan, err := s.readingPlan.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.markReviewPending(plan); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// markReviewPending flags the completed plans whose book has not been
+// reviewed yet.
+func (s *readingPlanService) markReviewPending(plans ...*models.ReadingPlan) error {
+	bookIDs := []int64{}
+	for _, plan := range plans {
+		if plan.Status == models.ReadingStatusCompleted {
+			bookIDs = append(bookIDs, plan.Book.ID)
+		}
+	}
+
+	reviews, err := s.review.GetByBookIDs(bookIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, plan := range plans {
+		_, reviewed := reviews[plan.Book.ID]
+		plan.ReviewPending = plan.NeedsReview(reviewed)
+	}
+
+	return nil
 }
 
 func (s *readingPlanService) Save(model *models.ReadingPlan, userID int64, v *validator.Validator) error {
-	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+	model.User = &models.User{
+		ID: userID,
+	}
+
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
 		if model.ValidateReadingPlan(v); !v.Valid() {
-			return errors.ErrInvalidData
+			return e.ErrInvalidData
 		}
 
-		if model.User == nil {
-			model.User = &models.User{
-				ID: userID,
-			}
+		if err := s.validateBook(model, userID, v); err != nil {
+			return err
 		}
 
 		return s.readingPlan.Insert(tx, model)
 	})
+	if err != nil {
+		return err
+	}
+
+	return s.markReviewPending(model)
 }
 
 func (s *readingPlanService) Update(model *models.ReadingPlan, userID int64, v *validator.Validator) error {
-	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+	model.User = &models.User{
+		ID: userID,
+	}
+
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
 		if model.ValidateReadingPlan(v); !v.Valid() {
-			return errors.ErrInvalidData
+			return e.ErrInvalidData
+		}
+
+		if err := s.validateBook(model, userID, v); err != nil {
+			return err
 		}
 
 		return s.readingPlan.Update(tx, model, userID)
 	})
+	if err != nil {
+		return err
+	}
+
+	return s.markReviewPending(model)
+}
+
+// validateBook checks the plan against the planned book, which must belong
+// to the user and have been acquired.
+func (s *readingPlanService) validateBook(model *models.ReadingPlan, userID int64, v *validator.Validator) error {
+	book, err := s.book.GetByID(model.Book.ID, userID)
+	if err != nil {
+		if errors.Is(err, e.ErrRecordNotFound) {
+			v.AddError("Book", "not found")
+			return e.ErrInvalidData
+		}
+		return err
+	}
+
+	v.Check(book.Acquired(), "Book", "must be acquired before it is planned, not on the wishlist")
+	if model.ValidatePace(v, book); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return nil
+}
+
+// Schedule spreads what is left of the book of a plan over the days from
+// today, or from the start of the plan when it starts later, optionally
+// aligned to the chapters of the book. Pages read so far are those of the
+// sessions of the plan.
+func (s *readingPlanService) Schedule(
+	id, userID int64,
+	alignToChapters bool,
+	v *validator.Validator,
+) (*models.Schedule, error) {
+	plan, err := s.readingPlan.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	today, err := userToday(s.preferences, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := today
+	if plan.StartDate != nil && plan.StartDate.After(from) {
+		from = time.Date(plan.StartDate.Year(), plan.StartDate.Month(), plan.StartDate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	v.Check(plan.Book.Pages > 0, "Book", "must have a page count to be scheduled")
+	v.Check(
+		plan.PagesPerDay > 0 || (plan.TargetDate != nil && !plan.TargetDate.Before(from)),
+		"Plan",
+		"either pagesPerDay or a targetDate not yet past must be provided to be scheduled",
+	)
+
+	chapters, err := bookChapters(s.chapter, plan.Book)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Check(!alignToChapters || len(chapters) > 0, "align", "the book has no chapters to align to")
+	if !v.Valid() {
+		return nil, e.ErrInvalidData
+	}
+
+	sessions, err := s.readingSession.GetByPlanIDs([]int64{plan.ID}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pagesRead := 0
+	for _, session := range sessions {
+		pagesRead += session.PagesRead
+	}
+
+	return models.BuildSchedule(plan, plan.Book.Pages, chapters, pagesRead, from, alignToChapters), nil
 }
 
 func (s *readingPlanService) Delete(id, userID int64) error {
@@ -85,3 +237,58 @@ func (s *readingPlanService) Delete(id, userID int64) error {
 		return s.readingPlan.Delete(tx, id, userID)
 	})
 }
+
+// activePlan returns the plan reading synced from a device counts towards:
+// the plan being read, or else the last plan not yet completed. Books read
+// again after their plans were completed have none.
+func activePlan(
+	readingPlan repositories.ReadingPlanRepository,
+	bookID, userID int64,
+) (*models.ReadingPlan, error) {
+	plans, err := readingPlan.GetByBookIDs([]int64{bookID}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var active *models.ReadingPlan
+	for _, plan := range plans {
+		switch plan.Status {
+		case models.ReadingStatusReading:
+			return plan, nil
+		case models.ReadingStatusPlanned, models.ReadingStatusPaused:
+			active = plan
+		}
+	}
+
+	return active, nil
+}
+
+// advancePlan starts plan once reading was recorded in it and completes it
+// once the book was finished, on date when it had no start date. It reports
+// whether the plan was completed.
+func advancePlan(
+	tx *sql.Tx,
+	readingPlan repositories.ReadingPlanRepository,
+	plan *models.ReadingPlan,
+	read, finished bool,
+	date time.Time,
+	userID int64,
+) (bool, error) {
+	status := models.ReadingStatusReading
+	if finished {
+		status = models.ReadingStatusCompleted
+	}
+	if plan.Status == status || (!read && status == models.ReadingStatusReading) {
+		return false, nil
+	}
+
+	plan.Status = status
+	if plan.StartDate == nil {
+		plan.StartDate = &date
+	}
+	if err := readingPlan.Update(tx, plan, userID); err != nil {
+		return false, err
+	}
+
+	return finished, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"bookwise/internal/models"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"time"
+)
+
+type reviewService struct {
+	review repositories.ReviewRepository
+	book   repositories.BookRepository
+	db     *sql.DB
+}
+
+type ReviewService interface {
+	FindByBookID(bookID, userID int64) (*models.Review, error)
+	Save(review *models.Review, userID int64, v *validator.Validator) error
+	Update(review *models.Review, userID int64, v *validator.Validator) error
+	Delete(bookID, userID int64) error
+}
+
+func NewReviewService(
+	review repositories.ReviewRepository,
+	book repositories.BookRepository,
+	db *sql.DB,
+) *reviewService {
+	return &reviewService{
+		review: review,
+		book:   book,
+		db:     db,
+	}
+}
+
+func (s *reviewService) FindByBookID(bookID, userID int64) (*models.Review, error) {
+	return s.review.GetByBookID(bookID, userID)
+}
+
+func (s *reviewService) Save(review *models.Review, userID int64, v *validator.Validator) error {
+	if err := s.prepare(review, userID, v); err != nil {
+		return err
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.review.Insert(tx, review, userID)
+	})
+}
+
+func (s *reviewService) Update(review *models.Review, userID int64, v *validator.Validator) error {
+	if err := s.prepare(review, userID, v); err != nil {
+		return err
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.review.Update(tx, review, userID)
+	})
+}
+
+// prepare defaults the review date to today and validates the review, whose
+// book must belong to the user.
+func (s *reviewService) prepare(review *models.Review, userID int64, v *validator.Validator) error {
+	if review.ReviewedAt.IsZero() {
+		review.ReviewedAt = time.Now()
+	}
+
+	if review.ValidateReview(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	_, err := s.book.GetByID(review.BookID, userID)
+	return err
+}
+
+func (s *reviewService) Delete(bookID, userID int64) error {
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.review.Delete(tx, bookID, userID)
+	})
+}
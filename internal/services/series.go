@@ -0,0 +1,68 @@
+package services
+
+import (
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+)
+
+type seriesService struct {
+	series repositories.SeriesRepository
+	db     *sql.DB
+}
+
+type SeriesService interface {
+	FindAll(name string, userID int64, f filters.Filters) ([]*models.Series, filters.Metadata, error)
+	FindByID(id, userID int64) (*models.Series, error)
+	Update(series *models.Series, userID int64, v *validator.Validator) error
+}
+
+func NewSeriesService(
+	series repositories.SeriesRepository,
+	db *sql.DB,
+) *seriesService {
+	return &seriesService{
+		series: series,
+		db:     db,
+	}
+}
+
+func (s *seriesService) FindAll(name string, userID int64, f filters.Filters) ([]*models.Series, filters.Metadata, error) {
+	series, metadata, err := s.series.GetAll(name, userID, f)
+	if err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	if err := s.series.LoadEntries(series, userID); err != nil {
+		return nil, filters.Metadata{}, err
+	}
+
+	return series, metadata, nil
+}
+
+func (s *seriesService) FindByID(id, userID int64) (*models.Series, error) {
+	series, err := s.series.GetByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.series.LoadEntries([]*models.Series{series}, userID); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func (s *seriesService) Update(series *models.Series, userID int64, v *validator.Validator) error {
+	if series.ValidateSeries(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.series.Update(tx, series, userID)
+	})
+}
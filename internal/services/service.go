@@ -3,10 +3,15 @@ package services
 import (
 	"bookwise/internal/config"
 	"bookwise/internal/jsonlog"
+	"bookwise/internal/metadata"
 	"bookwise/internal/models"
+	"bookwise/internal/notifications"
 	"bookwise/internal/repositories"
+	"bookwise/internal/storage"
 	"bookwise/utils/validator"
 	"database/sql"
+	"fmt"
+	"strconv"
 )
 
 type GenericServiceInterface[
@@ -20,20 +25,175 @@ type GenericServiceInterface[
 }
 
 type Services struct {
-	User        UserService
-	Auth        AuthServiceInterface
-	Book        BookService
-	ReadingPlan ReadingPlanService
+	User          UserService
+	Auth          AuthServiceInterface
+	Book          BookService
+	ReadingPlan   ReadingPlanService
+	Preferences   PreferencesService
+	Shelf         ShelfService
+	Author        AuthorService
+	Series        SeriesService
+	Trash         TrashService
+	Review        ReviewService
+	Highlight     HighlightService
+	LibraryImport LibraryImportService
+	LibraryExport LibraryExportService
+	BookFile      BookFileService
+	KOReader      KOReaderService
+	Kobo          KoboService
+	Loan          LoanService
+	Location      LocationService
+	Label         LabelService
+	Chapter       ChapterService
 }
 
 func NewServices(logger jsonlog.Logger, db *sql.DB, config config.Config) *Services {
 	r := repositories.NewRepository(logger, db)
-	userService := NewUserService(r.User, db)
+	blobs := newBlobStore(logger, config)
+	userService := NewUserService(
+		r.User,
+		db,
+		notifications.NewLogSMSSender(logger),
+		notifications.NewLogMailer(logger),
+		config,
+	)
+	preferencesService := NewPreferencesService(r.Preferences, db)
+	bookService := NewBookService(
+		r.Book,
+		r.Author,
+		r.Series,
+		r.Review,
+		r.Loan,
+		r.Location,
+		r.Chapter,
+		preferencesService,
+		newMetadataProvider(logger, r, config),
+		blobs,
+		db,
+		config,
+	)
+
+	readingPlanService := NewReadingPlanService(
+		r.ReadingPlan,
+		r.ReadingSession,
+		r.Book,
+		r.Review,
+		r.Chapter,
+		preferencesService,
+		db,
+	)
 
 	return &Services{
 		User:        userService,
-		Auth:        NewAuthService(userService, config),
-		Book:        NewBookService(r.Book, db),
-		ReadingPlan: NewReadingPlanService(r.ReadingPlan, db),
+		Auth:        NewAuthService(userService, r.FeedToken, r.KOReader, db, config, logger),
+		Book:        bookService,
+		ReadingPlan: readingPlanService,
+		Preferences: preferencesService,
+		Shelf:       NewShelfService(r.Shelf, db),
+		Author:      NewAuthorService(r.Author, db),
+		Series:      NewSeriesService(r.Series, db),
+		Trash:       NewTrashService(r.Trash, bookService, blobs, db, config),
+		Review:      NewReviewService(r.Review, r.Book, db),
+		Highlight:   NewHighlightService(r.Highlight, r.Book, bookService, db),
+		LibraryImport: NewLibraryImportService(
+			r.LibraryImport,
+			bookService,
+			r.Book,
+			r.Shelf,
+			r.ReadingPlan,
+			r.Review,
+			db,
+		),
+		LibraryExport: NewLibraryExportService(
+			bookService,
+			r.Review,
+			r.Shelf,
+			r.ReadingPlan,
+			r.ReadingSession,
+		),
+		BookFile: NewBookFileService(r.BookFile, r.Book, bookService, blobs, db, config),
+		KOReader: NewKOReaderService(
+			r.KOReader,
+			r.BookFile,
+			r.Book,
+			r.ReadingPlan,
+			r.ReadingSession,
+			r.Chapter,
+			blobs,
+			db,
+		),
+		Kobo: NewKoboService(
+			r.Kobo,
+			r.Book,
+			r.Highlight,
+			r.ReadingPlan,
+			r.ReadingSession,
+			r.Chapter,
+			db,
+		),
+		Loan: NewLoanService(
+			r.Loan,
+			r.Book,
+			r.User,
+			preferencesService,
+			notifications.NewLogMailer(logger),
+			db,
+		),
+		Location: NewLocationService(r.Location, db),
+		Label:    NewLabelService(bookService, config),
+		Chapter:  NewChapterService(r.Chapter, r.Book, r.BookFile, blobs, db),
+	}
+}
+
+// newMetadataProvider reads the configured metadata dump, if any, behind the
+// database cache.
+func newMetadataProvider(logger jsonlog.Logger, r *repositories.Repository, config config.Config) metadata.Provider {
+	var provider metadata.Provider = metadata.NewNopProvider()
+
+	if config.Metadata.DumpPath != "" {
+		dump, err := metadata.NewDumpProvider(config.Metadata.DumpPath)
+		if err != nil {
+			logger.PrintFatal(err, map[string]string{
+				"path": config.Metadata.DumpPath,
+			})
+		}
+
+		logger.PrintInfo("metadata dump indexed", map[string]string{
+			"path":  config.Metadata.DumpPath,
+			"isbns": strconv.Itoa(dump.Len()),
+		})
+		provider = dump
+	}
+
+	return metadata.NewCachedProvider(provider, r.MetadataCache, config.Metadata.CacheTTL, logger)
+}
+
+// newBlobStore opens the configured blob storage backend.
+func newBlobStore(logger jsonlog.Logger, config config.Config) storage.BlobStore {
+	var (
+		blobs storage.BlobStore
+		err   error
+	)
+
+	switch config.Storage.Driver {
+	case "s3":
+		blobs, err = storage.NewS3Store(storage.S3Config{
+			Endpoint:  config.Storage.S3.Endpoint,
+			Region:    config.Storage.S3.Region,
+			Bucket:    config.Storage.S3.Bucket,
+			AccessKey: config.Storage.S3.AccessKey,
+			SecretKey: config.Storage.S3.SecretKey,
+			PathStyle: config.Storage.S3.PathStyle,
+		})
+	case "local":
+		blobs, err = storage.NewLocalStore(config.Storage.LocalPath)
+	default:
+		err = fmt.Errorf("unknown storage driver %q", config.Storage.Driver)
 	}
+
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	return blobs
 }
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/repositories"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+)
+
+type shelfService struct {
+	shelf repositories.ShelfRepository
+	db    *sql.DB
+}
+
+type ShelfService interface {
+	FindAll(userID int64) ([]*models.Shelf, error)
+	CountBooks(search filters.BookFilters, userID int64) ([]*models.Shelf, error)
+	AddBooks(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error)
+	RemoveBooks(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error)
+	GenericServiceInterface[models.Shelf, models.ShelfDTO]
+}
+
+func NewShelfService(
+	shelf repositories.ShelfRepository,
+	db *sql.DB,
+) *shelfService {
+	return &shelfService{
+		shelf: shelf,
+		db:    db,
+	}
+}
+
+func (s *shelfService) FindAll(userID int64) ([]*models.Shelf, error) {
+	return s.shelf.GetAll(userID)
+}
+
+func (s *shelfService) CountBooks(search filters.BookFilters, userID int64) ([]*models.Shelf, error) {
+	return s.shelf.CountBooks(search, userID)
+}
+
+func (s *shelfService) FindByID(id, userID int64) (*models.Shelf, error) {
+	return s.shelf.GetByID(id, userID)
+}
+
+func (s *shelfService) Save(shelf *models.Shelf, userID int64, v *validator.Validator) error {
+	if shelf.ValidateShelf(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.shelf.Insert(tx, shelf, userID)
+	})
+}
+
+func (s *shelfService) Update(shelf *models.Shelf, userID int64, v *validator.Validator) error {
+	if shelf.ValidateShelf(v); !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.shelf.Update(tx, shelf, userID)
+	})
+}
+
+func (s *shelfService) Delete(id, userID int64) error {
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.shelf.Delete(tx, id, userID)
+	})
+}
+
+func (s *shelfService) AddBooks(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error) {
+	if models.ValidateBookIDs(v, bookIDs); !v.Valid() {
+		return 0, e.ErrInvalidData
+	}
+
+	var added int64
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		if _, err := s.shelf.GetByID(id, userID); err != nil {
+			return err
+		}
+
+		var err error
+		added, err = s.shelf.AddBooks(tx, id, userID, bookIDs)
+		return err
+	})
+
+	return added, err
+}
+
+func (s *shelfService) RemoveBooks(id, userID int64, bookIDs []int64, v *validator.Validator) (int64, error) {
+	if models.ValidateBookIDs(v, bookIDs); !v.Valid() {
+		return 0, e.ErrInvalidData
+	}
+
+	var removed int64
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		if _, err := s.shelf.GetByID(id, userID); err != nil {
+			return err
+		}
+
+		var err error
+		removed, err = s.shelf.RemoveBooks(tx, id, userID, bookIDs)
+		return err
+	})
+
+	return removed, err
+}
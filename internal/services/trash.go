@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bookwise/internal/config"
+	"bookwise/internal/models"
+	"bookwise/internal/models/filters"
+	"bookwise/internal/repositories"
+	"bookwise/internal/storage"
+	"bookwise/utils"
+	e "bookwise/utils/errors"
+	"bookwise/utils/validator"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// maxTitleSuffix bounds the search for a free title when restoring a book.
+const maxTitleSuffix = 1000
+
+type trashService struct {
+	trash  repositories.TrashRepository
+	book   BookService
+	blobs  storage.BlobStore
+	db     *sql.DB
+	config config.Config
+}
+
+type TrashService interface {
+	FindAll(itemType models.TrashItemType, userID int64, f filters.Filters) ([]*models.TrashItem, filters.Metadata, error)
+	RestoreBook(id, userID int64, title string, v *validator.Validator) (*models.Book, error)
+	RestorePlan(id, userID int64, v *validator.Validator) error
+	PurgeBook(id, userID int64) error
+	PurgePlan(id, userID int64) error
+	Empty(userID int64) error
+	PurgeExpired() (int64, error)
+}
+
+func NewTrashService(
+	trash repositories.TrashRepository,
+	book BookService,
+	blobs storage.BlobStore,
+	db *sql.DB,
+	config config.Config,
+) *trashService {
+	return &trashService{
+		trash:  trash,
+		book:   book,
+		blobs:  blobs,
+		db:     db,
+		config: config,
+	}
+}
+
+func (s *trashService) FindAll(
+	itemType models.TrashItemType,
+	userID int64,
+	f filters.Filters,
+) ([]*models.TrashItem, filters.Metadata, error) {
+	return s.trash.GetAll(itemType, userID, f)
+}
+
+// RestoreBook takes a book out of the trash. When another book has taken its
+// title in the meantime, the book is restored under title if one was given,
+// or under the first free "Title (n)" otherwise.
+func (s *trashService) RestoreBook(id, userID int64, title string, v *validator.Validator) (*models.Book, error) {
+	item, err := s.trash.GetBook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if title != "" {
+		v.Check(len(title) <= 500, "title", "must not be more than 500 bytes long")
+		if !v.Valid() {
+			return nil, e.ErrInvalidData
+		}
+	}
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		restored := title
+		if restored == "" {
+			restored, err = s.freeTitle(tx, item.Title, userID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.trash.RestoreBook(tx, id, userID, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.book.FindByID(id, userID)
+}
+
+func (s *trashService) freeTitle(tx *sql.Tx, title string, userID int64) (string, error) {
+	candidate := title
+	for n := 2; n <= maxTitleSuffix; n++ {
+		taken, err := s.trash.TitleTaken(tx, candidate, userID)
+		if err != nil {
+			return "", err
+		}
+
+		if !taken {
+			return candidate, nil
+		}
+
+		candidate = fmt.Sprintf("%s (%d)", title, n)
+	}
+
+	return "", e.ErrBookTitle
+}
+
+func (s *trashService) RestorePlan(id, userID int64, v *validator.Validator) error {
+	item, err := s.trash.GetPlan(id, userID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.trash.GetBook(item.BookID, userID); err == nil {
+		v.AddError("book", "is in the trash and must be restored first")
+		return e.ErrInvalidData
+	}
+
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.trash.RestorePlan(tx, id, userID)
+	})
+}
+
+func (s *trashService) PurgeBook(id, userID int64) error {
+	var coverKey string
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		coverKey, err = s.trash.PurgeBook(tx, id, userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteCovers(coverKey)
+	return nil
+}
+
+func (s *trashService) PurgePlan(id, userID int64) error {
+	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.trash.PurgePlan(tx, id, userID)
+	})
+}
+
+func (s *trashService) Empty(userID int64) error {
+	var coverKeys []string
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		coverKeys, err = s.trash.Empty(tx, userID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteCovers(coverKeys...)
+	return nil
+}
+
+// PurgeExpired permanently deletes every item that has been in the trash for
+// longer than the configured retention and returns how many were removed.
+func (s *trashService) PurgeExpired() (int64, error) {
+	before := time.Now().Add(-s.config.Trash.Retention)
+
+	var (
+		purged    int64
+		coverKeys []string
+	)
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		purged, coverKeys, err = s.trash.PurgeExpired(tx, before)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.deleteCovers(coverKeys...)
+	return purged, nil
+}
+
+// deleteCovers removes the blobs of purged books. Failures only leave orphaned
+// blobs behind, so they are ignored.
+func (s *trashService) deleteCovers(keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+
+		s.blobs.Delete(key)
+		s.blobs.Delete(models.CoverThumbnailKey(key))
+	}
+}
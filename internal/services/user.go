@@ -1,18 +1,30 @@
 package services
 
 import (
+	"bookwise/internal/config"
 	"bookwise/internal/models"
+	"bookwise/internal/notifications"
 	"bookwise/internal/repositories"
 	"bookwise/utils"
 	e "bookwise/utils/errors"
+	"bookwise/utils/phone"
 	"bookwise/utils/validator"
 	"database/sql"
 	"errors"
+	"fmt"
+	"time"
 )
 
+// activationCodeCooldown is how long an account waits for a new activation
+// code, so codes cannot be rotated or sent over and over.
+const activationCodeCooldown = time.Minute
+
 type userService struct {
-	user repositories.UserRepositoryInterface
-	db   *sql.DB
+	user   repositories.UserRepositoryInterface
+	db     *sql.DB
+	sms    notifications.SMSSender
+	mailer notifications.Mailer
+	config config.Config
 }
 
 type UserService interface {
@@ -21,15 +33,22 @@ type UserService interface {
 	Update(user *models.User, v *validator.Validator) error
 	GetUserByCodAndEmail(cod int, email string, v *validator.Validator) (*models.User, error)
 	Save(user *models.User, v *validator.Validator) error
+	SendActivationCode(email string, channel models.ActivationChannel, v *validator.Validator) error
 }
 
 func NewUserService(
 	userRepository repositories.UserRepositoryInterface,
 	db *sql.DB,
+	sms notifications.SMSSender,
+	mailer notifications.Mailer,
+	config config.Config,
 ) *userService {
 	return &userService{
-		user: userRepository,
-		db:   db,
+		user:   userRepository,
+		db:     db,
+		sms:    sms,
+		mailer: mailer,
+		config: config,
 	}
 }
 
@@ -56,6 +75,10 @@ func (s *userService) ActivateUser(cod int, email string, v *validator.Validator
 	user.Activated = true
 	user.Cod = 0
 
+	if user.CodChannel == models.ActivationChannelSMS {
+		user.PhoneVerified = true
+	}
+
 	if err = s.Update(user, v); err != nil {
 		return nil, err
 	}
@@ -88,13 +111,81 @@ func (s *userService) GetUserByCodAndEmail(cod int, email string, v *validator.V
 }
 
 func (s *userService) Save(user *models.User, v *validator.Validator) error {
-	return utils.RunInTx(s.db, func(tx *sql.Tx) error {
+	if normalized, err := phone.Normalize(user.Phone, s.config.Phone.DefaultCountryCode); err == nil {
+		user.Phone = normalized
+	}
+
+	err := utils.RunInTx(s.db, func(tx *sql.Tx) error {
 		if user.ValidateUser(v); !v.Valid() {
 			return e.ErrInvalidData
 		}
 		user.Cod = utils.GenerateRandomCode()
 		return s.user.Insert(tx, user)
 	})
+	if err != nil {
+		return err
+	}
+
+	return s.sendCode(user)
+}
+
+// SendActivationCode sends a new activation code to the account of email.
+// Unknown and already activated accounts, and accounts sent a code less than
+// activationCodeCooldown ago, are skipped without an error, so the answer
+// does not tell which emails have an account.
+func (s *userService) SendActivationCode(
+	email string,
+	channel models.ActivationChannel,
+	v *validator.Validator,
+) error {
+	models.ValidateEmail(v, email)
+	models.ValidateActivationChannel(v, channel)
+	if !v.Valid() {
+		return e.ErrInvalidData
+	}
+
+	user, err := s.user.GetByEmail(email)
+	switch {
+	case errors.Is(err, e.ErrRecordNotFound):
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if user.Activated {
+		return nil
+	}
+
+	if user.CodSentAt != nil && time.Since(*user.CodSentAt) < activationCodeCooldown {
+		return nil
+	}
+
+	user.Cod = utils.GenerateRandomCode()
+	user.CodChannel = channel
+
+	err = utils.RunInTx(s.db, func(tx *sql.Tx) error {
+		return s.user.UpdateCodByEmail(tx, user)
+	})
+	switch {
+	case errors.Is(err, e.ErrRecordNotFound):
+		// Another request sent a code in the meantime.
+		return nil
+	case err != nil:
+		return err
+	}
+
+	return s.sendCode(user)
+}
+
+func (s *userService) sendCode(user *models.User) error {
+	message := fmt.Sprintf("Your Bookwise activation code is %d", user.Cod)
+
+	switch user.CodChannel {
+	case models.ActivationChannelSMS:
+		return s.sms.SendSMS(user.Phone, message)
+	default:
+		return s.mailer.SendMail(user.Email, "Activate your Bookwise account", message)
+	}
 }
 
 func (s *userService) Delete(idUser int64) error {
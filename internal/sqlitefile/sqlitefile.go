@@ -0,0 +1,51 @@
+// Package sqlitefile opens SQLite databases held in memory, such as uploaded
+// ones.
+package sqlitefile
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+
+	_ "modernc.org/sqlite"
+)
+
+// header starts every SQLite database file.
+const header = "SQLite format 3\x00"
+
+// Detect tells whether data looks like a SQLite database.
+func Detect(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(header))
+}
+
+// Open opens the database in data read-only. The driver only opens files, so
+// data is copied to a temporary one named after pattern, as os.CreateTemp
+// names it. Closing with the returned function closes the database and
+// removes the file.
+func Open(data []byte, pattern string) (*sql.DB, func(), error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, nil, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return nil, nil, err
+	}
+
+	db, err := sql.Open("sqlite", "file:"+f.Name()+"?mode=ro&immutable=1")
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, nil, err
+	}
+
+	return db, func() {
+		db.Close()
+		os.Remove(f.Name())
+	}, nil
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobInfo describes a stored blob.
+type BlobInfo struct {
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// BlobStore keeps binary objects, such as book covers, under slash-separated
+// keys. Get and Delete return ErrBlobNotFound for unknown keys.
+type BlobStore interface {
+	Put(key string, body io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, *BlobInfo, error)
+	Delete(key string) error
+}
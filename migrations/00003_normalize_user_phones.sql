@@ -0,0 +1,80 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS phone_verified bool NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS cod_channel text NOT NULL DEFAULT 'email',
+    ADD COLUMN IF NOT EXISTS phone_legacy bool NOT NULL DEFAULT false;
+
+-- Phones are normalized the way phone.Normalize does it: numbers without a
+-- "+" or "00" prefix belong to the default country, without trunk zeros.
+-- When several phones normalize to the same number, the one already stored
+-- that way, or else the one of the oldest activated user, gets it.
+WITH candidates AS (
+    SELECT
+        u.id,
+        u.phone,
+        u.activated,
+        CASE
+            WHEN p.rest ~ '[^0-9 ().\-/]' THEN NULL
+            WHEN p.international THEN '+' || regexp_replace(p.rest, '[^0-9]', '', 'g')
+            ELSE '+' || d.country_code || ltrim(regexp_replace(p.rest, '[^0-9]', '', 'g'), '0')
+        END AS normalized
+    FROM users u
+    CROSS JOIN LATERAL (
+        SELECT
+            btrim(u.phone) LIKE '+%' OR btrim(u.phone) LIKE '00%' AS international,
+            CASE
+                WHEN btrim(u.phone) LIKE '+%' THEN substr(btrim(u.phone), 2)
+                WHEN btrim(u.phone) LIKE '00%' THEN substr(btrim(u.phone), 3)
+                ELSE btrim(u.phone)
+            END AS rest
+    ) p
+    CROSS JOIN (
+-- +goose ENVSUB ON
+        SELECT '${PHONE_DEFAULT_COUNTRY_CODE:-55}'::text AS country_code
+-- +goose ENVSUB OFF
+    ) d
+),
+ranked AS (
+    SELECT
+        id,
+        phone,
+        normalized,
+        row_number() OVER (
+            PARTITION BY normalized
+            ORDER BY phone = normalized DESC, activated DESC, id
+        ) AS rank
+    FROM candidates
+    WHERE normalized ~ '^\+[1-9][0-9]{6,14}$'
+)
+UPDATE users u
+SET phone = r.normalized
+FROM ranked r
+WHERE
+    u.id = r.id
+    AND r.rank = 1
+    AND u.phone <> r.normalized;
+
+-- Phones that could not be normalized are kept as they are until their user
+-- sets a new one, so the rest of the account can still be updated.
+UPDATE users
+SET phone_legacy = true
+WHERE phone !~ '^\+[1-9][0-9]{6,14}$';
+
+ALTER TABLE users
+    ADD CONSTRAINT chk_users_phone_e164
+        CHECK (phone_legacy OR phone ~ '^\+[1-9][0-9]{6,14}$');
+
+ALTER TABLE users
+    ADD CONSTRAINT chk_users_cod_channel CHECK (cod_channel IN ('email', 'sms'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS chk_users_cod_channel,
+    DROP CONSTRAINT IF EXISTS chk_users_phone_e164,
+    DROP COLUMN IF EXISTS phone_legacy,
+    DROP COLUMN IF EXISTS cod_channel,
+    DROP COLUMN IF EXISTS phone_verified;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id BIGINT PRIMARY KEY,
+    timezone text NOT NULL DEFAULT 'UTC',
+    locale text NOT NULL DEFAULT 'en-US',
+    progress_unit text NOT NULL DEFAULT 'pages',
+    week_start smallint NOT NULL DEFAULT 1,
+    page_size integer NOT NULL DEFAULT 20,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_user_preferences_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT chk_user_preferences_progress_unit CHECK (progress_unit IN ('pages', 'minutes')),
+    CONSTRAINT chk_user_preferences_week_start CHECK (week_start BETWEEN 0 AND 6),
+    CONSTRAINT chk_user_preferences_page_size CHECK (page_size BETWEEN 1 AND 100)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_preferences;
+-- +goose StatementEnd
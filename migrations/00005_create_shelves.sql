@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS shelves (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    user_id BIGINT NOT NULL,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_shelves_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_shelf_name_per_user
+    ON shelves(user_id, name) WHERE NOT deleted;
+
+CREATE TABLE IF NOT EXISTS book_shelves (
+    book_id BIGINT NOT NULL,
+    shelf_id BIGINT NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (book_id, shelf_id),
+    CONSTRAINT fk_book_shelves_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE CASCADE,
+    CONSTRAINT fk_book_shelves_shelf FOREIGN KEY (shelf_id)
+        REFERENCES shelves(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_shelves_shelf_id ON book_shelves(shelf_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS book_shelves;
+DROP TABLE IF EXISTS shelves;
+-- +goose StatementEnd
@@ -0,0 +1,119 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS authors (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    sort_name text NOT NULL,
+    name_key text NOT NULL,
+    user_id BIGINT NOT NULL,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_authors_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_author_per_user
+    ON authors(user_id, name_key) WHERE NOT deleted;
+
+CREATE INDEX IF NOT EXISTS idx_authors_name_tsvector
+    ON authors USING GIN (to_tsvector('simple', name));
+
+CREATE TABLE IF NOT EXISTS author_aliases (
+    author_id BIGINT NOT NULL,
+    alias text NOT NULL,
+    alias_key text NOT NULL,
+
+    PRIMARY KEY (author_id, alias),
+    CONSTRAINT fk_author_aliases_author FOREIGN KEY (author_id)
+        REFERENCES authors(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_author_aliases_alias_key ON author_aliases(alias_key);
+
+CREATE INDEX IF NOT EXISTS idx_author_aliases_tsvector
+    ON author_aliases USING GIN (to_tsvector('simple', alias));
+
+CREATE TABLE IF NOT EXISTS book_authors (
+    book_id BIGINT NOT NULL,
+    author_id BIGINT NOT NULL,
+    role text NOT NULL DEFAULT 'author',
+    position smallint NOT NULL DEFAULT 1,
+
+    PRIMARY KEY (book_id, author_id, role),
+    CONSTRAINT fk_book_authors_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE CASCADE,
+    CONSTRAINT fk_book_authors_author FOREIGN KEY (author_id)
+        REFERENCES authors(id) ON DELETE CASCADE,
+    CONSTRAINT chk_book_authors_role
+        CHECK (role IN ('author', 'translator', 'editor', 'illustrator'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_authors_author_id ON book_authors(author_id);
+
+-- Split the existing free-text credits ("A & B", "A; B", "A and B") and turn
+-- "Last, First" into "First Last", keeping the original spelling as alias.
+CREATE TEMPORARY TABLE tmp_book_author_names ON COMMIT DROP AS
+SELECT
+    book_id,
+    user_id,
+    position,
+    raw,
+    name,
+    regexp_replace(name, '^(.*\S)\s+(\S+)$', '\2, \1') AS sort_name,
+    lower(regexp_replace(name, '[^[:alnum:]]+', '', 'g')) AS name_key
+FROM (
+    SELECT
+        b.id AS book_id,
+        b.user_id,
+        p.position,
+        trim(p.part) AS raw,
+        CASE
+            WHEN trim(p.part) ~ '^[^,]+,[^,]+$'
+                THEN trim(split_part(p.part, ',', 2)) || ' ' || trim(split_part(p.part, ',', 1))
+            ELSE trim(p.part)
+        END AS name
+    FROM books b
+    CROSS JOIN LATERAL regexp_split_to_table(b.author, '\s*(;|&|\s+and\s+)\s*')
+        WITH ORDINALITY AS p(part, position)
+    WHERE trim(p.part) <> ''
+) parts;
+
+INSERT INTO authors (name, sort_name, name_key, user_id, created_by)
+SELECT DISTINCT ON (user_id, name_key) name, sort_name, name_key, user_id, user_id
+FROM tmp_book_author_names
+WHERE name_key <> ''
+ORDER BY user_id, name_key, position
+ON CONFLICT (user_id, name_key) WHERE NOT deleted DO NOTHING;
+
+INSERT INTO author_aliases (author_id, alias, alias_key)
+SELECT DISTINCT a.id, t.raw, lower(regexp_replace(t.raw, '[^[:alnum:]]+', '', 'g'))
+FROM tmp_book_author_names t
+JOIN authors a ON a.user_id = t.user_id AND a.name_key = t.name_key AND NOT a.deleted
+WHERE t.raw <> a.name
+ON CONFLICT DO NOTHING;
+
+INSERT INTO book_authors (book_id, author_id, role, position)
+SELECT t.book_id, a.id, 'author', min(t.position)
+FROM tmp_book_author_names t
+JOIN authors a ON a.user_id = t.user_id AND a.name_key = t.name_key AND NOT a.deleted
+GROUP BY t.book_id, a.id
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS idx_books_author_tsvector;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_books_author_tsvector
+    ON books USING GIN (to_tsvector('simple', author));
+
+DROP TABLE IF EXISTS book_authors;
+DROP TABLE IF EXISTS author_aliases;
+DROP TABLE IF EXISTS authors;
+-- +goose StatementEnd
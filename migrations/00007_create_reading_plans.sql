@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reading_plans (
+    id bigserial PRIMARY KEY,
+    status integer NOT NULL,
+    start_date timestamp(0) with time zone,
+    target_date timestamp(0) with time zone,
+    priority integer NOT NULL,
+    pages_per_day integer NOT NULL DEFAULT 0,
+    minutes_per_day integer NOT NULL DEFAULT 0,
+    book_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_reading_plans_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reading_plans_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT chk_reading_plans_status CHECK (status BETWEEN 1 AND 4),
+    CONSTRAINT chk_reading_plans_priority CHECK (priority BETWEEN 1 AND 3)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reading_plans_user_book ON reading_plans(user_id, book_id);
+CREATE INDEX IF NOT EXISTS idx_reading_plans_deleted ON reading_plans(deleted) WHERE NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reading_plans;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS series (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    total_count integer,
+    user_id BIGINT NOT NULL,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_series_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT chk_series_total_count_positive CHECK (total_count IS NULL OR total_count > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_series_name_per_user
+    ON series(user_id, lower(name)) WHERE NOT deleted;
+
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS series_id BIGINT,
+    ADD COLUMN IF NOT EXISTS series_position numeric(7, 2),
+    ADD CONSTRAINT fk_books_series FOREIGN KEY (series_id)
+        REFERENCES series(id) ON DELETE SET NULL,
+    ADD CONSTRAINT chk_books_series_position_positive
+        CHECK (series_position IS NULL OR series_position > 0);
+
+CREATE INDEX IF NOT EXISTS idx_books_series ON books(series_id, series_position);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE books
+    DROP CONSTRAINT IF EXISTS chk_books_series_position_positive,
+    DROP CONSTRAINT IF EXISTS fk_books_series,
+    DROP COLUMN IF EXISTS series_position,
+    DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS series;
+-- +goose StatementEnd
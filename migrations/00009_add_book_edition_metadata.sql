@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS isbn text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS publisher text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS published_date date,
+    ADD COLUMN IF NOT EXISTS language text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS format text NOT NULL DEFAULT 'paperback',
+    ADD COLUMN IF NOT EXISTS duration_minutes integer NOT NULL DEFAULT 0,
+    ADD CONSTRAINT chk_books_isbn13 CHECK (isbn = '' OR isbn ~ '^97[89][0-9]{10}$'),
+    ADD CONSTRAINT chk_books_format
+        CHECK (format IN ('paperback', 'hardcover', 'ebook', 'audiobook')),
+    ADD CONSTRAINT chk_books_duration_minutes
+        CHECK (duration_minutes >= 0 AND (format = 'audiobook' OR duration_minutes = 0));
+
+-- Audiobooks are measured in minutes and may have no page count.
+ALTER TABLE books DROP CONSTRAINT IF EXISTS chk_books_pages_positive;
+ALTER TABLE books ADD CONSTRAINT chk_books_pages_positive
+    CHECK (pages > 0 OR (format = 'audiobook' AND pages = 0));
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_isbn_per_user
+    ON books(user_id, isbn) WHERE isbn <> '' AND NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS unique_isbn_per_user;
+
+ALTER TABLE books DROP CONSTRAINT IF EXISTS chk_books_pages_positive;
+ALTER TABLE books ADD CONSTRAINT chk_books_pages_positive CHECK (pages > 0) NOT VALID;
+
+ALTER TABLE books
+    DROP CONSTRAINT IF EXISTS chk_books_duration_minutes,
+    DROP CONSTRAINT IF EXISTS chk_books_format,
+    DROP CONSTRAINT IF EXISTS chk_books_isbn13,
+    DROP COLUMN IF EXISTS duration_minutes,
+    DROP COLUMN IF EXISTS format,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS published_date,
+    DROP COLUMN IF EXISTS publisher,
+    DROP COLUMN IF EXISTS isbn;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS book_metadata_cache (
+    isbn text PRIMARY KEY,
+    data jsonb NOT NULL,
+    fetched_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE books ADD COLUMN IF NOT EXISTS cover_url text NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE books DROP COLUMN IF EXISTS cover_url;
+
+DROP TABLE IF EXISTS book_metadata_cache;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE reading_plans ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE shelves ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE authors ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+ALTER TABLE series ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+
+UPDATE books SET deleted_at = coalesce(updated_at, created_at) WHERE deleted;
+UPDATE reading_plans SET deleted_at = coalesce(updated_at, created_at) WHERE deleted;
+
+-- A unique constraint over (user_id, title, deleted) allowed only one deleted
+-- copy of each title; only books outside the trash need unique titles.
+ALTER TABLE books DROP CONSTRAINT IF EXISTS unique_title_per_user;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_title_per_user
+    ON books(user_id, title) WHERE NOT deleted;
+
+CREATE INDEX IF NOT EXISTS idx_books_deleted_at ON books(deleted_at) WHERE deleted;
+CREATE INDEX IF NOT EXISTS idx_reading_plans_deleted_at ON reading_plans(deleted_at) WHERE deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_reading_plans_deleted_at;
+DROP INDEX IF EXISTS idx_books_deleted_at;
+
+DROP INDEX IF EXISTS unique_title_per_user;
+ALTER TABLE books ADD CONSTRAINT unique_title_per_user UNIQUE (user_id, title, deleted);
+
+ALTER TABLE series DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE authors DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE shelves DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE reading_plans DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE books DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
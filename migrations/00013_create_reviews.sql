@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reviews (
+    id bigserial PRIMARY KEY,
+    book_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    rating numeric(2, 1) NOT NULL,
+    body text NOT NULL DEFAULT '',
+    spoiler bool NOT NULL DEFAULT false,
+    reviewed_at date NOT NULL DEFAULT CURRENT_DATE,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    deleted_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_reviews_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reviews_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT chk_reviews_rating
+        CHECK (rating BETWEEN 0.5 AND 5 AND rating * 2 = trunc(rating * 2))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_review_per_book
+    ON reviews(book_id) WHERE NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reviews;
+-- +goose StatementEnd
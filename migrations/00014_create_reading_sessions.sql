@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reading_sessions (
+    id bigserial PRIMARY KEY,
+    reading_plan_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    pages_read integer NOT NULL DEFAULT 0,
+    minutes integer NOT NULL DEFAULT 0,
+    notes text NOT NULL DEFAULT '',
+    date date NOT NULL,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    deleted_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_reading_sessions_plan FOREIGN KEY (reading_plan_id)
+        REFERENCES reading_plans(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reading_sessions_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT chk_reading_sessions_progress CHECK (pages_read >= 0 AND minutes >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reading_sessions_plan ON reading_sessions(reading_plan_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS reading_sessions;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS highlights (
+    id bigserial PRIMARY KEY,
+    book_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    session_id BIGINT,
+    text text NOT NULL,
+    page integer NOT NULL DEFAULT 0,
+    chapter text NOT NULL DEFAULT '',
+    note text NOT NULL DEFAULT '',
+    color text NOT NULL DEFAULT 'yellow',
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    deleted_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_highlights_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE CASCADE,
+    CONSTRAINT fk_highlights_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_highlights_session FOREIGN KEY (session_id)
+        REFERENCES reading_sessions(id) ON DELETE SET NULL,
+    CONSTRAINT chk_highlights_page CHECK (page >= 0),
+    CONSTRAINT chk_highlights_color
+        CHECK (color IN ('yellow', 'green', 'blue', 'pink', 'purple'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_highlights_user_book ON highlights(user_id, book_id, page);
+CREATE INDEX IF NOT EXISTS idx_highlights_search
+    ON highlights USING GIN (to_tsvector('simple', text || ' ' || chapter || ' ' || note));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS highlights;
+-- +goose StatementEnd
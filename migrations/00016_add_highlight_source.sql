@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT '';
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS source_hash text NOT NULL DEFAULT '';
+
+-- Deleted highlights keep their hash so re-importing an export does not bring
+-- them back.
+CREATE UNIQUE INDEX IF NOT EXISTS unique_highlight_source_hash
+    ON highlights(user_id, book_id, source_hash) WHERE source_hash <> '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS unique_highlight_source_hash;
+ALTER TABLE highlights DROP COLUMN IF EXISTS source_hash;
+ALTER TABLE highlights DROP COLUMN IF EXISTS source;
+-- +goose StatementEnd
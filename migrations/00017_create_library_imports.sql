@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS library_imports (
+    id bigserial PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    source text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    dry_run bool NOT NULL DEFAULT false,
+    data bytea,
+    total integer NOT NULL DEFAULT 0,
+    processed integer NOT NULL DEFAULT 0,
+    created integer NOT NULL DEFAULT 0,
+    linked integer NOT NULL DEFAULT 0,
+    skipped integer NOT NULL DEFAULT 0,
+    failed integer NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    started_at timestamp(0) with time zone,
+    finished_at timestamp(0) with time zone,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    deleted_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_library_imports_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT chk_library_imports_source
+        CHECK (source IN ('goodreads', 'storygraph')),
+    CONSTRAINT chk_library_imports_status
+        CHECK (status IN ('pending', 'running', 'completed', 'failed'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_library_imports_unfinished
+    ON library_imports(id) WHERE status IN ('pending', 'running');
+
+CREATE TABLE IF NOT EXISTS library_import_errors (
+    id bigserial PRIMARY KEY,
+    import_id BIGINT NOT NULL,
+    line integer NOT NULL,
+    title text NOT NULL DEFAULT '',
+    field text NOT NULL,
+    message text NOT NULL,
+
+    CONSTRAINT fk_library_import_errors_import FOREIGN KEY (import_id)
+        REFERENCES library_imports(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_library_import_errors_import_id
+    ON library_import_errors(import_id, line);
+
+-- book_external_ids remembers which book a row of another service's export
+-- became, so uploading the same export again does not duplicate books.
+CREATE TABLE IF NOT EXISTS book_external_ids (
+    user_id BIGINT NOT NULL,
+    source text NOT NULL,
+    external_id text NOT NULL,
+    book_id BIGINT NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, source, external_id),
+    CONSTRAINT fk_book_external_ids_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_book_external_ids_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_external_ids_book_id ON book_external_ids(book_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS book_external_ids;
+DROP TABLE IF EXISTS library_import_errors;
+DROP TABLE IF EXISTS library_imports;
+-- +goose StatementEnd
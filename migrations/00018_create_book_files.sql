@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+-- book_files keeps the ebook files uploaded by users. Files without a book
+-- are uploads waiting to be confirmed, or files of books purged from the
+-- trash, and are removed after a while.
+CREATE TABLE IF NOT EXISTS book_files (
+    id bigserial PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    book_id BIGINT,
+    format text NOT NULL,
+    file_name text NOT NULL DEFAULT '',
+    size BIGINT NOT NULL,
+    content_hash text NOT NULL,
+    blob_key text NOT NULL,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    deleted_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_book_files_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_book_files_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE SET NULL,
+    CONSTRAINT chk_book_files_format
+        CHECK (format IN ('epub', 'pdf')),
+    CONSTRAINT unique_book_files_content_hash UNIQUE (user_id, content_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_files_book_id ON book_files(book_id);
+CREATE INDEX IF NOT EXISTS idx_book_files_unattached
+    ON book_files(coalesce(updated_at, created_at)) WHERE book_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS book_files;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE book_files DROP CONSTRAINT IF EXISTS chk_book_files_format;
+ALTER TABLE book_files ADD CONSTRAINT chk_book_files_format
+    CHECK (format IN ('epub', 'pdf', 'mobi'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM book_files WHERE format = 'mobi';
+ALTER TABLE book_files DROP CONSTRAINT IF EXISTS chk_book_files_format;
+ALTER TABLE book_files ADD CONSTRAINT chk_book_files_format
+    CHECK (format IN ('epub', 'pdf'));
+-- +goose StatementEnd
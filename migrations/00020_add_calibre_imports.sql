@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE library_imports DROP CONSTRAINT IF EXISTS chk_library_imports_source;
+ALTER TABLE library_imports ADD CONSTRAINT chk_library_imports_source
+    CHECK (source IN ('goodreads', 'storygraph', 'calibre'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM library_imports WHERE source = 'calibre';
+ALTER TABLE library_imports DROP CONSTRAINT IF EXISTS chk_library_imports_source;
+ALTER TABLE library_imports ADD CONSTRAINT chk_library_imports_source
+    CHECK (source IN ('goodreads', 'storygraph'));
+-- +goose StatementEnd
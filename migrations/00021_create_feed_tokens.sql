@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- feed_tokens lets e-reader apps, which cannot log in for a bearer token,
+-- read the catalog feed of a user. Only a hash of the token is kept.
+CREATE TABLE IF NOT EXISTS feed_tokens (
+    user_id BIGINT PRIMARY KEY,
+    token_hash bytea NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    last_used_at timestamp(0) with time zone,
+
+    CONSTRAINT fk_feed_tokens_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT unique_feed_tokens_hash UNIQUE (token_hash)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS feed_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+-- document_hash is the partial MD5 KOReader identifies a file by. Files
+-- stored before it was kept get theirs when a sync first misses.
+ALTER TABLE book_files ADD COLUMN IF NOT EXISTS document_hash text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_book_files_document_hash
+    ON book_files(user_id, document_hash);
+
+-- koreader_accounts holds the credentials the progress sync plugin of
+-- KOReader logs in with. The plugin only sends the MD5 of the password, so
+-- password_hash is the hash of that MD5.
+CREATE TABLE IF NOT EXISTS koreader_accounts (
+    user_id BIGINT PRIMARY KEY,
+    username text NOT NULL,
+    password_hash bytea NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone,
+
+    CONSTRAINT fk_koreader_accounts_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT unique_koreader_accounts_username UNIQUE (username)
+);
+
+-- koreader_progress is the last position synced for every document, with
+-- the book the document was matched to.
+CREATE TABLE IF NOT EXISTS koreader_progress (
+    user_id BIGINT NOT NULL,
+    document text NOT NULL,
+    book_id BIGINT,
+    progress text NOT NULL DEFAULT '',
+    percentage double precision NOT NULL DEFAULT 0,
+    device text NOT NULL DEFAULT '',
+    device_id text NOT NULL DEFAULT '',
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, document),
+    CONSTRAINT fk_koreader_progress_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_koreader_progress_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE SET NULL,
+    CONSTRAINT chk_koreader_progress_percentage
+        CHECK (percentage >= 0 AND percentage <= 1)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS koreader_progress;
+DROP TABLE IF EXISTS koreader_accounts;
+DROP INDEX IF EXISTS idx_book_files_document_hash;
+ALTER TABLE book_files DROP COLUMN IF EXISTS document_hash;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- kobo_progress is the reading time and progress last imported for every
+-- book of a Kobo database, so importing the database again only records
+-- what was read since.
+CREATE TABLE IF NOT EXISTS kobo_progress (
+    user_id BIGINT NOT NULL,
+    content_id text NOT NULL,
+    book_id BIGINT,
+    seconds_read integer NOT NULL DEFAULT 0,
+    percent_read double precision NOT NULL DEFAULT 0,
+    read_status integer NOT NULL DEFAULT 0,
+    last_read timestamp(0) with time zone,
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, content_id),
+    CONSTRAINT fk_kobo_progress_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_kobo_progress_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE SET NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS kobo_progress;
+-- +goose StatementEnd
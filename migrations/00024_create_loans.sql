@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- loans records the books lent to someone, either by name or as another
+-- user through borrower_id. reminded_at is set once the lender was reminded
+-- of an overdue loan and cleared when its due date changes.
+CREATE TABLE IF NOT EXISTS loans (
+    id bigserial PRIMARY KEY,
+    book_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    borrower_name text NOT NULL DEFAULT '',
+    borrower_id BIGINT,
+    lent_at date NOT NULL DEFAULT CURRENT_DATE,
+    due_at date,
+    returned_at date,
+    notes text NOT NULL DEFAULT '',
+    reminded_at timestamp(0) with time zone,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    deleted_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_loans_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE CASCADE,
+    CONSTRAINT fk_loans_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_loans_borrower FOREIGN KEY (borrower_id)
+        REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT chk_loans_borrower
+        CHECK (borrower_name <> '' OR borrower_id IS NOT NULL),
+    CONSTRAINT chk_loans_due_at CHECK (due_at >= lent_at),
+    CONSTRAINT chk_loans_returned_at CHECK (returned_at >= lent_at)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_open_loan_per_book
+    ON loans(book_id) WHERE returned_at IS NULL AND NOT deleted;
+
+CREATE INDEX IF NOT EXISTS idx_loans_open_due
+    ON loans(due_at) WHERE returned_at IS NULL AND NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS loans;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- acquisition_status tells how the user has the book, or had it. Prices are
+-- in currency, an ISO 4217 code, which is required along with a price.
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS acquisition_status text NOT NULL DEFAULT 'owned',
+    ADD COLUMN IF NOT EXISTS purchased_at date,
+    ADD COLUMN IF NOT EXISTS price numeric(12, 2),
+    ADD COLUMN IF NOT EXISTS currency text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS store text NOT NULL DEFAULT '',
+    ADD CONSTRAINT chk_books_acquisition_status
+        CHECK (acquisition_status IN ('owned', 'borrowed', 'library', 'wishlist', 'sold', 'donated')),
+    ADD CONSTRAINT chk_books_price CHECK (price >= 0),
+    ADD CONSTRAINT chk_books_currency CHECK (price IS NULL OR currency <> '');
+
+CREATE INDEX IF NOT EXISTS idx_books_acquisition_status
+    ON books(user_id, acquisition_status) WHERE NOT deleted;
+
+CREATE INDEX IF NOT EXISTS idx_books_purchased_at
+    ON books(user_id, purchased_at) WHERE price IS NOT NULL AND NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_books_purchased_at;
+DROP INDEX IF EXISTS idx_books_acquisition_status;
+
+ALTER TABLE books
+    DROP CONSTRAINT IF EXISTS chk_books_currency,
+    DROP CONSTRAINT IF EXISTS chk_books_price,
+    DROP CONSTRAINT IF EXISTS chk_books_acquisition_status,
+    DROP COLUMN IF EXISTS store,
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS price,
+    DROP COLUMN IF EXISTS purchased_at,
+    DROP COLUMN IF EXISTS acquisition_status;
+-- +goose StatementEnd
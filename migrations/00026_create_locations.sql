@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+-- locations is where the user keeps books, as a hierarchy of houses, rooms,
+-- bookcases and shelves. Books reference the location they are kept in.
+CREATE TABLE IF NOT EXISTS locations (
+    id bigserial PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    parent_id BIGINT,
+    name text NOT NULL,
+    kind text NOT NULL,
+
+    version integer NOT NULL DEFAULT 1,
+    deleted bool NOT NULL DEFAULT false,
+    deleted_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    created_by BIGINT,
+    updated_at timestamp(0) with time zone,
+    updated_by BIGINT,
+
+    CONSTRAINT fk_locations_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_locations_parent FOREIGN KEY (parent_id)
+        REFERENCES locations(id) ON DELETE CASCADE,
+    CONSTRAINT chk_locations_kind
+        CHECK (kind IN ('house', 'room', 'bookcase', 'shelf')),
+    CONSTRAINT chk_locations_parent CHECK (parent_id <> id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_location_name_per_parent
+    ON locations(user_id, coalesce(parent_id, 0), lower(name)) WHERE NOT deleted;
+
+CREATE INDEX IF NOT EXISTS idx_locations_parent ON locations(parent_id);
+
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS location_id BIGINT,
+    ADD CONSTRAINT fk_books_location FOREIGN KEY (location_id)
+        REFERENCES locations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_books_location
+    ON books(location_id) WHERE location_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_books_location;
+
+ALTER TABLE books
+    DROP CONSTRAINT IF EXISTS fk_books_location,
+    DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS locations;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- book_chapters is the table of contents of a book, numbered by position
+-- from 1. A book's chapters are always replaced as a whole, so sessions
+-- record the chapter reached by its number rather than by reference.
+CREATE TABLE IF NOT EXISTS book_chapters (
+    id bigserial PRIMARY KEY,
+    book_id BIGINT NOT NULL,
+    position integer NOT NULL,
+    title text NOT NULL,
+    start_page integer NOT NULL,
+    minutes integer,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fk_book_chapters_book FOREIGN KEY (book_id)
+        REFERENCES books(id) ON DELETE CASCADE,
+    CONSTRAINT unique_book_chapter_position UNIQUE (book_id, position),
+    CONSTRAINT chk_book_chapters_position CHECK (position > 0),
+    CONSTRAINT chk_book_chapters_start_page CHECK (start_page > 0),
+    CONSTRAINT chk_book_chapters_minutes CHECK (minutes > 0)
+);
+
+ALTER TABLE reading_sessions
+    ADD COLUMN IF NOT EXISTS chapter integer,
+    ADD CONSTRAINT chk_reading_sessions_chapter CHECK (chapter > 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE reading_sessions
+    DROP CONSTRAINT IF EXISTS chk_reading_sessions_chapter,
+    DROP COLUMN IF EXISTS chapter;
+
+DROP TABLE IF EXISTS book_chapters;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- borrower_email is the email the lender gave for the borrower. The loan is
+-- linked to the user with that email, if any, without telling the lender.
+-- Loans linked before without a borrower name get the email as their name.
+ALTER TABLE loans
+    ADD COLUMN IF NOT EXISTS borrower_email text NOT NULL DEFAULT '';
+
+UPDATE loans l
+SET borrower_email = u.email
+FROM users u
+WHERE u.id = l.borrower_id;
+
+UPDATE loans
+SET borrower_name = borrower_email
+WHERE borrower_name = '' AND borrower_email <> '';
+
+ALTER TABLE loans
+    DROP CONSTRAINT IF EXISTS chk_loans_borrower,
+    ADD CONSTRAINT chk_loans_borrower CHECK (borrower_name <> '');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE loans
+    DROP CONSTRAINT IF EXISTS chk_loans_borrower,
+    ADD CONSTRAINT chk_loans_borrower
+        CHECK (borrower_name <> '' OR borrower_id IS NOT NULL),
+    DROP COLUMN IF EXISTS borrower_email;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- cod_sent_at is when the current activation code was sent, so a new one is
+-- not sent again right away.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS cod_sent_at timestamptz;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN IF EXISTS cod_sent_at;
+-- +goose StatementEnd
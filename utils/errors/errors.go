@@ -27,12 +27,26 @@ var (
 	ErrStartDateAfterEndDate = errors.New("start date must be before end date")
 	ErrInvalidRole           = errors.New("invalid role")
 	ErrScanModel             = errors.New("dest must be a pointer")
+	ErrPayloadTooLarge       = errors.New("payload too large")
 
 	ErrDuplicateEmail = ValidationFieldError{"email", "a register with this email address already exists"}
 	ErrDuplicateName  = ValidationFieldError{"name", "a register with this name already exists"}
 	ErrDuplicatePhone = ValidationFieldError{"phone", "a register with this phone number already exists"}
+	ErrInvalidPhone   = ValidationFieldError{"phone", "must be a valid phone number"}
 	ErrBookPages      = ValidationFieldError{"pages", "pages must be a positive number"}
 	ErrBookTitle      = ValidationFieldError{"title", "book with this title already exists for this user"}
+	ErrBookISBN       = ValidationFieldError{"isbn", "book with this ISBN already exists for this user"}
+	ErrBookDuration   = ValidationFieldError{"durationMinutes", "must only be provided for audiobooks"}
+	ErrAuthorNotFound = ValidationFieldError{"authors", "author not found"}
+	ErrSeriesNotFound = ValidationFieldError{"series", "series not found"}
+	ErrSeriesPosition = ValidationFieldError{"series", "position must be greater than zero"}
+	ErrReviewExists   = ValidationFieldError{"review", "this book has already been reviewed"}
+	ErrReviewRating   = ValidationFieldError{"rating", "must be between 0.5 and 5 in steps of 0.5"}
+	ErrBookFileExists = ValidationFieldError{"file", "this file has already been uploaded"}
+	ErrDuplicateUser  = ValidationFieldError{"username", "a register with this username already exists"}
+	ErrBookOnLoan     = ValidationFieldError{"book", "this book is already on loan"}
+	ErrLoanDueAt      = ValidationFieldError{"dueAt", "must not be before lentAt"}
+	ErrLoanReturnedAt = ValidationFieldError{"returnedAt", "must not be before lentAt"}
 )
 
 type errorResponse struct {
@@ -53,6 +67,7 @@ type ErrorResponseInterface interface {
 	BadRequestResponse(w http.ResponseWriter, r *http.Request, err error)
 	FailedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string)
 	EditConflictResponse(w http.ResponseWriter, r *http.Request)
+	PayloadTooLargeResponse(w http.ResponseWriter, r *http.Request)
 	HandlerErrorResponse(w http.ResponseWriter, r *http.Request, err error, v *validator.Validator)
 }
 
@@ -84,6 +99,9 @@ func (e *errorResponse) HandlerErrorResponse(w http.ResponseWriter, r *http.Requ
 	case errors.Is(err, ErrInvalidRole):
 		e.InvalidRoleResponse(w, r)
 
+	case errors.Is(err, ErrPayloadTooLarge):
+		e.PayloadTooLargeResponse(w, r)
+
 	default:
 		e.ServerErrorResponse(w, r, err)
 	}
@@ -153,6 +171,11 @@ func (e *errorResponse) EditConflictResponse(w http.ResponseWriter, r *http.Requ
 	e.errorResponse(w, r, http.StatusConflict, message)
 }
 
+func (e *errorResponse) PayloadTooLargeResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the request body is too large"
+	e.errorResponse(w, r, http.StatusRequestEntityTooLarge, message)
+}
+
 func (e *errorResponse) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	env := utils.Envelope{"error": message}
 	err := utils.WriteJSON(w, status, env, nil)
@@ -0,0 +1,125 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image dimensions are too large")
+)
+
+// MaxPixels bounds the decoded size of an image so small files cannot expand
+// into huge bitmaps.
+const MaxPixels = 40_000_000
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Sniff detects the type of an image from its content, ignoring whatever the
+// client claimed, and returns the content type and file extension.
+func Sniff(data []byte) (contentType, ext string, err error) {
+	contentType = http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return "", "", ErrUnsupportedFormat
+	}
+	return contentType, ext, nil
+}
+
+// Decode decodes a JPEG, PNG or GIF image after checking its dimensions.
+func Decode(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	return img, nil
+}
+
+// Thumbnail scales img to exactly width by height, cropping the centre when
+// the aspect ratios differ, and encodes it as JPEG.
+func Thumbnail(img image.Image, width, height int) ([]byte, error) {
+	src := cropToRatio(img.Bounds(), width, height)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	scale(dst, img, src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// cropToRatio returns the largest centred rectangle of bounds with the aspect
+// ratio width:height.
+func cropToRatio(bounds image.Rectangle, width, height int) image.Rectangle {
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if w*height > h*width {
+		cw := h * width / height
+		x := bounds.Min.X + (w-cw)/2
+		return image.Rect(x, bounds.Min.Y, x+cw, bounds.Max.Y)
+	}
+
+	ch := w * height / width
+	y := bounds.Min.Y + (h-ch)/2
+	return image.Rect(bounds.Min.X, y, bounds.Max.X, y+ch)
+}
+
+// scale resamples the src region of img into dst by averaging the source
+// pixels that fall into each destination pixel. Transparent areas are
+// flattened onto white, since JPEG has no alpha channel.
+func scale(dst *image.RGBA, img image.Image, src image.Rectangle) {
+	dw, dh := dst.Bounds().Dx(), dst.Bounds().Dy()
+	sw, sh := src.Dx(), src.Dy()
+
+	for y := 0; y < dh; y++ {
+		y0 := src.Min.Y + y*sh/dh
+		y1 := max(src.Min.Y+(y+1)*sh/dh, y0+1)
+
+		for x := 0; x < dw; x++ {
+			x0 := src.Min.X + x*sw/dw
+			x1 := max(src.Min.X+(x+1)*sw/dw, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			// Colours are alpha-premultiplied, so adding the uncovered share
+			// of white composites the pixel over a white background.
+			white := 0xffff - a/n
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r/n + white),
+				G: uint16(g/n + white),
+				B: uint16(b/n + white),
+				A: 0xffff,
+			})
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidISBN = errors.New("invalid ISBN")
+
+// Normalize parses an ISBN-10 or ISBN-13, ignoring hyphens and spaces, and
+// returns it in ISBN-13 form.
+func Normalize(raw string) (string, error) {
+	s := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(raw)))
+
+	switch {
+	case Valid13(s):
+		return s, nil
+	case Valid10(s):
+		return To13(s)
+	}
+
+	return "", ErrInvalidISBN
+}
+
+// Valid10 reports whether s is a ten character ISBN with a correct check
+// digit. The check digit may be X, standing for ten.
+func Valid10(s string) bool {
+	if len(s) != 10 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 10; i++ {
+		c := s[i]
+		var d int
+		switch {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case c == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += d * (10 - i)
+	}
+
+	return sum%11 == 0
+}
+
+// Valid13 reports whether s is a thirteen digit ISBN with a 978 or 979
+// prefix and a correct check digit.
+func Valid13(s string) bool {
+	if len(s) != 13 || !digits(s) {
+		return false
+	}
+
+	if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+		return false
+	}
+
+	return checkDigit13(s[:12]) == s[12]
+}
+
+// To13 converts a valid ISBN-10 into its ISBN-13 form.
+func To13(isbn10 string) (string, error) {
+	if !Valid10(isbn10) {
+		return "", ErrInvalidISBN
+	}
+
+	body := "978" + isbn10[:9]
+	return body + string(checkDigit13(body)), nil
+}
+
+// To10 converts an ISBN-13 into its ISBN-10 form. Only 978-prefixed numbers
+// have an ISBN-10 equivalent.
+func To10(isbn13 string) (string, error) {
+	if !Valid13(isbn13) || !strings.HasPrefix(isbn13, "978") {
+		return "", ErrInvalidISBN
+	}
+
+	body := isbn13[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X", nil
+	}
+
+	return body + string(rune('0'+check)), nil
+}
+
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(body[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+
+	return byte('0' + (10-sum%10)%10)
+}
+
+func digits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package phone
+
+import (
+	"bookwise/utils/validator"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// Normalize parses a free-text phone number into E.164 form. Numbers written
+// without an international prefix ("+" or "00") are assumed to belong to
+// defaultCountryCode, with any leading trunk zeros dropped.
+func Normalize(raw, defaultCountryCode string) (string, error) {
+	s := strings.TrimSpace(raw)
+	international := false
+
+	switch {
+	case strings.HasPrefix(s, "+"):
+		international = true
+		s = s[1:]
+	case strings.HasPrefix(s, "00"):
+		international = true
+		s = s[2:]
+	}
+
+	var digits strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" -.()/", r):
+			continue
+		default:
+			return "", ErrInvalidNumber
+		}
+	}
+
+	number := digits.String()
+	if !international {
+		number = defaultCountryCode + strings.TrimLeft(number, "0")
+	}
+
+	number = "+" + number
+	if !validator.Matches(number, validator.PhoneRX) {
+		return "", ErrInvalidNumber
+	}
+
+	return number, nil
+}
@@ -173,6 +173,21 @@ func ReadDate(qs url.Values, key string, layout string) *time.Time {
 	return &t
 }
 
+// ReadDateIn parses the date as a wall-clock time in loc, so "2024-03-01"
+// means midnight in the user's time zone rather than in UTC.
+func ReadDateIn(qs url.Values, key string, layout string, loc *time.Location) *time.Time {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
 func ReadInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
 	s := qs.Get(key)
 
@@ -188,6 +203,56 @@ func ReadInt(qs url.Values, key string, defaultValue int, v *validator.Validator
 	return i
 }
 
+// ReadFloat reads an optional decimal number, returning nil when the key is
+// absent.
+func ReadFloat(qs url.Values, key string, v *validator.Validator) *float64 {
+	s := qs.Get(key)
+	if s == "" {
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		v.AddError(key, "must be a number")
+		return nil
+	}
+	return &f
+}
+
+// ReadIntList reads a comma separated list of integers such as "1,2,3".
+func ReadIntList(qs url.Values, key string, v *validator.Validator) []int64 {
+	s := qs.Get(key)
+	if s == "" {
+		return []int64{}
+	}
+
+	parts := strings.Split(s, ",")
+	values := make([]int64, 0, len(parts))
+
+	for _, part := range parts {
+		i, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			v.AddError(key, "must be a comma separated list of integers")
+			return []int64{}
+		}
+		values = append(values, i)
+	}
+
+	return values
+}
+
+// ReadStringList reads a comma separated list of values such as "a,b,c",
+// skipping empty ones.
+func ReadStringList(qs url.Values, key string) []string {
+	values := []string{}
+	for _, part := range strings.Split(qs.Get(key), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
 func ReadJSON(
 	w http.ResponseWriter,
 	r *http.Request,